@@ -195,6 +195,10 @@ export const useRetrospectiveStore = create<RetrospectiveState>((set, get) => ({
       case 'actionItemUpdated':
         state.updateActionItem(event.actionItem);
         break;
+
+      case 'actionItemDeleted':
+        state.removeActionItem(event.actionItemId);
+        break;
     }
   },
 
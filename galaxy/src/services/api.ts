@@ -102,6 +102,18 @@ const mockUser = {
 
 const API_BASE = '/api/retrospective/v1';
 
+// Bearer token sent with every request; the backend rejects requests without one
+let authToken: string | undefined;
+
+// Sets the token of the signed-in user, or clears it on sign-out
+export function setAuthToken(token: string | undefined) {
+  authToken = token;
+}
+
+function authHeaders(): Record<string, string> {
+  return authToken ? { Authorization: `Bearer ${authToken}` } : {};
+}
+
 // Helper for making API requests
 async function apiRequest<T>(
   endpoint: string,
@@ -111,6 +123,7 @@ async function apiRequest<T>(
     ...options,
     headers: {
       'Content-Type': 'application/json',
+      ...authHeaders(),
       ...options.headers,
     },
   });
@@ -320,7 +333,8 @@ export const retrospectiveService = {
       return new Blob([content], { type: 'text/plain' });
     }
     const response = await fetch(
-      `${API_BASE}/retrospectives/${retrospectiveId}/export?format=${format}`
+      `${API_BASE}/retrospectives/${retrospectiveId}/export?format=${format}`,
+      { headers: authHeaders() }
     );
     return response.blob();
   },
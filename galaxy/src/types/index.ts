@@ -152,4 +152,5 @@ export type RetrospectiveEvent =
   | { type: 'participantLeft'; userId: string; participantCount: number }
   | { type: 'statusChanged'; previousStatus: RetrospectiveStatus; newStatus: RetrospectiveStatus; changedBy: string }
   | { type: 'actionItemCreated'; actionItem: ActionItem }
-  | { type: 'actionItemUpdated'; actionItem: ActionItem };
+  | { type: 'actionItemUpdated'; actionItem: ActionItem }
+  | { type: 'actionItemDeleted'; actionItemId: string };
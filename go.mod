@@ -3,8 +3,9 @@ module github.com/vendasta/retrospective
 go 1.21
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/vendasta/generated-protos-go v0.0.0
-	github.com/vendasta/gosdks v0.0.0
+	go.etcd.io/bbolt v1.3.10
 	google.golang.org/grpc v1.60.0
 	google.golang.org/protobuf v1.32.0
 )
@@ -14,18 +14,21 @@ import (
 // ActionItemService implements the ActionItemService gRPC service
 type ActionItemService struct {
 	pb.UnimplementedActionItemServiceServer
-	actionItemStore *InMemoryActionItemStore
-	retroStore      *InMemoryRetrospectiveStore
+	actionItemStore ActionItemStore
+	retroStore      RetrospectiveStore
+	teamStore       TeamStore
 }
 
 // NewActionItemService creates a new ActionItemService
 func NewActionItemService(
-	actionItemStore *InMemoryActionItemStore,
-	retroStore *InMemoryRetrospectiveStore,
+	actionItemStore ActionItemStore,
+	retroStore RetrospectiveStore,
+	teamStore TeamStore,
 ) *ActionItemService {
 	return &ActionItemService{
 		actionItemStore: actionItemStore,
 		retroStore:      retroStore,
+		teamStore:       teamStore,
 	}
 }
 
@@ -52,6 +55,16 @@ func (s *ActionItemService) Create(ctx context.Context, req *pb.CreateActionItem
 	if teamID == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
 	}
+	if retro != nil && retro.TeamID != teamID {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective belongs to a different team", ErrInvalidArgument))
+	}
+	member, err := requireTeamMember(ctx, s.teamStore, teamID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireTeamWriter(member); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Generate ID
 	actionItemID := fmt.Sprintf("ACTION-%d", time.Now().UnixNano())
@@ -63,7 +76,7 @@ func (s *ActionItemService) Create(ctx context.Context, req *pb.CreateActionItem
 		TeamID:          teamID,
 		Description:     req.Description,
 		AssigneeID:      req.AssigneeId,
-		AssigneeName:    getAssigneeName(req.AssigneeId),
+		AssigneeName:    s.getAssigneeName(teamID, req.AssigneeId),
 		Status:          vstore.ActionItemStatusNotStarted,
 		Priority:        vstore.ActionItemPriority(req.Priority),
 		CreatedBy:       getUserIDFromContext(ctx),
@@ -92,8 +105,13 @@ func (s *ActionItemService) Create(ctx context.Context, req *pb.CreateActionItem
 		s.retroStore.Update(retro)
 	}
 
+	pbActionItem := convertVstoreActionItemToPb(actionItem)
+	if actionItem.RetrospectiveID != "" {
+		BroadcastActionItemCreated(actionItem.RetrospectiveID, pbActionItem)
+	}
+
 	return &pb.CreateActionItemResponse{
-		ActionItem: convertVstoreActionItemToPb(actionItem),
+		ActionItem: pbActionItem,
 	}, nil
 }
 
@@ -107,6 +125,9 @@ func (s *ActionItemService) Update(ctx context.Context, req *pb.UpdateActionItem
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := s.requireWriter(ctx, existing.TeamID); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Update allowed fields based on field mask (simplified)
 	if req.ActionItem.Description != "" {
@@ -114,7 +135,7 @@ func (s *ActionItemService) Update(ctx context.Context, req *pb.UpdateActionItem
 	}
 	if req.ActionItem.AssigneeId != "" {
 		existing.AssigneeID = req.ActionItem.AssigneeId
-		existing.AssigneeName = getAssigneeName(req.ActionItem.AssigneeId)
+		existing.AssigneeName = s.getAssigneeName(existing.TeamID, req.ActionItem.AssigneeId)
 	}
 	if req.ActionItem.Status != pb.ActionItemStatus_ACTION_ITEM_STATUS_UNSPECIFIED {
 		existing.Status = vstore.ActionItemStatus(req.ActionItem.Status)
@@ -133,6 +154,10 @@ func (s *ActionItemService) Update(ctx context.Context, req *pb.UpdateActionItem
 		return nil, ToGRPCError(err)
 	}
 
+	if existing.RetrospectiveID != "" {
+		BroadcastActionItemUpdated(existing.RetrospectiveID, convertVstoreActionItemToPb(existing))
+	}
+
 	return &emptypb.Empty{}, nil
 }
 
@@ -146,6 +171,9 @@ func (s *ActionItemService) UpdateStatus(ctx context.Context, req *pb.UpdateActi
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := s.requireWriter(ctx, existing.TeamID); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	existing.Status = vstore.ActionItemStatus(req.Status)
 	if req.Notes != "" {
@@ -156,6 +184,10 @@ func (s *ActionItemService) UpdateStatus(ctx context.Context, req *pb.UpdateActi
 		return nil, ToGRPCError(err)
 	}
 
+	if existing.RetrospectiveID != "" {
+		BroadcastActionItemUpdated(existing.RetrospectiveID, convertVstoreActionItemToPb(existing))
+	}
+
 	return &emptypb.Empty{}, nil
 }
 
@@ -170,6 +202,9 @@ func (s *ActionItemService) Delete(ctx context.Context, req *pb.DeleteActionItem
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := s.requireWriter(ctx, actionItem.TeamID); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	if err := s.actionItemStore.Delete(req.ActionItemId); err != nil {
 		return nil, ToGRPCError(err)
@@ -182,6 +217,7 @@ func (s *ActionItemService) Delete(ctx context.Context, req *pb.DeleteActionItem
 			retro.ActionItemCount--
 			s.retroStore.Update(retro)
 		}
+		BroadcastActionItemDeleted(actionItem.RetrospectiveID, actionItem.ActionItemID)
 	}
 
 	return &emptypb.Empty{}, nil
@@ -193,9 +229,19 @@ func (s *ActionItemService) List(ctx context.Context, req *pb.ListActionItemsReq
 	var err error
 
 	if req.Filters != nil && req.Filters.RetrospectiveId != "" {
+		if _, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.Filters.RetrospectiveId); err != nil {
+			return nil, ToGRPCError(err)
+		}
 		actionItems, err = s.actionItemStore.ListByRetrospective(req.Filters.RetrospectiveId)
 	} else if req.Filters != nil && req.Filters.TeamId != "" {
-		actionItems, err = s.actionItemStore.ListByTeam(req.Filters.TeamId, true)
+		if _, err := requireTeamMember(ctx, s.teamStore, req.Filters.TeamId); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		if req.Filters.AssigneeId != "" {
+			actionItems, err = s.actionItemStore.ListByAssignee(req.Filters.TeamId, req.Filters.AssigneeId)
+		} else {
+			actionItems, err = s.actionItemStore.ListByTeam(req.Filters.TeamId, true)
+		}
 	} else {
 		return nil, ToGRPCError(fmt.Errorf("%w: either retrospective_id or team_id filter is required", ErrInvalidArgument))
 	}
@@ -261,6 +307,9 @@ func (s *ActionItemService) ListByTeam(ctx context.Context, req *pb.ListActionIt
 	if req.TeamId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
 	}
+	if _, err := requireTeamMember(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	actionItems, err := s.actionItemStore.ListByTeam(req.TeamId, req.IncludeCompleted)
 	if err != nil {
@@ -280,10 +329,22 @@ func (s *ActionItemService) ListByTeam(ctx context.Context, req *pb.ListActionIt
 	}, nil
 }
 
-func getAssigneeName(assigneeID string) string {
-	// In production, look up user name from IAM
+// requireWriter rejects callers that are not members of teamID or only observe it
+func (s *ActionItemService) requireWriter(ctx context.Context, teamID string) error {
+	member, err := requireTeamMember(ctx, s.teamStore, teamID)
+	if err != nil {
+		return err
+	}
+	return requireTeamWriter(member)
+}
+
+// getAssigneeName returns the assignee's display name from the team roster
+func (s *ActionItemService) getAssigneeName(teamID, assigneeID string) string {
 	if assigneeID == "" {
 		return ""
 	}
+	if member, err := s.teamStore.GetMember(teamID, assigneeID); err == nil && member.DisplayName != "" {
+		return member.DisplayName
+	}
 	return "Team Member"
 }
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+)
+
+// Access scopes granted to callers in their bearer token
+const (
+	ScopeRead  = "retrospective:read"
+	ScopeWrite = "retrospective:write"
+)
+
+// ScopeMode controls what happens when a caller lacks the scope an RPC requires
+type ScopeMode string
+
+const (
+	// ScopeModeEnforce rejects the call with ErrPermissionDenied
+	ScopeModeEnforce ScopeMode = "enforce"
+	// ScopeModeAudit logs the missing scope and lets the call through
+	ScopeModeAudit ScopeMode = "audit"
+	// ScopeModeDisabled skips scope checks entirely
+	ScopeModeDisabled ScopeMode = "disabled"
+)
+
+// ParseScopeMode parses a ScopeMode, defaulting to ScopeModeEnforce when s is empty
+func ParseScopeMode(s string) (ScopeMode, error) {
+	switch mode := ScopeMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return ScopeModeEnforce, nil
+	case ScopeModeEnforce, ScopeModeAudit, ScopeModeDisabled:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unknown scope mode %q", ErrInvalidArgument, s)
+	}
+}
+
+// methodScopes maps each method of the registered services to the scope it
+// requires, keyed by service name and then method name. Methods of these
+// services that are missing here are denied.
+var methodScopes = map[string]map[string]string{
+	pb.RetrospectiveService_ServiceDesc.ServiceName: {
+		"Create":           ScopeWrite,
+		"Get":              ScopeRead,
+		"GetMulti":         ScopeRead,
+		"List":             ScopeRead,
+		"Update":           ScopeWrite,
+		"Delete":           ScopeWrite,
+		"Start":            ScopeWrite,
+		"StartVoting":      ScopeWrite,
+		"StartDiscussion":  ScopeWrite,
+		"Complete":         ScopeWrite,
+		"GoBack":           ScopeWrite,
+		"Reopen":           ScopeWrite,
+		"Archive":          ScopeWrite,
+		"GetPhaseHistory":  ScopeRead,
+		"StartTimer":       ScopeWrite,
+		"PauseTimer":       ScopeWrite,
+		"ResumeTimer":      ScopeWrite,
+		"ExtendTimer":      ScopeWrite,
+		"CancelTimer":      ScopeWrite,
+		"GetTimer":         ScopeRead,
+		"Export":           ScopeRead,
+		"Import":           ScopeWrite,
+		"ExportTeamReport": ScopeRead,
+	},
+	pb.RetrospectiveItemService_ServiceDesc.ServiceName: {
+		"Create":          ScopeWrite,
+		"Update":          ScopeWrite,
+		"Delete":          ScopeWrite,
+		"List":            ScopeRead,
+		"RevealItems":     ScopeWrite,
+		"MoveToColumn":    ScopeWrite,
+		"ReorderItem":     ScopeWrite,
+		"SuggestMerges":   ScopeRead,
+		"MergeItems":      ScopeWrite,
+		"CreateComment":   ScopeWrite,
+		"UpdateComment":   ScopeWrite,
+		"DeleteComment":   ScopeWrite,
+		"ListComments":    ScopeRead,
+		"AddReaction":     ScopeWrite,
+		"RemoveReaction":  ScopeWrite,
+		"CreateGroup":     ScopeWrite,
+		"UpdateGroup":     ScopeWrite,
+		"DeleteGroup":     ScopeWrite,
+		"ListGroups":      ScopeRead,
+		"AddToGroup":      ScopeWrite,
+		"RemoveFromGroup": ScopeWrite,
+	},
+	pb.VotingService_ServiceDesc.ServiceName: {
+		"CastVote":       ScopeWrite,
+		"RemoveVote":     ScopeWrite,
+		"GetVoteSummary": ScopeRead,
+		"GetUserVotes":   ScopeRead,
+		"RevealResults":  ScopeWrite,
+	},
+	pb.ActionItemService_ServiceDesc.ServiceName: {
+		"Create":       ScopeWrite,
+		"Update":       ScopeWrite,
+		"UpdateStatus": ScopeWrite,
+		"Delete":       ScopeWrite,
+		"List":         ScopeRead,
+		"ListByTeam":   ScopeRead,
+	},
+	// Presence only tracks who is watching the board, so read access is enough
+	pb.RealtimeService_ServiceDesc.ServiceName: {
+		"Subscribe":          ScopeRead,
+		"JoinRetrospective":  ScopeRead,
+		"LeaveRetrospective": ScopeRead,
+		"GetParticipants":    ScopeRead,
+		"Heartbeat":          ScopeRead,
+	},
+	pb.TemplateService_ServiceDesc.ServiceName: {
+		"GetDefaultTemplate": ScopeRead,
+	},
+	pb.TeamService_ServiceDesc.ServiceName: {
+		"Create":           ScopeWrite,
+		"Get":              ScopeRead,
+		"Update":           ScopeWrite,
+		"Delete":           ScopeWrite,
+		"List":             ScopeRead,
+		"AddMember":        ScopeWrite,
+		"UpdateMemberRole": ScopeWrite,
+		"RemoveMember":     ScopeWrite,
+		"ListMembers":      ScopeRead,
+	},
+}
+
+// requiredScope returns the scope needed to call fullMethod ("/service/method").
+// covered is false for methods outside the retrospective services.
+func requiredScope(fullMethod string) (scope string, covered bool) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	methods, ok := methodScopes[service]
+	if !ok {
+		return "", false
+	}
+	return methods[method], true
+}
+
+// authorize checks the caller's scopes against the scope fullMethod requires
+func authorize(ctx context.Context, mode ScopeMode, fullMethod string) error {
+	if mode == ScopeModeDisabled {
+		return nil
+	}
+	scope, covered := requiredScope(fullMethod)
+	if !covered {
+		return nil
+	}
+
+	var err error
+	if scope == "" {
+		err = fmt.Errorf("%w: %s has no access scope configured", ErrPermissionDenied, fullMethod)
+	} else if !hasScope(getScopesFromContext(ctx), scope) {
+		err = fmt.Errorf("%w: %s requires scope %s", ErrPermissionDenied, fullMethod, scope)
+	}
+	if err != nil && mode == ScopeModeAudit {
+		log.Printf("scope audit: user %q: %v", getUserIDFromContext(ctx), err)
+		return nil
+	}
+	return err
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeUnaryInterceptor enforces methodScopes on unary calls. It must run
+// after the auth interceptor that stores the caller's principal.
+func ScopeUnaryInterceptor(mode ScopeMode) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, mode, info.FullMethod); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ScopeStreamInterceptor is the streaming counterpart of ScopeUnaryInterceptor
+func ScopeStreamInterceptor(mode ScopeMode) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), mode, info.FullMethod); err != nil {
+			return ToGRPCError(err)
+		}
+		return handler(srv, ss)
+	}
+}
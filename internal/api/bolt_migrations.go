@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names used by the bolt stores. Index buckets hold composite keys
+// (see indexKey) with empty values and point back at the primary bucket.
+var (
+	bucketMeta                 = []byte("meta")
+	bucketRetrospectives       = []byte("retrospectives")
+	bucketRetrospectivesByTeam = []byte("retrospectives_by_team")
+	bucketItems                = []byte("items")
+	bucketItemsByRetrospective = []byte("items_by_retrospective")
+	bucketVotes                = []byte("votes")
+	bucketVotesByRetrospective = []byte("votes_by_retrospective")
+	bucketActionItems          = []byte("action_items")
+	bucketActionItemsByRetro   = []byte("action_items_by_retrospective")
+	bucketActionItemsByTeam    = []byte("action_items_by_team")
+	bucketParticipants         = []byte("participants")
+	bucketTeams                = []byte("teams")
+	bucketTeamMembers          = []byte("team_members")
+	bucketTeamMembersByUser    = []byte("team_members_by_user")
+	bucketPhaseTimers          = []byte("phase_timers")
+	bucketItemGroups           = []byte("item_groups")
+	bucketItemGroupsByRetro    = []byte("item_groups_by_retrospective")
+	bucketComments             = []byte("comments")
+	bucketCommentsByRetro      = []byte("comments_by_retrospective")
+	bucketCommentsByItem       = []byte("comments_by_item")
+	bucketReactions            = []byte("reactions")
+	schemaVersionKey           = []byte("schema_version")
+)
+
+// boltMigration upgrades the database file by one schema version
+type boltMigration struct {
+	version     uint64
+	description string
+	up          func(tx *bolt.Tx) error
+}
+
+// boltMigrations lists every schema version in order. Never edit an applied
+// migration; append a new one instead.
+var boltMigrations = []boltMigration{
+	{
+		version:     1,
+		description: "create entity and index buckets",
+		up: func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{
+				bucketRetrospectives, bucketRetrospectivesByTeam,
+				bucketItems, bucketItemsByRetrospective,
+				bucketVotes, bucketVotesByRetrospective,
+				bucketActionItems, bucketActionItemsByRetro, bucketActionItemsByTeam,
+				bucketParticipants,
+			} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     2,
+		description: "create team and team member buckets",
+		up: func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{bucketTeams, bucketTeamMembers, bucketTeamMembersByUser} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     3,
+		description: "create phase timer bucket",
+		up: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucketPhaseTimers)
+			return err
+		},
+	},
+	{
+		version:     4,
+		description: "create item group buckets",
+		up: func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{bucketItemGroups, bucketItemGroupsByRetro} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     5,
+		description: "create comment buckets",
+		up: func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{bucketComments, bucketCommentsByRetro, bucketCommentsByItem} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     6,
+		description: "create reaction bucket",
+		up: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucketReactions)
+			return err
+		},
+	},
+}
+
+// OpenBoltDB opens (creating if needed) the database file at path and
+// applies any pending schema migrations
+func OpenBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := migrateBoltDB(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrateBoltDB(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(bucketMeta)
+		if err != nil {
+			return err
+		}
+		var current uint64
+		if raw := meta.Get(schemaVersionKey); raw != nil {
+			current = binary.BigEndian.Uint64(raw)
+		}
+		latest := boltMigrations[len(boltMigrations)-1].version
+		if current > latest {
+			return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+		}
+		for _, m := range boltMigrations {
+			if m.version <= current {
+				continue
+			}
+			if err := m.up(tx); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+			}
+			current = m.version
+			log.Printf("Applied database migration %d: %s", m.version, m.description)
+		}
+		raw := make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, current)
+		return meta.Put(schemaVersionKey, raw)
+	})
+}
@@ -0,0 +1,1182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// NewBoltStores creates a set of stores backed by a bolt database opened with OpenBoltDB
+func NewBoltStores(db *bolt.DB) Stores {
+	return Stores{
+		Retrospectives: NewBoltRetrospectiveStore(db),
+		Items:          NewBoltItemStore(db),
+		Votes:          NewBoltVoteStore(db),
+		ActionItems:    NewBoltActionItemStore(db),
+		Participants:   NewBoltParticipantStore(db),
+		Teams:          NewBoltTeamStore(db),
+		PhaseTimers:    NewBoltPhaseTimerStore(db),
+		ItemGroups:     NewBoltItemGroupStore(db),
+		Comments:       NewBoltCommentStore(db),
+		Reactions:      NewBoltReactionStore(db),
+	}
+}
+
+var (
+	_ RetrospectiveStore = (*BoltRetrospectiveStore)(nil)
+	_ ItemStore          = (*BoltItemStore)(nil)
+	_ VoteStore          = (*BoltVoteStore)(nil)
+	_ ActionItemStore    = (*BoltActionItemStore)(nil)
+	_ ParticipantStore   = (*BoltParticipantStore)(nil)
+	_ TeamStore          = (*BoltTeamStore)(nil)
+	_ PhaseTimerStore    = (*BoltPhaseTimerStore)(nil)
+	_ ItemGroupStore     = (*BoltItemGroupStore)(nil)
+	_ CommentStore       = (*BoltCommentStore)(nil)
+	_ ReactionStore      = (*BoltReactionStore)(nil)
+)
+
+// BoltRetrospectiveStore provides bolt-backed storage for retrospectives
+type BoltRetrospectiveStore struct {
+	db *bolt.DB
+}
+
+func NewBoltRetrospectiveStore(db *bolt.DB) *BoltRetrospectiveStore {
+	return &BoltRetrospectiveStore{db: db}
+}
+
+func (s *BoltRetrospectiveStore) Create(retro *vstore.Retrospective) error {
+	retro.Created = time.Now()
+	retro.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, retro) })
+}
+
+func (s *BoltRetrospectiveStore) Get(id string) (*vstore.Retrospective, error) {
+	retro := &vstore.Retrospective{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketRetrospectives), []byte(id), retro)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retro, nil
+}
+
+func (s *BoltRetrospectiveStore) Update(retro *vstore.Retrospective) error {
+	retro.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, retro) })
+}
+
+func (s *BoltRetrospectiveStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.Retrospective{}
+		if err := getRecord(tx.Bucket(bucketRetrospectives), []byte(id), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		if err := tx.Bucket(bucketRetrospectivesByTeam).Delete(indexKey(existing.TeamID, id)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketRetrospectives).Delete([]byte(id))
+	})
+}
+
+func (s *BoltRetrospectiveStore) put(tx *bolt.Tx, retro *vstore.Retrospective) error {
+	id := retro.RetrospectiveID
+	existing := &vstore.Retrospective{}
+	if err := getRecord(tx.Bucket(bucketRetrospectives), []byte(id), existing); err == nil {
+		if err := tx.Bucket(bucketRetrospectivesByTeam).Delete(indexKey(existing.TeamID, id)); err != nil {
+			return err
+		}
+	}
+	if err := putRecord(tx.Bucket(bucketRetrospectives), []byte(id), retro); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketRetrospectivesByTeam).Put(indexKey(retro.TeamID, id), nil)
+}
+
+func (s *BoltRetrospectiveStore) List(teamID string, statuses []vstore.RetrospectiveStatus, cursor string, pageSize int) ([]*vstore.Retrospective, string, bool, error) {
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			return nil, "", false, fmt.Errorf("%w: invalid cursor", ErrInvalidArgument)
+		}
+	}
+
+	var retros []*vstore.Retrospective
+	err := s.db.View(func(tx *bolt.Tx) error {
+		keep := func(retro *vstore.Retrospective) {
+			if len(statuses) > 0 && !containsStatus(statuses, retro.Status) {
+				return
+			}
+			retros = append(retros, retro)
+		}
+		if teamID == "" {
+			return tx.Bucket(bucketRetrospectives).ForEach(func(_, v []byte) error {
+				retro := &vstore.Retrospective{}
+				if err := decodeRecord(v, retro); err != nil {
+					return err
+				}
+				keep(retro)
+				return nil
+			})
+		}
+		return forEachIndexed(tx.Bucket(bucketRetrospectivesByTeam), tx.Bucket(bucketRetrospectives), teamID, func(v []byte) error {
+			retro := &vstore.Retrospective{}
+			if err := decodeRecord(v, retro); err != nil {
+				return err
+			}
+			keep(retro)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	sort.SliceStable(retros, func(i, j int) bool {
+		return retros[i].Created.After(retros[j].Created)
+	})
+	if offset > len(retros) {
+		offset = len(retros)
+	}
+	retros = retros[offset:]
+	if len(retros) > pageSize {
+		return retros[:pageSize], strconv.Itoa(offset + pageSize), true, nil
+	}
+	return retros, "", false, nil
+}
+
+// BoltItemStore provides bolt-backed storage for retrospective items
+type BoltItemStore struct {
+	db *bolt.DB
+}
+
+func NewBoltItemStore(db *bolt.DB) *BoltItemStore {
+	return &BoltItemStore{db: db}
+}
+
+func (s *BoltItemStore) Create(item *vstore.RetrospectiveItem) error {
+	item.Created = time.Now()
+	item.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, item) })
+}
+
+func (s *BoltItemStore) Get(id string) (*vstore.RetrospectiveItem, error) {
+	item := &vstore.RetrospectiveItem{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketItems), []byte(id), item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *BoltItemStore) Update(item *vstore.RetrospectiveItem) error {
+	item.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.RetrospectiveItem{}
+		if err := getRecord(tx.Bucket(bucketItems), []byte(item.ItemID), existing); err == nil {
+			item.VoteCount = existing.VoteCount
+		}
+		return s.put(tx, item)
+	})
+}
+
+func (s *BoltItemStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.RetrospectiveItem{}
+		if err := getRecord(tx.Bucket(bucketItems), []byte(id), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		if err := tx.Bucket(bucketItemsByRetrospective).Delete(indexKey(existing.RetrospectiveID, id)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketItems).Delete([]byte(id))
+	})
+}
+
+func (s *BoltItemStore) put(tx *bolt.Tx, item *vstore.RetrospectiveItem) error {
+	id := item.ItemID
+	existing := &vstore.RetrospectiveItem{}
+	if err := getRecord(tx.Bucket(bucketItems), []byte(id), existing); err == nil {
+		if err := tx.Bucket(bucketItemsByRetrospective).Delete(indexKey(existing.RetrospectiveID, id)); err != nil {
+			return err
+		}
+	}
+	if err := putRecord(tx.Bucket(bucketItems), []byte(id), item); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketItemsByRetrospective).Put(indexKey(item.RetrospectiveID, id), nil)
+}
+
+func (s *BoltItemStore) ListByRetrospective(retroID string, columnID string, sortByVotes bool) ([]*vstore.RetrospectiveItem, error) {
+	var results []*vstore.RetrospectiveItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIndexed(tx.Bucket(bucketItemsByRetrospective), tx.Bucket(bucketItems), retroID, func(v []byte) error {
+			item := &vstore.RetrospectiveItem{}
+			if err := decodeRecord(v, item); err != nil {
+				return err
+			}
+			if columnID != "" && item.ColumnID != columnID {
+				return nil
+			}
+			results = append(results, item)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortItems(results, sortByVotes)
+	return results, nil
+}
+
+func (s *BoltItemStore) IncrementVoteCount(itemID string) error {
+	return s.adjustVoteCount(itemID, 1)
+}
+
+func (s *BoltItemStore) DecrementVoteCount(itemID string) error {
+	return s.adjustVoteCount(itemID, -1)
+}
+
+// adjustVoteCount reads and writes the item in one transaction so concurrent
+// votes never lose updates
+func (s *BoltItemStore) adjustVoteCount(itemID string, delta int32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		item := &vstore.RetrospectiveItem{}
+		if err := getRecord(tx.Bucket(bucketItems), []byte(itemID), item); err != nil {
+			return err
+		}
+		item.VoteCount += delta
+		if item.VoteCount < 0 {
+			item.VoteCount = 0
+		}
+		item.Updated = time.Now()
+		return putRecord(tx.Bucket(bucketItems), []byte(itemID), item)
+	})
+}
+
+// BoltVoteStore provides bolt-backed storage for votes
+type BoltVoteStore struct {
+	db *bolt.DB
+}
+
+func NewBoltVoteStore(db *bolt.DB) *BoltVoteStore {
+	return &BoltVoteStore{db: db}
+}
+
+// Cast runs the limit check and both writes in one bolt transaction
+func (s *BoltVoteStore) Cast(vote *vstore.Vote, limits VoteLimits) (int32, error) {
+	var count int32
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketVotes).Get([]byte(vote.VoteID)) != nil {
+			return fmt.Errorf("%w: vote %s", ErrAlreadyExists, vote.VoteID)
+		}
+		userVotes, err := s.listTx(tx, vote.RetrospectiveID, func(v *vstore.Vote) bool { return v.UserID == vote.UserID })
+		if err != nil {
+			return err
+		}
+		replaced, err := limits.check(userVotes, vote)
+		if err != nil {
+			return err
+		}
+
+		delta := voteWeight(vote)
+		if replaced != nil {
+			if err := s.delete(tx, replaced); err != nil {
+				return err
+			}
+			delta -= voteWeight(replaced)
+		}
+		vote.Created = time.Now()
+		if err := s.put(tx, vote); err != nil {
+			return err
+		}
+		// A missing item or group fails the transaction, undoing the writes above
+		count, err = adjustVoteTargetTx(tx, vote.RetrospectiveID, vote.ItemID, vote.GroupVote, delta)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Retract deletes the vote and decrements the item in one bolt transaction
+func (s *BoltVoteStore) Retract(retroID, itemID, userID string) (int32, error) {
+	var count int32
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		votes, err := s.listTx(tx, retroID, func(v *vstore.Vote) bool {
+			return v.ItemID == itemID && v.UserID == userID
+		})
+		if err != nil {
+			return err
+		}
+		if len(votes) == 0 {
+			return errNoVote
+		}
+		if err := s.delete(tx, votes[0]); err != nil {
+			return err
+		}
+		count, err = adjustVoteTargetTx(tx, retroID, itemID, votes[0].GroupVote, -voteWeight(votes[0]))
+		return ignoreNotFound(err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MoveVotes re-points the votes and moves their weight in one bolt transaction
+func (s *BoltVoteStore) MoveVotes(retroID, fromItemID, toItemID string) (int32, error) {
+	var count int32
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		to := &vstore.RetrospectiveItem{}
+		if err := getRecord(tx.Bucket(bucketItems), []byte(toItemID), to); err != nil {
+			return err
+		}
+		if to.RetrospectiveID != retroID {
+			return ErrNotFound
+		}
+		votes, err := s.listTx(tx, retroID, func(v *vstore.Vote) bool {
+			return v.ItemID == fromItemID && !v.GroupVote
+		})
+		if err != nil {
+			return err
+		}
+		var moved int32
+		for _, vote := range votes {
+			vote.ItemID = toItemID
+			vote.ColumnID = to.ColumnID
+			if err := s.put(tx, vote); err != nil {
+				return err
+			}
+			moved += voteWeight(vote)
+		}
+		if _, err := adjustVoteTargetTx(tx, retroID, fromItemID, false, -moved); err != nil {
+			return err
+		}
+		count, err = adjustVoteTargetTx(tx, retroID, toItemID, false, moved)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// adjustVoteTargetTx adds delta to the VoteCount of the item, or the group,
+// a vote is for, returning the new count
+func adjustVoteTargetTx(tx *bolt.Tx, retroID, id string, group bool, delta int32) (int32, error) {
+	if group {
+		g := &vstore.ItemGroup{}
+		if err := getRecord(tx.Bucket(bucketItemGroups), []byte(id), g); err != nil {
+			return 0, err
+		}
+		if g.RetrospectiveID != retroID {
+			return 0, ErrNotFound
+		}
+		g.VoteCount += delta
+		if g.VoteCount < 0 {
+			g.VoteCount = 0
+		}
+		g.Updated = time.Now()
+		return g.VoteCount, putRecord(tx.Bucket(bucketItemGroups), []byte(id), g)
+	}
+	item := &vstore.RetrospectiveItem{}
+	if err := getRecord(tx.Bucket(bucketItems), []byte(id), item); err != nil {
+		return 0, err
+	}
+	if item.RetrospectiveID != retroID {
+		return 0, ErrNotFound
+	}
+	item.VoteCount += delta
+	if item.VoteCount < 0 {
+		item.VoteCount = 0
+	}
+	item.Updated = time.Now()
+	return item.VoteCount, putRecord(tx.Bucket(bucketItems), []byte(id), item)
+}
+
+func (s *BoltVoteStore) Create(vote *vstore.Vote) error {
+	vote.Created = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, vote) })
+}
+
+func (s *BoltVoteStore) Delete(voteID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.Vote{}
+		if err := getRecord(tx.Bucket(bucketVotes), []byte(voteID), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		return s.delete(tx, existing)
+	})
+}
+
+func (s *BoltVoteStore) put(tx *bolt.Tx, vote *vstore.Vote) error {
+	if err := putRecord(tx.Bucket(bucketVotes), []byte(vote.VoteID), vote); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketVotesByRetrospective).Put(indexKey(vote.RetrospectiveID, vote.VoteID), nil)
+}
+
+func (s *BoltVoteStore) delete(tx *bolt.Tx, vote *vstore.Vote) error {
+	if err := tx.Bucket(bucketVotesByRetrospective).Delete(indexKey(vote.RetrospectiveID, vote.VoteID)); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketVotes).Delete([]byte(vote.VoteID))
+}
+
+func (s *BoltVoteStore) GetByUserAndItem(retroID, itemID, userID string) (*vstore.Vote, error) {
+	votes, err := s.list(retroID, func(v *vstore.Vote) bool {
+		return v.ItemID == itemID && v.UserID == userID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) == 0 {
+		return nil, ErrNotFound
+	}
+	return votes[0], nil
+}
+
+func (s *BoltVoteStore) CountByUser(retroID, userID string) (int, error) {
+	votes, err := s.ListByUser(retroID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(votes), nil
+}
+
+func (s *BoltVoteStore) ListByUser(retroID, userID string) ([]*vstore.Vote, error) {
+	return s.list(retroID, func(v *vstore.Vote) bool { return v.UserID == userID })
+}
+
+func (s *BoltVoteStore) ListByItem(retroID, itemID string) ([]*vstore.Vote, error) {
+	return s.list(retroID, func(v *vstore.Vote) bool { return v.ItemID == itemID })
+}
+
+func (s *BoltVoteStore) list(retroID string, match func(*vstore.Vote) bool) ([]*vstore.Vote, error) {
+	var results []*vstore.Vote
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		results, err = s.listTx(tx, retroID, match)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *BoltVoteStore) listTx(tx *bolt.Tx, retroID string, match func(*vstore.Vote) bool) ([]*vstore.Vote, error) {
+	var results []*vstore.Vote
+	err := forEachIndexed(tx.Bucket(bucketVotesByRetrospective), tx.Bucket(bucketVotes), retroID, func(v []byte) error {
+		vote := &vstore.Vote{}
+		if err := decodeRecord(v, vote); err != nil {
+			return err
+		}
+		if match(vote) {
+			results = append(results, vote)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BoltActionItemStore provides bolt-backed storage for action items
+type BoltActionItemStore struct {
+	db *bolt.DB
+}
+
+func NewBoltActionItemStore(db *bolt.DB) *BoltActionItemStore {
+	return &BoltActionItemStore{db: db}
+}
+
+func (s *BoltActionItemStore) Create(item *vstore.ActionItem) error {
+	item.Created = time.Now()
+	item.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, item) })
+}
+
+func (s *BoltActionItemStore) Get(id string) (*vstore.ActionItem, error) {
+	item := &vstore.ActionItem{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketActionItems), []byte(id), item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *BoltActionItemStore) Update(item *vstore.ActionItem) error {
+	item.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, item) })
+}
+
+func (s *BoltActionItemStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.ActionItem{}
+		if err := getRecord(tx.Bucket(bucketActionItems), []byte(id), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		if err := s.unindex(tx, existing); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketActionItems).Delete([]byte(id))
+	})
+}
+
+func (s *BoltActionItemStore) put(tx *bolt.Tx, item *vstore.ActionItem) error {
+	id := item.ActionItemID
+	existing := &vstore.ActionItem{}
+	if err := getRecord(tx.Bucket(bucketActionItems), []byte(id), existing); err == nil {
+		if err := s.unindex(tx, existing); err != nil {
+			return err
+		}
+	}
+	if err := putRecord(tx.Bucket(bucketActionItems), []byte(id), item); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketActionItemsByRetro).Put(indexKey(item.RetrospectiveID, id), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketActionItemsByTeam).Put(indexKey(item.TeamID, id), nil)
+}
+
+func (s *BoltActionItemStore) unindex(tx *bolt.Tx, item *vstore.ActionItem) error {
+	if err := tx.Bucket(bucketActionItemsByRetro).Delete(indexKey(item.RetrospectiveID, item.ActionItemID)); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketActionItemsByTeam).Delete(indexKey(item.TeamID, item.ActionItemID))
+}
+
+func (s *BoltActionItemStore) ListByRetrospective(retroID string) ([]*vstore.ActionItem, error) {
+	return s.list(bucketActionItemsByRetro, retroID, func(*vstore.ActionItem) bool { return true })
+}
+
+func (s *BoltActionItemStore) ListByTeam(teamID string, includeCompleted bool) ([]*vstore.ActionItem, error) {
+	items, err := s.list(bucketActionItemsByTeam, teamID, func(item *vstore.ActionItem) bool {
+		return includeCompleted || (item.Status != vstore.ActionItemStatusDone && item.Status != vstore.ActionItemStatusWontDo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByDueDate(items)
+	return items, nil
+}
+
+func (s *BoltActionItemStore) ListByAssignee(teamID, assigneeID string) ([]*vstore.ActionItem, error) {
+	items, err := s.list(bucketActionItemsByTeam, teamID, func(item *vstore.ActionItem) bool {
+		return item.AssigneeID == assigneeID
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByDueDate(items)
+	return items, nil
+}
+
+func (s *BoltActionItemStore) list(index []byte, prefix string, match func(*vstore.ActionItem) bool) ([]*vstore.ActionItem, error) {
+	var results []*vstore.ActionItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIndexed(tx.Bucket(index), tx.Bucket(bucketActionItems), prefix, func(v []byte) error {
+			item := &vstore.ActionItem{}
+			if err := decodeRecord(v, item); err != nil {
+				return err
+			}
+			if match(item) {
+				results = append(results, item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func sortByDueDate(items []*vstore.ActionItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DueDate.Before(items[j].DueDate)
+	})
+}
+
+// BoltParticipantStore provides bolt-backed storage for participants
+type BoltParticipantStore struct {
+	db *bolt.DB
+}
+
+func NewBoltParticipantStore(db *bolt.DB) *BoltParticipantStore {
+	return &BoltParticipantStore{db: db}
+}
+
+func (s *BoltParticipantStore) Join(participant *vstore.Participant) error {
+	participant.JoinedAt = time.Now()
+	participant.LastActive = time.Now()
+	participant.IsOnline = true
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx.Bucket(bucketParticipants), indexKey(participant.RetrospectiveID, participant.UserID), participant)
+	})
+}
+
+func (s *BoltParticipantStore) Leave(retroID, userID string) error {
+	return s.setOnline(retroID, userID, false)
+}
+
+func (s *BoltParticipantStore) Heartbeat(retroID, userID string) error {
+	return s.setOnline(retroID, userID, true)
+}
+
+func (s *BoltParticipantStore) setOnline(retroID, userID string, online bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := indexKey(retroID, userID)
+		p := &vstore.Participant{}
+		if err := getRecord(tx.Bucket(bucketParticipants), key, p); err != nil {
+			return ignoreNotFound(err)
+		}
+		p.IsOnline = online
+		p.LastActive = time.Now()
+		return putRecord(tx.Bucket(bucketParticipants), key, p)
+	})
+}
+
+func (s *BoltParticipantStore) Get(retroID, userID string) (*vstore.Participant, error) {
+	p := &vstore.Participant{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketParticipants), indexKey(retroID, userID), p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *BoltParticipantStore) ListByRetrospective(retroID string) ([]*vstore.Participant, error) {
+	var results []*vstore.Participant
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketParticipants).Cursor()
+		prefix := indexKey(retroID, "")
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			p := &vstore.Participant{}
+			if err := decodeRecord(v, p); err != nil {
+				return err
+			}
+			if p.IsOnline {
+				results = append(results, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BoltTeamStore provides bolt-backed storage for teams and members
+type BoltTeamStore struct {
+	db *bolt.DB
+}
+
+func NewBoltTeamStore(db *bolt.DB) *BoltTeamStore {
+	return &BoltTeamStore{db: db}
+}
+
+func (s *BoltTeamStore) Create(team *vstore.Team) error {
+	team.Created = time.Now()
+	team.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx.Bucket(bucketTeams), []byte(team.TeamID), team)
+	})
+}
+
+func (s *BoltTeamStore) Get(id string) (*vstore.Team, error) {
+	team := &vstore.Team{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketTeams), []byte(id), team)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *BoltTeamStore) Update(team *vstore.Team) error {
+	team.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx.Bucket(bucketTeams), []byte(team.TeamID), team)
+	})
+}
+
+func (s *BoltTeamStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		members := tx.Bucket(bucketTeamMembers)
+		prefix := indexKey(id, "")
+		var userIDs []string
+		c := members.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			userIDs = append(userIDs, string(k[len(prefix):]))
+		}
+		for _, userID := range userIDs {
+			if err := s.removeMember(tx, id, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketTeams).Delete([]byte(id))
+	})
+}
+
+func (s *BoltTeamStore) ListByMember(userID string) ([]*vstore.Team, error) {
+	var results []*vstore.Team
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIndexed(tx.Bucket(bucketTeamMembersByUser), tx.Bucket(bucketTeams), userID, func(v []byte) error {
+			team := &vstore.Team{}
+			if err := decodeRecord(v, team); err != nil {
+				return err
+			}
+			results = append(results, team)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *BoltTeamStore) PutMember(member *vstore.TeamMember) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := indexKey(member.TeamID, member.UserID)
+		existing := &vstore.TeamMember{}
+		if err := getRecord(tx.Bucket(bucketTeamMembers), key, existing); err == nil {
+			member.Created = existing.Created
+		} else {
+			member.Created = time.Now()
+		}
+		member.Updated = time.Now()
+		if err := putRecord(tx.Bucket(bucketTeamMembers), key, member); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketTeamMembersByUser).Put(indexKey(member.UserID, member.TeamID), nil)
+	})
+}
+
+func (s *BoltTeamStore) GetMember(teamID, userID string) (*vstore.TeamMember, error) {
+	member := &vstore.TeamMember{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketTeamMembers), indexKey(teamID, userID), member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *BoltTeamStore) RemoveMember(teamID, userID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error { return s.removeMember(tx, teamID, userID) })
+}
+
+func (s *BoltTeamStore) removeMember(tx *bolt.Tx, teamID, userID string) error {
+	if err := tx.Bucket(bucketTeamMembersByUser).Delete(indexKey(userID, teamID)); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketTeamMembers).Delete(indexKey(teamID, userID))
+}
+
+func (s *BoltTeamStore) ListMembers(teamID string) ([]*vstore.TeamMember, error) {
+	var results []*vstore.TeamMember
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketTeamMembers).Cursor()
+		prefix := indexKey(teamID, "")
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			member := &vstore.TeamMember{}
+			if err := decodeRecord(v, member); err != nil {
+				return err
+			}
+			results = append(results, member)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// indexKey joins key parts with a separator that cannot appear in IDs
+func indexKey(parts ...string) []byte {
+	var buf bytes.Buffer
+	for i, part := range parts {
+		if i > 0 {
+			buf.WriteByte(0)
+		}
+		buf.WriteString(part)
+	}
+	return buf.Bytes()
+}
+
+// forEachIndexed calls fn with the primary record of every index entry under prefix
+func forEachIndexed(index, primary *bolt.Bucket, prefix string, fn func(v []byte) error) error {
+	seek := indexKey(prefix, "")
+	c := index.Cursor()
+	for k, _ := c.Seek(seek); k != nil && bytes.HasPrefix(k, seek); k, _ = c.Next() {
+		v := primary.Get(k[len(seek):])
+		if v == nil {
+			continue
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BoltPhaseTimerStore provides bolt-backed storage for phase timers
+type BoltPhaseTimerStore struct {
+	db *bolt.DB
+}
+
+func NewBoltPhaseTimerStore(db *bolt.DB) *BoltPhaseTimerStore {
+	return &BoltPhaseTimerStore{db: db}
+}
+
+func (s *BoltPhaseTimerStore) Put(timer *vstore.PhaseTimer) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(timer.RetrospectiveID)
+		existing := &vstore.PhaseTimer{}
+		if err := getRecord(tx.Bucket(bucketPhaseTimers), key, existing); err == nil {
+			timer.Created = existing.Created
+		} else {
+			timer.Created = time.Now()
+		}
+		timer.Updated = time.Now()
+		return putRecord(tx.Bucket(bucketPhaseTimers), key, timer)
+	})
+}
+
+func (s *BoltPhaseTimerStore) Get(retroID string) (*vstore.PhaseTimer, error) {
+	timer := &vstore.PhaseTimer{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketPhaseTimers), []byte(retroID), timer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return timer, nil
+}
+
+func (s *BoltPhaseTimerStore) Delete(retroID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPhaseTimers).Delete([]byte(retroID))
+	})
+}
+
+func (s *BoltPhaseTimerStore) List() ([]*vstore.PhaseTimer, error) {
+	var results []*vstore.PhaseTimer
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPhaseTimers).ForEach(func(_, v []byte) error {
+			timer := &vstore.PhaseTimer{}
+			if err := decodeRecord(v, timer); err != nil {
+				return err
+			}
+			results = append(results, timer)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BoltItemGroupStore provides bolt-backed storage for item groups
+type BoltItemGroupStore struct {
+	db *bolt.DB
+}
+
+func NewBoltItemGroupStore(db *bolt.DB) *BoltItemGroupStore {
+	return &BoltItemGroupStore{db: db}
+}
+
+func (s *BoltItemGroupStore) Create(group *vstore.ItemGroup) error {
+	group.Created = time.Now()
+	group.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, group) })
+}
+
+func (s *BoltItemGroupStore) Get(id string) (*vstore.ItemGroup, error) {
+	group := &vstore.ItemGroup{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketItemGroups), []byte(id), group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *BoltItemGroupStore) Update(group *vstore.ItemGroup) error {
+	group.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.ItemGroup{}
+		if err := getRecord(tx.Bucket(bucketItemGroups), []byte(group.GroupID), existing); err == nil {
+			group.VoteCount = existing.VoteCount
+		}
+		return s.put(tx, group)
+	})
+}
+
+func (s *BoltItemGroupStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.ItemGroup{}
+		if err := getRecord(tx.Bucket(bucketItemGroups), []byte(id), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		if err := tx.Bucket(bucketItemGroupsByRetro).Delete(indexKey(existing.RetrospectiveID, id)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketItemGroups).Delete([]byte(id))
+	})
+}
+
+func (s *BoltItemGroupStore) put(tx *bolt.Tx, group *vstore.ItemGroup) error {
+	if err := putRecord(tx.Bucket(bucketItemGroups), []byte(group.GroupID), group); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketItemGroupsByRetro).Put(indexKey(group.RetrospectiveID, group.GroupID), nil)
+}
+
+func (s *BoltItemGroupStore) ListByRetrospective(retroID string) ([]*vstore.ItemGroup, error) {
+	var results []*vstore.ItemGroup
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIndexed(tx.Bucket(bucketItemGroupsByRetro), tx.Bucket(bucketItemGroups), retroID, func(v []byte) error {
+			group := &vstore.ItemGroup{}
+			if err := decodeRecord(v, group); err != nil {
+				return err
+			}
+			results = append(results, group)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BoltCommentStore provides bolt-backed storage for comments
+type BoltCommentStore struct {
+	db *bolt.DB
+}
+
+func NewBoltCommentStore(db *bolt.DB) *BoltCommentStore {
+	return &BoltCommentStore{db: db}
+}
+
+func (s *BoltCommentStore) Create(comment *vstore.Comment) error {
+	comment.Created = time.Now()
+	comment.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error { return s.put(tx, comment) })
+}
+
+func (s *BoltCommentStore) Get(id string) (*vstore.Comment, error) {
+	comment := &vstore.Comment{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketComments), []byte(id), comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *BoltCommentStore) Update(comment *vstore.Comment) error {
+	comment.Updated = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// A comment moves to another item when the item it was on is merged away
+		existing := &vstore.Comment{}
+		if err := getRecord(tx.Bucket(bucketComments), []byte(comment.CommentID), existing); err == nil && existing.ItemID != comment.ItemID {
+			if err := tx.Bucket(bucketCommentsByItem).Delete(indexKey(existing.ItemID, comment.CommentID)); err != nil {
+				return err
+			}
+		}
+		return s.put(tx, comment)
+	})
+}
+
+func (s *BoltCommentStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing := &vstore.Comment{}
+		if err := getRecord(tx.Bucket(bucketComments), []byte(id), existing); err != nil {
+			return ignoreNotFound(err)
+		}
+		if err := tx.Bucket(bucketCommentsByRetro).Delete(indexKey(existing.RetrospectiveID, id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketCommentsByItem).Delete(indexKey(existing.ItemID, id)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketComments).Delete([]byte(id))
+	})
+}
+
+func (s *BoltCommentStore) put(tx *bolt.Tx, comment *vstore.Comment) error {
+	if err := putRecord(tx.Bucket(bucketComments), []byte(comment.CommentID), comment); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketCommentsByRetro).Put(indexKey(comment.RetrospectiveID, comment.CommentID), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketCommentsByItem).Put(indexKey(comment.ItemID, comment.CommentID), nil)
+}
+
+func (s *BoltCommentStore) ListByItem(itemID string) ([]*vstore.Comment, error) {
+	return s.list(bucketCommentsByItem, itemID)
+}
+
+func (s *BoltCommentStore) ListByRetrospective(retroID string) ([]*vstore.Comment, error) {
+	return s.list(bucketCommentsByRetro, retroID)
+}
+
+func (s *BoltCommentStore) list(index []byte, prefix string) ([]*vstore.Comment, error) {
+	var results []*vstore.Comment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIndexed(tx.Bucket(index), tx.Bucket(bucketComments), prefix, func(v []byte) error {
+			comment := &vstore.Comment{}
+			if err := decodeRecord(v, comment); err != nil {
+				return err
+			}
+			results = append(results, comment)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BoltReactionStore provides bolt-backed storage for reactions. Reactions are
+// keyed by retrospective, item, user and emoji, so listing is a prefix scan.
+type BoltReactionStore struct {
+	db *bolt.DB
+}
+
+func NewBoltReactionStore(db *bolt.DB) *BoltReactionStore {
+	return &BoltReactionStore{db: db}
+}
+
+func (s *BoltReactionStore) Add(reaction *vstore.Reaction) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketReactions)
+		key := indexKey(reaction.RetrospectiveID, reaction.ItemID, reaction.UserID, reaction.Emoji)
+		if bucket.Get(key) != nil {
+			return errReacted
+		}
+		reaction.Created = time.Now()
+		return putRecord(bucket, key, reaction)
+	})
+}
+
+func (s *BoltReactionStore) Remove(retroID, itemID, userID, emoji string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketReactions)
+		key := indexKey(retroID, itemID, userID, emoji)
+		if bucket.Get(key) == nil {
+			return errNoReaction
+		}
+		return bucket.Delete(key)
+	})
+}
+
+func (s *BoltReactionStore) ListByItem(retroID, itemID string) ([]*vstore.Reaction, error) {
+	return s.list(indexKey(retroID, itemID, ""))
+}
+
+func (s *BoltReactionStore) ListByRetrospective(retroID string) ([]*vstore.Reaction, error) {
+	return s.list(indexKey(retroID, ""))
+}
+
+func (s *BoltReactionStore) list(prefix []byte) ([]*vstore.Reaction, error) {
+	var results []*vstore.Reaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketReactions).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			reaction := &vstore.Reaction{}
+			if err := decodeRecord(v, reaction); err != nil {
+				return err
+			}
+			results = append(results, reaction)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Records are stored as JSON using the same property names as vstore so the
+// two backends share one data layout
+func putRecord(bucket *bolt.Bucket, key []byte, model interface{}) error {
+	props, err := vstore.EncodeProperties(model)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
+
+func getRecord(bucket *bolt.Bucket, key []byte, dst interface{}) error {
+	data := bucket.Get(key)
+	if data == nil {
+		return ErrNotFound
+	}
+	return decodeRecord(data, dst)
+}
+
+func decodeRecord(data []byte, dst interface{}) error {
+	var props map[string]interface{}
+	if err := json.Unmarshal(data, &props); err != nil {
+		return err
+	}
+	return vstore.DecodeProperties(props, dst)
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func containsStatus(statuses []vstore.RetrospectiveStatus, status vstore.RetrospectiveStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -25,6 +25,12 @@ var (
 
 	// ErrInvalidStatus is returned for invalid status transitions
 	ErrInvalidStatus = errors.New("invalid status transition")
+
+	// ErrFailedPrecondition is returned when a resource is not in a state that allows the operation
+	ErrFailedPrecondition = errors.New("failed precondition")
+
+	// ErrConflict is returned when a write keeps losing to concurrent writes; retrying may succeed
+	ErrConflict = errors.New("conflicting concurrent update")
 )
 
 // ToGRPCError converts internal errors to gRPC status errors
@@ -46,6 +52,10 @@ func ToGRPCError(err error) error {
 		return status.Error(codes.FailedPrecondition, err.Error())
 	case errors.Is(err, ErrInvalidStatus):
 		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrFailedPrecondition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
 	default:
 		return status.Error(codes.Internal, err.Error())
 	}
@@ -0,0 +1,443 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// ExportSchemaVersion is the version of ExportDocument. Adding a field keeps
+// the version; removing a field or changing what one means bumps it.
+const ExportSchemaVersion = 1
+
+// ExportDocument is a retrospective as its exports present it, and the schema
+// of the JSON export. It holds only what the exporting user may see: cards
+// still hidden by a silent brainstorm are left out, vote counts are zero
+// while blind voting results are hidden, and anonymous authors are unnamed.
+type ExportDocument struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Retrospective *ExportRetrospective `json:"retrospective"`
+	Columns       []*ExportColumn      `json:"columns"`      // in template order
+	ActionItems   []*ExportActionItem  `json:"action_items"` // oldest first
+	Participants  []*ExportParticipant `json:"participants"` // facilitators first, then by name
+}
+
+// ExportRetrospective describes the exported retrospective
+type ExportRetrospective struct {
+	ID              string     `json:"id"`
+	TeamID          string     `json:"team_id"`
+	TeamName        string     `json:"team_name"`
+	SprintName      string     `json:"sprint_name"`
+	Description     string     `json:"description,omitempty"`
+	Status          string     `json:"status"`      // draft, active, voting, discussing, completed or archived
+	VotingMode      string     `json:"voting_mode"` // dot, weighted, ranked_choice or fist_of_five
+	AnonymousVoting bool       `json:"anonymous_voting"`
+	VotesHidden     bool       `json:"votes_hidden"` // blind voting results are not revealed yet
+	ItemsHidden     bool       `json:"items_hidden"` // a silent brainstorm is under way
+	Created         time.Time  `json:"created"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+// ExportColumn is a template column with its items in board order. Items in
+// columns since removed from the template follow the template's columns.
+type ExportColumn struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Icon        string         `json:"icon,omitempty"`
+	Color       string         `json:"color,omitempty"`
+	SortOrder   int32          `json:"sort_order"`
+	HiddenItems int            `json:"hidden_items,omitempty"` // other people's cards still hidden by a silent brainstorm
+	Items       []*ExportItem  `json:"items"`
+	Groups      []*ExportGroup `json:"groups,omitempty"`
+}
+
+// ExportItem is a card on the board
+type ExportItem struct {
+	ID            string           `json:"id"`
+	Content       string           `json:"content"`
+	Author        string           `json:"author,omitempty"`    // empty when anonymous
+	AuthorID      string           `json:"author_id,omitempty"` // empty when anonymous
+	Anonymous     bool             `json:"anonymous"`
+	GroupID       string           `json:"group_id,omitempty"`
+	Position      int              `json:"position"`    // index within the column
+	Votes         int32            `json:"votes"`       // vote count, or points in weighted voting
+	VoterCount    int              `json:"voter_count"` // distinct people who voted for the item
+	HasActionItem bool             `json:"has_action_item"`
+	Comments      []*ExportComment `json:"comments,omitempty"` // in thread order
+	Created       time.Time        `json:"created"`
+}
+
+// ExportGroup is a set of related items voted on together
+type ExportGroup struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Votes      int32    `json:"votes"`
+	VoterCount int      `json:"voter_count"`
+	ItemIDs    []string `json:"item_ids"`
+}
+
+// ExportComment is a comment on an item; replies follow the comment they answer
+type ExportComment struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Depth     int       `json:"depth"` // 0 for top-level comments
+	Author    string    `json:"author,omitempty"`
+	AuthorID  string    `json:"author_id,omitempty"`
+	Anonymous bool      `json:"anonymous"`
+	Content   string    `json:"content"`
+	Created   time.Time `json:"created"`
+}
+
+// ExportActionItem is an action item raised in the retrospective
+type ExportActionItem struct {
+	ID           string     `json:"id"`
+	Description  string     `json:"description"`
+	Status       string     `json:"status"`             // not_started, in_progress, done or wont_do
+	Priority     string     `json:"priority,omitempty"` // low, medium, high or critical
+	AssigneeID   string     `json:"assignee_id,omitempty"`
+	AssigneeName string     `json:"assignee_name,omitempty"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+	SourceItemID string     `json:"source_item_id,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	Created      time.Time  `json:"created"`
+}
+
+// ExportParticipant is someone who joined the retrospective
+type ExportParticipant struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"` // facilitator, member or observer
+}
+
+var (
+	exportStatusNames = map[vstore.RetrospectiveStatus]string{
+		vstore.RetrospectiveStatusDraft:      "draft",
+		vstore.RetrospectiveStatusActive:     "active",
+		vstore.RetrospectiveStatusVoting:     "voting",
+		vstore.RetrospectiveStatusDiscussing: "discussing",
+		vstore.RetrospectiveStatusCompleted:  "completed",
+		vstore.RetrospectiveStatusArchived:   "archived",
+	}
+	exportVotingModeNames = map[vstore.VotingMode]string{
+		vstore.VotingModeDot:          "dot",
+		vstore.VotingModeWeighted:     "weighted",
+		vstore.VotingModeRankedChoice: "ranked_choice",
+		vstore.VotingModeFistOfFive:   "fist_of_five",
+	}
+	exportActionItemStatusNames = map[vstore.ActionItemStatus]string{
+		vstore.ActionItemStatusUnspecified: "not_started",
+		vstore.ActionItemStatusNotStarted:  "not_started",
+		vstore.ActionItemStatusInProgress:  "in_progress",
+		vstore.ActionItemStatusDone:        "done",
+		vstore.ActionItemStatusWontDo:      "wont_do",
+	}
+	exportPriorityNames = map[vstore.ActionItemPriority]string{
+		vstore.ActionItemPriorityLow:      "low",
+		vstore.ActionItemPriorityMedium:   "medium",
+		vstore.ActionItemPriorityHigh:     "high",
+		vstore.ActionItemPriorityCritical: "critical",
+	}
+	exportRoleNames = map[vstore.ParticipantRole]string{
+		vstore.ParticipantRoleUnspecified: "member",
+		vstore.ParticipantRoleMember:      "member",
+		vstore.ParticipantRoleFacilitator: "facilitator",
+		vstore.ParticipantRoleObserver:    "observer",
+	}
+)
+
+// buildExport gathers what userID may see of retro into an export document
+func (s *RetrospectiveService) buildExport(retro *vstore.Retrospective, userID string) (*ExportDocument, error) {
+	items, err := s.itemStore.ListByRetrospective(retro.RetrospectiveID, "", false)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupStore.ListByRetrospective(retro.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentStore.ListByRetrospective(retro.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+	actionItems, err := s.actionItemStore.ListByRetrospective(retro.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.participantStore.ListByRetrospective(retro.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ExportDocument{
+		SchemaVersion: ExportSchemaVersion,
+		Retrospective: &ExportRetrospective{
+			ID:              retro.RetrospectiveID,
+			TeamID:          retro.TeamID,
+			TeamName:        retro.TeamName,
+			SprintName:      retro.SprintName,
+			Description:     retro.Description,
+			Status:          exportStatusNames[retro.Status],
+			VotingMode:      exportVotingModeNames[votingMode(retro.VotingConfig)],
+			AnonymousVoting: anonymousVoting(retro),
+			VotesHidden:     votesHidden(retro),
+			ItemsHidden:     itemsHidden(retro),
+			Created:         retro.Created,
+			StartedAt:       optionalTime(retro.StartedAt),
+			CompletedAt:     optionalTime(retro.CompletedAt),
+		},
+		Columns:      []*ExportColumn{},
+		ActionItems:  []*ExportActionItem{},
+		Participants: []*ExportParticipant{},
+	}
+
+	columns := make(map[string]*ExportColumn)
+	for _, col := range sortedColumns(retro.TemplateColumns) {
+		column := &ExportColumn{
+			ID:          col.ColumnID,
+			Name:        col.Name,
+			Description: col.Description,
+			Icon:        col.Icon,
+			Color:       col.Color,
+			SortOrder:   col.SortOrder,
+			Items:       []*ExportItem{},
+		}
+		columns[col.ColumnID] = column
+		doc.Columns = append(doc.Columns, column)
+	}
+	columnFor := func(columnID string) *ExportColumn {
+		column, ok := columns[columnID]
+		if !ok {
+			column = &ExportColumn{ID: columnID, Name: columnID, Items: []*ExportItem{}}
+			columns[columnID] = column
+			doc.Columns = append(doc.Columns, column)
+		}
+		return column
+	}
+
+	threads := commentThreadsByItem(comments)
+	sortItems(items, false)
+	for _, item := range items {
+		column := columnFor(item.ColumnID)
+		if itemsHidden(retro) && item.CreatedBy != userID {
+			column.HiddenItems++
+			continue
+		}
+		exported := &ExportItem{
+			ID:            item.ItemID,
+			Content:       item.Content,
+			Anonymous:     item.IsAnonymous,
+			GroupID:       item.GroupID,
+			Position:      len(column.Items),
+			Votes:         visibleVoteCount(retro, item.VoteCount),
+			HasActionItem: item.HasActionItem,
+			Created:       item.Created,
+		}
+		if !item.IsAnonymous {
+			exported.Author, exported.AuthorID = item.CreatedByName, item.CreatedBy
+		}
+		if exported.VoterCount, err = s.voterCount(retro, item.ItemID); err != nil {
+			return nil, err
+		}
+		for _, c := range threads[item.ItemID] {
+			comment := &ExportComment{
+				ID:        c.comment.CommentID,
+				ParentID:  c.comment.ParentID,
+				Depth:     c.depth,
+				Anonymous: c.comment.IsAnonymous,
+				Content:   c.comment.Content,
+				Created:   c.comment.Created,
+			}
+			if !c.comment.IsAnonymous {
+				comment.Author, comment.AuthorID = c.comment.CreatedByName, c.comment.CreatedBy
+			}
+			exported.Comments = append(exported.Comments, comment)
+		}
+		column.Items = append(column.Items, exported)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Position != groups[j].Position {
+			return groups[i].Position < groups[j].Position
+		}
+		return groups[i].GroupID < groups[j].GroupID
+	})
+	for _, group := range groups {
+		column := columnFor(group.ColumnID)
+		exported := &ExportGroup{
+			ID:      group.GroupID,
+			Title:   group.Title,
+			Votes:   visibleVoteCount(retro, group.VoteCount),
+			ItemIDs: []string{},
+		}
+		for _, item := range column.Items {
+			if item.GroupID == group.GroupID {
+				exported.ItemIDs = append(exported.ItemIDs, item.ID)
+			}
+		}
+		if exported.VoterCount, err = s.voterCount(retro, group.GroupID); err != nil {
+			return nil, err
+		}
+		column.Groups = append(column.Groups, exported)
+	}
+
+	sort.SliceStable(actionItems, func(i, j int) bool {
+		if !actionItems[i].Created.Equal(actionItems[j].Created) {
+			return actionItems[i].Created.Before(actionItems[j].Created)
+		}
+		return actionItems[i].ActionItemID < actionItems[j].ActionItemID
+	})
+	for _, ai := range actionItems {
+		doc.ActionItems = append(doc.ActionItems, &ExportActionItem{
+			ID:           ai.ActionItemID,
+			Description:  ai.Description,
+			Status:       exportActionItemStatusNames[ai.Status],
+			Priority:     exportPriorityNames[ai.Priority],
+			AssigneeID:   ai.AssigneeID,
+			AssigneeName: ai.AssigneeName,
+			DueDate:      optionalTime(ai.DueDate),
+			SourceItemID: ai.SourceItemID,
+			Notes:        ai.Notes,
+			Created:      ai.Created,
+		})
+	}
+
+	for _, p := range participants {
+		name := p.DisplayName
+		if name == "" {
+			name = p.UserID
+		}
+		doc.Participants = append(doc.Participants, &ExportParticipant{
+			UserID:      p.UserID,
+			DisplayName: name,
+			Role:        exportRoleNames[p.Role],
+		})
+	}
+	sort.SliceStable(doc.Participants, func(i, j int) bool {
+		a, b := doc.Participants[i], doc.Participants[j]
+		if (a.Role == "facilitator") != (b.Role == "facilitator") {
+			return a.Role == "facilitator"
+		}
+		if !strings.EqualFold(a.DisplayName, b.DisplayName) {
+			return strings.ToLower(a.DisplayName) < strings.ToLower(b.DisplayName)
+		}
+		return a.UserID < b.UserID
+	})
+
+	return doc, nil
+}
+
+// voterCount returns how many people voted for an item or group, or zero
+// while blind voting results are hidden. Only the count is exported, so it is
+// safe under anonymous voting too.
+func (s *RetrospectiveService) voterCount(retro *vstore.Retrospective, itemID string) (int, error) {
+	if votesHidden(retro) {
+		return 0, nil
+	}
+	votes, err := s.voteStore.ListByItem(retro.RetrospectiveID, itemID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
+	}
+	voters := make(map[string]bool, len(votes))
+	for _, vote := range votes {
+		voters[vote.UserID] = true
+	}
+	return len(voters), nil
+}
+
+// RenderExport renders an export document in format, returning the content
+// with the filename and content type it should be downloaded as. Unknown
+// formats render as Markdown.
+func RenderExport(doc *ExportDocument, format pb.ExportFormat) ([]byte, string, string) {
+	switch format {
+	case pb.ExportFormat_EXPORT_FORMAT_JSON:
+		return exportJSON(doc)
+	case pb.ExportFormat_EXPORT_FORMAT_CSV:
+		return exportCSV(doc)
+	case pb.ExportFormat_EXPORT_FORMAT_PDF:
+		return exportPDF(doc)
+	case pb.ExportFormat_EXPORT_FORMAT_HTML:
+		return exportHTML(doc)
+	default:
+		return exportMarkdown(doc)
+	}
+}
+
+// exportFilename names an export after its sprint. Anything but ASCII
+// letters, digits, dots, dashes and underscores becomes a dash, so the name is
+// safe in a Content-Disposition header and on every file system.
+func exportFilename(sprintName, ext string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range sprintName {
+		ok := r < 0x80 && (r == '.' || r == '_' || r == '-' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+		if !ok {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+	}
+	name := strings.Trim(b.String(), ".-")
+	if len(name) > 100 {
+		name = strings.TrimRight(name[:100], ".-")
+	}
+	if name == "" {
+		name = "retrospective"
+	}
+	return fmt.Sprintf("%s.%s", name, ext)
+}
+
+// sortedColumns returns a template's columns by SortOrder, keeping template
+// order between columns with the same SortOrder
+func sortedColumns(cols []*vstore.TemplateColumn) []*vstore.TemplateColumn {
+	sorted := append([]*vstore.TemplateColumn(nil), cols...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SortOrder < sorted[j].SortOrder
+	})
+	return sorted
+}
+
+// exportSummary counts what the export holds, for the heading of a report
+func exportSummary(doc *ExportDocument) string {
+	items, votes := 0, 0
+	for _, col := range doc.Columns {
+		items += len(col.Items)
+		for _, item := range col.Items {
+			votes += int(item.Votes)
+		}
+		for _, group := range col.Groups {
+			votes += int(group.Votes)
+		}
+	}
+	summary := fmt.Sprintf("%s · %s", plural(items, "item"), plural(len(doc.ActionItems), "action item"))
+	if !doc.Retrospective.VotesHidden {
+		summary = fmt.Sprintf("%s · %s", summary, plural(votes, "vote"))
+	}
+	return summary
+}
+
+// exportAuthor is how an author is shown in an export
+func exportAuthor(name string, anonymous bool) string {
+	if anonymous || name == "" {
+		return "Anonymous"
+	}
+	return name
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Layout of the vote chart in the HTML export, in SVG user units
+const (
+	htmlChartWidth      = 640
+	htmlChartLabelWidth = 230
+	htmlChartBarWidth   = 360
+	htmlChartRowHeight  = 26
+	htmlChartMaxBars    = 10
+	htmlChartLabelRunes = 34
+)
+
+// cssHexColor matches the column colors that are safe to put in a stylesheet
+var cssHexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// htmlReport is what the HTML export template renders
+type htmlReport struct {
+	Title       string
+	Details     string
+	Summary     string
+	VotesHidden bool
+	Columns     []*htmlColumn
+	Chart       *htmlChart
+	ActionItems []*htmlActionItem
+}
+
+type htmlColumn struct {
+	Name       string
+	Icon       string
+	Color      string
+	HiddenNote string
+	ItemCount  int
+	Items      []*htmlItem // outside any group
+	Groups     []*htmlGroup
+}
+
+// htmlGroup is a group of cards, voted on as one
+type htmlGroup struct {
+	Title string
+	Votes string
+	Items []*htmlItem
+}
+
+type htmlItem struct {
+	Color         string // of the card's column
+	Content       string
+	Author        string
+	Votes         string
+	HasActionItem bool
+	Comments      []*htmlComment
+}
+
+type htmlComment struct {
+	Author  string
+	Content string
+	Indent  int
+}
+
+// htmlChart is a horizontal bar chart of the most voted items
+type htmlChart struct {
+	Width  int
+	Height int
+	BarX   int
+	Bars   []*htmlBar
+}
+
+type htmlBar struct {
+	Label  string
+	Title  string
+	Votes  int32
+	Color  string
+	Y      int
+	Width  int
+	LabelX int
+}
+
+type htmlActionItem struct {
+	Description string
+	Status      string
+	StatusColor string
+	Assignee    string
+	Due         string
+}
+
+// exportHTML renders the retrospective as a single offline page: the board
+// with its columns in their colors, a chart of the votes and the action items
+func exportHTML(doc *ExportDocument) ([]byte, string, string) {
+	retro := doc.Retrospective
+	report := &htmlReport{
+		Title:       fmt.Sprintf("%s Retrospective", retro.SprintName),
+		Details:     retro.Created.Format("January 2, 2006"),
+		Summary:     exportSummary(doc),
+		VotesHidden: retro.VotesHidden,
+	}
+	if retro.TeamName != "" {
+		report.Details = fmt.Sprintf("%s · %s", retro.TeamName, report.Details)
+	}
+
+	for _, col := range doc.Columns {
+		column := &htmlColumn{
+			Name:      col.Name,
+			Icon:      col.Icon,
+			Color:     htmlColor(col.Color),
+			ItemCount: len(col.Items),
+		}
+		if col.HiddenItems > 0 {
+			column.HiddenNote = fmt.Sprintf("%s hidden until the facilitator reveals the cards", plural(col.HiddenItems, "card"))
+		}
+		ungrouped, grouped := exportGroupItems(col)
+		for _, item := range ungrouped {
+			column.Items = append(column.Items, htmlCard(retro, item, column.Color))
+		}
+		for _, group := range col.Groups {
+			g := &htmlGroup{Title: group.Title, Votes: htmlVotes(retro, group.Votes)}
+			for _, item := range grouped[group.ID] {
+				g.Items = append(g.Items, htmlCard(retro, item, column.Color))
+			}
+			column.Groups = append(column.Groups, g)
+		}
+		report.Columns = append(report.Columns, column)
+	}
+
+	if !retro.VotesHidden {
+		report.Chart = voteChart(doc)
+	}
+
+	for _, ai := range doc.ActionItems {
+		status, color := actionItemStatusLabel(ai.Status)
+		row := &htmlActionItem{
+			Description: ai.Description,
+			Status:      status,
+			StatusColor: color,
+			Assignee:    ai.AssigneeName,
+			Due:         "—",
+		}
+		if row.Assignee == "" {
+			row.Assignee = "Unassigned"
+		}
+		if ai.DueDate != nil {
+			row.Due = ai.DueDate.Format("Jan 2, 2006")
+		}
+		report.ActionItems = append(report.ActionItems, row)
+	}
+
+	var buf bytes.Buffer
+	if err := htmlExportTemplate.Execute(&buf, report); err != nil {
+		log.Printf("html export %s: %v", retro.ID, err)
+	}
+	return buf.Bytes(), exportFilename(retro.SprintName, "html"), "text/html"
+}
+
+// htmlCard renders an item as a card
+func htmlCard(retro *ExportRetrospective, item *ExportItem, color string) *htmlItem {
+	card := &htmlItem{
+		Color:         color,
+		Content:       item.Content,
+		Author:        exportAuthor(item.Author, item.Anonymous),
+		Votes:         htmlVotes(retro, item.Votes),
+		HasActionItem: item.HasActionItem,
+	}
+	for _, c := range item.Comments {
+		card.Comments = append(card.Comments, &htmlComment{
+			Author:  exportAuthor(c.Author, c.Anonymous),
+			Content: c.Content,
+			Indent:  c.Depth * 16,
+		})
+	}
+	return card
+}
+
+// htmlVotes labels a vote count, or returns nothing when there are none or
+// they are hidden
+func htmlVotes(retro *ExportRetrospective, votes int32) string {
+	if votes <= 0 || retro.VotesHidden {
+		return ""
+	}
+	return plural(int(votes), "vote")
+}
+
+// voteChart charts the most voted items and groups, or returns nil when
+// nothing has votes
+func voteChart(doc *ExportDocument) *htmlChart {
+	var entries []htmlChartEntry
+	for _, col := range doc.Columns {
+		for _, item := range col.Items {
+			if item.Votes > 0 {
+				entries = append(entries, htmlChartEntry{Label: item.Content, Votes: item.Votes, Color: htmlColor(col.Color)})
+			}
+		}
+		for _, group := range col.Groups {
+			if group.Votes > 0 {
+				entries = append(entries, htmlChartEntry{Label: group.Title, Votes: group.Votes, Color: htmlColor(col.Color)})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Votes > entries[j].Votes
+	})
+	return newHTMLChart(entries)
+}
+
+// htmlChartEntry is a bar of a chart before it is laid out
+type htmlChartEntry struct {
+	Label string
+	Votes int32
+	Color string
+}
+
+// newHTMLChart lays out a bar for each of the first entries, which are in
+// rank order, or returns nil when there are none
+func newHTMLChart(entries []htmlChartEntry) *htmlChart {
+	if len(entries) == 0 {
+		return nil
+	}
+	if len(entries) > htmlChartMaxBars {
+		entries = entries[:htmlChartMaxBars]
+	}
+
+	chart := &htmlChart{
+		Width:  htmlChartWidth,
+		Height: len(entries) * htmlChartRowHeight,
+		BarX:   htmlChartLabelWidth,
+	}
+	for i, e := range entries {
+		width := int(e.Votes) * htmlChartBarWidth / int(entries[0].Votes)
+		if width < 2 {
+			width = 2
+		}
+		chart.Bars = append(chart.Bars, &htmlBar{
+			Label:  shortenLabel(e.Label, htmlChartLabelRunes),
+			Title:  e.Label,
+			Votes:  e.Votes,
+			Color:  e.Color,
+			Y:      i * htmlChartRowHeight,
+			Width:  width,
+			LabelX: htmlChartLabelWidth + width + 6,
+		})
+	}
+	return chart
+}
+
+// htmlColor returns color if it is a hex color, or the fallback column color
+func htmlColor(color string) string {
+	if cssHexColor.MatchString(color) {
+		return color
+	}
+	return "#64748b"
+}
+
+// shortenLabel cuts s down to at most n runes, ending it with an ellipsis
+func shortenLabel(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// htmlBase holds the stylesheet and the vote chart the HTML exports share
+var htmlBase = template.Must(template.New("base").Parse(`{{define "style"}}<style>
+body { margin: 0; padding: 32px; background: #f9fafb; color: #1f2937; font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; }
+h1 { margin: 0 0 4px; font-size: 28px; }
+h2 { margin: 40px 0 12px; font-size: 20px; }
+.muted { color: #6b7280; }
+.board { display: flex; flex-wrap: wrap; gap: 16px; align-items: flex-start; margin-top: 24px; }
+.column { flex: 1 1 220px; min-width: 220px; background: #ffffff; border-radius: 8px; box-shadow: 0 1px 2px rgba(0, 0, 0, 0.08); overflow: hidden; }
+.column-header { display: flex; justify-content: space-between; padding: 10px 14px; color: #ffffff; font-weight: 600; }
+.cards { padding: 10px; }
+.card { margin-bottom: 10px; padding: 10px 12px; background: #ffffff; border: 1px solid #e5e7eb; border-left-width: 4px; border-radius: 6px; }
+.card:last-child { margin-bottom: 0; }
+.card-content { white-space: pre-wrap; overflow-wrap: anywhere; }
+.card-meta { margin-top: 6px; font-size: 12px; color: #6b7280; }
+.votes { float: right; margin-left: 8px; padding: 0 8px; border-radius: 999px; background: #f3f4f6; font-size: 12px; font-weight: 600; }
+.comment { margin-top: 6px; padding-left: 8px; border-left: 2px solid #e5e7eb; font-size: 12px; overflow-wrap: anywhere; }
+.group { margin-bottom: 10px; padding: 8px; border: 1px dashed #d1d5db; border-radius: 6px; background: #f9fafb; }
+.group:last-child { margin-bottom: 0; }
+.group-title { margin-bottom: 8px; font-weight: 600; overflow-wrap: anywhere; }
+.empty { padding: 4px; color: #9ca3af; font-size: 13px; }
+.chart { max-width: 100%; height: auto; background: #ffffff; border-radius: 8px; padding: 12px; box-sizing: content-box; }
+table { width: 100%; border-collapse: collapse; background: #ffffff; border-radius: 8px; overflow: hidden; }
+th { padding: 8px 12px; background: #f3f4f6; color: #6b7280; font-size: 12px; text-align: left; text-transform: uppercase; }
+td { padding: 8px 12px; border-top: 1px solid #e5e7eb; vertical-align: top; }
+.status { display: inline-block; padding: 0 8px; border-radius: 999px; color: #ffffff; font-size: 12px; font-weight: 600; white-space: nowrap; }
+@media print { body { background: #ffffff; padding: 0; } .column, .card, tr { break-inside: avoid; } }
+</style>{{end}}
+{{define "chart"}}<svg class="chart" xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="Votes per item">
+{{- range .Bars}}
+<g transform="translate(0 {{.Y}})">
+<title>{{.Title}}: {{.Votes}}</title>
+<text x="0" y="17" font-size="12" fill="#1f2937">{{.Label}}</text>
+<rect x="{{$.BarX}}" y="4" width="{{.Width}}" height="16" rx="3" fill="{{.Color}}"></rect>
+<text x="{{.LabelX}}" y="17" font-size="12" font-weight="600" fill="#1f2937">{{.Votes}}</text>
+</g>
+{{- end}}
+</svg>{{end}}`))
+
+var htmlExportTemplate = template.Must(template.Must(htmlBase.Clone()).New("export").Parse(`{{define "card"}}<div class="card" style="border-left-color: {{.Color}}">
+{{- if .Votes}}<span class="votes">{{.Votes}}</span>{{end}}
+<div class="card-content">{{.Content}}</div>
+<div class="card-meta">{{.Author}}{{if .HasActionItem}} · Action item{{end}}</div>
+{{- range .Comments}}
+<div class="comment" style="margin-left: {{.Indent}}px"><strong>{{.Author}}:</strong> {{.Content}}</div>
+{{- end}}
+</div>{{end}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "style"}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="muted">{{.Details}}</div>
+<div class="muted">{{.Summary}}</div>
+{{- if .VotesHidden}}
+<p class="muted">Vote counts stay hidden until the facilitator reveals them.</p>
+{{- end}}
+
+<div class="board">
+{{- range .Columns}}
+<section class="column">
+<div class="column-header" style="background: {{.Color}}"><span>{{.Icon}} {{.Name}}</span><span>{{.ItemCount}}</span></div>
+<div class="cards">
+{{- range .Items}}
+{{template "card" .}}
+{{- end}}
+{{- range .Groups}}
+<div class="group">
+<div class="group-title">{{if .Votes}}<span class="votes">{{.Votes}}</span>{{end}}{{.Title}}</div>
+{{- range .Items}}
+{{template "card" .}}
+{{- end}}
+</div>
+{{- end}}
+{{- if and (not .ItemCount) (not .Groups) (not .HiddenNote)}}
+<div class="empty">No items</div>
+{{- end}}
+{{- if .HiddenNote}}
+<div class="empty">{{.HiddenNote}}</div>
+{{- end}}
+</div>
+</section>
+{{- end}}
+</div>
+
+{{- if .Chart}}
+<h2>Votes</h2>
+{{template "chart" .Chart}}
+{{- end}}
+
+<h2>Action Items</h2>
+{{- if .ActionItems}}
+<table>
+<thead><tr><th>Status</th><th>Action</th><th>Assignee</th><th>Due</th></tr></thead>
+<tbody>
+{{- range .ActionItems}}
+<tr><td><span class="status" style="background: {{.StatusColor}}">{{.Status}}</span></td><td>{{.Description}}</td><td>{{.Assignee}}</td><td>{{.Due}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p class="muted">No action items were created.</p>
+{{- end}}
+</body>
+</html>
+`))
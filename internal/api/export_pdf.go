@@ -0,0 +1,526 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vendasta/retrospective/internal/pdf"
+)
+
+// Layout of the PDF export, in points on an A4 page
+const (
+	pdfMargin     = 40.0
+	pdfFooterSize = 24.0
+	pdfLineHeight = 13.0
+	pdfBodySize   = 10.0
+	pdfSmallSize  = 8.5
+)
+
+var (
+	pdfTextColor  = pdf.Color{R: 0x1f, G: 0x29, B: 0x37}
+	pdfMutedColor = pdf.Color{R: 0x6b, G: 0x72, B: 0x80}
+	pdfRuleColor  = pdf.Color{R: 0xe5, G: 0xe7, B: 0xeb}
+
+	// pdfColumnColor is used for columns without a valid color of their own
+	pdfColumnColor = pdf.Color{R: 0x64, G: 0x74, B: 0x8b}
+
+	// pdfAvatarColors are handed out to participants by user ID
+	pdfAvatarColors = []string{"#3b82f6", "#22c55e", "#f59e0b", "#ec4899", "#8b5cf6", "#06b6d4", "#ef4444", "#6366f1"}
+)
+
+// exportPDF renders the retrospective as a report: each template column with
+// its items ranked by votes, then the action items and who took part
+func exportPDF(doc *ExportDocument) ([]byte, string, string) {
+	r := newPDFReport(fmt.Sprintf("%s Retrospective", doc.Retrospective.SprintName))
+	r.header(doc)
+	for _, col := range doc.Columns {
+		r.column(doc, col)
+	}
+	r.actionItems(doc.ActionItems)
+	r.participants(doc.Participants)
+
+	return r.doc.Bytes(), exportFilename(doc.Retrospective.SprintName, "pdf"), "application/pdf"
+}
+
+// pdfReport tracks where the next block of the report goes
+type pdfReport struct {
+	doc   *pdf.Document
+	title string
+	y     float64
+}
+
+func newPDFReport(title string) *pdfReport {
+	r := &pdfReport{
+		doc:   pdf.New(pdf.A4),
+		title: title,
+	}
+	r.doc.SetTitle(title)
+	r.addPage()
+	return r
+}
+
+func (r *pdfReport) width() float64 {
+	return r.doc.Size().Width - 2*pdfMargin
+}
+
+// addPage starts a new page with the report title and page number in its footer
+func (r *pdfReport) addPage() {
+	r.doc.AddPage()
+	r.y = pdfMargin
+
+	footerY := r.doc.Size().Height - pdfMargin + 12
+	r.doc.SetStrokeColor(pdfRuleColor)
+	r.doc.SetLineWidth(0.5)
+	r.doc.Line(pdfMargin, footerY-12, pdfMargin+r.width(), footerY-12)
+	r.doc.SetTextColor(pdfMutedColor)
+	r.doc.Text(pdfMargin, footerY, pdf.Helvetica, pdfSmallSize, r.title)
+	page := fmt.Sprintf("Page %d", r.doc.PageCount())
+	r.doc.Text(pdfMargin+r.width()-pdf.TextWidth(pdf.Helvetica, pdfSmallSize, page), footerY, pdf.Helvetica, pdfSmallSize, page)
+}
+
+// ensure moves to a new page unless height still fits on this one
+func (r *pdfReport) ensure(height float64) {
+	if r.y+height > r.doc.Size().Height-pdfMargin-pdfFooterSize {
+		r.addPage()
+	}
+}
+
+func (r *pdfReport) header(doc *ExportDocument) {
+	retro := doc.Retrospective
+	r.doc.SetTextColor(pdfTextColor)
+	for _, line := range pdf.WrapText(pdf.HelveticaBold, 22, r.title, r.width()) {
+		r.y += 24
+		r.doc.Text(pdfMargin, r.y, pdf.HelveticaBold, 22, line)
+	}
+
+	details := []string{retro.Created.Format("January 2, 2006")}
+	if retro.TeamName != "" {
+		details = append([]string{retro.TeamName}, details...)
+	}
+	r.y += 18
+	r.doc.SetTextColor(pdfMutedColor)
+	r.doc.Text(pdfMargin, r.y, pdf.Helvetica, 11, strings.Join(details, " · "))
+
+	r.y += 15
+	r.doc.Text(pdfMargin, r.y, pdf.Helvetica, pdfBodySize, exportSummary(doc))
+	if retro.VotesHidden {
+		r.y += 15
+		r.doc.Text(pdfMargin, r.y, pdf.Helvetica, pdfBodySize, "Vote counts stay hidden until the facilitator reveals them.")
+	}
+	r.y += 20
+}
+
+// pdfEntry is an item or a group in a column's ranking
+type pdfEntry struct {
+	text  string
+	font  pdf.Font
+	meta  string
+	votes int32
+	items []*ExportItem // a group's items
+}
+
+// column draws a column's colored header band and its standalone items and
+// groups in rank order, each group followed by its items
+func (r *pdfReport) column(doc *ExportDocument, col *ExportColumn) {
+	color, err := pdf.ParseColor(col.Color)
+	if err != nil {
+		color = pdfColumnColor
+	}
+	showVotes := !doc.Retrospective.VotesHidden
+
+	// Votes are cast on groups rather than their items, so groups are ranked
+	// alongside the standalone items, keeping board order between equal counts
+	ungrouped, grouped := exportGroupItems(col)
+	var entries []*pdfEntry
+	for _, item := range ungrouped {
+		entries = append(entries, &pdfEntry{text: item.Content, font: pdf.Helvetica, meta: pdfItemMeta(item), votes: item.Votes})
+	}
+	for _, group := range col.Groups {
+		items := grouped[group.ID]
+		entries = append(entries, &pdfEntry{text: group.Title, font: pdf.HelveticaBold, meta: "Group of " + plural(len(items), "item"), votes: group.Votes, items: items})
+	}
+	if showVotes {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].votes > entries[j].votes
+		})
+	}
+
+	const bandHeight = 26.0
+	r.ensure(bandHeight + 2*pdfLineHeight + 16)
+	r.doc.SetFillColor(color)
+	r.doc.RoundedRect(pdfMargin, r.y, r.width(), bandHeight, 4, pdf.Fill)
+	drawPDFIcon(r.doc, col.Icon, col.Name, pdfMargin+16, r.y+bandHeight/2, 7.5, color)
+	r.doc.SetTextColor(pdf.White)
+	r.doc.Text(pdfMargin+32, r.y+bandHeight/2+4.5, pdf.HelveticaBold, 12, truncatePDFText(pdf.HelveticaBold, 12, col.Name, r.width()-110))
+	count := plural(len(col.Items), "item")
+	r.doc.Text(pdfMargin+r.width()-12-pdf.TextWidth(pdf.Helvetica, 9, count), r.y+bandHeight/2+3.5, pdf.Helvetica, 9, count)
+	r.y += bandHeight + 6
+
+	if col.HiddenItems > 0 {
+		r.y += pdfLineHeight
+		r.doc.SetTextColor(pdfMutedColor)
+		r.doc.Text(pdfMargin+12, r.y, pdf.Helvetica, pdfBodySize, fmt.Sprintf("%s hidden until the facilitator reveals the cards", plural(col.HiddenItems, "card")))
+		r.y += 6
+	}
+	if len(entries) == 0 {
+		if col.HiddenItems == 0 {
+			r.y += pdfLineHeight
+			r.doc.SetTextColor(pdfMutedColor)
+			r.doc.Text(pdfMargin+12, r.y, pdf.Helvetica, pdfBodySize, "No items")
+		}
+		r.y += 18
+		return
+	}
+
+	rank := 0
+	for i, entry := range entries {
+		if i == 0 || entry.votes != entries[i-1].votes {
+			rank = i + 1
+		}
+		label, votes := "•", ""
+		if showVotes {
+			label, votes = fmt.Sprintf("#%d", rank), plural(int(entry.votes), "vote")
+		}
+		r.entry(color, 0, entry.font, entry.text, label, votes, entry.meta)
+		for _, item := range entry.items {
+			r.entry(color, 16, pdf.Helvetica, item.Content, "–", "", pdfItemMeta(item))
+		}
+	}
+	r.y += 18
+}
+
+// entry draws a ranked line of a column: its text, indented by indent, the
+// label and vote count beside its first line and the meta line below it
+func (r *pdfReport) entry(color pdf.Color, indent float64, font pdf.Font, text, label, votes, meta string) {
+	textX := pdfMargin + 38 + indent
+	textWidth := r.width() - 38 - 64 - indent
+	lines := pdf.WrapText(font, pdfBodySize, text, textWidth)
+
+	// Long items carry on over a page break line by line, with the
+	// accent bar drawn beside every line
+	r.ensure(2*pdfLineHeight + 8)
+	r.y += 4
+	for j, line := range append(lines, "") {
+		if j > 0 {
+			r.ensure(pdfLineHeight + 4)
+		}
+		r.y += pdfLineHeight
+		r.doc.SetFillColor(color)
+		r.doc.Rect(pdfMargin, r.y-pdfLineHeight+2, 3, pdfLineHeight, pdf.Fill)
+		if j == len(lines) {
+			r.doc.SetTextColor(pdfMutedColor)
+			r.doc.Text(textX, r.y, pdf.Helvetica, pdfSmallSize, meta)
+			continue
+		}
+		r.doc.SetTextColor(pdfTextColor)
+		r.doc.Text(textX, r.y, font, pdfBodySize, line)
+		if j == 0 {
+			r.doc.SetTextColor(color)
+			r.doc.Text(pdfMargin+12+indent, r.y, pdf.HelveticaBold, pdfBodySize, label)
+			if votes != "" {
+				r.doc.SetTextColor(pdfTextColor)
+				r.doc.Text(pdfMargin+r.width()-pdf.TextWidth(pdf.HelveticaBold, pdfBodySize, votes), r.y, pdf.HelveticaBold, pdfBodySize, votes)
+			}
+		}
+	}
+	r.y += 6
+	r.doc.SetStrokeColor(pdfRuleColor)
+	r.doc.SetLineWidth(0.5)
+	r.doc.Line(pdfMargin+12+indent, r.y, pdfMargin+r.width(), r.y)
+	r.y += 2
+}
+
+// pdfItemMeta is the line under an item: its author, comments and action item
+func pdfItemMeta(item *ExportItem) string {
+	meta := exportAuthor(item.Author, item.Anonymous)
+	if n := len(item.Comments); n > 0 {
+		meta = fmt.Sprintf("%s · %s", meta, plural(n, "comment"))
+	}
+	if item.HasActionItem {
+		meta += " · Action item"
+	}
+	return meta
+}
+
+// actionItems draws a table of the action items with their status, assignee
+// and due date
+func (r *pdfReport) actionItems(actionItems []*ExportActionItem) {
+	r.section("Action Items")
+	if len(actionItems) == 0 {
+		r.note("No action items were created.")
+		return
+	}
+
+	widths := []float64{72, r.width() - 72 - 110 - 80, 110, 80}
+	xs := []float64{pdfMargin}
+	for _, w := range widths[:3] {
+		xs = append(xs, xs[len(xs)-1]+w)
+	}
+	tableHeader := func() {
+		r.doc.SetFillColor(pdf.Color{R: 0xf3, G: 0xf4, B: 0xf6})
+		r.doc.Rect(pdfMargin, r.y, r.width(), 20, pdf.Fill)
+		r.doc.SetTextColor(pdfMutedColor)
+		for i, title := range []string{"STATUS", "ACTION", "ASSIGNEE", "DUE"} {
+			r.doc.Text(xs[i]+6, r.y+13.5, pdf.HelveticaBold, pdfSmallSize, title)
+		}
+		r.y += 20
+	}
+
+	r.ensure(20 + pdfLineHeight + 10)
+	tableHeader()
+	for _, ai := range actionItems {
+		lines := pdf.WrapText(pdf.Helvetica, pdfBodySize, ai.Description, widths[1]-12)
+		if r.y+pdfLineHeight+10 > r.doc.Size().Height-pdfMargin-pdfFooterSize {
+			r.addPage()
+			tableHeader()
+		}
+		r.y += 4
+		for j, line := range lines {
+			if j > 0 && r.y+pdfLineHeight+6 > r.doc.Size().Height-pdfMargin-pdfFooterSize {
+				r.addPage()
+				tableHeader()
+			}
+			r.y += pdfLineHeight
+			r.doc.SetTextColor(pdfTextColor)
+			r.doc.Text(xs[1]+6, r.y, pdf.Helvetica, pdfBodySize, line)
+			if j > 0 {
+				continue
+			}
+
+			label, hex := actionItemStatusLabel(ai.Status)
+			color, _ := pdf.ParseColor(hex)
+			r.doc.SetTextColor(color)
+			r.doc.Text(xs[0]+6, r.y, pdf.HelveticaBold, pdfSmallSize, strings.ToUpper(label))
+
+			assignee := ai.AssigneeName
+			r.doc.SetTextColor(pdfTextColor)
+			if assignee == "" {
+				assignee = "Unassigned"
+				r.doc.SetTextColor(pdfMutedColor)
+			}
+			r.doc.Text(xs[2]+6, r.y, pdf.Helvetica, pdfBodySize, truncatePDFText(pdf.Helvetica, pdfBodySize, assignee, widths[2]-12))
+
+			due := "—"
+			if ai.DueDate != nil {
+				due = ai.DueDate.Format("Jan 2, 2006")
+			}
+			r.doc.SetTextColor(pdfTextColor)
+			r.doc.Text(xs[3]+6, r.y, pdf.Helvetica, pdfBodySize, due)
+		}
+		r.y += 6
+		r.doc.SetStrokeColor(pdfRuleColor)
+		r.doc.SetLineWidth(0.5)
+		r.doc.Line(pdfMargin, r.y, pdfMargin+r.width(), r.y)
+	}
+	r.y += 24
+}
+
+// participants draws everyone who joined, facilitators first, three to a row
+func (r *pdfReport) participants(participants []*ExportParticipant) {
+	r.section("Participants")
+	if len(participants) == 0 {
+		r.note("No one joined this retrospective.")
+		return
+	}
+
+	const perRow, rowHeight = 3, 30.0
+	cellWidth := r.width() / perRow
+	for i, p := range participants {
+		if i%perRow == 0 {
+			if i > 0 {
+				r.y += rowHeight
+			}
+			r.ensure(rowHeight)
+		}
+		x := pdfMargin + float64(i%perRow)*cellWidth
+		name := p.DisplayName
+
+		color, _ := pdf.ParseColor(pdfAvatarColors[avatarIndex(p.UserID)])
+		r.doc.SetFillColor(color)
+		r.doc.Circle(x+10, r.y+11, 10, pdf.Fill)
+		initial, _ := utf8.DecodeRuneInString(strings.ToUpper(name))
+		r.doc.SetTextColor(pdf.White)
+		r.doc.Text(x+10-pdf.TextWidth(pdf.HelveticaBold, 9, string(initial))/2, r.y+14.5, pdf.HelveticaBold, 9, string(initial))
+
+		r.doc.SetTextColor(pdfTextColor)
+		r.doc.Text(x+26, r.y+9, pdf.HelveticaBold, pdfBodySize, truncatePDFText(pdf.HelveticaBold, pdfBodySize, name, cellWidth-32))
+		r.doc.SetTextColor(pdfMutedColor)
+		r.doc.Text(x+26, r.y+20, pdf.Helvetica, pdfSmallSize, participantRoleLabel(p.Role))
+	}
+	r.y += rowHeight + 12
+}
+
+func (r *pdfReport) section(title string) {
+	r.ensure(28 + 2*pdfLineHeight)
+	r.y += 16
+	r.doc.SetTextColor(pdfTextColor)
+	r.doc.Text(pdfMargin, r.y, pdf.HelveticaBold, 15, title)
+	r.y += 10
+}
+
+func (r *pdfReport) note(text string) {
+	r.y += pdfLineHeight
+	r.doc.SetTextColor(pdfMutedColor)
+	r.doc.Text(pdfMargin, r.y, pdf.Helvetica, pdfBodySize, text)
+	r.y += 18
+}
+
+// actionItemStatusLabel names an exported action item status and the color it is shown in
+func actionItemStatusLabel(status string) (string, string) {
+	switch status {
+	case "in_progress":
+		return "In progress", "#3b82f6"
+	case "done":
+		return "Done", "#16a34a"
+	case "wont_do":
+		return "Won't do", "#9ca3af"
+	default:
+		return "To do", "#6b7280"
+	}
+}
+
+// participantRoleLabel names an exported participant role
+func participantRoleLabel(role string) string {
+	switch role {
+	case "facilitator":
+		return "Facilitator"
+	case "observer":
+		return "Observer"
+	default:
+		return "Member"
+	}
+}
+
+// avatarIndex picks a participant's avatar color, the same one every export
+func avatarIndex(userID string) int {
+	h := 0
+	for _, c := range userID {
+		h = (h*31 + int(c)) % len(pdfAvatarColors)
+	}
+	return h
+}
+
+// truncatePDFText shortens s with an ellipsis until it fits in width
+func truncatePDFText(font pdf.Font, size float64, s string, width float64) string {
+	if pdf.TextWidth(font, size, s) <= width {
+		return s
+	}
+	for s != "" {
+		_, n := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-n]
+		if pdf.TextWidth(font, size, s+"…") <= width {
+			break
+		}
+	}
+	return s + "…"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// drawPDFIcon draws a column's emoji icon as a white vector shape centred on
+// cx, cy over background bg. The standard PDF fonts have no emoji, so icons
+// without a drawing of their own show the column's initial instead.
+func drawPDFIcon(doc *pdf.Document, icon, name string, cx, cy, r float64, bg pdf.Color) {
+	doc.SetFillColor(pdf.White)
+	doc.SetStrokeColor(pdf.White)
+	doc.SetTextColor(pdf.White)
+
+	switch strings.TrimSuffix(icon, "️") {
+	case "👍":
+		doc.RoundedRect(cx-0.35*r, cy-0.15*r, 1.05*r, 0.95*r, 0.25*r, pdf.Fill)
+		doc.RoundedRect(cx-0.3*r, cy-0.95*r, 0.4*r, r, 0.2*r, pdf.Fill)
+		doc.Rect(cx-0.95*r, cy-0.1*r, 0.45*r, 0.9*r, pdf.Fill)
+	case "🔧":
+		doc.SetLineWidth(0.35 * r)
+		doc.Line(cx-0.7*r, cy+0.7*r, cx+0.15*r, cy-0.15*r)
+		doc.Circle(cx+0.35*r, cy-0.35*r, 0.5*r, pdf.Fill)
+		doc.SetFillColor(bg)
+		doc.Circle(cx+0.6*r, cy-0.6*r, 0.25*r, pdf.Fill)
+	case "✅", "✔", "☑":
+		doc.SetLineWidth(0.3 * r)
+		p := &pdf.Path{}
+		p.MoveTo(cx-0.65*r, cy)
+		p.LineTo(cx-0.15*r, cy+0.5*r)
+		p.LineTo(cx+0.7*r, cy-0.55*r)
+		doc.DrawPath(p, pdf.Stroke)
+	case "🚀":
+		doc.Polygon([]pdf.Point{{X: cx, Y: cy - r}, {X: cx + 0.35*r, Y: cy - 0.4*r}, {X: cx + 0.35*r, Y: cy + 0.45*r}, {X: cx - 0.35*r, Y: cy + 0.45*r}, {X: cx - 0.35*r, Y: cy - 0.4*r}}, pdf.Fill)
+		doc.Polygon([]pdf.Point{{X: cx - 0.35*r, Y: cy}, {X: cx - 0.75*r, Y: cy + 0.6*r}, {X: cx - 0.35*r, Y: cy + 0.45*r}}, pdf.Fill)
+		doc.Polygon([]pdf.Point{{X: cx + 0.35*r, Y: cy}, {X: cx + 0.75*r, Y: cy + 0.6*r}, {X: cx + 0.35*r, Y: cy + 0.45*r}}, pdf.Fill)
+		doc.Polygon([]pdf.Point{{X: cx - 0.2*r, Y: cy + 0.55*r}, {X: cx + 0.2*r, Y: cy + 0.55*r}, {X: cx, Y: cy + r}}, pdf.Fill)
+		doc.SetFillColor(bg)
+		doc.Circle(cx, cy-0.25*r, 0.15*r, pdf.Fill)
+	case "🛑":
+		var points []pdf.Point
+		for i := 0; i < 8; i++ {
+			a := math.Pi/8 + float64(i)*math.Pi/4
+			points = append(points, pdf.Point{X: cx + r*math.Cos(a), Y: cy + r*math.Sin(a)})
+		}
+		doc.Polygon(points, pdf.Fill)
+		doc.SetFillColor(bg)
+		doc.Rect(cx-0.55*r, cy-0.12*r, 1.1*r, 0.24*r, pdf.Fill)
+	case "➡":
+		doc.Polygon([]pdf.Point{{X: cx - 0.8*r, Y: cy - 0.2*r}, {X: cx + 0.1*r, Y: cy - 0.2*r}, {X: cx + 0.1*r, Y: cy - 0.6*r}, {X: cx + 0.85*r, Y: cy}, {X: cx + 0.1*r, Y: cy + 0.6*r}, {X: cx + 0.1*r, Y: cy + 0.2*r}, {X: cx - 0.8*r, Y: cy + 0.2*r}}, pdf.Fill)
+	case "❤":
+		p := &pdf.Path{}
+		p.MoveTo(cx, cy+0.8*r)
+		p.CurveTo(cx-0.2*r, cy+0.55*r, cx-r, cy+0.1*r, cx-r, cy-0.3*r)
+		p.CurveTo(cx-r, cy-0.95*r, cx-0.15*r, cy-0.95*r, cx, cy-0.45*r)
+		p.CurveTo(cx+0.15*r, cy-0.95*r, cx+r, cy-0.95*r, cx+r, cy-0.3*r)
+		p.CurveTo(cx+r, cy+0.1*r, cx+0.2*r, cy+0.55*r, cx, cy+0.8*r)
+		p.Close()
+		doc.DrawPath(p, pdf.Fill)
+	case "📚":
+		doc.Rect(cx-0.85*r, cy-0.6*r, 0.4*r, 1.4*r, pdf.Fill)
+		doc.Rect(cx-0.35*r, cy-0.8*r, 0.45*r, 1.6*r, pdf.Fill)
+		doc.Polygon([]pdf.Point{{X: cx + 0.2*r, Y: cy - 0.55*r}, {X: cx + 0.55*r, Y: cy - 0.7*r}, {X: cx + 0.95*r, Y: cy + 0.65*r}, {X: cx + 0.6*r, Y: cy + 0.8*r}}, pdf.Fill)
+	case "✨":
+		doc.Polygon([]pdf.Point{{X: cx, Y: cy - r}, {X: cx + 0.25*r, Y: cy - 0.25*r}, {X: cx + r, Y: cy}, {X: cx + 0.25*r, Y: cy + 0.25*r}, {X: cx, Y: cy + r}, {X: cx - 0.25*r, Y: cy + 0.25*r}, {X: cx - r, Y: cy}, {X: cx - 0.25*r, Y: cy - 0.25*r}}, pdf.Fill)
+	case "😊", "🙂", "😀", "😄":
+		drawPDFFace(doc, cx, cy, r, bg, true)
+	case "😢", "😞", "🙁":
+		drawPDFFace(doc, cx, cy, r, bg, false)
+		doc.SetFillColor(bg)
+		doc.Circle(cx+0.35*r, cy+0.05*r, 0.1*r, pdf.Fill)
+	case "😠", "😡":
+		drawPDFFace(doc, cx, cy, r, bg, false)
+		doc.Line(cx-0.55*r, cy-0.6*r, cx-0.15*r, cy-0.4*r)
+		doc.Line(cx+0.55*r, cy-0.6*r, cx+0.15*r, cy-0.4*r)
+	case "🤔", "❓", "❔":
+		doc.Text(cx-pdf.TextWidth(pdf.HelveticaBold, 1.9*r, "?")/2, cy+0.68*r, pdf.HelveticaBold, 1.9*r, "?")
+	default:
+		initial, _ := utf8.DecodeRuneInString(strings.ToUpper(name))
+		doc.Circle(cx, cy, r, pdf.Fill)
+		doc.SetTextColor(bg)
+		doc.Text(cx-pdf.TextWidth(pdf.HelveticaBold, 1.2*r, string(initial))/2, cy+0.43*r, pdf.HelveticaBold, 1.2*r, string(initial))
+	}
+}
+
+// drawPDFFace draws a round face smiling or frowning, leaving the stroke in
+// the background color so callers can add brows
+func drawPDFFace(doc *pdf.Document, cx, cy, r float64, bg pdf.Color, smile bool) {
+	doc.Circle(cx, cy, r, pdf.Fill)
+	doc.SetFillColor(bg)
+	doc.Circle(cx-0.35*r, cy-0.2*r, 0.13*r, pdf.Fill)
+	doc.Circle(cx+0.35*r, cy-0.2*r, 0.13*r, pdf.Fill)
+
+	doc.SetStrokeColor(bg)
+	doc.SetLineWidth(0.13 * r)
+	p := &pdf.Path{}
+	if smile {
+		p.MoveTo(cx-0.45*r, cy+0.2*r)
+		p.CurveTo(cx-0.25*r, cy+0.6*r, cx+0.25*r, cy+0.6*r, cx+0.45*r, cy+0.2*r)
+	} else {
+		p.MoveTo(cx-0.4*r, cy+0.55*r)
+		p.CurveTo(cx-0.2*r, cy+0.25*r, cx+0.2*r, cy+0.25*r, cx+0.4*r, cy+0.55*r)
+	}
+	doc.DrawPath(p, pdf.Stroke)
+}
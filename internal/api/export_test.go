@@ -0,0 +1,193 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/api/exporttest"
+	"github.com/vendasta/retrospective/internal/auth"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+var update = flag.Bool("update", false, "rewrite export golden files")
+
+func TestExportGolden(t *testing.T) {
+	exporttest.Run(t, *update)
+}
+
+// TestExportFromStores seeds in-memory stores with the boards the golden
+// documents describe and checks that exporting them through the service
+// reproduces the golden files. Everything is stored out of order, so the
+// goldens only match when the export sorts columns, items, action items and
+// participants itself.
+func TestExportFromStores(t *testing.T) {
+	stores := api.NewInMemoryStores()
+	svc := api.NewRetrospectiveService(stores.Retrospectives, stores.Items, stores.ItemGroups, stores.Comments,
+		stores.Votes, stores.ActionItems, stores.Participants, stores.Teams, nil)
+	seedExportBoards(t, stores)
+
+	export := func(userID, retroID string) func(pb.ExportFormat) ([]byte, string, string) {
+		ctx := auth.NewContext(context.Background(), &auth.Principal{UserID: userID})
+		return func(format pb.ExportFormat) ([]byte, string, string) {
+			resp, err := svc.Export(ctx, &pb.ExportRetrospectiveRequest{RetrospectiveId: retroID, Format: format})
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			return resp.Content, resp.Filename, resp.ContentType
+		}
+	}
+	exporttest.Check(t, "board", export("bob", "RETRO-1"))
+	// Alice sees only her own card of the silent brainstorm, and no tallies
+	// or voter counts of the blind vote
+	exporttest.Check(t, "hidden", export("alice", "RETRO-2"))
+
+	// Anonymous voting hides who voted, not how many did
+	retro, err := stores.Retrospectives.Get("RETRO-1")
+	if err != nil {
+		t.Fatalf("Retrospectives.Get: %v", err)
+	}
+	retro.VotingConfig = &vstore.VotingConfig{Mode: vstore.VotingModeDot, AnonymousVoting: true}
+	if err := stores.Retrospectives.Update(retro); err != nil {
+		t.Fatalf("Retrospectives.Update: %v", err)
+	}
+	content, _, _ := export("bob", "RETRO-1")(pb.ExportFormat_EXPORT_FORMAT_JSON)
+	var doc api.ExportDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Retrospective.AnonymousVoting {
+		t.Error("anonymous_voting = false, want true")
+	}
+	if item := doc.Columns[0].Items[0]; item.VoterCount != 2 {
+		t.Errorf("%s voter_count under anonymous voting = %d, want 2", item.ID, item.VoterCount)
+	}
+	if group := doc.Columns[0].Groups[0]; group.VoterCount != 3 {
+		t.Errorf("%s voter_count under anonymous voting = %d, want 3", group.ID, group.VoterCount)
+	}
+}
+
+// seedExportBoards stores the retrospectives of exporttest.Documents. The
+// stores stamp records with the time they are created, so each is updated
+// afterwards with its creation time in the documents.
+func seedExportBoards(t *testing.T, stores api.Stores) {
+	t.Helper()
+	day := time.Date(2024, time.September, 27, 15, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return day.Add(time.Duration(minutes) * time.Minute)
+	}
+	must := func(err error, op string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+	}
+
+	must(stores.Teams.Create(&vstore.Team{TeamID: "TEAM-1", Name: "Platform & Tools"}), "Teams.Create")
+	for _, userID := range []string{"alice", "bob", "carol"} {
+		must(stores.Teams.PutMember(&vstore.TeamMember{TeamID: "TEAM-1", UserID: userID, Role: vstore.TeamRoleMember}), "Teams.PutMember")
+	}
+
+	for _, retro := range []*vstore.Retrospective{
+		{
+			RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", TeamName: "Platform & Tools", SprintName: "Sprint 42: Q3/Retro",
+			Status: vstore.RetrospectiveStatusCompleted, FacilitatorID: "alice", StartedAt: at(0), CompletedAt: at(60),
+			TemplateColumns: []*vstore.TemplateColumn{
+				{ColumnID: "action_items", Name: "Action Items", Icon: "✅", Color: "#3b82f6", SortOrder: 3},
+				{ColumnID: "went_well", Name: "What Went Well", Icon: "👍", Color: "#22c55e", SortOrder: 1},
+				{ColumnID: "to_improve", Name: "What To Improve", Icon: "🔧", Color: "#f59e0b", SortOrder: 2},
+			},
+		},
+		{
+			RetrospectiveID: "RETRO-2", TeamID: "TEAM-1", TeamName: "Platform & Tools", SprintName: "Sprint 42: Q3/Retro",
+			Status: vstore.RetrospectiveStatusActive, FacilitatorID: "bob", StartedAt: at(0), SilentBrainstorm: true,
+			VotingConfig: &vstore.VotingConfig{Mode: vstore.VotingModeWeighted, MaxVotesPerUser: 5, AnonymousVoting: true, BlindVoting: true},
+			TemplateColumns: []*vstore.TemplateColumn{
+				{ColumnID: "continue", Name: "Continue", Icon: "➡️", Color: "#3b82f6", SortOrder: 3},
+				{ColumnID: "stop", Name: "Stop", Icon: "🛑", Color: "not-a-color", SortOrder: 2},
+				{ColumnID: "start", Name: "Start", Icon: "🚀", Color: "#22c55e", SortOrder: 1},
+			},
+		},
+	} {
+		must(stores.Retrospectives.Create(retro), "Retrospectives.Create")
+		retro.Created = day
+		must(stores.Retrospectives.Update(retro), "Retrospectives.Update")
+	}
+
+	// Cards are stored in reverse board order; Position sets the board order
+	for _, item := range []*vstore.RetrospectiveItem{
+		{ItemID: "ITEM-10", RetrospectiveID: "RETRO-2", ColumnID: "stop", Content: "Bob's hidden card", CreatedBy: "bob", CreatedByName: "Bob", Created: at(12)},
+		{ItemID: "ITEM-9", RetrospectiveID: "RETRO-2", ColumnID: "start", Content: "Carol's hidden card", CreatedBy: "carol", CreatedByName: "Carol", Position: 2, Created: at(11)},
+		{ItemID: "ITEM-8", RetrospectiveID: "RETRO-2", ColumnID: "start", Content: "Bob's hidden card", CreatedBy: "bob", CreatedByName: "Bob", Position: 1, Created: at(10)},
+		{ItemID: "ITEM-7", RetrospectiveID: "RETRO-2", ColumnID: "start", Content: "Start writing ADRs", CreatedBy: "alice", CreatedByName: "Alice", VoteCount: 3, Created: at(5)},
+		{ItemID: "ITEM-6", RetrospectiveID: "RETRO-1", ColumnID: "retired", Content: "Card from a removed column", CreatedBy: "dan", CreatedByName: "Dan", Created: at(10)},
+		{ItemID: "ITEM-5", RetrospectiveID: "RETRO-1", ColumnID: "to_improve", Content: "Deploys failed twice,\nboth on Friday", CreatedBy: "carol", CreatedByName: "Carol", IsAnonymous: true, VoteCount: 5, HasActionItem: true, Created: at(9)},
+		{ItemID: "ITEM-4", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Focus time on Fridays", CreatedBy: "carol", CreatedByName: "Carol", GroupID: "GROUP-1", Position: 3, Created: at(8)},
+		{ItemID: "ITEM-3", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Fewer meetings", CreatedBy: "bob", CreatedByName: "Bob", GroupID: "GROUP-1", Position: 2, VoteCount: 2, Created: at(7)},
+		{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Café demo went well, even the ☕ machine", CreatedBy: "carol", CreatedByName: "Carol", IsAnonymous: true, Position: 1, VoteCount: 1, Created: at(6)},
+		{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Pairing on the <billing> migration", CreatedBy: "alice", CreatedByName: "Alice", VoteCount: 3, Created: at(5)},
+	} {
+		created := item.Created
+		must(stores.Items.Create(item), "Items.Create")
+		item.Created = created
+		must(stores.Items.Update(item), "Items.Update")
+	}
+	must(stores.ItemGroups.Create(&vstore.ItemGroup{GroupID: "GROUP-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Title: "Time to focus", CreatedBy: "bob", VoteCount: 4}), "ItemGroups.Create")
+
+	// Vote records only give the voter counts; the counts are on the items
+	for i, vote := range []struct{ retroID, itemID, userID string }{
+		{"RETRO-1", "ITEM-1", "alice"}, {"RETRO-1", "ITEM-1", "alice"}, {"RETRO-1", "ITEM-1", "bob"},
+		{"RETRO-1", "ITEM-2", "bob"},
+		{"RETRO-1", "ITEM-3", "alice"}, {"RETRO-1", "ITEM-3", "carol"},
+		{"RETRO-1", "GROUP-1", "alice"}, {"RETRO-1", "GROUP-1", "alice"}, {"RETRO-1", "GROUP-1", "bob"}, {"RETRO-1", "GROUP-1", "carol"},
+		{"RETRO-1", "ITEM-5", "alice"}, {"RETRO-1", "ITEM-5", "bob"}, {"RETRO-1", "ITEM-5", "carol"},
+		{"RETRO-2", "ITEM-7", "bob"}, {"RETRO-2", "ITEM-7", "carol"},
+	} {
+		must(stores.Votes.Create(&vstore.Vote{
+			VoteID:          fmt.Sprintf("VOTE-%d", i+1),
+			RetrospectiveID: vote.retroID,
+			ItemID:          vote.itemID,
+			UserID:          vote.userID,
+			GroupVote:       vote.itemID == "GROUP-1",
+		}), "Votes.Create")
+	}
+
+	for _, comment := range []*vstore.Comment{
+		{CommentID: "COMMENT-3", RetrospectiveID: "RETRO-1", ItemID: "ITEM-5", Content: "=SUM(A1) is not a formula here", CreatedBy: "bob", CreatedByName: "Bob", IsAnonymous: true, Created: at(25)},
+		{CommentID: "COMMENT-2", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", ParentID: "COMMENT-1", Content: "Thanks, \"let's\" keep it up", CreatedBy: "alice", CreatedByName: "Alice", Created: at(21)},
+		{CommentID: "COMMENT-1", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", Content: "Strongly agree", CreatedBy: "bob", CreatedByName: "Bob", Created: at(20)},
+	} {
+		created := comment.Created
+		must(stores.Comments.Create(comment), "Comments.Create")
+		comment.Created = created
+		must(stores.Comments.Update(comment), "Comments.Update")
+	}
+
+	for _, ai := range []*vstore.ActionItem{
+		{ActionItemID: "AI-3", RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", Description: "Retire the old dashboard", Status: vstore.ActionItemStatusWontDo, Notes: "Superseded", Created: at(42)},
+		{ActionItemID: "AI-2", RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", Description: "Write up the pairing guide", Status: vstore.ActionItemStatusNotStarted, Created: at(41)},
+		{
+			ActionItemID: "AI-1", RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", Description: "Add a deploy freeze on Fridays",
+			Status: vstore.ActionItemStatusInProgress, Priority: vstore.ActionItemPriorityHigh, AssigneeID: "alice", AssigneeName: "Alice",
+			DueDate: day.AddDate(0, 0, 14), SourceItemID: "ITEM-5", Created: at(40),
+		},
+	} {
+		created := ai.Created
+		must(stores.ActionItems.Create(ai), "ActionItems.Create")
+		ai.Created = created
+		must(stores.ActionItems.Update(ai), "ActionItems.Update")
+	}
+
+	for _, p := range []*vstore.Participant{
+		{RetrospectiveID: "RETRO-1", UserID: "carol", DisplayName: "Carol", Role: vstore.ParticipantRoleObserver},
+		{RetrospectiveID: "RETRO-1", UserID: "bob", DisplayName: "Bob", Role: vstore.ParticipantRoleMember},
+		{RetrospectiveID: "RETRO-1", UserID: "alice", DisplayName: "Alice", Role: vstore.ParticipantRoleFacilitator},
+	} {
+		must(stores.Participants.Join(p), "Participants.Join")
+	}
+}
@@ -0,0 +1,212 @@
+// Package exporttest checks every export format against golden files.
+//
+// The golden files in testdata hold the expected rendering of fixed export
+// documents. A test calls Run; after an intended change to an export format,
+// it is rerun with update set to rewrite them:
+//
+//	var update = flag.Bool("update", false, "rewrite export golden files")
+//
+//	func TestExportGolden(t *testing.T) {
+//		exporttest.Run(t, *update)
+//	}
+//
+// A test that builds the same documents from seeded stores passes Check a
+// function exporting them, to compare its output with the same golden files.
+package exporttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/api"
+)
+
+// formats are the export formats with the golden file extension of each
+var formats = []struct {
+	format      pb.ExportFormat
+	ext         string
+	contentType string
+}{
+	{pb.ExportFormat_EXPORT_FORMAT_JSON, "json", "application/json"},
+	{pb.ExportFormat_EXPORT_FORMAT_CSV, "csv", "text/csv"},
+	{pb.ExportFormat_EXPORT_FORMAT_MARKDOWN, "md", "text/markdown"},
+	{pb.ExportFormat_EXPORT_FORMAT_HTML, "html", "text/html"},
+	{pb.ExportFormat_EXPORT_FORMAT_PDF, "pdf", "application/pdf"},
+}
+
+// Run renders every document in Documents in every format and compares the
+// output with the golden files, or rewrites them when update is set
+func Run(t *testing.T, update bool) {
+	for name, doc := range Documents() {
+		check(t, name, update, func(format pb.ExportFormat) ([]byte, string, string) {
+			return api.RenderExport(doc, format)
+		})
+
+		t.Run(name+"/json-round-trip", func(t *testing.T) {
+			content, _, _ := api.RenderExport(doc, pb.ExportFormat_EXPORT_FORMAT_JSON)
+			var decoded api.ExportDocument
+			if err := json.Unmarshal(content, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			again, _, _ := api.RenderExport(&decoded, pb.ExportFormat_EXPORT_FORMAT_JSON)
+			if !bytes.Equal(again, content) {
+				t.Error("JSON export does not survive a round trip through ExportDocument")
+			}
+			if decoded.SchemaVersion != api.ExportSchemaVersion {
+				t.Errorf("schema_version = %d, want %d", decoded.SchemaVersion, api.ExportSchemaVersion)
+			}
+		})
+	}
+}
+
+// Check compares what export returns in every format with the golden files
+// of the document named name, for tests that export a document built from
+// stores rather than rendering the one in Documents
+func Check(t *testing.T, name string, export func(format pb.ExportFormat) (content []byte, filename, contentType string)) {
+	check(t, name, false, export)
+}
+
+func check(t *testing.T, name string, update bool, export func(format pb.ExportFormat) ([]byte, string, string)) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "testdata")
+
+	for _, f := range formats {
+		t.Run(name+"/"+f.ext, func(t *testing.T) {
+			content, filename, contentType := export(f.format)
+			if want := "Sprint-42-Q3-Retro." + f.ext; filename != want {
+				t.Errorf("filename = %q, want %q", filename, want)
+			}
+			if contentType != f.contentType {
+				t.Errorf("content type = %q, want %q", contentType, f.contentType)
+			}
+
+			golden := filepath.Join(dir, name+"."+f.ext+".golden")
+			if update {
+				if err := os.WriteFile(golden, content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, want) {
+				t.Errorf("export differs from %s; rerun with update set if the change is intended", golden)
+			}
+		})
+	}
+}
+
+// Documents returns the export documents the golden files are rendered from,
+// by golden file name
+func Documents() map[string]*api.ExportDocument {
+	day := time.Date(2024, time.September, 27, 15, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return day.Add(time.Duration(minutes) * time.Minute)
+	}
+	started, completed, due := at(0), at(60), day.AddDate(0, 0, 14)
+
+	board := &api.ExportDocument{
+		SchemaVersion: api.ExportSchemaVersion,
+		Retrospective: &api.ExportRetrospective{
+			ID:          "RETRO-1",
+			TeamID:      "TEAM-1",
+			TeamName:    "Platform & Tools",
+			SprintName:  "Sprint 42: Q3/Retro",
+			Status:      "completed",
+			VotingMode:  "dot",
+			Created:     day,
+			StartedAt:   &started,
+			CompletedAt: &completed,
+		},
+		Columns: []*api.ExportColumn{
+			{
+				ID: "went_well", Name: "What Went Well", Icon: "👍", Color: "#22c55e", SortOrder: 1,
+				Items: []*api.ExportItem{
+					{
+						ID: "ITEM-1", Content: "Pairing on the <billing> migration", Author: "Alice", AuthorID: "alice",
+						Votes: 3, VoterCount: 2, Created: at(5),
+						Comments: []*api.ExportComment{
+							{ID: "COMMENT-1", Author: "Bob", AuthorID: "bob", Content: "Strongly agree", Created: at(20)},
+							{ID: "COMMENT-2", ParentID: "COMMENT-1", Depth: 1, Author: "Alice", AuthorID: "alice", Content: "Thanks, \"let's\" keep it up", Created: at(21)},
+						},
+					},
+					{ID: "ITEM-2", Content: "Café demo went well, even the ☕ machine", Anonymous: true, Position: 1, Votes: 1, VoterCount: 1, Created: at(6)},
+					{ID: "ITEM-3", Content: "Fewer meetings", Author: "Bob", AuthorID: "bob", GroupID: "GROUP-1", Position: 2, Votes: 2, VoterCount: 2, Created: at(7)},
+					{ID: "ITEM-4", Content: "Focus time on Fridays", Author: "Carol", AuthorID: "carol", GroupID: "GROUP-1", Position: 3, Created: at(8)},
+				},
+				Groups: []*api.ExportGroup{
+					{ID: "GROUP-1", Title: "Time to focus", Votes: 4, VoterCount: 3, ItemIDs: []string{"ITEM-3", "ITEM-4"}},
+				},
+			},
+			{
+				ID: "to_improve", Name: "What To Improve", Icon: "🔧", Color: "#f59e0b", SortOrder: 2,
+				Items: []*api.ExportItem{
+					{
+						ID: "ITEM-5", Content: "Deploys failed twice,\nboth on Friday", Anonymous: true, Votes: 5, VoterCount: 3,
+						HasActionItem: true, Created: at(9),
+						Comments: []*api.ExportComment{
+							{ID: "COMMENT-3", Anonymous: true, Content: "=SUM(A1) is not a formula here", Created: at(25)},
+						},
+					},
+				},
+			},
+			{ID: "action_items", Name: "Action Items", Icon: "✅", Color: "#3b82f6", SortOrder: 3, Items: []*api.ExportItem{}},
+			{ID: "retired", Name: "retired", Items: []*api.ExportItem{
+				{ID: "ITEM-6", Content: "Card from a removed column", Author: "Dan", AuthorID: "dan", Created: at(10)},
+			}},
+		},
+		ActionItems: []*api.ExportActionItem{
+			{ID: "AI-1", Description: "Add a deploy freeze on Fridays", Status: "in_progress", Priority: "high", AssigneeID: "alice", AssigneeName: "Alice", DueDate: &due, SourceItemID: "ITEM-5", Created: at(40)},
+			{ID: "AI-2", Description: "Write up the pairing guide", Status: "not_started", Created: at(41)},
+			{ID: "AI-3", Description: "Retire the old dashboard", Status: "wont_do", Notes: "Superseded", Created: at(42)},
+		},
+		Participants: []*api.ExportParticipant{
+			{UserID: "alice", DisplayName: "Alice", Role: "facilitator"},
+			{UserID: "bob", DisplayName: "Bob", Role: "member"},
+			{UserID: "carol", DisplayName: "Carol", Role: "observer"},
+		},
+	}
+
+	// A blind vote and a silent brainstorm are both still under way
+	hidden := &api.ExportDocument{
+		SchemaVersion: api.ExportSchemaVersion,
+		Retrospective: &api.ExportRetrospective{
+			ID:              "RETRO-2",
+			TeamID:          "TEAM-1",
+			TeamName:        "Platform & Tools",
+			SprintName:      "Sprint 42: Q3/Retro",
+			Status:          "active",
+			VotingMode:      "weighted",
+			AnonymousVoting: true,
+			VotesHidden:     true,
+			ItemsHidden:     true,
+			Created:         day,
+			StartedAt:       &started,
+		},
+		Columns: []*api.ExportColumn{
+			{
+				ID: "start", Name: "Start", Icon: "🚀", Color: "#22c55e", SortOrder: 1, HiddenItems: 2,
+				Items: []*api.ExportItem{
+					{ID: "ITEM-7", Content: "Start writing ADRs", Author: "Alice", AuthorID: "alice", Created: at(5)},
+				},
+			},
+			{ID: "stop", Name: "Stop", Icon: "🛑", Color: "not-a-color", SortOrder: 2, HiddenItems: 1, Items: []*api.ExportItem{}},
+			{ID: "continue", Name: "Continue", Icon: "➡️", Color: "#3b82f6", SortOrder: 3, Items: []*api.ExportItem{}},
+		},
+		ActionItems:  []*api.ExportActionItem{},
+		Participants: []*api.ExportParticipant{},
+	}
+
+	return map[string]*api.ExportDocument{
+		"board":  board,
+		"hidden": hidden,
+	}
+}
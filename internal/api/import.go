@@ -0,0 +1,543 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// maxImportProblems caps the problems an import reports, so a file in the
+// wrong format doesn't produce one for every line
+const maxImportProblems = 100
+
+// utf8BOM starts files saved by some spreadsheet programs
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// importProblems collects what stops a file from being imported
+type importProblems []*pb.ImportProblem
+
+func (p *importProblems) add(location, format string, args ...interface{}) {
+	if len(*p) < maxImportProblems {
+		*p = append(*p, &pb.ImportProblem{Location: location, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// summary describes the problems in an error message
+func (p importProblems) summary() string {
+	first := fmt.Sprintf("%s: %s", p[0].Location, p[0].Message)
+	if len(p) == 1 {
+		return first
+	}
+	return fmt.Sprintf("%s (and %s; a dry run lists them all)", first, plural(len(p)-1, "more problem"))
+}
+
+// importPlan is an imported retrospective that is ready to be written
+type importPlan struct {
+	retro       *vstore.Retrospective
+	groups      []*vstore.ItemGroup
+	items       []*vstore.RetrospectiveItem
+	comments    []*vstore.Comment
+	actionItems []*vstore.ActionItem
+}
+
+// Import creates a completed retrospective from a JSON export of this service,
+// a CSV file or a Trello board export. With dry_run set nothing is written and
+// the response lists the problems that would stop the import.
+func (s *RetrospectiveService) Import(ctx context.Context, req *pb.ImportRetrospectiveRequest) (*pb.ImportRetrospectiveResponse, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+	if len(req.Content) == 0 {
+		return nil, ToGRPCError(fmt.Errorf("%w: content is required", ErrInvalidArgument))
+	}
+
+	team, err := s.teamStore.Get(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	member, err := requireTeamMember(ctx, s.teamStore, req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireTeamWriter(member); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	var problems importProblems
+	doc := parseImport(req.Format, req.Content, &problems)
+	if req.SprintName != "" {
+		doc.Retrospective.SprintName = req.SprintName
+	}
+	plan := s.planImport(ctx, team, doc, &problems)
+
+	resp := &pb.ImportRetrospectiveResponse{
+		ItemCount:       int32(len(plan.items)),
+		GroupCount:      int32(len(plan.groups)),
+		CommentCount:    int32(len(plan.comments)),
+		ActionItemCount: int32(len(plan.actionItems)),
+		Problems:        problems,
+	}
+	if req.DryRun {
+		resp.Retrospective = convertVstoreRetroToPb(plan.retro)
+		resp.Retrospective.RetrospectiveId = ""
+		return resp, nil
+	}
+	if len(problems) > 0 {
+		return nil, ToGRPCError(fmt.Errorf("%w: %s", ErrInvalidArgument, problems.summary()))
+	}
+
+	if err := s.writeImport(plan); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	resp.RetrospectiveId = plan.retro.RetrospectiveID
+	resp.Retrospective = convertVstoreRetroToPb(plan.retro)
+	return resp, nil
+}
+
+// parseImport reads content in format into an export document. Unspecified
+// formats are told apart by their content. The document is empty, never nil,
+// when content cannot be read.
+func parseImport(format pb.ImportFormat, content []byte, problems *importProblems) *ExportDocument {
+	content = bytes.TrimPrefix(content, utf8BOM)
+	if format == pb.ImportFormat_IMPORT_FORMAT_UNSPECIFIED {
+		format = detectImportFormat(content)
+	}
+	switch format {
+	case pb.ImportFormat_IMPORT_FORMAT_JSON:
+		return parseImportJSON(content, problems)
+	case pb.ImportFormat_IMPORT_FORMAT_CSV:
+		return parseImportCSV(content, problems)
+	case pb.ImportFormat_IMPORT_FORMAT_TRELLO:
+		return parseImportTrello(content, problems)
+	default:
+		problems.add("format", "unknown import format %d", format)
+		return emptyImportDocument()
+	}
+}
+
+// detectImportFormat guesses the format of content: JSON objects are this
+// service's export unless they have the lists and cards of a Trello board, and
+// anything else is read as CSV
+func detectImportFormat(content []byte) pb.ImportFormat {
+	trimmed := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return pb.ImportFormat_IMPORT_FORMAT_CSV
+	}
+	var probe struct {
+		SchemaVersion *int            `json:"schema_version"`
+		Lists         json.RawMessage `json:"lists"`
+		Cards         json.RawMessage `json:"cards"`
+	}
+	if json.Unmarshal(trimmed, &probe) == nil && probe.SchemaVersion == nil && probe.Lists != nil && probe.Cards != nil {
+		return pb.ImportFormat_IMPORT_FORMAT_TRELLO
+	}
+	return pb.ImportFormat_IMPORT_FORMAT_JSON
+}
+
+func emptyImportDocument() *ExportDocument {
+	return &ExportDocument{SchemaVersion: ExportSchemaVersion, Retrospective: &ExportRetrospective{}}
+}
+
+// parseImportJSON reads the JSON export of this service
+func parseImportJSON(content []byte, problems *importProblems) *ExportDocument {
+	doc := &ExportDocument{}
+	if err := json.Unmarshal(content, doc); err != nil {
+		problems.add("file", "not a JSON export of a retrospective: %v", err)
+		return emptyImportDocument()
+	}
+	switch {
+	case doc.SchemaVersion == 0:
+		problems.add("schema_version", "missing; the file is not a JSON export of a retrospective")
+	case doc.SchemaVersion > ExportSchemaVersion:
+		problems.add("schema_version", "version %d is newer than the supported version %d", doc.SchemaVersion, ExportSchemaVersion)
+	}
+	if doc.Retrospective == nil {
+		doc.Retrospective = &ExportRetrospective{}
+	}
+	return doc
+}
+
+// planImport turns an import into the records to write for team, reporting
+// what is wrong with it. Imported retrospectives are completed. Authors are
+// kept by name only, since the people of another tool aren't users here, and
+// votes are kept as counts without voters.
+func (s *RetrospectiveService) planImport(ctx context.Context, team *vstore.Team, doc *ExportDocument, problems *importProblems) *importPlan {
+	userID := getUserIDFromContext(ctx)
+	nextID := importIDs()
+	src := doc.Retrospective
+
+	sprintName := strings.TrimSpace(src.SprintName)
+	if sprintName == "" {
+		problems.add("retrospective", "the sprint name is missing; set sprint_name on the request")
+	}
+	mode := vstore.VotingModeDot
+	if src.VotingMode != "" {
+		if m, ok := importName(exportVotingModeNames, src.VotingMode); ok {
+			mode = m
+		} else {
+			problems.add("retrospective", "unknown voting mode %q", src.VotingMode)
+		}
+	}
+
+	held := src.Created
+	if src.StartedAt != nil {
+		held = *src.StartedAt
+	}
+	if held.IsZero() {
+		held = time.Now()
+	}
+	completed := held
+	if src.CompletedAt != nil {
+		completed = *src.CompletedAt
+	}
+
+	plan := &importPlan{
+		retro: &vstore.Retrospective{
+			RetrospectiveID: nextID("RETRO"),
+			TeamID:          team.TeamID,
+			TeamName:        team.Name,
+			SprintName:      sprintName,
+			Description:     src.Description,
+			TemplateType:    vstore.TemplateTypeCustom,
+			Status:          vstore.RetrospectiveStatusCompleted,
+			VotingConfig: &vstore.VotingConfig{
+				Mode:            mode,
+				MaxVotesPerUser: 5,
+				AnonymousVoting: src.AnonymousVoting,
+			},
+			CreatedBy:     userID,
+			FacilitatorID: userID,
+			StartedAt:     held,
+			CompletedAt:   completed,
+			Created:       src.Created,
+		},
+	}
+	retroID := plan.retro.RetrospectiveID
+
+	items := make(map[string]*vstore.RetrospectiveItem) // by their ID in the file
+	columnIDs := make(map[string]bool)
+	for i, col := range doc.Columns {
+		location := fmt.Sprintf("column %d", i+1)
+		if col == nil {
+			problems.add(location, "the column is empty")
+			continue
+		}
+		column := &vstore.TemplateColumn{
+			ColumnID:    col.ID,
+			Name:        strings.TrimSpace(col.Name),
+			Description: col.Description,
+			Icon:        col.Icon,
+			Color:       col.Color,
+			SortOrder:   int32(len(plan.retro.TemplateColumns) + 1),
+		}
+		if column.Name == "" {
+			problems.add(location, "the column has no name")
+		} else {
+			location = fmt.Sprintf("column %q", column.Name)
+		}
+		matchTemplateColumn(column)
+		if column.ColumnID == "" {
+			column.ColumnID = importColumnID(column.Name, i)
+		}
+		if columnIDs[column.ColumnID] {
+			problems.add(location, "another column has the ID %q", column.ColumnID)
+		}
+		columnIDs[column.ColumnID] = true
+		plan.retro.TemplateColumns = append(plan.retro.TemplateColumns, column)
+
+		groups := make(map[string]string) // new group IDs by their ID in the file
+		for j, g := range col.Groups {
+			if g == nil {
+				continue
+			}
+			groupLocation := fmt.Sprintf("%s, group %d", location, j+1)
+			title := strings.TrimSpace(g.Title)
+			if title == "" {
+				problems.add(groupLocation, "the group has no title")
+			}
+			if g.Votes < 0 {
+				problems.add(groupLocation, "negative vote count %d", g.Votes)
+			}
+			group := &vstore.ItemGroup{
+				GroupID:         nextID("GROUP"),
+				RetrospectiveID: retroID,
+				ColumnID:        column.ColumnID,
+				Title:           title,
+				CreatedBy:       userID,
+				CreatedByName:   getUserNameFromContext(ctx),
+				VoteCount:       g.Votes,
+				Position:        int32(j),
+			}
+			groups[g.ID] = group.GroupID
+			plan.groups = append(plan.groups, group)
+		}
+
+		sortKey := ""
+		for j, item := range col.Items {
+			itemLocation := fmt.Sprintf("%s, item %d", location, j+1)
+			if item == nil {
+				problems.add(itemLocation, "the item is empty")
+				continue
+			}
+			sortKey = sortKeyBetween(sortKey, "")
+			record := &vstore.RetrospectiveItem{
+				ItemID:          nextID("ITEM"),
+				RetrospectiveID: retroID,
+				ColumnID:        column.ColumnID,
+				Content:         strings.TrimSpace(item.Content),
+				IsAnonymous:     item.Anonymous,
+				VoteCount:       item.Votes,
+				Position:        int32(j),
+				SortKey:         sortKey,
+			}
+			if record.Content == "" {
+				problems.add(itemLocation, "the item has no content")
+			}
+			if item.Votes < 0 {
+				problems.add(itemLocation, "negative vote count %d", item.Votes)
+			}
+			if !item.Anonymous {
+				record.CreatedByName = item.Author
+			}
+			if item.GroupID != "" {
+				groupID, ok := groups[item.GroupID]
+				if !ok {
+					problems.add(itemLocation, "group %q is not in this column", item.GroupID)
+				}
+				record.GroupID = groupID
+			}
+			if item.ID != "" {
+				if _, ok := items[item.ID]; ok {
+					problems.add(itemLocation, "another item has the ID %q", item.ID)
+				}
+				items[item.ID] = record
+			}
+			plan.items = append(plan.items, record)
+
+			comments := make(map[string]string) // new comment IDs by their ID in the file
+			for k, c := range item.Comments {
+				commentLocation := fmt.Sprintf("%s, comment %d", itemLocation, k+1)
+				if c == nil {
+					problems.add(commentLocation, "the comment is empty")
+					continue
+				}
+				comment := &vstore.Comment{
+					CommentID:       nextID("COMMENT"),
+					RetrospectiveID: retroID,
+					ItemID:          record.ItemID,
+					Content:         strings.TrimSpace(c.Content),
+					IsAnonymous:     c.Anonymous,
+				}
+				if comment.Content == "" {
+					problems.add(commentLocation, "the comment has no content")
+				}
+				if !c.Anonymous {
+					comment.CreatedByName = c.Author
+				}
+				if c.ParentID != "" {
+					parentID, ok := comments[c.ParentID]
+					if !ok {
+						problems.add(commentLocation, "it replies to %q, which is not an earlier comment on the item", c.ParentID)
+					}
+					comment.ParentID = parentID
+				}
+				if c.ID != "" {
+					comments[c.ID] = comment.CommentID
+				}
+				plan.comments = append(plan.comments, comment)
+			}
+		}
+	}
+	if len(plan.retro.TemplateColumns) == 0 {
+		problems.add("file", "there are no columns to import")
+	}
+
+	for i, ai := range doc.ActionItems {
+		location := fmt.Sprintf("action item %d", i+1)
+		if ai == nil {
+			problems.add(location, "the action item is empty")
+			continue
+		}
+		record := &vstore.ActionItem{
+			ActionItemID:     nextID("ACTION"),
+			RetrospectiveID:  retroID,
+			TeamID:           team.TeamID,
+			Description:      strings.TrimSpace(ai.Description),
+			AssigneeName:     ai.AssigneeName,
+			Status:           vstore.ActionItemStatusNotStarted,
+			Priority:         vstore.ActionItemPriorityMedium,
+			CreatedBy:        userID,
+			SourceSprintName: sprintName,
+			Notes:            ai.Notes,
+		}
+		if record.Description == "" {
+			problems.add(location, "the action item has no description")
+		}
+		if ai.Status != "" {
+			status, ok := importName(exportActionItemStatusNames, ai.Status)
+			if !ok {
+				problems.add(location, "unknown status %q", ai.Status)
+			}
+			record.Status = status
+		}
+		if ai.Priority != "" {
+			priority, ok := importName(exportPriorityNames, ai.Priority)
+			if !ok {
+				problems.add(location, "unknown priority %q", ai.Priority)
+			}
+			record.Priority = priority
+		}
+		if ai.DueDate != nil {
+			record.DueDate = *ai.DueDate
+		}
+		if ai.SourceItemID != "" {
+			if source, ok := items[ai.SourceItemID]; ok {
+				record.SourceItemID = source.ItemID
+				source.HasActionItem = true
+			} else {
+				problems.add(location, "its source item %q is not in the file", ai.SourceItemID)
+			}
+		}
+		// Assignees stay linked only to people who are on the team
+		if ai.AssigneeID != "" {
+			if _, err := s.teamStore.GetMember(team.TeamID, ai.AssigneeID); err == nil {
+				record.AssigneeID = ai.AssigneeID
+			}
+		}
+		plan.actionItems = append(plan.actionItems, record)
+	}
+
+	plan.retro.ItemCount = int32(len(plan.items))
+	plan.retro.ActionItemCount = int32(len(plan.actionItems))
+	return plan
+}
+
+// writeImport stores a planned import. The retrospective is written last so
+// it never shows up half imported, and whatever was written is deleted again
+// when a write fails.
+func (s *RetrospectiveService) writeImport(plan *importPlan) error {
+	var undo []func() error
+	fail := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				log.Printf("import %s: undo: %v", plan.retro.RetrospectiveID, undoErr)
+			}
+		}
+		return err
+	}
+
+	for _, group := range plan.groups {
+		if err := s.groupStore.Create(group); err != nil {
+			return fail(err)
+		}
+		id := group.GroupID
+		undo = append(undo, func() error { return s.groupStore.Delete(id) })
+	}
+	for _, item := range plan.items {
+		if err := s.itemStore.Create(item); err != nil {
+			return fail(err)
+		}
+		id := item.ItemID
+		undo = append(undo, func() error { return s.itemStore.Delete(id) })
+	}
+	for _, comment := range plan.comments {
+		if err := s.commentStore.Create(comment); err != nil {
+			return fail(err)
+		}
+		id := comment.CommentID
+		undo = append(undo, func() error { return s.commentStore.Delete(id) })
+	}
+	for _, ai := range plan.actionItems {
+		if err := s.actionItemStore.Create(ai); err != nil {
+			return fail(err)
+		}
+		id := ai.ActionItemID
+		undo = append(undo, func() error { return s.actionItemStore.Delete(id) })
+	}
+
+	// Stores stamp Created, so the date the retrospective was held is put back
+	held := plan.retro.Created
+	if err := s.retroStore.Create(plan.retro); err != nil {
+		return fail(err)
+	}
+	if !held.IsZero() {
+		plan.retro.Created = held
+		if err := s.retroStore.Update(plan.retro); err != nil {
+			undo = append(undo, func() error { return s.retroStore.Delete(plan.retro.RetrospectiveID) })
+			return fail(err)
+		}
+	}
+	return nil
+}
+
+// importIDs returns a function making record IDs in the style of the rest of
+// the service, counting up so IDs made in one import never collide
+func importIDs() func(prefix string) string {
+	next := time.Now().UnixNano()
+	return func(prefix string) string {
+		next++
+		return fmt.Sprintf("%s-%d", prefix, next)
+	}
+}
+
+// importName looks up the value an export name stands for
+func importName[K comparable](names map[K]string, name string) (K, bool) {
+	var zero K
+	for value, n := range names {
+		if value != zero && strings.EqualFold(n, strings.TrimSpace(name)) {
+			return value, true
+		}
+	}
+	return zero, false
+}
+
+// matchTemplateColumn fills in the ID, icon, color and description of a
+// column named like one of the built-in template columns
+func matchTemplateColumn(column *vstore.TemplateColumn) {
+	for templateType := pb.RetrospectiveTemplateType_RETROSPECTIVE_TEMPLATE_TYPE_WENT_WELL_TO_IMPROVE; templateType <= pb.RetrospectiveTemplateType_RETROSPECTIVE_TEMPLATE_TYPE_MAD_SAD_GLAD; templateType++ {
+		for _, builtIn := range getDefaultTemplateColumns(templateType) {
+			if !strings.EqualFold(builtIn.Name, column.Name) {
+				continue
+			}
+			if column.ColumnID == "" {
+				column.ColumnID = builtIn.ColumnID
+			}
+			if column.Icon == "" {
+				column.Icon = builtIn.Icon
+			}
+			if column.Color == "" {
+				column.Color = builtIn.Color
+			}
+			if column.Description == "" {
+				column.Description = builtIn.Description
+			}
+			return
+		}
+	}
+}
+
+// importColumnID makes a column ID from its name, such as to_discuss from
+// "To discuss"
+func importColumnID(name string, index int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	if id := strings.TrimSuffix(b.String(), "_"); id != "" {
+		return id
+	}
+	return fmt.Sprintf("column_%d", index+1)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseImportCSV reads a CSV file whose first row names its columns. Column
+// and Content are required; every row is an item unless a Type column says
+// otherwise. Votes, Author and the other columns of the CSV export are
+// optional, so a CSV export imports back with its groups, comments and
+// action items. Column names are matched ignoring case.
+func parseImportCSV(content []byte, problems *importProblems) *ExportDocument {
+	doc := emptyImportDocument()
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		problems.add("line 1", "the header row cannot be read: %v", err)
+		return doc
+	}
+	fields := make(map[string]int, len(header))
+	for i, name := range header {
+		fields[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"column", "content"} {
+		if _, ok := fields[required]; !ok {
+			problems.add("line 1", "the header row has no %s column", required)
+		}
+	}
+	if len(*problems) > 0 {
+		return doc
+	}
+	field := func(row []string, name string) string {
+		i, ok := fields[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(csvValue(row[i]))
+	}
+	count := func(row []string, name, location string) int32 {
+		value := field(row, name)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 0 {
+			problems.add(location, "%s %q is not a count", name, value)
+			return 0
+		}
+		return int32(n)
+	}
+	author := func(row []string) (string, bool) {
+		name := field(row, "author")
+		return name, strings.EqualFold(name, "Anonymous")
+	}
+
+	columns := make(map[string]*ExportColumn)
+	columnNamed := func(name string) *ExportColumn {
+		key := strings.ToLower(name)
+		column, ok := columns[key]
+		if !ok {
+			column = &ExportColumn{Name: name, Items: []*ExportItem{}}
+			columns[key] = column
+			doc.Columns = append(doc.Columns, column)
+		}
+		return column
+	}
+	groupNamed := func(column *ExportColumn, title string) *ExportGroup {
+		for _, group := range column.Groups {
+			if strings.EqualFold(group.Title, title) {
+				return group
+			}
+		}
+		group := &ExportGroup{ID: fmt.Sprintf("group-%d", len(column.Groups)+1), Title: title}
+		column.Groups = append(column.Groups, group)
+		return group
+	}
+
+	var last *ExportItem
+	var thread []string // IDs of the comments last seen at each depth on last
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		location := fmt.Sprintf("line %d", line)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				location = fmt.Sprintf("line %d", parseErr.Line)
+			}
+			problems.add(location, "%v", err)
+			break
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		switch kind := strings.ToLower(field(row, "type")); kind {
+		case "", "item":
+			name := field(row, "column")
+			if name == "" {
+				problems.add(location, "the item has no column")
+				continue
+			}
+			column := columnNamed(name)
+			item := &ExportItem{
+				ID:         fmt.Sprintf("line-%d", line),
+				Content:    field(row, "content"),
+				Votes:      count(row, "votes", location),
+				VoterCount: int(count(row, "voters", location)),
+			}
+			item.Author, item.Anonymous = author(row)
+			if item.Anonymous {
+				item.Author = ""
+			}
+			if title := field(row, "group"); title != "" {
+				item.GroupID = groupNamed(column, title).ID
+			}
+			column.Items = append(column.Items, item)
+			last, thread = item, nil
+
+		case "comment":
+			if last == nil {
+				problems.add(location, "the comment comes before any item")
+				continue
+			}
+			// Replies are marked with a "> " for each level, as in the export
+			text, depth := field(row, "content"), 0
+			for strings.HasPrefix(text, "> ") {
+				text, depth = text[2:], depth+1
+			}
+			if depth > len(thread) {
+				depth = len(thread)
+			}
+			comment := &ExportComment{ID: fmt.Sprintf("line-%d", line), Depth: depth, Content: text}
+			if depth > 0 {
+				comment.ParentID = thread[depth-1]
+			}
+			comment.Author, comment.Anonymous = author(row)
+			if comment.Anonymous {
+				comment.Author = ""
+			}
+			thread = append(thread[:depth], comment.ID)
+			last.Comments = append(last.Comments, comment)
+
+		case "group":
+			column, title := field(row, "column"), field(row, "group")
+			if column == "" || title == "" {
+				problems.add(location, "the group needs a column and a group title")
+				continue
+			}
+			group := groupNamed(columnNamed(column), title)
+			group.Votes = count(row, "votes", location)
+			group.VoterCount = int(count(row, "voters", location))
+
+		case "action item":
+			ai := &ExportActionItem{
+				Description:  field(row, "content"),
+				Status:       csvActionItemStatus(field(row, "status")),
+				AssigneeName: field(row, "assignee"),
+			}
+			if due := field(row, "due date"); due != "" {
+				date, err := time.Parse("2006-01-02", due)
+				if err != nil {
+					problems.add(location, "due date %q is not a date like 2006-01-02", due)
+				} else {
+					ai.DueDate = &date
+				}
+			}
+			doc.ActionItems = append(doc.ActionItems, ai)
+
+		default:
+			problems.add(location, "unknown row type %q", field(row, "type"))
+		}
+	}
+	return doc
+}
+
+// csvValue undoes the quoting csvCell gives cells that look like formulas
+func csvValue(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// csvActionItemStatus turns a status label of the CSV export, such as
+// "In progress", into its export name. Anything else is kept for the import
+// to report.
+func csvActionItemStatus(status string) string {
+	for _, name := range exportActionItemStatusNames {
+		if label, _ := actionItemStatusLabel(name); strings.EqualFold(label, status) {
+			return name
+		}
+	}
+	return status
+}
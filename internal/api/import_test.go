@@ -0,0 +1,576 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/auth"
+	"github.com/vendasta/retrospective/internal/vstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newImportService returns a service over stores with the team TEAM-1, whose
+// member bob is the caller of the returned context
+func newImportService(t *testing.T, stores api.Stores) (*api.RetrospectiveService, context.Context) {
+	t.Helper()
+	if _, err := stores.Teams.Get("TEAM-1"); errors.Is(err, api.ErrNotFound) {
+		if err := stores.Teams.Create(&vstore.Team{TeamID: "TEAM-1", Name: "Platform & Tools"}); err != nil {
+			t.Fatalf("Teams.Create: %v", err)
+		}
+		if err := stores.Teams.PutMember(&vstore.TeamMember{TeamID: "TEAM-1", UserID: "bob", Role: vstore.TeamRoleMember}); err != nil {
+			t.Fatalf("Teams.PutMember: %v", err)
+		}
+	}
+	svc := api.NewRetrospectiveService(stores.Retrospectives, stores.Items, stores.ItemGroups, stores.Comments,
+		stores.Votes, stores.ActionItems, stores.Participants, stores.Teams, nil)
+	return svc, auth.NewContext(context.Background(), &auth.Principal{UserID: "bob"})
+}
+
+func readImportFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "import", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+// problemList describes import problems as "location: message"
+func problemList(problems []*pb.ImportProblem) []string {
+	var list []string
+	for _, p := range problems {
+		list = append(list, p.Location+": "+p.Message)
+	}
+	return list
+}
+
+// exportJSON exports a retrospective as the JSON document
+func exportJSON(t *testing.T, svc *api.RetrospectiveService, ctx context.Context, retroID string) *api.ExportDocument {
+	t.Helper()
+	resp, err := svc.Export(ctx, &pb.ExportRetrospectiveRequest{RetrospectiveId: retroID, Format: pb.ExportFormat_EXPORT_FORMAT_JSON})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	doc := &api.ExportDocument{}
+	if err := json.Unmarshal(resp.Content, doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// importOutline describes the board of doc without the IDs, times and voter
+// counts an import doesn't keep. CSV doesn't carry empty columns or action
+// items' priorities, notes and source items, so without full those are left
+// out.
+func importOutline(doc *api.ExportDocument, full bool) string {
+	var b strings.Builder
+	sources := make(map[string]string) // item contents by ID
+	for _, col := range doc.Columns {
+		if !full && len(col.Items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "column %s\n", col.Name)
+		titles := make(map[string]string)
+		for _, g := range col.Groups {
+			titles[g.ID] = g.Title
+			fmt.Fprintf(&b, "  group %q votes=%d items=%d\n", g.Title, g.Votes, len(g.ItemIDs))
+		}
+		for _, item := range col.Items {
+			sources[item.ID] = item.Content
+			fmt.Fprintf(&b, "  item %q author=%q anonymous=%t votes=%d group=%q", item.Content, item.Author, item.Anonymous, item.Votes, titles[item.GroupID])
+			if full {
+				fmt.Fprintf(&b, " action=%t", item.HasActionItem)
+			}
+			b.WriteString("\n")
+			for _, c := range item.Comments {
+				fmt.Fprintf(&b, "    comment %q depth=%d author=%q anonymous=%t\n", c.Content, c.Depth, c.Author, c.Anonymous)
+			}
+		}
+	}
+	for _, ai := range doc.ActionItems {
+		due := ""
+		if ai.DueDate != nil {
+			due = ai.DueDate.Format("2006-01-02")
+		}
+		fmt.Fprintf(&b, "action %q status=%s assignee=%q due=%s", ai.Description, ai.Status, ai.AssigneeName, due)
+		if full {
+			// Imports give action items without a priority the default one
+			priority := ai.Priority
+			if priority == "" {
+				priority = "medium"
+			}
+			fmt.Fprintf(&b, " priority=%s notes=%q source=%q", priority, ai.Notes, sources[ai.SourceItemID])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// TestImportRoundTrip imports the JSON and CSV exports of a board and checks
+// the imported retrospective exports as the same board
+func TestImportRoundTrip(t *testing.T) {
+	stores := api.NewInMemoryStores()
+	seedExportBoards(t, stores)
+	svc, ctx := newImportService(t, stores)
+	original := exportJSON(t, svc, ctx, "RETRO-1")
+
+	for _, tt := range []struct {
+		name       string
+		format     pb.ExportFormat
+		sprintName string // the CSV export doesn't hold the sprint name
+		full       bool
+	}{
+		{name: "JSON", format: pb.ExportFormat_EXPORT_FORMAT_JSON, full: true},
+		{name: "CSV", format: pb.ExportFormat_EXPORT_FORMAT_CSV, sprintName: "Sprint 42: Q3/Retro"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := svc.Export(ctx, &pb.ExportRetrospectiveRequest{RetrospectiveId: "RETRO-1", Format: tt.format})
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			resp, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Content: export.Content, SprintName: tt.sprintName})
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if len(resp.Problems) > 0 {
+				t.Errorf("problems = %q, want none", problemList(resp.Problems))
+			}
+			if resp.ItemCount != 6 || resp.GroupCount != 1 || resp.CommentCount != 3 || resp.ActionItemCount != 3 {
+				t.Errorf("counts = %d items, %d groups, %d comments, %d action items, want 6, 1, 3 and 3",
+					resp.ItemCount, resp.GroupCount, resp.CommentCount, resp.ActionItemCount)
+			}
+
+			imported := exportJSON(t, svc, ctx, resp.RetrospectiveId)
+			if got, want := importOutline(imported, tt.full), importOutline(original, tt.full); got != want {
+				t.Errorf("imported board:\n%s\nwant:\n%s", got, want)
+			}
+			retro := imported.Retrospective
+			if retro.SprintName != original.Retrospective.SprintName || retro.Status != "completed" || retro.TeamID != "TEAM-1" {
+				t.Errorf("imported retrospective = %+v", retro)
+			}
+			if tt.full && (!retro.Created.Equal(original.Retrospective.Created) || !retro.StartedAt.Equal(*original.Retrospective.StartedAt) ||
+				!retro.CompletedAt.Equal(*original.Retrospective.CompletedAt)) {
+				t.Errorf("imported retrospective dates = %v, %v, %v, want those of the original", retro.Created, retro.StartedAt, retro.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestImportTrello(t *testing.T) {
+	stores := api.NewInMemoryStores()
+	svc, ctx := newImportService(t, stores)
+	resp, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{
+		TeamId:  "TEAM-1",
+		Content: readImportFixture(t, "trello.json"),
+		Format:  pb.ImportFormat_IMPORT_FORMAT_TRELLO,
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(resp.Problems) > 0 {
+		t.Errorf("problems = %q, want none", problemList(resp.Problems))
+	}
+
+	// Closed lists and cards are left out, cards of the action list and
+	// checklist items become action items, and comments are oldest first
+	doc := exportJSON(t, svc, ctx, resp.RetrospectiveId)
+	want := `column To improve
+  item "Flaky tests" author="Alice Smith" anonymous=false votes=3 group="" action=true
+    comment "I see these daily" depth=0 author="Alice Smith" anonymous=false
+    comment "Same here" depth=0 author="bob" anonymous=false
+column Went well
+  item "Good pairing" author="" anonymous=false votes=0 group="" action=false
+  item "Shipped the beta\n\nOn time" author="Carol" anonymous=false votes=2 group="" action=false
+action "Fix the CI cache" status=done assignee="bob" due=2024-05-10 priority=medium notes="Keyed on the lockfile" source=""
+action "Add retries" status=done assignee="" due= priority=medium notes="" source="Flaky tests"
+action "Quarantine flaky tests" status=not_started assignee="Alice Smith" due= priority=medium notes="" source="Flaky tests"
+`
+	if got := importOutline(doc, true); got != want {
+		t.Errorf("imported board:\n%s\nwant:\n%s", got, want)
+	}
+	retro := doc.Retrospective
+	if retro.SprintName != "Sprint 7 retro" || retro.Description != "Two-week sprint" {
+		t.Errorf("imported retrospective = %+v", retro)
+	}
+	if want := time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC); !retro.Created.Equal(want) {
+		t.Errorf("created = %v, want the board's first activity %v", retro.Created, want)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	stores := api.NewInMemoryStores()
+	svc, ctx := newImportService(t, stores)
+	// A byte order mark, as spreadsheet programs write, is skipped
+	content := append([]byte("\xef\xbb\xbf"), readImportFixture(t, "board.csv")...)
+	resp, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Content: content, SprintName: "Sprint 8"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(resp.Problems) > 0 {
+		t.Errorf("problems = %q, want none", problemList(resp.Problems))
+	}
+
+	// Columns and groups are matched ignoring case, and comments belong to
+	// the item above them
+	doc := exportJSON(t, svc, ctx, resp.RetrospectiveId)
+	want := `column Stop
+  group "Meetings" votes=3 items=2
+  item "Standups ran long" author="Dana" anonymous=false votes=2 group="Meetings" action=false
+  item "Too many syncs" author="" anonymous=false votes=1 group="Meetings" action=false
+    comment "Agreed" depth=0 author="Eve" anonymous=false
+    comment "Me too" depth=1 author="" anonymous=true
+column Start
+  item "Pair more" author="" anonymous=true votes=0 group="" action=false
+action "Shorter standups" status=in_progress assignee="Dana" due=2024-06-01 priority=medium notes="" source=""
+`
+	if got := importOutline(doc, true); got != want {
+		t.Errorf("imported board:\n%s\nwant:\n%s", got, want)
+	}
+	if doc.Retrospective.SprintName != "Sprint 8" {
+		t.Errorf("sprint name = %q, want Sprint 8", doc.Retrospective.SprintName)
+	}
+}
+
+// TestImportDetectsFormat checks which parser reads content when the request
+// leaves the format unspecified
+func TestImportDetectsFormat(t *testing.T) {
+	svc, ctx := newImportService(t, api.NewInMemoryStores())
+	board, err := os.ReadFile(filepath.Join("exporttest", "testdata", "board.json.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range map[string]struct {
+		content []byte
+		items   int32
+	}{
+		"JSON export": {content: board, items: 6},
+		"Trello":      {content: readImportFixture(t, "trello.json"), items: 3},
+		"CSV":         {content: readImportFixture(t, "board.csv"), items: 3},
+		// A schema version marks this service's export even with Trello's
+		// lists and cards
+		"JSON export with lists": {
+			content: []byte(`{"schema_version": 1, "lists": [], "cards": [], "retrospective": {"sprint_name": "S"}, "columns": [{"name": "A", "items": [{"content": "a"}]}]}`),
+			items:   1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Content: tt.content, SprintName: "Sprint", DryRun: true})
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if len(resp.Problems) > 0 || resp.ItemCount != tt.items {
+				t.Errorf("dry run = %d items, problems %q, want %d items and no problems", resp.ItemCount, problemList(resp.Problems), tt.items)
+			}
+		})
+	}
+}
+
+// TestImportProblems checks a dry run reports what is wrong with a file,
+// where, and that nothing is written
+func TestImportProblems(t *testing.T) {
+	stores := api.NewInMemoryStores()
+	svc, ctx := newImportService(t, stores)
+
+	for name, tt := range map[string]struct {
+		format       pb.ImportFormat
+		content      string
+		noSprintName bool
+		want         []string // "location: part of the message"
+	}{
+		"CSV without a content column": {
+			content: "Column,Text\nStop,a\n",
+			want:    []string{"line 1: no content column", "file: no columns"},
+		},
+		"CSV rows": {
+			content: "Type,Column,Content,Votes,Due Date\n" +
+				"Comment,Stop,Early comment,,\n" +
+				"Item,,No column,,\n" +
+				"Item,Stop,,-1,\n" +
+				"Item,Stop,Lots,many,\n" +
+				"Group,Stop,,,\n" +
+				"Note,Stop,What is this,,\n" +
+				"Action Item,,Soon,,next week\n",
+			want: []string{
+				"line 2: comes before any item",
+				"line 3: has no column",
+				`line 4: votes "-1" is not a count`,
+				`line 5: votes "many" is not a count`,
+				"line 6: needs a column and a group title",
+				`line 7: unknown row type "Note"`,
+				`line 8: due date "next week"`,
+				`column "Stop", item 1: has no content`,
+			},
+		},
+		"CSV quoting": {
+			content: "Column,Content\nStop,\"unterminated\n",
+			want:    []string{"line 2: ", "file: no columns"},
+		},
+		"CSV without a sprint name": {
+			content:      "Column,Content\nStop,a\n",
+			noSprintName: true,
+			want:         []string{"retrospective: sprint name is missing"},
+		},
+		"not JSON": {
+			format:  pb.ImportFormat_IMPORT_FORMAT_JSON,
+			content: "Column,Content\n",
+			want:    []string{"file: not a JSON export", "file: no columns"},
+		},
+		"JSON without a schema version": {
+			content: `{"retrospective": {}, "columns": [{"name": "A", "items": [{"content": "a"}]}]}`,
+			want:    []string{"schema_version: missing"},
+		},
+		"JSON from a newer version": {
+			content: `{"schema_version": 99, "retrospective": {}, "columns": [{"name": "A", "items": [{"content": "a"}]}]}`,
+			want:    []string{"schema_version: version 99 is newer"},
+		},
+		"JSON records": {
+			content: `{"schema_version": 1, "retrospective": {"voting_mode": "loudest"}, "columns": [
+				{"id": "a", "name": "A", "groups": [{"id": "g1", "title": " ", "votes": -1}], "items": [
+					{"id": "i1", "content": "a", "votes": -2, "group_id": "g2", "comments": [
+						{"id": "c1", "parent_id": "c2", "content": "reply"},
+						{"id": "c2", "content": " "}
+					]},
+					{"id": "i1", "content": "again"},
+					null
+				]},
+				{"id": "a", "name": ""}
+			], "action_items": [
+				{"description": "Do it", "status": "someday", "priority": "urgent", "source_item_id": "i9"},
+				{"description": ""}
+			]}`,
+			want: []string{
+				`retrospective: unknown voting mode "loudest"`,
+				`column "A", group 1: has no title`,
+				`column "A", group 1: negative vote count -1`,
+				`column "A", item 1: negative vote count -2`,
+				`column "A", item 1: group "g2" is not in this column`,
+				`column "A", item 1, comment 1: replies to "c2"`,
+				`column "A", item 1, comment 2: has no content`,
+				`column "A", item 2: another item has the ID "i1"`,
+				`column "A", item 3: the item is empty`,
+				"column 2: the column has no name",
+				`column 2: another column has the ID "a"`,
+				`action item 1: unknown status "someday"`,
+				`action item 1: unknown priority "urgent"`,
+				`action item 1: source item "i9" is not in the file`,
+				"action item 2: has no description",
+			},
+		},
+		"not a Trello board": {
+			format:  pb.ImportFormat_IMPORT_FORMAT_TRELLO,
+			content: `{"lists": "none"}`,
+			want:    []string{"file: not a Trello board export", "file: no columns"},
+		},
+		"unknown format": {
+			format:  pb.ImportFormat(99),
+			content: "Column,Content\nStop,a\n",
+			want:    []string{"format: unknown import format 99", "file: no columns"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sprintName := "Sprint"
+			if tt.noSprintName {
+				sprintName = ""
+			}
+			req := &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Format: tt.format, Content: []byte(tt.content), SprintName: sprintName, DryRun: true}
+			resp, err := svc.Import(ctx, req)
+			if err != nil {
+				t.Fatalf("dry run: %v", err)
+			}
+			got := problemList(resp.Problems)
+			if len(got) != len(tt.want) {
+				t.Fatalf("problems =\n%s\nwant %d:\n%s", strings.Join(got, "\n"), len(tt.want), strings.Join(tt.want, "\n"))
+			}
+			for i, want := range tt.want {
+				location, message, _ := strings.Cut(want, ": ")
+				if p := resp.Problems[i]; p.Location != location || !strings.Contains(p.Message, message) {
+					t.Errorf("problem %d = %s, want %s", i+1, got[i], want)
+				}
+			}
+
+			// Without a dry run the first problem is the error
+			req.DryRun = false
+			_, err = svc.Import(ctx, req)
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), resp.Problems[0].Message) {
+				t.Errorf("import error = %v, want InvalidArgument with the first problem", err)
+			}
+			if len(resp.Problems) > 1 && !strings.Contains(err.Error(), "a dry run lists them all") {
+				t.Errorf("import error = %v, want it to point at the dry run", err)
+			}
+		})
+	}
+
+	retros, _, _, err := stores.Retrospectives.List("TEAM-1", nil, "", 100)
+	if err != nil {
+		t.Fatalf("Retrospectives.List: %v", err)
+	}
+	if len(retros) > 0 {
+		t.Errorf("%d retrospectives written by imports with problems, want none", len(retros))
+	}
+
+	// A file in the wrong format reports a bounded number of problems
+	content := "Column,Content,Votes\n" + strings.Repeat("Stop,a,x\n", 500)
+	resp, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Content: []byte(content), SprintName: "Sprint", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(resp.Problems) != 100 {
+		t.Errorf("%d problems, want them capped at 100", len(resp.Problems))
+	}
+}
+
+var errImportWrite = errors.New("write failed")
+
+// importWrites records what an import creates and fails the write named by
+// fail after after others of its kind succeeded
+type importWrites struct {
+	fail    string
+	after   int
+	created map[string][]string
+}
+
+func (w *importWrites) create(kind, id string) error {
+	if kind == w.fail && len(w.created[kind]) == w.after {
+		return errImportWrite
+	}
+	w.created[kind] = append(w.created[kind], id)
+	return nil
+}
+
+type importRetros struct {
+	api.RetrospectiveStore
+	w *importWrites
+}
+
+func (s importRetros) Create(retro *vstore.Retrospective) error {
+	if err := s.w.create("retrospective", retro.RetrospectiveID); err != nil {
+		return err
+	}
+	return s.RetrospectiveStore.Create(retro)
+}
+
+func (s importRetros) Update(retro *vstore.Retrospective) error {
+	if s.w.fail == "retrospective update" {
+		return errImportWrite
+	}
+	return s.RetrospectiveStore.Update(retro)
+}
+
+type importItems struct {
+	api.ItemStore
+	w *importWrites
+}
+
+func (s importItems) Create(item *vstore.RetrospectiveItem) error {
+	if err := s.w.create("item", item.ItemID); err != nil {
+		return err
+	}
+	return s.ItemStore.Create(item)
+}
+
+type importGroups struct {
+	api.ItemGroupStore
+	w *importWrites
+}
+
+func (s importGroups) Create(group *vstore.ItemGroup) error {
+	if err := s.w.create("group", group.GroupID); err != nil {
+		return err
+	}
+	return s.ItemGroupStore.Create(group)
+}
+
+type importComments struct {
+	api.CommentStore
+	w *importWrites
+}
+
+func (s importComments) Create(comment *vstore.Comment) error {
+	if err := s.w.create("comment", comment.CommentID); err != nil {
+		return err
+	}
+	return s.CommentStore.Create(comment)
+}
+
+type importActionItems struct {
+	api.ActionItemStore
+	w *importWrites
+}
+
+func (s importActionItems) Create(ai *vstore.ActionItem) error {
+	if err := s.w.create("action item", ai.ActionItemID); err != nil {
+		return err
+	}
+	return s.ActionItemStore.Create(ai)
+}
+
+// TestImportRollback fails each kind of write an import makes and checks
+// whatever it had written is deleted again
+func TestImportRollback(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("exporttest", "testdata", "board.json.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		fail  string
+		after int
+	}{
+		{fail: "group"},
+		{fail: "item", after: 3},
+		{fail: "comment", after: 1},
+		{fail: "action item", after: 2},
+		{fail: "retrospective"},
+		{fail: "retrospective update"},
+	} {
+		t.Run(tt.fail, func(t *testing.T) {
+			stores := api.NewInMemoryStores()
+			_, ctx := newImportService(t, stores)
+			w := &importWrites{fail: tt.fail, after: tt.after, created: make(map[string][]string)}
+			svc := api.NewRetrospectiveService(importRetros{stores.Retrospectives, w}, importItems{stores.Items, w},
+				importGroups{stores.ItemGroups, w}, importComments{stores.Comments, w}, stores.Votes,
+				importActionItems{stores.ActionItems, w}, stores.Participants, stores.Teams, nil)
+
+			_, err := svc.Import(ctx, &pb.ImportRetrospectiveRequest{TeamId: "TEAM-1", Content: content})
+			if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), errImportWrite.Error()) {
+				t.Fatalf("Import error = %v, want the failed write", err)
+			}
+			if len(w.created) == 0 && tt.fail != "group" {
+				t.Fatal("nothing was written before the failure")
+			}
+
+			gets := map[string]func(id string) error{
+				"retrospective": func(id string) error { _, err := stores.Retrospectives.Get(id); return err },
+				"group":         func(id string) error { _, err := stores.ItemGroups.Get(id); return err },
+				"item":          func(id string) error { _, err := stores.Items.Get(id); return err },
+				"comment":       func(id string) error { _, err := stores.Comments.Get(id); return err },
+				"action item":   func(id string) error { _, err := stores.ActionItems.Get(id); return err },
+			}
+			for kind, ids := range w.created {
+				for _, id := range ids {
+					if err := gets[kind](id); !errors.Is(err, api.ErrNotFound) {
+						t.Errorf("%s %s after the failed import: %v, want it deleted", kind, id, err)
+					}
+				}
+			}
+			actionItems, err := stores.ActionItems.ListByTeam("TEAM-1", true)
+			if err != nil {
+				t.Fatalf("ActionItems.ListByTeam: %v", err)
+			}
+			if len(actionItems) > 0 {
+				t.Errorf("%d action items left on the team, want none", len(actionItems))
+			}
+		})
+	}
+}
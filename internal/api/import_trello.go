@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trelloBoard is the part of a Trello board's JSON export that is imported
+type trelloBoard struct {
+	Name             string             `json:"name"`
+	Desc             string             `json:"desc"`
+	DateLastActivity *time.Time         `json:"dateLastActivity"`
+	Lists            []*trelloList      `json:"lists"`
+	Cards            []*trelloCard      `json:"cards"`
+	Checklists       []*trelloChecklist `json:"checklists"`
+	Members          []*trelloMember    `json:"members"`
+	Actions          []*trelloAction    `json:"actions"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Desc           string     `json:"desc"`
+	IDList         string     `json:"idList"`
+	Closed         bool       `json:"closed"`
+	Pos            float64    `json:"pos"`
+	Due            *time.Time `json:"due"`
+	DueComplete    bool       `json:"dueComplete"`
+	IDMembers      []string   `json:"idMembers"`
+	IDMembersVoted []string   `json:"idMembersVoted"`
+	Badges         struct {
+		Votes int32 `json:"votes"`
+	} `json:"badges"`
+}
+
+type trelloChecklist struct {
+	IDCard     string `json:"idCard"`
+	CheckItems []struct {
+		Name     string     `json:"name"`
+		State    string     `json:"state"` // complete or incomplete
+		Pos      float64    `json:"pos"`
+		Due      *time.Time `json:"due"`
+		IDMember string     `json:"idMember"`
+	} `json:"checkItems"`
+}
+
+type trelloMember struct {
+	ID       string `json:"id"`
+	FullName string `json:"fullName"`
+	Username string `json:"username"`
+}
+
+// trelloAction is an entry of the board's history, which holds who created
+// each card and the comments on it
+type trelloAction struct {
+	Type            string    `json:"type"`
+	Date            time.Time `json:"date"`
+	IDMemberCreator string    `json:"idMemberCreator"`
+	MemberCreator   struct {
+		FullName string `json:"fullName"`
+	} `json:"memberCreator"`
+	Data struct {
+		Text string `json:"text"`
+		Card struct {
+			ID string `json:"id"`
+		} `json:"card"`
+	} `json:"data"`
+}
+
+// trelloActionListNames are the names of lists whose cards are imported as
+// action items rather than as items
+var trelloActionListNames = map[string]bool{
+	"action items":  true,
+	"action points": true,
+	"actions":       true,
+}
+
+// parseImportTrello reads the JSON export of a Trello board. Open lists become
+// columns and their open cards items, with the Voting Power-Up's votes, the
+// card's creator as author and its comments. Cards in a list named like
+// "Action items" and checklist items on open cards become action items.
+func parseImportTrello(content []byte, problems *importProblems) *ExportDocument {
+	board := &trelloBoard{}
+	if err := json.Unmarshal(content, board); err != nil {
+		problems.add("file", "not a Trello board export: %v", err)
+		return emptyImportDocument()
+	}
+
+	doc := emptyImportDocument()
+	doc.Retrospective.SprintName = board.Name
+	doc.Retrospective.Description = board.Desc
+
+	members := make(map[string]string)
+	for _, m := range board.Members {
+		if m == nil {
+			continue
+		}
+		members[m.ID] = m.FullName
+		if members[m.ID] == "" {
+			members[m.ID] = m.Username
+		}
+	}
+
+	// The board's history is newest first; comments are imported oldest first
+	actions := make([]*trelloAction, 0, len(board.Actions))
+	for _, a := range board.Actions {
+		if a != nil {
+			actions = append(actions, a)
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Date.Before(actions[j].Date)
+	})
+	creators := make(map[string]string)
+	comments := make(map[string][]*ExportComment)
+	for _, a := range actions {
+		author := members[a.IDMemberCreator]
+		if author == "" {
+			author = a.MemberCreator.FullName
+		}
+		switch a.Type {
+		case "createCard", "copyCard", "convertToCardFromCheckItem":
+			creators[a.Data.Card.ID] = author
+		case "commentCard":
+			comments[a.Data.Card.ID] = append(comments[a.Data.Card.ID], &ExportComment{
+				Author:  author,
+				Content: a.Data.Text,
+				Created: a.Date,
+			})
+		}
+		if doc.Retrospective.Created.IsZero() {
+			doc.Retrospective.Created = a.Date
+		}
+	}
+	if doc.Retrospective.Created.IsZero() && board.DateLastActivity != nil {
+		doc.Retrospective.Created = *board.DateLastActivity
+	}
+
+	var lists []*trelloList
+	for _, list := range board.Lists {
+		if list != nil && !list.Closed {
+			lists = append(lists, list)
+		}
+	}
+	sort.SliceStable(lists, func(i, j int) bool {
+		return lists[i].Pos < lists[j].Pos
+	})
+	cards := make([]*trelloCard, 0, len(board.Cards))
+	for _, card := range board.Cards {
+		if card != nil && !card.Closed {
+			cards = append(cards, card)
+		}
+	}
+	sort.SliceStable(cards, func(i, j int) bool {
+		return cards[i].Pos < cards[j].Pos
+	})
+
+	onBoard := make(map[string]bool)  // IDs of the cards in open lists
+	imported := make(map[string]bool) // IDs of the cards imported as items
+	for _, list := range lists {
+		actionList := trelloActionListNames[strings.ToLower(strings.TrimSpace(list.Name))]
+		var column *ExportColumn
+		if !actionList {
+			column = &ExportColumn{Name: list.Name, Items: []*ExportItem{}}
+			doc.Columns = append(doc.Columns, column)
+		}
+		for _, card := range cards {
+			if card.IDList != list.ID {
+				continue
+			}
+			onBoard[card.ID] = true
+			if actionList {
+				ai := &ExportActionItem{
+					Description: card.Name,
+					Status:      "not_started",
+					DueDate:     card.Due,
+					Notes:       card.Desc,
+				}
+				if card.DueComplete {
+					ai.Status = "done"
+				}
+				if len(card.IDMembers) > 0 {
+					ai.AssigneeName = members[card.IDMembers[0]]
+				}
+				doc.ActionItems = append(doc.ActionItems, ai)
+				continue
+			}
+
+			item := &ExportItem{
+				ID:         card.ID,
+				Content:    card.Name,
+				Author:     creators[card.ID],
+				Votes:      card.Badges.Votes,
+				VoterCount: len(card.IDMembersVoted),
+				Comments:   comments[card.ID],
+			}
+			if desc := strings.TrimSpace(card.Desc); desc != "" {
+				item.Content += "\n\n" + desc
+			}
+			if n := int32(len(card.IDMembersVoted)); n > item.Votes {
+				item.Votes = n
+			}
+			column.Items = append(column.Items, item)
+			imported[card.ID] = true
+		}
+	}
+
+	for _, checklist := range board.Checklists {
+		if checklist == nil || !onBoard[checklist.IDCard] {
+			continue
+		}
+		checkItems := checklist.CheckItems
+		sort.SliceStable(checkItems, func(i, j int) bool {
+			return checkItems[i].Pos < checkItems[j].Pos
+		})
+		for _, check := range checkItems {
+			ai := &ExportActionItem{
+				Description:  check.Name,
+				Status:       "not_started",
+				AssigneeName: members[check.IDMember],
+				DueDate:      check.Due,
+			}
+			if check.State == "complete" {
+				ai.Status = "done"
+			}
+			if imported[checklist.IDCard] {
+				ai.SourceItemID = checklist.IDCard
+			}
+			doc.ActionItems = append(doc.ActionItems, ai)
+		}
+	}
+	return doc
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// CreateComment adds a comment to an item, or a reply to one of its comments
+func (s *RetrospectiveItemService) CreateComment(ctx context.Context, req *pb.CreateCommentRequest) (*pb.CreateCommentResponse, error) {
+	if req.ItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	}
+	if req.Content == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: content is required", ErrInvalidArgument))
+	}
+
+	item, err := s.itemStore.Get(req.ItemId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if itemsHidden(retro) {
+		return nil, ToGRPCError(fmt.Errorf("%w: comments open once the cards are revealed", ErrInvalidStatus))
+	}
+	if req.IsAnonymous && !item.IsAnonymous {
+		return nil, ToGRPCError(fmt.Errorf("%w: only anonymous items take anonymous comments", ErrInvalidArgument))
+	}
+	if req.ParentCommentId != "" {
+		parent, err := s.commentStore.Get(req.ParentCommentId)
+		if err != nil {
+			return nil, ToGRPCError(err)
+		}
+		if parent.ItemID != item.ItemID {
+			return nil, ToGRPCError(fmt.Errorf("%w: parent comment belongs to another item", ErrInvalidArgument))
+		}
+	}
+
+	userName := "Anonymous"
+	if !req.IsAnonymous {
+		userName = getUserNameFromContext(ctx)
+	}
+
+	comment := &vstore.Comment{
+		CommentID:       fmt.Sprintf("COMMENT-%d", time.Now().UnixNano()),
+		RetrospectiveID: item.RetrospectiveID,
+		ItemID:          item.ItemID,
+		ParentID:        req.ParentCommentId,
+		Content:         req.Content,
+		CreatedBy:       getUserIDFromContext(ctx),
+		CreatedByName:   userName,
+		IsAnonymous:     req.IsAnonymous,
+	}
+	if err := s.commentStore.Create(comment); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	pbComment := convertVstoreCommentToPb(comment)
+	BroadcastCommentCreated(comment.RetrospectiveID, pbComment)
+
+	return &pb.CreateCommentResponse{
+		Comment: pbComment,
+	}, nil
+}
+
+// UpdateComment edits the content of a comment
+func (s *RetrospectiveItemService) UpdateComment(ctx context.Context, req *pb.UpdateCommentRequest) (*emptypb.Empty, error) {
+	if req.CommentId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: comment_id is required", ErrInvalidArgument))
+	}
+	if req.Content == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: content is required", ErrInvalidArgument))
+	}
+
+	comment, err := s.getCommentForEditor(ctx, req.CommentId, "edit")
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	comment.Content = req.Content
+	if err := s.commentStore.Update(comment); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	BroadcastCommentUpdated(comment.RetrospectiveID, convertVstoreCommentToPb(comment))
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteComment deletes a comment along with the replies under it
+func (s *RetrospectiveItemService) DeleteComment(ctx context.Context, req *pb.DeleteCommentRequest) (*emptypb.Empty, error) {
+	if req.CommentId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: comment_id is required", ErrInvalidArgument))
+	}
+
+	comment, err := s.getCommentForEditor(ctx, req.CommentId, "delete")
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	comments, err := s.commentStore.ListByItem(comment.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	for _, c := range commentSubtree(comments, comment.CommentID) {
+		if err := s.commentStore.Delete(c.CommentID); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		BroadcastCommentDeleted(c.RetrospectiveID, c.CommentID, c.ItemID)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListComments lists the comments on an item in thread order
+func (s *RetrospectiveItemService) ListComments(ctx context.Context, req *pb.ListCommentsRequest) (*pb.ListCommentsResponse, error) {
+	if req.ItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	}
+
+	item, err := s.itemStore.Get(req.ItemId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	comments, err := s.commentStore.ListByItem(item.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	var pbComments []*pb.ItemComment
+	for _, c := range threadComments(comments) {
+		pbComments = append(pbComments, convertVstoreCommentToPb(c.comment))
+	}
+
+	return &pb.ListCommentsResponse{
+		Comments: pbComments,
+	}, nil
+}
+
+// getCommentForEditor loads a comment that the caller may change: their own,
+// or any comment when they facilitate its retrospective
+func (s *RetrospectiveItemService) getCommentForEditor(ctx context.Context, commentID, action string) (*vstore.Comment, error) {
+	comment, err := s.commentStore.Get(commentID)
+	if err != nil {
+		return nil, err
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, comment.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireContributor(ctx, s.participantStore, member, comment.RetrospectiveID); err != nil {
+		return nil, err
+	}
+	if comment.CreatedBy != getUserIDFromContext(ctx) && !canFacilitate(ctx, member, retro) {
+		return nil, fmt.Errorf("%w: only the author, the facilitator or a team admin can %s this comment", ErrPermissionDenied, action)
+	}
+	return comment, nil
+}
+
+// moveComments re-points the comments on one item at another
+func (s *RetrospectiveItemService) moveComments(fromItemID, toItemID string) error {
+	comments, err := s.commentStore.ListByItem(fromItemID)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		comment.ItemID = toItemID
+		if err := s.commentStore.Update(comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteComments deletes every comment on an item
+func (s *RetrospectiveItemService) deleteComments(itemID string) error {
+	comments, err := s.commentStore.ListByItem(itemID)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := s.commentStore.Delete(comment.CommentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// threadedComment is a comment and how deeply it is nested in its thread
+type threadedComment struct {
+	comment *vstore.Comment
+	depth   int
+}
+
+// threadComments orders comments depth first, so every reply follows the
+// comment it answers, with siblings oldest first. Replies whose parent is gone
+// are treated as top-level comments.
+func threadComments(comments []*vstore.Comment) []threadedComment {
+	byID := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		byID[c.CommentID] = true
+	}
+	children := make(map[string][]*vstore.Comment)
+	for _, c := range comments {
+		parent := c.ParentID
+		if !byID[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], c)
+	}
+	for _, siblings := range children {
+		sort.Slice(siblings, func(i, j int) bool {
+			if !siblings[i].Created.Equal(siblings[j].Created) {
+				return siblings[i].Created.Before(siblings[j].Created)
+			}
+			return siblings[i].CommentID < siblings[j].CommentID
+		})
+	}
+
+	var result []threadedComment
+	var walk func(parentID string, depth int)
+	walk = func(parentID string, depth int) {
+		for _, c := range children[parentID] {
+			result = append(result, threadedComment{comment: c, depth: depth})
+			walk(c.CommentID, depth+1)
+		}
+	}
+	walk("", 0)
+	return result
+}
+
+// commentThreadsByItem threads the comments of every item
+func commentThreadsByItem(comments []*vstore.Comment) map[string][]threadedComment {
+	byItem := make(map[string][]*vstore.Comment)
+	for _, c := range comments {
+		byItem[c.ItemID] = append(byItem[c.ItemID], c)
+	}
+	threads := make(map[string][]threadedComment, len(byItem))
+	for itemID, itemComments := range byItem {
+		threads[itemID] = threadComments(itemComments)
+	}
+	return threads
+}
+
+// commentSubtree returns the comment rootID and every reply under it
+func commentSubtree(comments []*vstore.Comment, rootID string) []*vstore.Comment {
+	children := make(map[string][]*vstore.Comment)
+	var root *vstore.Comment
+	for _, c := range comments {
+		if c.CommentID == rootID {
+			root = c
+		}
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+	if root == nil {
+		return nil
+	}
+
+	result := []*vstore.Comment{root}
+	for i := 0; i < len(result); i++ {
+		result = append(result, children[result[i].CommentID]...)
+	}
+	return result
+}
+
+// convertVstoreCommentToPb converts a comment, keeping the identity of
+// anonymous authors hidden
+func convertVstoreCommentToPb(c *vstore.Comment) *pb.ItemComment {
+	result := &pb.ItemComment{
+		CommentId:       c.CommentID,
+		RetrospectiveId: c.RetrospectiveID,
+		ItemId:          c.ItemID,
+		ParentCommentId: c.ParentID,
+		Content:         c.Content,
+		CreatedBy:       c.CreatedBy,
+		CreatedByName:   c.CreatedByName,
+		IsAnonymous:     c.IsAnonymous,
+		Created:         timestamppb.New(c.Created),
+		Updated:         timestamppb.New(c.Updated),
+	}
+	if c.IsAnonymous {
+		result.CreatedBy = ""
+	}
+	return result
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// Items are ordered within their column by SortKey, a fractional index: a
+// base-62 string read as the digits of a fraction between 0 and 1. A key can
+// always be made between two others, so moving an item rewrites only that
+// item and concurrent moves never renumber the rest of the column.
+
+// sortKeyDigits are the key digits in ascending byte order, so keys compare
+// as plain strings
+const sortKeyDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// sortKeyBetween returns a key that sorts after a and before b. An empty a
+// stands for the top of the column and an empty b for its bottom.
+func sortKeyBetween(a, b string) string {
+	switch {
+	case a != "" && b == "":
+		// Appending steps the first digit up instead of halving the gap, so the
+		// keys of items added one after another stay short
+		if i := strings.IndexByte(sortKeyDigits, a[0]); i < len(sortKeyDigits)-1 {
+			return sortKeyDigits[i+1 : i+2]
+		}
+		return a[:1] + sortKeyBetween(a[1:], "")
+	case a == "" && b != "":
+		// Prepending steps it down, stopping short of the zero digit
+		if i := strings.IndexByte(sortKeyDigits, b[0]); i > 1 {
+			return sortKeyDigits[i-1 : i]
+		}
+		if b[0] == sortKeyDigits[0] {
+			return b[:1] + sortKeyBetween("", b[1:])
+		}
+	}
+	return sortKeyMidpoint(a, b)
+}
+
+// sortKeyMidpoint returns a key roughly halfway between a and b. Keys never
+// end in the zero digit, which leaves room below every key.
+func sortKeyMidpoint(a, b string) string {
+	if b != "" {
+		// Keep the digits the keys share, reading a missing digit of a as zero
+		n := 0
+		for n < len(b) && sortKeyDigit(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + sortKeyMidpoint(sortKeyTail(a, n), b[n:])
+		}
+	}
+
+	lo, hi := 0, len(sortKeyDigits)
+	if a != "" {
+		lo = strings.IndexByte(sortKeyDigits, a[0])
+	}
+	if b != "" {
+		hi = strings.IndexByte(sortKeyDigits, b[0])
+	}
+	if hi-lo > 1 {
+		mid := (lo + hi + 1) / 2
+		return sortKeyDigits[mid : mid+1]
+	}
+	// The first digits are adjacent: b's first digit alone still sorts below b
+	// when b has more digits, otherwise look for room after a's first digit
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return sortKeyDigits[lo:lo+1] + sortKeyMidpoint(sortKeyTail(a, 1), "")
+}
+
+func sortKeyDigit(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return sortKeyDigits[0]
+}
+
+func sortKeyTail(key string, i int) string {
+	if i < len(key) {
+		return key[i:]
+	}
+	return ""
+}
+
+// itemBefore reports whether a sorts before b in their column. Items that
+// predate sort keys fall back to their position and creation time, and items
+// given the same key by concurrent moves are told apart by ID.
+func itemBefore(a, b *vstore.RetrospectiveItem) bool {
+	if a.SortKey != b.SortKey {
+		return a.SortKey < b.SortKey
+	}
+	if a.Position != b.Position {
+		return a.Position < b.Position
+	}
+	if !a.Created.Equal(b.Created) {
+		return a.Created.Before(b.Created)
+	}
+	return a.ItemID < b.ItemID
+}
+
+// sortItems orders items by position, or by vote count with position
+// breaking ties
+func sortItems(items []*vstore.RetrospectiveItem, sortByVotes bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if sortByVotes && items[i].VoteCount != items[j].VoteCount {
+			return items[i].VoteCount > items[j].VoteCount
+		}
+		return itemBefore(items[i], items[j])
+	})
+}
+
+// columnIndexes returns the index of every item within its column
+func columnIndexes(items []*vstore.RetrospectiveItem) map[string]int32 {
+	ordered := append([]*vstore.RetrospectiveItem(nil), items...)
+	sortItems(ordered, false)
+	indexes := make(map[string]int32, len(ordered))
+	next := make(map[string]int32)
+	for _, item := range ordered {
+		indexes[item.ItemID] = next[item.ColumnID]
+		next[item.ColumnID]++
+	}
+	return indexes
+}
+
+// indexOfItem returns the index of the item with itemID in items, or -1
+func indexOfItem(items []*vstore.RetrospectiveItem, itemID string) int {
+	for i, item := range items {
+		if item.ItemID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnKeysOrdered reports whether every item in column, which is in
+// position order, has a key of its own
+func columnKeysOrdered(column []*vstore.RetrospectiveItem) bool {
+	for i, item := range column {
+		if item.SortKey == "" || (i > 0 && column[i-1].SortKey >= item.SortKey) {
+			return false
+		}
+	}
+	return true
+}
+
+// placeItem gives item the key and position that put it at index among the
+// other items of its column, which are in position order. When the column
+// lacks room there, because it predates sort keys or concurrent moves left
+// two items with the same key, the column is rekeyed first and the rekeyed
+// items are returned so they can be saved.
+func placeItem(item *vstore.RetrospectiveItem, column []*vstore.RetrospectiveItem, index int) []*vstore.RetrospectiveItem {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(column) {
+		index = len(column)
+	}
+
+	var rekeyed []*vstore.RetrospectiveItem
+	if !columnKeysOrdered(column) {
+		key := ""
+		for i, other := range column {
+			key = sortKeyBetween(key, "")
+			if other.SortKey != key || other.Position != int32(i) {
+				other.SortKey = key
+				other.Position = int32(i)
+				rekeyed = append(rekeyed, other)
+			}
+		}
+	}
+
+	before, after := "", ""
+	if index > 0 {
+		before = column[index-1].SortKey
+	}
+	if index < len(column) {
+		after = column[index].SortKey
+	}
+	item.SortKey = sortKeyBetween(before, after)
+	item.Position = int32(index)
+	return rekeyed
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// defaultReactionEmojis are offered when a retrospective doesn't pick its own
+var defaultReactionEmojis = []string{"👍", "❤️", "😂", "🎉", "🤔", "👀"}
+
+// maxReactionEmojis caps the size of a retrospective's emoji set
+const maxReactionEmojis = 12
+
+// reactionEmojis returns the emojis items in the retrospective can be reacted with
+func reactionEmojis(retro *vstore.Retrospective) []string {
+	if len(retro.ReactionEmojis) == 0 {
+		return defaultReactionEmojis
+	}
+	return retro.ReactionEmojis
+}
+
+// validateReactionEmojis checks an emoji set chosen for a retrospective
+func validateReactionEmojis(emojis []string) error {
+	if len(emojis) > maxReactionEmojis {
+		return fmt.Errorf("%w: at most %d reaction emojis are allowed", ErrInvalidArgument, maxReactionEmojis)
+	}
+	seen := make(map[string]bool, len(emojis))
+	for _, emoji := range emojis {
+		if emoji == "" {
+			return fmt.Errorf("%w: reaction emojis cannot be empty", ErrInvalidArgument)
+		}
+		if seen[emoji] {
+			return fmt.Errorf("%w: reaction emoji %s is listed twice", ErrInvalidArgument, emoji)
+		}
+		seen[emoji] = true
+	}
+	return nil
+}
+
+// AddReaction reacts to an item with one of the retrospective's emojis
+func (s *RetrospectiveItemService) AddReaction(ctx context.Context, req *pb.AddReactionRequest) (*pb.AddReactionResponse, error) {
+	retro, item, err := s.getItemForReaction(ctx, req.ItemId, req.Emoji)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	offered := false
+	for _, emoji := range reactionEmojis(retro) {
+		if emoji == req.Emoji {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return nil, ToGRPCError(fmt.Errorf("%w: %s is not one of this retrospective's reaction emojis", ErrInvalidArgument, req.Emoji))
+	}
+
+	err = s.reactionStore.Add(&vstore.Reaction{
+		RetrospectiveID: item.RetrospectiveID,
+		ItemID:          item.ItemID,
+		UserID:          getUserIDFromContext(ctx),
+		Emoji:           req.Emoji,
+	})
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	reactions, err := s.broadcastReactions(retro, item.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.AddReactionResponse{
+		Reactions: countReactions(retro, reactions, getUserIDFromContext(ctx)),
+	}, nil
+}
+
+// RemoveReaction takes back the caller's reaction to an item. Reactions with
+// emojis since dropped from the retrospective's set can still be removed.
+func (s *RetrospectiveItemService) RemoveReaction(ctx context.Context, req *pb.RemoveReactionRequest) (*pb.RemoveReactionResponse, error) {
+	retro, item, err := s.getItemForReaction(ctx, req.ItemId, req.Emoji)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if err := s.reactionStore.Remove(item.RetrospectiveID, item.ItemID, getUserIDFromContext(ctx), req.Emoji); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	reactions, err := s.broadcastReactions(retro, item.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.RemoveReactionResponse{
+		Reactions: countReactions(retro, reactions, getUserIDFromContext(ctx)),
+	}, nil
+}
+
+// getItemForReaction loads an item the caller may react to
+func (s *RetrospectiveItemService) getItemForReaction(ctx context.Context, itemID, emoji string) (*vstore.Retrospective, *vstore.RetrospectiveItem, error) {
+	if itemID == "" {
+		return nil, nil, fmt.Errorf("%w: item_id is required", ErrInvalidArgument)
+	}
+	if emoji == "" {
+		return nil, nil, fmt.Errorf("%w: emoji is required", ErrInvalidArgument)
+	}
+
+	item, err := s.itemStore.Get(itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, nil, err
+	}
+	if itemsHidden(retro) {
+		return nil, nil, fmt.Errorf("%w: reactions open once the cards are revealed", ErrInvalidStatus)
+	}
+	return retro, item, nil
+}
+
+// broadcastReactions sends everyone the item's new reaction counts and
+// returns the reactions they were counted from
+func (s *RetrospectiveItemService) broadcastReactions(retro *vstore.Retrospective, itemID string) ([]*vstore.Reaction, error) {
+	reactions, err := s.reactionStore.ListByItem(retro.RetrospectiveID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	BroadcastReactionsUpdated(retro, itemID, reactions)
+	return reactions, nil
+}
+
+// moveReactions re-points the reactions on one item at another. A user who
+// reacted to both with the same emoji keeps a single reaction. Each reaction
+// is added to the target before it is removed from the source, so a failure
+// part way leaves it on both items rather than on neither.
+func (s *RetrospectiveItemService) moveReactions(retroID, fromItemID, toItemID string) error {
+	reactions, err := s.reactionStore.ListByItem(retroID, fromItemID)
+	if err != nil {
+		return err
+	}
+	for _, reaction := range reactions {
+		moved := *reaction
+		moved.ItemID = toItemID
+		if err := s.reactionStore.Add(&moved); err != nil && !errors.Is(err, ErrAlreadyExists) {
+			return err
+		}
+		if err := s.reactionStore.Remove(retroID, fromItemID, reaction.UserID, reaction.Emoji); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteReactions deletes every reaction on an item
+func (s *RetrospectiveItemService) deleteReactions(retroID, itemID string) error {
+	reactions, err := s.reactionStore.ListByItem(retroID, itemID)
+	if err != nil {
+		return err
+	}
+	for _, reaction := range reactions {
+		if err := s.reactionStore.Remove(retroID, itemID, reaction.UserID, reaction.Emoji); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reactionsByItem groups reactions by the item they are on
+func reactionsByItem(reactions []*vstore.Reaction) map[string][]*vstore.Reaction {
+	byItem := make(map[string][]*vstore.Reaction)
+	for _, r := range reactions {
+		byItem[r.ItemID] = append(byItem[r.ItemID], r)
+	}
+	return byItem
+}
+
+// countReactions totals an item's reactions per emoji, in the order of the
+// retrospective's emoji set. Emojis since taken out of the set follow in the
+// order they were first reacted with. Emojis nobody reacted with are left out.
+func countReactions(retro *vstore.Retrospective, reactions []*vstore.Reaction, userID string) []*pb.ReactionCount {
+	counts := make(map[string]*pb.ReactionCount)
+	firstReacted := make(map[string]time.Time)
+	for _, r := range reactions {
+		count, ok := counts[r.Emoji]
+		if !ok {
+			count = &pb.ReactionCount{Emoji: r.Emoji}
+			counts[r.Emoji] = count
+		}
+		if first, ok := firstReacted[r.Emoji]; !ok || r.Created.Before(first) {
+			firstReacted[r.Emoji] = r.Created
+		}
+		count.Count++
+		if r.UserID == userID {
+			count.CurrentUserReacted = true
+		}
+	}
+
+	var result []*pb.ReactionCount
+	for _, emoji := range reactionEmojis(retro) {
+		if count, ok := counts[emoji]; ok {
+			result = append(result, count)
+			delete(counts, emoji)
+		}
+	}
+	leftover := make([]*pb.ReactionCount, 0, len(counts))
+	for _, count := range counts {
+		leftover = append(leftover, count)
+	}
+	sort.Slice(leftover, func(i, j int) bool {
+		a, b := firstReacted[leftover[i].Emoji], firstReacted[leftover[j].Emoji]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return leftover[i].Emoji < leftover[j].Emoji
+	})
+	return append(result, leftover...)
+}
@@ -2,30 +2,53 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/auth"
 	"github.com/vendasta/retrospective/internal/vstore"
 )
 
 // RetrospectiveItemService implements the RetrospectiveItemService gRPC service
 type RetrospectiveItemService struct {
 	pb.UnimplementedRetrospectiveItemServiceServer
-	itemStore  *InMemoryItemStore
-	retroStore *InMemoryRetrospectiveStore
+	itemStore        ItemStore
+	groupStore       ItemGroupStore
+	commentStore     CommentStore
+	reactionStore    ReactionStore
+	voteStore        VoteStore
+	retroStore       RetrospectiveStore
+	participantStore ParticipantStore
+	teamStore        TeamStore
 }
 
 // NewRetrospectiveItemService creates a new RetrospectiveItemService
 func NewRetrospectiveItemService(
-	itemStore *InMemoryItemStore,
-	retroStore *InMemoryRetrospectiveStore,
+	itemStore ItemStore,
+	groupStore ItemGroupStore,
+	commentStore CommentStore,
+	reactionStore ReactionStore,
+	voteStore VoteStore,
+	retroStore RetrospectiveStore,
+	participantStore ParticipantStore,
+	teamStore TeamStore,
 ) *RetrospectiveItemService {
 	return &RetrospectiveItemService{
-		itemStore:  itemStore,
-		retroStore: retroStore,
+		itemStore:        itemStore,
+		groupStore:       groupStore,
+		commentStore:     commentStore,
+		reactionStore:    reactionStore,
+		voteStore:        voteStore,
+		retroStore:       retroStore,
+		participantStore: participantStore,
+		teamStore:        teamStore,
 	}
 }
 
@@ -42,26 +65,26 @@ func (s *RetrospectiveItemService) Create(ctx context.Context, req *pb.CreateIte
 	}
 
 	// Verify retrospective exists
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := requireContributor(ctx, s.participantStore, member, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Verify column exists in template
-	columnValid := false
-	for _, col := range retro.TemplateColumns {
-		if col.ColumnID == req.ColumnId {
-			columnValid = true
-			break
-		}
-	}
-	if !columnValid {
+	if !hasColumn(retro, req.ColumnId) {
 		return nil, ToGRPCError(fmt.Errorf("%w: invalid column_id", ErrInvalidArgument))
 	}
 
-	// Get next position
-	existingItems, _ := s.itemStore.ListByRetrospective(req.RetrospectiveId, req.ColumnId, false)
-	position := int32(len(existingItems))
+	// Add the item to the bottom of its column
+	column, _ := s.itemStore.ListByRetrospective(req.RetrospectiveId, req.ColumnId, false)
+	position := int32(len(column))
+	sortKey := sortKeyBetween("", "")
+	if len(column) > 0 {
+		sortKey = sortKeyBetween(column[len(column)-1].SortKey, "")
+	}
 
 	userID := getUserIDFromContext(ctx)
 	userName := "Anonymous"
@@ -82,6 +105,7 @@ func (s *RetrospectiveItemService) Create(ctx context.Context, req *pb.CreateIte
 		VoteCount:       0,
 		IsAnonymous:     req.IsAnonymous,
 		Position:        position,
+		SortKey:         sortKey,
 		HasActionItem:   false,
 	}
 
@@ -93,8 +117,21 @@ func (s *RetrospectiveItemService) Create(ctx context.Context, req *pb.CreateIte
 	retro.ItemCount++
 	s.retroStore.Update(retro)
 
+	pbItem := convertVstoreItemToPb(retro, item)
+	BroadcastItemCreated(retro, item)
+
+	// Flag likely duplicates so the author can merge or reword their card, unless
+	// a silent brainstorm keeps other people's cards out of sight
+	var duplicateIDs []string
+	if others, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, "", false); err == nil && !itemsHidden(retro) {
+		for _, other := range similarItems(item, others, defaultMergeThreshold) {
+			duplicateIDs = append(duplicateIDs, other.ItemID)
+		}
+	}
+
 	return &pb.CreateItemResponse{
-		Item: convertVstoreItemToPb(item),
+		Item:                 pbItem,
+		PossibleDuplicateIds: duplicateIDs,
 	}, nil
 }
 
@@ -108,6 +145,16 @@ func (s *RetrospectiveItemService) Update(ctx context.Context, req *pb.UpdateIte
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, existing.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, existing.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, existing, "edit"); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Update allowed fields
 	if req.Item.Content != "" {
@@ -118,10 +165,12 @@ func (s *RetrospectiveItemService) Update(ctx context.Context, req *pb.UpdateIte
 		return nil, ToGRPCError(err)
 	}
 
+	BroadcastItemUpdated(retro, existing)
+
 	return &emptypb.Empty{}, nil
 }
 
-// Delete deletes an item
+// Delete deletes an item along with its votes, comments and reactions
 func (s *RetrospectiveItemService) Delete(ctx context.Context, req *pb.DeleteItemRequest) (*emptypb.Empty, error) {
 	if req.ItemId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
@@ -136,16 +185,45 @@ func (s *RetrospectiveItemService) Delete(ctx context.Context, req *pb.DeleteIte
 	if req.RetrospectiveId != "" && item.RetrospectiveID != req.RetrospectiveId {
 		return nil, ToGRPCError(fmt.Errorf("%w: item does not belong to this retrospective", ErrInvalidArgument))
 	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, item, "delete"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	// Withdraw the item's votes so they stop counting against voters' limits
+	votes, err := s.voteStore.ListByItem(item.RetrospectiveID, item.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	for _, vote := range votes {
+		if _, err := s.voteStore.Retract(vote.RetrospectiveID, vote.ItemID, vote.UserID); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, ToGRPCError(err)
+		}
+	}
 
 	if err := s.itemStore.Delete(req.ItemId); err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := s.deleteComments(req.ItemId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := s.deleteReactions(item.RetrospectiveID, req.ItemId); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Update item count on retrospective
-	retro, err := s.retroStore.Get(item.RetrospectiveID)
-	if err == nil {
-		retro.ItemCount--
-		s.retroStore.Update(retro)
+	retro.ItemCount--
+	s.retroStore.Update(retro)
+
+	BroadcastItemDeleted(item.RetrospectiveID, item.ItemID, item.ColumnID)
+	if item.GroupID != "" {
+		s.broadcastGroupUpdated(retro, item.GroupID)
 	}
 
 	return &emptypb.Empty{}, nil
@@ -157,14 +235,37 @@ func (s *RetrospectiveItemService) List(ctx context.Context, req *pb.ListItemsRe
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	items, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, req.ColumnId, req.SortByVotes)
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
+	// Sorting by votes would give away the hidden tallies of a blind vote
+	sortByVotes := req.SortByVotes && !votesHidden(retro)
+	items, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, req.ColumnId, sortByVotes)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	reactions, err := s.reactionStore.ListByRetrospective(req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	byItem := reactionsByItem(reactions)
+
+	// Report each item's current index in its column, which moves and deletes
+	// of other items change without rewriting it
+	indexes := columnIndexes(items)
+
+	userID := getUserIDFromContext(ctx)
 	var pbItems []*pb.RetrospectiveItem
 	for _, item := range items {
-		pbItems = append(pbItems, convertVstoreItemToPb(item))
+		item.Position = indexes[item.ItemID]
+		pbItem := convertVstoreItemForViewer(retro, item, userID)
+		if !pbItem.IsHidden {
+			pbItem.Reactions = countReactions(retro, byItem[item.ItemID], userID)
+		}
+		pbItems = append(pbItems, pbItem)
 	}
 
 	return &pb.ListItemsResponse{
@@ -172,7 +273,38 @@ func (s *RetrospectiveItemService) List(ctx context.Context, req *pb.ListItemsRe
 	}, nil
 }
 
-// MoveToColumn moves an item to a different column
+// RevealItems ends the silent brainstorm of a retrospective, showing everyone's cards at once
+func (s *RetrospectiveItemService) RevealItems(ctx context.Context, req *pb.RevealItemsRequest) (*emptypb.Empty, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireFacilitator(ctx, member, retro, "reveal items"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if !retro.SilentBrainstorm {
+		return nil, ToGRPCError(fmt.Errorf("%w: silent brainstorm is not enabled for this retrospective", ErrInvalidArgument))
+	}
+
+	if !retro.ItemsRevealedAt.IsZero() {
+		return &emptypb.Empty{}, nil
+	}
+	retro.ItemsRevealedAt = time.Now()
+	if err := s.retroStore.Update(retro); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	broadcastItemsRevealed(s.itemStore, retro, getUserIDFromContext(ctx))
+
+	return &emptypb.Empty{}, nil
+}
+
+// MoveToColumn moves an item to a different column, inserting it at position
+// among the items already there
 func (s *RetrospectiveItemService) MoveToColumn(ctx context.Context, req *pb.MoveItemToColumnRequest) (*emptypb.Empty, error) {
 	if req.ItemId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
@@ -190,35 +322,870 @@ func (s *RetrospectiveItemService) MoveToColumn(ctx context.Context, req *pb.Mov
 	if req.RetrospectiveId != "" && item.RetrospectiveID != req.RetrospectiveId {
 		return nil, ToGRPCError(fmt.Errorf("%w: item does not belong to this retrospective", ErrInvalidArgument))
 	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, item, "move"); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Verify target column exists
-	retro, err := s.retroStore.Get(item.RetrospectiveID)
+	if !hasColumn(retro, req.TargetColumnId) {
+		return nil, ToGRPCError(fmt.Errorf("%w: invalid target_column_id", ErrInvalidArgument))
+	}
+	column, err := s.otherItemsInColumn(item, req.TargetColumnId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	columnValid := false
-	for _, col := range retro.TemplateColumns {
-		if col.ColumnID == req.TargetColumnId {
-			columnValid = true
-			break
+	// An item moved out of its group's column leaves the group
+	leftGroup := ""
+	if item.GroupID != "" && item.ColumnID != req.TargetColumnId {
+		leftGroup = item.GroupID
+		item.GroupID = ""
+	}
+	item.ColumnID = req.TargetColumnId
+	if err := s.saveRekeyed(retro, placeItem(item, column, int(req.Position))); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if err := s.itemStore.Update(item); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	BroadcastItemUpdated(retro, item)
+	if leftGroup != "" {
+		s.broadcastGroupUpdated(retro, leftGroup)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ReorderItem moves an item within its column or into another one. The item
+// goes straight after after_item_id, else straight before before_item_id, else
+// to the top of the column. Those neighbours are looked up when the move is
+// applied, so a drag made from a slightly stale board still lands next to the
+// card it was dropped by, and only the moved item is rewritten.
+func (s *RetrospectiveItemService) ReorderItem(ctx context.Context, req *pb.ReorderItemRequest) (*pb.ReorderItemResponse, error) {
+	if req.ItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	}
+
+	item, err := s.itemStore.Get(req.ItemId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if req.RetrospectiveId != "" && item.RetrospectiveID != req.RetrospectiveId {
+		return nil, ToGRPCError(fmt.Errorf("%w: item does not belong to this retrospective", ErrInvalidArgument))
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, item, "move"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	columnID := req.ColumnId
+	if columnID == "" {
+		columnID = item.ColumnID
+	}
+	if !hasColumn(retro, columnID) {
+		return nil, ToGRPCError(fmt.Errorf("%w: invalid column_id", ErrInvalidArgument))
+	}
+	column, err := s.otherItemsInColumn(item, columnID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	index := 0
+	switch {
+	case req.AfterItemId != "":
+		i := indexOfItem(column, req.AfterItemId)
+		if i < 0 {
+			return nil, ToGRPCError(fmt.Errorf("%w: after_item_id is not another item in the column", ErrInvalidArgument))
+		}
+		index = i + 1
+	case req.BeforeItemId != "":
+		i := indexOfItem(column, req.BeforeItemId)
+		if i < 0 {
+			return nil, ToGRPCError(fmt.Errorf("%w: before_item_id is not another item in the column", ErrInvalidArgument))
 		}
+		index = i
 	}
-	if !columnValid {
-		return nil, ToGRPCError(fmt.Errorf("%w: invalid target_column_id", ErrInvalidArgument))
+
+	// An item moved out of its group's column leaves the group
+	leftGroup := ""
+	if item.GroupID != "" && item.ColumnID != columnID {
+		leftGroup = item.GroupID
+		item.GroupID = ""
+	}
+	item.ColumnID = columnID
+	if err := s.saveRekeyed(retro, placeItem(item, column, index)); err != nil {
+		return nil, ToGRPCError(err)
 	}
 
-	item.ColumnID = req.TargetColumnId
-	item.Position = req.Position
+	if err := s.itemStore.Update(item); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	BroadcastItemUpdated(retro, item)
+	if leftGroup != "" {
+		s.broadcastGroupUpdated(retro, leftGroup)
+	}
+
+	return &pb.ReorderItemResponse{
+		Item: convertVstoreItemForViewer(retro, item, getUserIDFromContext(ctx)),
+	}, nil
+}
+
+// otherItemsInColumn lists the items in columnID other than item, in position order
+func (s *RetrospectiveItemService) otherItemsInColumn(item *vstore.RetrospectiveItem, columnID string) ([]*vstore.RetrospectiveItem, error) {
+	items, err := s.itemStore.ListByRetrospective(item.RetrospectiveID, columnID, false)
+	if err != nil {
+		return nil, err
+	}
+	column := make([]*vstore.RetrospectiveItem, 0, len(items))
+	for _, other := range items {
+		if other.ItemID != item.ItemID {
+			column = append(column, other)
+		}
+	}
+	return column, nil
+}
+
+// saveRekeyed saves the new keys placeItem gave the items of a column. Each
+// item is reloaded first so only its place changes, not concurrent edits.
+func (s *RetrospectiveItemService) saveRekeyed(retro *vstore.Retrospective, items []*vstore.RetrospectiveItem) error {
+	for _, rekeyed := range items {
+		item, err := s.itemStore.Get(rekeyed.ItemID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		item.SortKey = rekeyed.SortKey
+		item.Position = rekeyed.Position
+		if err := s.itemStore.Update(item); err != nil {
+			return err
+		}
+		BroadcastItemUpdated(retro, item)
+	}
+	return nil
+}
+
+// SuggestMerges returns clusters of items that look like duplicates of one
+// another. Clusters would reveal other people's cards, so it waits until the
+// cards are revealed.
+func (s *RetrospectiveItemService) SuggestMerges(ctx context.Context, req *pb.SuggestMergesRequest) (*pb.SuggestMergesResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+	if req.MinSimilarity < 0 || req.MinSimilarity > 1 {
+		return nil, ToGRPCError(fmt.Errorf("%w: min_similarity must be between 0 and 1", ErrInvalidArgument))
+	}
+
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if itemsHidden(retro) {
+		return nil, ToGRPCError(fmt.Errorf("%w: merge suggestions open once the cards are revealed", ErrInvalidStatus))
+	}
+
+	items, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, req.ColumnId, false)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	threshold := req.MinSimilarity
+	if threshold == 0 {
+		threshold = defaultMergeThreshold
+	}
+	var suggestions []*pb.MergeSuggestion
+	for _, cluster := range findDuplicates(items, threshold) {
+		suggestion := &pb.MergeSuggestion{Similarity: cluster.similarity}
+		for _, item := range cluster.items {
+			suggestion.ItemIds = append(suggestion.ItemIds, item.ItemID)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return &pb.SuggestMergesResponse{
+		Suggestions: suggestions,
+	}, nil
+}
+
+// MergeItems merges duplicate items into a target item. The target keeps the
+// content and creators of every source and takes over their votes, comments
+// and reactions; the sources are deleted. Like SuggestMerges it waits until
+// the cards of a silent brainstorm are revealed.
+func (s *RetrospectiveItemService) MergeItems(ctx context.Context, req *pb.MergeItemsRequest) (*pb.MergeItemsResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+	if req.TargetItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: target_item_id is required", ErrInvalidArgument))
+	}
+	if len(req.SourceItemIds) == 0 {
+		return nil, ToGRPCError(fmt.Errorf("%w: source_item_ids is required", ErrInvalidArgument))
+	}
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireFacilitator(ctx, member, retro, "merge items"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if itemsHidden(retro) {
+		return nil, ToGRPCError(fmt.Errorf("%w: items can be merged once the cards are revealed", ErrInvalidStatus))
+	}
+
+	// Check every item before changing anything
+	target, err := s.getItemInRetro(req.TargetItemId, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	var sources []*vstore.RetrospectiveItem
+	seen := map[string]bool{target.ItemID: true}
+	for _, itemID := range req.SourceItemIds {
+		if itemID == target.ItemID {
+			return nil, ToGRPCError(fmt.Errorf("%w: an item cannot be merged into itself", ErrInvalidArgument))
+		}
+		if seen[itemID] {
+			continue
+		}
+		seen[itemID] = true
+		source, err := s.getItemInRetro(itemID, req.RetrospectiveId)
+		if err != nil {
+			return nil, ToGRPCError(err)
+		}
+		sources = append(sources, source)
+	}
+
+	if err := s.dropDuplicateVotes(retro, append([]*vstore.RetrospectiveItem{target}, sources...)); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := s.dropColumnSurplus(retro, target, sources); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	for _, source := range sources {
+		if _, err := s.voteStore.MoveVotes(req.RetrospectiveId, source.ItemID, target.ItemID); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		if err := s.moveComments(source.ItemID, target.ItemID); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		if err := s.moveReactions(req.RetrospectiveId, source.ItemID, target.ItemID); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		mergeItemInto(target, source)
+	}
+	if err := s.itemStore.Update(target); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	for _, source := range sources {
+		if err := s.itemStore.Delete(source.ItemID); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		BroadcastItemDeleted(source.RetrospectiveID, source.ItemID, source.ColumnID)
+		if source.GroupID != "" && source.GroupID != target.GroupID {
+			s.broadcastGroupUpdated(retro, source.GroupID)
+		}
+	}
+
+	// Update item count on retrospective
+	retro.ItemCount -= int32(len(sources))
+	s.retroStore.Update(retro)
+
+	pbItem := convertVstoreItemToPb(retro, target)
+	BroadcastItemUpdated(retro, target)
+	if target.GroupID != "" {
+		s.broadcastGroupUpdated(retro, target.GroupID)
+	}
+	reactions, err := s.broadcastReactions(retro, target.ItemID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	pbItem.Reactions = countReactions(retro, reactions, getUserIDFromContext(ctx))
+
+	return &pb.MergeItemsResponse{
+		Item: pbItem,
+	}, nil
+}
+
+// dropDuplicateVotes leaves each voter at most one vote across the items being
+// merged, keeping the one that counts most, so the merged item never holds
+// more votes from a user than the voting mode allows on one item
+func (s *RetrospectiveItemService) dropDuplicateVotes(retro *vstore.Retrospective, items []*vstore.RetrospectiveItem) error {
+	mode := votingMode(retro.VotingConfig)
+	if mode == vstore.VotingModeDot && retro.VotingConfig != nil && retro.VotingConfig.AllowMultipleVotesPerItem {
+		return nil
+	}
+
+	kept := make(map[string]*vstore.Vote) // key: user_id
+	var dropped []*vstore.Vote
+	for _, item := range items {
+		votes, err := s.voteStore.ListByItem(retro.RetrospectiveID, item.ItemID)
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			if vote.GroupVote {
+				continue
+			}
+			current, ok := kept[vote.UserID]
+			switch {
+			case !ok:
+				kept[vote.UserID] = vote
+			case outweighs(mode, vote, current):
+				dropped = append(dropped, current)
+				kept[vote.UserID] = vote
+			default:
+				dropped = append(dropped, vote)
+			}
+		}
+	}
+
+	// A user has at most one vote per item in these modes, so Retract removes exactly this vote
+	for _, vote := range dropped {
+		if _, err := s.voteStore.Retract(vote.RetrospectiveID, vote.ItemID, vote.UserID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumnSurplus keeps voters within the column budget of target's column.
+// Votes on sources from other columns move into it with the merge, so while a
+// voter would spend more there than the budget allows, their lightest such
+// vote is withdrawn.
+func (s *RetrospectiveItemService) dropColumnSurplus(retro *vstore.Retrospective, target *vstore.RetrospectiveItem, sources []*vstore.RetrospectiveItem) error {
+	limits := voteLimits(retro.VotingConfig)
+	if limits.MaxVotesPerColumn == 0 || (limits.Mode != vstore.VotingModeDot && limits.Mode != vstore.VotingModeWeighted) {
+		return nil
+	}
+	moving := make(map[string]bool)
+	for _, source := range sources {
+		if source.ColumnID != target.ColumnID {
+			moving[source.ItemID] = true
+		}
+	}
+	if len(moving) == 0 {
+		return nil
+	}
+
+	columns, err := boardColumns(s.itemStore, s.groupStore, retro.RetrospectiveID)
+	if err != nil {
+		return err
+	}
+	limits.Columns = columns
+	for itemID := range moving {
+		limits.Columns[itemID] = target.ColumnID
+	}
+
+	voters := make(map[string]bool)
+	for itemID := range moving {
+		votes, err := s.voteStore.ListByItem(retro.RetrospectiveID, itemID)
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			voters[vote.UserID] = true
+		}
+	}
+	for userID := range voters {
+		votes, err := s.voteStore.ListByUser(retro.RetrospectiveID, userID)
+		if err != nil {
+			return err
+		}
+		_, used := limits.votePoints(votes, target.ColumnID)
+		var movingVotes []*vstore.Vote
+		for _, vote := range votes {
+			if moving[vote.ItemID] && !vote.GroupVote {
+				movingVotes = append(movingVotes, vote)
+			}
+		}
+		sort.SliceStable(movingVotes, func(i, j int) bool { return voteWeight(movingVotes[i]) < voteWeight(movingVotes[j]) })
+		for _, vote := range movingVotes {
+			if used <= limits.MaxVotesPerColumn {
+				break
+			}
+			if _, err := s.voteStore.Retract(vote.RetrospectiveID, vote.ItemID, vote.UserID); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			used -= voteWeight(vote)
+		}
+	}
+	return nil
+}
+
+// outweighs reports whether vote counts for more than other in mode: more
+// points, or a better rank. Otherwise the vote seen first is kept.
+func outweighs(mode vstore.VotingMode, vote, other *vstore.Vote) bool {
+	switch mode {
+	case vstore.VotingModeWeighted:
+		return vote.Points > other.Points
+	case vstore.VotingModeRankedChoice:
+		return vote.Rank < other.Rank
+	default:
+		return false
+	}
+}
+
+// mergeItemInto appends source's content to target and records its creators
+// as contributors of target
+func mergeItemInto(target, source *vstore.RetrospectiveItem) {
+	if !containsContent(target.Content, source.Content) {
+		target.Content += "\n\n" + source.Content
+	}
+	addContributor(target, &vstore.ItemContributor{
+		UserID:      source.CreatedBy,
+		DisplayName: source.CreatedByName,
+		IsAnonymous: source.IsAnonymous,
+	})
+	for _, c := range source.Contributors {
+		addContributor(target, c)
+	}
+	target.HasActionItem = target.HasActionItem || source.HasActionItem
+}
+
+// containsContent reports whether merged content already holds part
+func containsContent(content, part string) bool {
+	part = strings.TrimSpace(part)
+	for _, p := range strings.Split(content, "\n\n") {
+		if strings.EqualFold(strings.TrimSpace(p), part) {
+			return true
+		}
+	}
+	return false
+}
+
+// addContributor records c on item unless it is the item's creator or already listed
+func addContributor(item *vstore.RetrospectiveItem, c *vstore.ItemContributor) {
+	if c.UserID == item.CreatedBy && c.IsAnonymous == item.IsAnonymous {
+		return
+	}
+	for _, existing := range item.Contributors {
+		if existing.UserID == c.UserID && existing.IsAnonymous == c.IsAnonymous {
+			return
+		}
+	}
+	item.Contributors = append(item.Contributors, c)
+}
+
+// CreateGroup creates an item group in a column, optionally moving items into it
+func (s *RetrospectiveItemService) CreateGroup(ctx context.Context, req *pb.CreateItemGroupRequest) (*pb.CreateItemGroupResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+	if req.ColumnId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: column_id is required", ErrInvalidArgument))
+	}
+	if req.Title == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: title is required", ErrInvalidArgument))
+	}
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if !hasColumn(retro, req.ColumnId) {
+		return nil, ToGRPCError(fmt.Errorf("%w: invalid column_id", ErrInvalidArgument))
+	}
+
+	// Check every item before changing anything
+	var items []*vstore.RetrospectiveItem
+	for _, itemID := range req.ItemIds {
+		item, err := s.getItemInRetro(itemID, req.RetrospectiveId)
+		if err != nil {
+			return nil, ToGRPCError(err)
+		}
+		if err := requireItemEditor(ctx, member, retro, item, "group"); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		items = append(items, item)
+	}
+
+	// Get next position
+	existingGroups, _ := s.groupStore.ListByRetrospective(req.RetrospectiveId)
+	position := int32(0)
+	for _, g := range existingGroups {
+		if g.ColumnID == req.ColumnId {
+			position++
+		}
+	}
+
+	group := &vstore.ItemGroup{
+		GroupID:         fmt.Sprintf("GROUP-%d", time.Now().UnixNano()),
+		RetrospectiveID: req.RetrospectiveId,
+		ColumnID:        req.ColumnId,
+		Title:           req.Title,
+		CreatedBy:       getUserIDFromContext(ctx),
+		CreatedByName:   getUserNameFromContext(ctx),
+		Position:        position,
+	}
+	if err := s.groupStore.Create(group); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	for _, item := range items {
+		if err := s.moveIntoGroup(retro, item, group); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	pbGroup, err := s.convertGroup(retro, group)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	BroadcastItemGroupCreated(group.RetrospectiveID, pbGroup)
+
+	return &pb.CreateItemGroupResponse{
+		Group: pbGroup,
+	}, nil
+}
+
+// UpdateGroup renames an item group
+func (s *RetrospectiveItemService) UpdateGroup(ctx context.Context, req *pb.UpdateItemGroupRequest) (*emptypb.Empty, error) {
+	if req.GroupId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: group_id is required", ErrInvalidArgument))
+	}
+	if req.Title == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: title is required", ErrInvalidArgument))
+	}
+
+	group, retro, member, err := s.getGroupForContributor(ctx, req.GroupId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireGroupEditor(ctx, member, retro, group, "rename"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	group.Title = req.Title
+	if err := s.groupStore.Update(group); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	s.broadcastGroupUpdated(retro, group.GroupID)
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteGroup deletes an item group. Its items stay on the board as
+// standalone items and the votes cast for the group are withdrawn, so groups
+// can only be deleted until voting starts.
+func (s *RetrospectiveItemService) DeleteGroup(ctx context.Context, req *pb.DeleteItemGroupRequest) (*emptypb.Empty, error) {
+	if req.GroupId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: group_id is required", ErrInvalidArgument))
+	}
+
+	group, retro, member, err := s.getGroupForContributor(ctx, req.GroupId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireGroupEditor(ctx, member, retro, group, "delete"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if retro.Status != vstore.RetrospectiveStatusDraft && retro.Status != vstore.RetrospectiveStatusActive {
+		return nil, ToGRPCError(fmt.Errorf("%w: groups cannot be deleted once voting has started", ErrInvalidStatus))
+	}
+
+	members, err := s.groupMembers(group)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	for _, item := range members {
+		item.GroupID = ""
+		if err := s.itemStore.Update(item); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		BroadcastItemUpdated(retro, item)
+	}
+
+	// Withdraw the group's votes so they stop counting against voters' limits
+	votes, err := s.voteStore.ListByItem(group.RetrospectiveID, group.GroupID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	for _, vote := range votes {
+		if _, err := s.voteStore.Retract(vote.RetrospectiveID, vote.ItemID, vote.UserID); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	if err := s.groupStore.Delete(group.GroupID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	BroadcastItemGroupDeleted(group.RetrospectiveID, group.GroupID, group.ColumnID)
+
+	return &emptypb.Empty{}, nil
+}
 
+// ListGroups lists the item groups in a retrospective
+func (s *RetrospectiveItemService) ListGroups(ctx context.Context, req *pb.ListItemGroupsRequest) (*pb.ListItemGroupsResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	groups, err := s.groupStore.ListByRetrospective(req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	var pbGroups []*pb.ItemGroup
+	for _, group := range groups {
+		pbGroup, err := s.convertGroup(retro, group)
+		if err != nil {
+			return nil, ToGRPCError(err)
+		}
+		pbGroups = append(pbGroups, pbGroup)
+	}
+
+	return &pb.ListItemGroupsResponse{
+		Groups: pbGroups,
+	}, nil
+}
+
+// AddToGroup moves an item into a group, and into the group's column
+func (s *RetrospectiveItemService) AddToGroup(ctx context.Context, req *pb.AddItemToGroupRequest) (*emptypb.Empty, error) {
+	if req.GroupId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: group_id is required", ErrInvalidArgument))
+	}
+	if req.ItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	}
+
+	group, retro, member, err := s.getGroupForContributor(ctx, req.GroupId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	item, err := s.getItemInRetro(req.ItemId, group.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, item, "group"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if item.GroupID == group.GroupID {
+		return &emptypb.Empty{}, nil
+	}
+
+	previousGroup := item.GroupID
+	if err := s.moveIntoGroup(retro, item, group); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if previousGroup != "" {
+		s.broadcastGroupUpdated(retro, previousGroup)
+	}
+	s.broadcastGroupUpdated(retro, group.GroupID)
+
+	return &emptypb.Empty{}, nil
+}
+
+// RemoveFromGroup takes an item out of its group, leaving it in the same column
+func (s *RetrospectiveItemService) RemoveFromGroup(ctx context.Context, req *pb.RemoveItemFromGroupRequest) (*emptypb.Empty, error) {
+	if req.ItemId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	}
+
+	item, err := s.itemStore.Get(req.ItemId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, item.RetrospectiveID)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, item.RetrospectiveID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireItemEditor(ctx, member, retro, item, "ungroup"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if item.GroupID == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: item is not in a group", ErrInvalidArgument))
+	}
+
+	groupID := item.GroupID
+	item.GroupID = ""
 	if err := s.itemStore.Update(item); err != nil {
 		return nil, ToGRPCError(err)
 	}
 
+	BroadcastItemUpdated(retro, item)
+	s.broadcastGroupUpdated(retro, groupID)
+
 	return &emptypb.Empty{}, nil
 }
 
+// getGroupForContributor loads a group and checks that the caller may change
+// its retrospective's board
+func (s *RetrospectiveItemService) getGroupForContributor(ctx context.Context, groupID string) (*vstore.ItemGroup, *vstore.Retrospective, *vstore.TeamMember, error) {
+	group, err := s.groupStore.Get(groupID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, group.RetrospectiveID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := requireContributor(ctx, s.participantStore, member, group.RetrospectiveID); err != nil {
+		return nil, nil, nil, err
+	}
+	return group, retro, member, nil
+}
+
+// getItemInRetro loads an item and checks that it belongs to retroID
+func (s *RetrospectiveItemService) getItemInRetro(itemID, retroID string) (*vstore.RetrospectiveItem, error) {
+	item, err := s.itemStore.Get(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.RetrospectiveID != retroID {
+		return nil, fmt.Errorf("%w: item %s does not belong to this retrospective", ErrInvalidArgument, itemID)
+	}
+	return item, nil
+}
+
+// hasColumn reports whether the retrospective's template has the column
+func hasColumn(retro *vstore.Retrospective, columnID string) bool {
+	for _, col := range retro.TemplateColumns {
+		if col.ColumnID == columnID {
+			return true
+		}
+	}
+	return false
+}
+
+// moveIntoGroup puts item in group and broadcasts the change to the item
+func (s *RetrospectiveItemService) moveIntoGroup(retro *vstore.Retrospective, item *vstore.RetrospectiveItem, group *vstore.ItemGroup) error {
+	if item.ColumnID != group.ColumnID {
+		// Items joining from another column go to the bottom of the group's column
+		column, err := s.otherItemsInColumn(item, group.ColumnID)
+		if err != nil {
+			return err
+		}
+		if err := s.saveRekeyed(retro, placeItem(item, column, len(column))); err != nil {
+			return err
+		}
+	}
+	item.GroupID = group.GroupID
+	item.ColumnID = group.ColumnID
+	if err := s.itemStore.Update(item); err != nil {
+		return err
+	}
+	BroadcastItemUpdated(retro, item)
+	return nil
+}
+
+// groupMembers returns the items in a group
+func (s *RetrospectiveItemService) groupMembers(group *vstore.ItemGroup) ([]*vstore.RetrospectiveItem, error) {
+	items, err := s.itemStore.ListByRetrospective(group.RetrospectiveID, group.ColumnID, false)
+	if err != nil {
+		return nil, err
+	}
+	var members []*vstore.RetrospectiveItem
+	for _, item := range items {
+		if item.GroupID == group.GroupID {
+			members = append(members, item)
+		}
+	}
+	return members, nil
+}
+
+// convertGroup converts a group along with the IDs of its items
+func (s *RetrospectiveItemService) convertGroup(retro *vstore.Retrospective, group *vstore.ItemGroup) (*pb.ItemGroup, error) {
+	members, err := s.groupMembers(group)
+	if err != nil {
+		return nil, err
+	}
+	return convertVstoreItemGroupToPb(retro, group, members), nil
+}
+
+// broadcastGroupUpdated sends the current state of a group to subscribers
+func (s *RetrospectiveItemService) broadcastGroupUpdated(retro *vstore.Retrospective, groupID string) {
+	group, err := s.groupStore.Get(groupID)
+	if err != nil {
+		return
+	}
+	pbGroup, err := s.convertGroup(retro, group)
+	if err != nil {
+		return
+	}
+	BroadcastItemGroupUpdated(group.RetrospectiveID, pbGroup)
+}
+
+// convertVstoreItemGroupToPb converts a group and its items. Its vote count
+// includes the votes its items received before they were grouped.
+func convertVstoreItemGroupToPb(retro *vstore.Retrospective, group *vstore.ItemGroup, members []*vstore.RetrospectiveItem) *pb.ItemGroup {
+	voteCount := group.VoteCount
+	var itemIDs []string
+	for _, item := range members {
+		itemIDs = append(itemIDs, item.ItemID)
+		voteCount += item.VoteCount
+	}
+	return &pb.ItemGroup{
+		GroupId:         group.GroupID,
+		RetrospectiveId: group.RetrospectiveID,
+		ColumnId:        group.ColumnID,
+		Title:           group.Title,
+		ItemIds:         itemIDs,
+		VoteCount:       visibleVoteCount(retro, voteCount),
+		Position:        group.Position,
+		CreatedBy:       group.CreatedBy,
+		CreatedByName:   group.CreatedByName,
+		Created:         timestamppb.New(group.Created),
+		Updated:         timestamppb.New(group.Updated),
+	}
+}
+
+// itemsHidden reports whether a silent brainstorm still hides the cards of retro
+// from everyone but their authors
+func itemsHidden(retro *vstore.Retrospective) bool {
+	return retro.SilentBrainstorm && retro.ItemsRevealedAt.IsZero()
+}
+
+// broadcastItemsRevealed sends every card of retro to its subscribers once a
+// silent brainstorm ends
+func broadcastItemsRevealed(itemStore ItemStore, retro *vstore.Retrospective, revealedBy string) {
+	items, err := itemStore.ListByRetrospective(retro.RetrospectiveID, "", false)
+	if err != nil {
+		return
+	}
+	var pbItems []*pb.RetrospectiveItem
+	for _, item := range items {
+		pbItems = append(pbItems, convertVstoreItemToPb(retro, item))
+	}
+	BroadcastItemsRevealed(retro.RetrospectiveID, pbItems, revealedBy)
+}
+
+// getUserNameFromContext returns the authenticated caller's display name
 func getUserNameFromContext(ctx context.Context) string {
-	// In production, extract from auth context
-	return "Mock User"
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.DisplayName
+	}
+	return ""
 }
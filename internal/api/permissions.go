@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// requireTeamMember returns the caller's membership in teamID, or
+// ErrPermissionDenied when the caller does not belong to the team
+func requireTeamMember(ctx context.Context, teamStore TeamStore, teamID string) (*vstore.TeamMember, error) {
+	member, err := teamStore.GetMember(teamID, getUserIDFromContext(ctx))
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: not a member of team %s", ErrPermissionDenied, teamID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// requireTeamAdmin returns the caller's membership in teamID when they administer it
+func requireTeamAdmin(ctx context.Context, teamStore TeamStore, teamID string) (*vstore.TeamMember, error) {
+	member, err := requireTeamMember(ctx, teamStore, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != vstore.TeamRoleAdmin {
+		return nil, fmt.Errorf("%w: only team admins can manage team %s", ErrPermissionDenied, teamID)
+	}
+	return member, nil
+}
+
+// requireTeamWriter rejects team observers, who are read-only
+func requireTeamWriter(member *vstore.TeamMember) error {
+	if member.Role == vstore.TeamRoleObserver {
+		return fmt.Errorf("%w: observers have read-only access", ErrPermissionDenied)
+	}
+	return nil
+}
+
+// getRetroForMember loads a retrospective and the caller's membership in the team that owns it
+func getRetroForMember(ctx context.Context, retroStore RetrospectiveStore, teamStore TeamStore, retroID string) (*vstore.Retrospective, *vstore.TeamMember, error) {
+	retro, err := retroStore.Get(retroID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := requireTeamMember(ctx, teamStore, retro.TeamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return retro, member, nil
+}
+
+// canFacilitate reports whether the caller is the retrospective's facilitator or a team admin
+func canFacilitate(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective) bool {
+	userID := getUserIDFromContext(ctx)
+	if userID != "" && userID == retro.FacilitatorID {
+		return true
+	}
+	return member != nil && member.Role == vstore.TeamRoleAdmin
+}
+
+// requireFacilitator rejects callers that may not administer retro
+func requireFacilitator(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective, action string) error {
+	if !canFacilitate(ctx, member, retro) {
+		return fmt.Errorf("%w: only the facilitator or a team admin can %s", ErrPermissionDenied, action)
+	}
+	return nil
+}
+
+// requireItemEditor rejects callers that may not change item. Authors may
+// change their own cards until the retrospective leaves the Active phase; the
+// facilitator or a team admin may moderate any card at any time.
+func requireItemEditor(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective, item *vstore.RetrospectiveItem, action string) error {
+	if canFacilitate(ctx, member, retro) {
+		return nil
+	}
+	if item.CreatedBy != getUserIDFromContext(ctx) {
+		return fmt.Errorf("%w: only the author, the facilitator or a team admin can %s this item", ErrPermissionDenied, action)
+	}
+	if retro.Status != vstore.RetrospectiveStatusDraft && retro.Status != vstore.RetrospectiveStatusActive {
+		return fmt.Errorf("%w: items are locked once the retrospective leaves the ACTIVE phase", ErrPermissionDenied)
+	}
+	return nil
+}
+
+// requireGroupEditor rejects callers that may not change group. Like cards,
+// groups belong to their creator until the retrospective leaves the Active
+// phase; the facilitator or a team admin may change any group at any time.
+func requireGroupEditor(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective, group *vstore.ItemGroup, action string) error {
+	if canFacilitate(ctx, member, retro) {
+		return nil
+	}
+	if group.CreatedBy != getUserIDFromContext(ctx) {
+		return fmt.Errorf("%w: only the group's creator, the facilitator or a team admin can %s this group", ErrPermissionDenied, action)
+	}
+	if retro.Status != vstore.RetrospectiveStatusDraft && retro.Status != vstore.RetrospectiveStatusActive {
+		return fmt.Errorf("%w: groups are locked once the retrospective leaves the ACTIVE phase", ErrPermissionDenied)
+	}
+	return nil
+}
+
+// participantRole returns the caller's role in a retrospective session. Team
+// observers and callers with a read-only token are observers; everyone else is
+// a member unless they facilitate the retrospective.
+func participantRole(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective) vstore.ParticipantRole {
+	if getUserIDFromContext(ctx) == retro.FacilitatorID {
+		return vstore.ParticipantRoleFacilitator
+	}
+	if member.Role == vstore.TeamRoleObserver {
+		return vstore.ParticipantRoleObserver
+	}
+	scopes := getScopesFromContext(ctx)
+	if hasScope(scopes, ScopeRead) && !hasScope(scopes, ScopeWrite) {
+		return vstore.ParticipantRoleObserver
+	}
+	return vstore.ParticipantRoleMember
+}
+
+// requireContributor rejects observers, who are read-only on items and votes
+func requireContributor(ctx context.Context, participantStore ParticipantStore, member *vstore.TeamMember, retroID string) error {
+	if err := requireTeamWriter(member); err != nil {
+		return err
+	}
+	p, err := participantStore.Get(retroID, getUserIDFromContext(ctx))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if p.Role == vstore.ParticipantRoleObserver {
+		return fmt.Errorf("%w: observers cannot change items or votes", ErrPermissionDenied)
+	}
+	return nil
+}
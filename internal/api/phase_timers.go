@@ -0,0 +1,318 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+const (
+	// timerTickInterval is how often a running timer broadcasts the time it has left
+	timerTickInterval = time.Second
+	// maxTimerDuration bounds a phase timer, extensions included
+	maxTimerDuration = 4 * time.Hour
+)
+
+// PhaseTimers runs the countdown timers of retrospective phases. Timer state
+// lives in a PhaseTimerStore; PhaseTimers keeps one goroutine per running timer
+// that broadcasts ticks to subscribers and expires the timer when it runs out,
+// moving the retrospective to its next phase when auto-advance is on.
+type PhaseTimers struct {
+	mu         sync.Mutex
+	timerStore PhaseTimerStore
+	retroStore RetrospectiveStore
+	itemStore  ItemStore
+	running    map[string]chan struct{} // key: retrospective_id; closed to stop the timer's goroutine
+}
+
+// NewPhaseTimers creates PhaseTimers that keep their state in timerStore
+func NewPhaseTimers(timerStore PhaseTimerStore, retroStore RetrospectiveStore, itemStore ItemStore) *PhaseTimers {
+	return &PhaseTimers{
+		timerStore: timerStore,
+		retroStore: retroStore,
+		itemStore:  itemStore,
+		running:    make(map[string]chan struct{}),
+	}
+}
+
+// Restore schedules the running timers found in the store, for use at startup.
+// Timers that ran out while the server was down expire right away.
+func (p *PhaseTimers) Restore() error {
+	timers, err := p.timerStore.List()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, timer := range timers {
+		if !timer.Paused {
+			p.schedule(timer)
+		}
+	}
+	return nil
+}
+
+// Stop stops every timer goroutine, leaving the stored timers to Restore
+func (p *PhaseTimers) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for retroID := range p.running {
+		p.unschedule(retroID)
+	}
+}
+
+// Start starts a countdown for the current phase of retro, replacing any timer it already has
+func (p *PhaseTimers) Start(retro *vstore.Retrospective, duration time.Duration, autoAdvance bool, startedBy, startedByName string) (*vstore.PhaseTimer, error) {
+	if _, ok := timerPhaseActions[retro.Status]; !ok {
+		return nil, fmt.Errorf("%w: cannot time a retrospective in %s status", ErrInvalidStatus, statusName(retro.Status))
+	}
+	if duration <= 0 || duration > maxTimerDuration {
+		return nil, fmt.Errorf("%w: duration must be between 1 second and %s", ErrInvalidArgument, maxTimerDuration)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timer := &vstore.PhaseTimer{
+		RetrospectiveID: retro.RetrospectiveID,
+		Phase:           retro.Status,
+		Duration:        duration,
+		EndsAt:          time.Now().Add(duration),
+		AutoAdvance:     autoAdvance,
+		StartedBy:       startedBy,
+		StartedByName:   startedByName,
+	}
+	if err := p.timerStore.Put(timer); err != nil {
+		return nil, err
+	}
+	p.schedule(timer)
+	BroadcastPhaseTimer(timer.RetrospectiveID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_STARTED)
+	return timer, nil
+}
+
+// Pause stops the countdown of a running timer, keeping the time it has left
+func (p *PhaseTimers) Pause(retroID string) (*vstore.PhaseTimer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timer, err := p.get(retroID)
+	if err != nil {
+		return nil, err
+	}
+	if timer.Paused {
+		return nil, fmt.Errorf("%w: the timer is already paused", ErrInvalidStatus)
+	}
+
+	timer.Remaining = timerRemaining(timer)
+	timer.EndsAt = time.Time{}
+	timer.Paused = true
+	if err := p.timerStore.Put(timer); err != nil {
+		return nil, err
+	}
+	p.unschedule(retroID)
+	BroadcastPhaseTimer(retroID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_PAUSED)
+	return timer, nil
+}
+
+// Resume restarts the countdown of a paused timer
+func (p *PhaseTimers) Resume(retroID string) (*vstore.PhaseTimer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timer, err := p.get(retroID)
+	if err != nil {
+		return nil, err
+	}
+	if !timer.Paused {
+		return nil, fmt.Errorf("%w: the timer is not paused", ErrInvalidStatus)
+	}
+
+	timer.EndsAt = time.Now().Add(timer.Remaining)
+	timer.Remaining = 0
+	timer.Paused = false
+	if err := p.timerStore.Put(timer); err != nil {
+		return nil, err
+	}
+	p.schedule(timer)
+	BroadcastPhaseTimer(retroID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_RESUMED)
+	return timer, nil
+}
+
+// Extend adds extra time to a running or paused timer
+func (p *PhaseTimers) Extend(retroID string, extra time.Duration) (*vstore.PhaseTimer, error) {
+	if extra <= 0 {
+		return nil, fmt.Errorf("%w: the extension must be positive", ErrInvalidArgument)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timer, err := p.get(retroID)
+	if err != nil {
+		return nil, err
+	}
+	if timer.Duration+extra > maxTimerDuration {
+		return nil, fmt.Errorf("%w: a timer cannot run longer than %s", ErrInvalidArgument, maxTimerDuration)
+	}
+
+	timer.Duration += extra
+	if timer.Paused {
+		timer.Remaining += extra
+	} else {
+		timer.EndsAt = timer.EndsAt.Add(extra)
+	}
+	if err := p.timerStore.Put(timer); err != nil {
+		return nil, err
+	}
+	if !timer.Paused {
+		p.schedule(timer)
+	}
+	BroadcastPhaseTimer(retroID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_EXTENDED)
+	return timer, nil
+}
+
+// Cancel removes the timer of a retrospective
+func (p *PhaseTimers) Cancel(retroID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timer, err := p.get(retroID)
+	if err != nil {
+		return err
+	}
+	if err := p.timerStore.Delete(retroID); err != nil {
+		return err
+	}
+	p.unschedule(retroID)
+	BroadcastPhaseTimer(retroID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_CANCELLED)
+	return nil
+}
+
+// Get returns the timer of a retrospective
+func (p *PhaseTimers) Get(retroID string) (*vstore.PhaseTimer, error) {
+	return p.get(retroID)
+}
+
+func (p *PhaseTimers) get(retroID string) (*vstore.PhaseTimer, error) {
+	timer, err := p.timerStore.Get(retroID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: no timer is set for this retrospective", ErrNotFound)
+	}
+	return timer, err
+}
+
+// schedule (re)starts the goroutine of a running timer. p.mu must be held.
+func (p *PhaseTimers) schedule(timer *vstore.PhaseTimer) {
+	p.unschedule(timer.RetrospectiveID)
+	stop := make(chan struct{})
+	p.running[timer.RetrospectiveID] = stop
+	go p.run(*timer, stop)
+}
+
+// unschedule stops the goroutine of a timer, if any. p.mu must be held.
+func (p *PhaseTimers) unschedule(retroID string) {
+	if stop, ok := p.running[retroID]; ok {
+		close(stop)
+		delete(p.running, retroID)
+	}
+}
+
+// run broadcasts ticks until the timer expires or stop is closed
+func (p *PhaseTimers) run(timer vstore.PhaseTimer, stop chan struct{}) {
+	ticker := time.NewTicker(timerTickInterval)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(timer.EndsAt))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			BroadcastPhaseTimer(timer.RetrospectiveID, convertVstorePhaseTimerToPb(&timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_TICK)
+		case <-expiry.C:
+			p.expire(&timer, stop)
+			return
+		}
+	}
+}
+
+// expire deletes a timer that ran out and advances its retrospective if asked to
+func (p *PhaseTimers) expire(timer *vstore.PhaseTimer, stop chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The timer was paused, extended or cancelled while it was expiring
+	if p.running[timer.RetrospectiveID] != stop {
+		return
+	}
+	delete(p.running, timer.RetrospectiveID)
+
+	if err := p.timerStore.Delete(timer.RetrospectiveID); err != nil {
+		log.Printf("phase timer %s: delete expired timer: %v", timer.RetrospectiveID, err)
+	}
+	BroadcastPhaseTimer(timer.RetrospectiveID, convertVstorePhaseTimerToPb(timer), pb.PhaseTimerAction_PHASE_TIMER_ACTION_EXPIRED)
+
+	if timer.AutoAdvance {
+		if err := p.advance(timer); err != nil {
+			log.Printf("phase timer %s: advance phase: %v", timer.RetrospectiveID, err)
+		}
+	}
+}
+
+// advance moves the retrospective past the phase its timer was set for,
+// unless someone already changed the phase by hand
+func (p *PhaseTimers) advance(timer *vstore.PhaseTimer) error {
+	retro, err := p.retroStore.Get(timer.RetrospectiveID)
+	if err != nil {
+		return err
+	}
+	if retro.Status != timer.Phase {
+		return nil
+	}
+	t, ok := findPhaseTransition(timerPhaseActions[timer.Phase], retro.Status)
+	if !ok {
+		return nil
+	}
+
+	hidden := itemsHidden(retro)
+	change := moveToPhase(retro, t, timer.StartedBy, timer.StartedByName)
+	if err := p.retroStore.Update(retro); err != nil {
+		return err
+	}
+	BroadcastStatusChanged(retro.RetrospectiveID, pb.RetrospectiveStatus(change.FromStatus), pb.RetrospectiveStatus(change.ToStatus), change.ChangedBy)
+	if hidden && !itemsHidden(retro) {
+		broadcastItemsRevealed(p.itemStore, retro, change.ChangedBy)
+	}
+	return nil
+}
+
+// timerRemaining returns the time a timer has left
+func timerRemaining(timer *vstore.PhaseTimer) time.Duration {
+	if timer.Paused {
+		return timer.Remaining
+	}
+	if remaining := time.Until(timer.EndsAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func convertVstorePhaseTimerToPb(timer *vstore.PhaseTimer) *pb.PhaseTimer {
+	result := &pb.PhaseTimer{
+		RetrospectiveId: timer.RetrospectiveID,
+		Phase:           pb.RetrospectiveStatus(timer.Phase),
+		DurationSeconds: int32(timer.Duration / time.Second),
+		// Round up so a timer shows 0 only once it has run out
+		RemainingSeconds: int32((timerRemaining(timer) + time.Second - 1) / time.Second),
+		Paused:           timer.Paused,
+		AutoAdvance:      timer.AutoAdvance,
+		StartedBy:        timer.StartedBy,
+		StartedByName:    timer.StartedByName,
+	}
+	if !timer.Paused {
+		result.EndsAt = timestamppb.New(timer.EndsAt)
+	}
+	return result
+}
@@ -0,0 +1,262 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// newTimerStores returns stores holding an active retrospective RETRO-1. The
+// vstore stores copy records in and out, so tests can read a retrospective
+// while a timer goroutine advances it.
+func newTimerStores(t *testing.T) api.Stores {
+	t.Helper()
+	stores := api.NewVStoreStores(vstore.NewLocalClient())
+	retro := &vstore.Retrospective{RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", SprintName: "Sprint 1", Status: vstore.RetrospectiveStatusActive}
+	if err := stores.Retrospectives.Create(retro); err != nil {
+		t.Fatalf("Retrospectives.Create: %v", err)
+	}
+	return stores
+}
+
+func newPhaseTimers(t *testing.T, stores api.Stores) *api.PhaseTimers {
+	t.Helper()
+	timers := api.NewPhaseTimers(stores.PhaseTimers, stores.Retrospectives, stores.Items)
+	t.Cleanup(timers.Stop)
+	return timers
+}
+
+func getRetro(t *testing.T, stores api.Stores) *vstore.Retrospective {
+	t.Helper()
+	retro, err := stores.Retrospectives.Get("RETRO-1")
+	if err != nil {
+		t.Fatalf("Retrospectives.Get: %v", err)
+	}
+	return retro
+}
+
+// eventually waits for cond to hold
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// timerGone reports whether the stored timer of RETRO-1 is gone
+func timerGone(stores api.Stores) func() bool {
+	return func() bool {
+		_, err := stores.PhaseTimers.Get("RETRO-1")
+		return errors.Is(err, api.ErrNotFound)
+	}
+}
+
+func TestPhaseTimerControls(t *testing.T) {
+	stores := newTimerStores(t)
+	timers := newPhaseTimers(t, stores)
+	retro := getRetro(t, stores)
+
+	if _, err := timers.Start(retro, 0, false, "alice", "Alice"); !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("Start without a duration error = %v, want ErrInvalidArgument", err)
+	}
+	if _, err := timers.Start(retro, 5*time.Hour, false, "alice", "Alice"); !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("Start over the maximum error = %v, want ErrInvalidArgument", err)
+	}
+	draft := *retro
+	draft.Status = vstore.RetrospectiveStatusDraft
+	if _, err := timers.Start(&draft, time.Minute, false, "alice", "Alice"); !errors.Is(err, api.ErrInvalidStatus) {
+		t.Errorf("Start in draft error = %v, want ErrInvalidStatus", err)
+	}
+	if _, err := timers.Pause("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Pause without a timer error = %v, want ErrNotFound", err)
+	}
+
+	timer, err := timers.Start(retro, time.Hour, true, "alice", "Alice")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if timer.Phase != vstore.RetrospectiveStatusActive || timer.Duration != time.Hour || timer.Paused || !timer.AutoAdvance {
+		t.Errorf("started timer = %+v", timer)
+	}
+	if _, err := timers.Resume("RETRO-1"); !errors.Is(err, api.ErrInvalidStatus) {
+		t.Errorf("Resume of a running timer error = %v, want ErrInvalidStatus", err)
+	}
+
+	timer, err = timers.Pause("RETRO-1")
+	if err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if !timer.Paused || timer.Remaining <= 59*time.Minute || timer.Remaining > time.Hour || !timer.EndsAt.IsZero() {
+		t.Errorf("paused timer = %+v", timer)
+	}
+	if _, err := timers.Pause("RETRO-1"); !errors.Is(err, api.ErrInvalidStatus) {
+		t.Errorf("second Pause error = %v, want ErrInvalidStatus", err)
+	}
+
+	// Extending a paused timer adds to the time it has left
+	remaining := timer.Remaining
+	timer, err = timers.Extend("RETRO-1", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Extend paused: %v", err)
+	}
+	if timer.Duration != 70*time.Minute || timer.Remaining != remaining+10*time.Minute {
+		t.Errorf("extended paused timer = %+v", timer)
+	}
+
+	timer, err = timers.Resume("RETRO-1")
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if timer.Paused || timer.Remaining != 0 || time.Until(timer.EndsAt) <= 69*time.Minute {
+		t.Errorf("resumed timer = %+v", timer)
+	}
+
+	// Extending a running timer moves its end
+	endsAt := timer.EndsAt
+	timer, err = timers.Extend("RETRO-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Extend running: %v", err)
+	}
+	if timer.Duration != 75*time.Minute || !timer.EndsAt.Equal(endsAt.Add(5*time.Minute)) {
+		t.Errorf("extended running timer = %+v", timer)
+	}
+	if _, err := timers.Extend("RETRO-1", 0); !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("Extend by nothing error = %v, want ErrInvalidArgument", err)
+	}
+	if _, err := timers.Extend("RETRO-1", 3*time.Hour); !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("Extend over the maximum error = %v, want ErrInvalidArgument", err)
+	}
+
+	stored, err := timers.Get("RETRO-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Duration != 75*time.Minute || !stored.EndsAt.Equal(timer.EndsAt) {
+		t.Errorf("stored timer = %+v, want %+v", stored, timer)
+	}
+
+	if err := timers.Cancel("RETRO-1"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if _, err := timers.Get("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Cancel error = %v, want ErrNotFound", err)
+	}
+	if err := timers.Cancel("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("second Cancel error = %v, want ErrNotFound", err)
+	}
+	if got := getRetro(t, stores).Status; got != vstore.RetrospectiveStatusActive {
+		t.Errorf("status after Cancel = %v, want %v", got, vstore.RetrospectiveStatusActive)
+	}
+}
+
+func TestPhaseTimerExpiry(t *testing.T) {
+	t.Run("without auto-advance", func(t *testing.T) {
+		stores := newTimerStores(t)
+		timers := newPhaseTimers(t, stores)
+		if _, err := timers.Start(getRetro(t, stores), 20*time.Millisecond, false, "alice", "Alice"); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		eventually(t, "the timer to expire", timerGone(stores))
+		if got := getRetro(t, stores).Status; got != vstore.RetrospectiveStatusActive {
+			t.Errorf("status = %v, want %v", got, vstore.RetrospectiveStatusActive)
+		}
+	})
+
+	t.Run("with auto-advance", func(t *testing.T) {
+		stores := newTimerStores(t)
+		timers := newPhaseTimers(t, stores)
+		if _, err := timers.Start(getRetro(t, stores), 20*time.Millisecond, true, "alice", "Alice"); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		eventually(t, "voting to start", func() bool {
+			return getRetro(t, stores).Status == vstore.RetrospectiveStatusVoting
+		})
+		if !timerGone(stores)() {
+			t.Error("expired timer is still stored")
+		}
+		history := getRetro(t, stores).PhaseHistory
+		if n := len(history); n == 0 || history[n-1].ChangedBy != "alice" {
+			t.Errorf("phase history = %+v, want the change recorded for the timer's starter", history)
+		}
+	})
+
+	t.Run("phase changed by hand", func(t *testing.T) {
+		stores := newTimerStores(t)
+		timers := newPhaseTimers(t, stores)
+		if _, err := timers.Start(getRetro(t, stores), 50*time.Millisecond, true, "alice", "Alice"); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		retro := getRetro(t, stores)
+		retro.Status = vstore.RetrospectiveStatusVoting
+		if err := stores.Retrospectives.Update(retro); err != nil {
+			t.Fatalf("Retrospectives.Update: %v", err)
+		}
+		eventually(t, "the timer to expire", timerGone(stores))
+		// Cancel waits for the expiry holding the lock to finish
+		if err := timers.Cancel("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+			t.Errorf("Cancel after expiry error = %v, want ErrNotFound", err)
+		}
+		if got := getRetro(t, stores).Status; got != vstore.RetrospectiveStatusVoting {
+			t.Errorf("status = %v, want %v: the timer was set for the phase before it", got, vstore.RetrospectiveStatusVoting)
+		}
+	})
+
+	t.Run("paused timers do not expire", func(t *testing.T) {
+		stores := newTimerStores(t)
+		timers := newPhaseTimers(t, stores)
+		if _, err := timers.Start(getRetro(t, stores), 30*time.Millisecond, true, "alice", "Alice"); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		if _, err := timers.Pause("RETRO-1"); err != nil {
+			t.Fatalf("Pause: %v", err)
+		}
+		time.Sleep(80 * time.Millisecond)
+		if timerGone(stores)() {
+			t.Error("paused timer expired")
+		}
+		if got := getRetro(t, stores).Status; got != vstore.RetrospectiveStatusActive {
+			t.Errorf("status = %v, want %v", got, vstore.RetrospectiveStatusActive)
+		}
+	})
+}
+
+func TestPhaseTimerRestore(t *testing.T) {
+	stores := newTimerStores(t)
+	// Timers a previous server process left behind
+	for _, timer := range []*vstore.PhaseTimer{
+		{RetrospectiveID: "RETRO-1", Phase: vstore.RetrospectiveStatusActive, Duration: time.Minute, EndsAt: time.Now().Add(-time.Minute), AutoAdvance: true, StartedBy: "alice"},
+		{RetrospectiveID: "RETRO-2", Phase: vstore.RetrospectiveStatusActive, Duration: time.Minute, Remaining: 30 * time.Millisecond, Paused: true},
+		{RetrospectiveID: "RETRO-3", Phase: vstore.RetrospectiveStatusActive, Duration: time.Hour, EndsAt: time.Now().Add(time.Hour)},
+	} {
+		if err := stores.PhaseTimers.Put(timer); err != nil {
+			t.Fatalf("PhaseTimers.Put: %v", err)
+		}
+	}
+
+	timers := newPhaseTimers(t, stores)
+	if err := timers.Restore(); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	eventually(t, "the overdue timer to advance its retrospective", func() bool {
+		return getRetro(t, stores).Status == vstore.RetrospectiveStatusVoting
+	})
+	if !timerGone(stores)() {
+		t.Error("overdue timer is still stored")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if _, err := stores.PhaseTimers.Get("RETRO-2"); err != nil {
+		t.Errorf("paused timer after Restore: %v", err)
+	}
+	// The running timer is scheduled again and still controllable
+	if _, err := timers.Pause("RETRO-3"); err != nil {
+		t.Errorf("Pause of a restored timer: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// phaseAction names a step in the retrospective lifecycle
+type phaseAction string
+
+const (
+	phaseStart           phaseAction = "start"
+	phaseStartVoting     phaseAction = "start voting"
+	phaseStartDiscussion phaseAction = "start discussion"
+	phaseComplete        phaseAction = "complete"
+	phaseGoBack          phaseAction = "go back a phase"
+	phaseReopen          phaseAction = "reopen"
+	phaseArchive         phaseAction = "archive"
+)
+
+// phaseRoles is the set of callers allowed to take a phase action
+type phaseRoles int
+
+const (
+	phaseRoleFacilitator phaseRoles = 1 << iota
+	phaseRoleTeamAdmin
+)
+
+// phaseTransition is one allowed move between retrospective phases
+type phaseTransition struct {
+	action phaseAction
+	from   vstore.RetrospectiveStatus
+	to     vstore.RetrospectiveStatus
+	roles  phaseRoles
+}
+
+// phaseTransitions is the retrospective state machine. A phase action is only
+// allowed from the statuses listed here, and only for the listed roles.
+var phaseTransitions = []phaseTransition{
+	{phaseStart, vstore.RetrospectiveStatusDraft, vstore.RetrospectiveStatusActive, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseStartVoting, vstore.RetrospectiveStatusDraft, vstore.RetrospectiveStatusVoting, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseStartVoting, vstore.RetrospectiveStatusActive, vstore.RetrospectiveStatusVoting, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseStartDiscussion, vstore.RetrospectiveStatusVoting, vstore.RetrospectiveStatusDiscussing, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseComplete, vstore.RetrospectiveStatusDiscussing, vstore.RetrospectiveStatusCompleted, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseGoBack, vstore.RetrospectiveStatusVoting, vstore.RetrospectiveStatusActive, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseGoBack, vstore.RetrospectiveStatusDiscussing, vstore.RetrospectiveStatusVoting, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseReopen, vstore.RetrospectiveStatusCompleted, vstore.RetrospectiveStatusDiscussing, phaseRoleFacilitator | phaseRoleTeamAdmin},
+	{phaseArchive, vstore.RetrospectiveStatusCompleted, vstore.RetrospectiveStatusArchived, phaseRoleTeamAdmin},
+}
+
+// timerPhaseActions maps each timed phase to the action that ends it, taken
+// when the phase's timer expires with auto-advance on
+var timerPhaseActions = map[vstore.RetrospectiveStatus]phaseAction{
+	vstore.RetrospectiveStatusActive:     phaseStartVoting,
+	vstore.RetrospectiveStatusVoting:     phaseStartDiscussion,
+	vstore.RetrospectiveStatusDiscussing: phaseComplete,
+}
+
+// findPhaseTransition returns the transition action takes from status
+func findPhaseTransition(action phaseAction, from vstore.RetrospectiveStatus) (phaseTransition, bool) {
+	for _, t := range phaseTransitions {
+		if t.action == action && t.from == from {
+			return t, true
+		}
+	}
+	return phaseTransition{}, false
+}
+
+// callerPhaseRoles returns the phase roles the caller holds on retro
+func callerPhaseRoles(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective) phaseRoles {
+	var roles phaseRoles
+	if userID := getUserIDFromContext(ctx); userID != "" && userID == retro.FacilitatorID {
+		roles |= phaseRoleFacilitator
+	}
+	if member != nil && member.Role == vstore.TeamRoleAdmin {
+		roles |= phaseRoleTeamAdmin
+	}
+	return roles
+}
+
+// applyPhaseTransition validates action against the transition table and the
+// caller's roles, then moves retro to the new status and records the change in
+// its phase history. The caller persists retro.
+func applyPhaseTransition(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective, action phaseAction) (*vstore.PhaseTransition, error) {
+	t, ok := findPhaseTransition(action, retro.Status)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot %s a retrospective in %s status", ErrInvalidStatus, action, statusName(retro.Status))
+	}
+	if callerPhaseRoles(ctx, member, retro)&t.roles == 0 {
+		if t.roles == phaseRoleTeamAdmin {
+			return nil, fmt.Errorf("%w: only a team admin can %s a retrospective", ErrPermissionDenied, action)
+		}
+		return nil, fmt.Errorf("%w: only the facilitator or a team admin can %s a retrospective", ErrPermissionDenied, action)
+	}
+	return moveToPhase(retro, t, getUserIDFromContext(ctx), getUserNameFromContext(ctx)), nil
+}
+
+// moveToPhase applies t to retro on behalf of changedBy without checking roles
+func moveToPhase(retro *vstore.Retrospective, t phaseTransition, changedBy, changedByName string) *vstore.PhaseTransition {
+	now := time.Now()
+	change := &vstore.PhaseTransition{
+		FromStatus:    t.from,
+		ToStatus:      t.to,
+		ChangedBy:     changedBy,
+		ChangedByName: changedByName,
+		ChangedAt:     now,
+	}
+	retro.Status = t.to
+	retro.PhaseHistory = append(retro.PhaseHistory, change)
+
+	switch t.to {
+	case vstore.RetrospectiveStatusActive, vstore.RetrospectiveStatusVoting:
+		if retro.StartedAt.IsZero() {
+			retro.StartedAt = now
+		}
+	case vstore.RetrospectiveStatusCompleted:
+		retro.CompletedAt = now
+	case vstore.RetrospectiveStatusDiscussing:
+		// Reopening clears the completion time until the retrospective is completed again
+		retro.CompletedAt = time.Time{}
+	}
+
+	// Cards written in a silent brainstorm show once voting starts
+	if t.to == vstore.RetrospectiveStatusVoting && retro.SilentBrainstorm && retro.ItemsRevealedAt.IsZero() {
+		retro.ItemsRevealedAt = now
+	}
+	return change
+}
+
+func statusName(status vstore.RetrospectiveStatus) string {
+	switch status {
+	case vstore.RetrospectiveStatusDraft:
+		return "DRAFT"
+	case vstore.RetrospectiveStatusActive:
+		return "ACTIVE"
+	case vstore.RetrospectiveStatusVoting:
+		return "VOTING"
+	case vstore.RetrospectiveStatusDiscussing:
+		return "DISCUSSING"
+	case vstore.RetrospectiveStatusCompleted:
+		return "COMPLETED"
+	case vstore.RetrospectiveStatusArchived:
+		return "ARCHIVED"
+	default:
+		return "UNSPECIFIED"
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"time"
 
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -16,23 +15,29 @@ import (
 // EventBroadcaster manages real-time event broadcasting
 type EventBroadcaster struct {
 	mu          sync.RWMutex
-	subscribers map[string][]chan *pb.RetrospectiveEvent // key: retrospective_id
+	subscribers map[string][]*subscriber // key: retrospective_id
+}
+
+// subscriber is one event stream and the user it is streamed to
+type subscriber struct {
+	userID string
+	ch     chan *pb.RetrospectiveEvent
 }
 
 // NewEventBroadcaster creates a new EventBroadcaster
 func NewEventBroadcaster() *EventBroadcaster {
 	return &EventBroadcaster{
-		subscribers: make(map[string][]chan *pb.RetrospectiveEvent),
+		subscribers: make(map[string][]*subscriber),
 	}
 }
 
-// Subscribe adds a subscriber for a retrospective
-func (b *EventBroadcaster) Subscribe(retroID string) chan *pb.RetrospectiveEvent {
+// Subscribe adds a subscriber for a retrospective on behalf of userID
+func (b *EventBroadcaster) Subscribe(retroID, userID string) chan *pb.RetrospectiveEvent {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	ch := make(chan *pb.RetrospectiveEvent, 100)
-	b.subscribers[retroID] = append(b.subscribers[retroID], ch)
+	b.subscribers[retroID] = append(b.subscribers[retroID], &subscriber{userID: userID, ch: ch})
 	return ch
 }
 
@@ -43,7 +48,7 @@ func (b *EventBroadcaster) Unsubscribe(retroID string, ch chan *pb.Retrospective
 
 	subs := b.subscribers[retroID]
 	for i, sub := range subs {
-		if sub == ch {
+		if sub.ch == ch {
 			b.subscribers[retroID] = append(subs[:i], subs[i+1:]...)
 			close(ch)
 			break
@@ -53,12 +58,18 @@ func (b *EventBroadcaster) Unsubscribe(retroID string, ch chan *pb.Retrospective
 
 // Broadcast sends an event to all subscribers of a retrospective
 func (b *EventBroadcaster) Broadcast(retroID string, event *pb.RetrospectiveEvent) {
+	b.BroadcastEach(retroID, func(string) *pb.RetrospectiveEvent { return event })
+}
+
+// BroadcastEach sends every subscriber of a retrospective the event eventFor
+// builds for its user, for events whose content depends on who receives them
+func (b *EventBroadcaster) BroadcastEach(retroID string, eventFor func(userID string) *pb.RetrospectiveEvent) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	for _, ch := range b.subscribers[retroID] {
+	for _, sub := range b.subscribers[retroID] {
 		select {
-		case ch <- event:
+		case sub.ch <- eventFor(sub.userID):
 		default:
 			// Channel full, skip
 		}
@@ -71,18 +82,21 @@ var broadcaster = NewEventBroadcaster()
 // RealtimeService implements the RealtimeService gRPC service
 type RealtimeService struct {
 	pb.UnimplementedRealtimeServiceServer
-	participantStore *InMemoryParticipantStore
-	retroStore       *InMemoryRetrospectiveStore
+	participantStore ParticipantStore
+	retroStore       RetrospectiveStore
+	teamStore        TeamStore
 }
 
 // NewRealtimeService creates a new RealtimeService
 func NewRealtimeService(
-	participantStore *InMemoryParticipantStore,
-	retroStore *InMemoryRetrospectiveStore,
+	participantStore ParticipantStore,
+	retroStore RetrospectiveStore,
+	teamStore TeamStore,
 ) *RealtimeService {
 	return &RealtimeService{
 		participantStore: participantStore,
 		retroStore:       retroStore,
+		teamStore:        teamStore,
 	}
 }
 
@@ -92,14 +106,14 @@ func (s *RealtimeService) Subscribe(req *pb.SubscribeRequest, stream pb.Realtime
 		return ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	// Verify retrospective exists
-	_, err := s.retroStore.Get(req.RetrospectiveId)
+	// Verify retrospective exists and the caller belongs to its team
+	_, _, err := getRetroForMember(stream.Context(), s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return ToGRPCError(err)
 	}
 
 	// Subscribe to events
-	ch := broadcaster.Subscribe(req.RetrospectiveId)
+	ch := broadcaster.Subscribe(req.RetrospectiveId, getUserIDFromContext(stream.Context()))
 	defer broadcaster.Unsubscribe(req.RetrospectiveId, ch)
 
 	// Stream events until client disconnects
@@ -124,8 +138,8 @@ func (s *RealtimeService) JoinRetrospective(ctx context.Context, req *pb.JoinRet
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	// Verify retrospective exists
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	// Verify retrospective exists and the caller belongs to its team
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
@@ -136,11 +150,7 @@ func (s *RealtimeService) JoinRetrospective(ctx context.Context, req *pb.JoinRet
 		displayName = getUserNameFromContext(ctx)
 	}
 
-	// Determine role
-	role := vstore.ParticipantRoleMember
-	if retro.FacilitatorID == userID {
-		role = vstore.ParticipantRoleFacilitator
-	}
+	role := participantRole(ctx, member, retro)
 
 	participant := &vstore.Participant{
 		ParticipantID:   fmt.Sprintf("%s:%s", req.RetrospectiveId, userID),
@@ -186,6 +196,11 @@ func (s *RealtimeService) LeaveRetrospective(ctx context.Context, req *pb.LeaveR
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
 	userID := getUserIDFromContext(ctx)
 
 	if err := s.participantStore.Leave(req.RetrospectiveId, userID); err != nil {
@@ -208,11 +223,8 @@ func (s *RealtimeService) LeaveRetrospective(ctx context.Context, req *pb.LeaveR
 	})
 
 	// Update participant count on retrospective
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
-	if err == nil {
-		retro.ParticipantCount = int32(len(participants))
-		s.retroStore.Update(retro)
-	}
+	retro.ParticipantCount = int32(len(participants))
+	s.retroStore.Update(retro)
 
 	return &emptypb.Empty{}, nil
 }
@@ -223,6 +235,10 @@ func (s *RealtimeService) GetParticipants(ctx context.Context, req *pb.GetPartic
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
+	if _, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
 	participants, err := s.participantStore.ListByRetrospective(req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
@@ -239,6 +255,10 @@ func (s *RealtimeService) Heartbeat(ctx context.Context, req *pb.HeartbeatReques
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
+	if _, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
 	userID := getUserIDFromContext(ctx)
 
 	if err := s.participantStore.Heartbeat(req.RetrospectiveId, userID); err != nil {
@@ -248,27 +268,50 @@ func (s *RealtimeService) Heartbeat(ctx context.Context, req *pb.HeartbeatReques
 	return &emptypb.Empty{}, nil
 }
 
-// BroadcastItemCreated broadcasts an item created event
-func BroadcastItemCreated(retroID string, item *pb.RetrospectiveItem) {
-	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
-		RetrospectiveId: retroID,
-		Timestamp:       timestamppb.Now(),
-		Event: &pb.RetrospectiveEvent_ItemCreated{
-			ItemCreated: &pb.ItemCreatedEvent{
-				Item: item,
+// BroadcastItemCreated broadcasts an item created event. While a silent
+// brainstorm hides cards, everyone but the author receives a placeholder.
+func BroadcastItemCreated(retro *vstore.Retrospective, item *vstore.RetrospectiveItem) {
+	now := timestamppb.Now()
+	broadcaster.BroadcastEach(retro.RetrospectiveID, func(userID string) *pb.RetrospectiveEvent {
+		return &pb.RetrospectiveEvent{
+			RetrospectiveId: retro.RetrospectiveID,
+			Timestamp:       now,
+			Event: &pb.RetrospectiveEvent_ItemCreated{
+				ItemCreated: &pb.ItemCreatedEvent{
+					Item: convertVstoreItemForViewer(retro, item, userID),
+				},
 			},
-		},
+		}
 	})
 }
 
-// BroadcastItemUpdated broadcasts an item updated event
-func BroadcastItemUpdated(retroID string, item *pb.RetrospectiveItem) {
+// BroadcastItemUpdated broadcasts an item updated event. While a silent
+// brainstorm hides cards, everyone but the author receives a placeholder.
+func BroadcastItemUpdated(retro *vstore.Retrospective, item *vstore.RetrospectiveItem) {
+	now := timestamppb.Now()
+	broadcaster.BroadcastEach(retro.RetrospectiveID, func(userID string) *pb.RetrospectiveEvent {
+		return &pb.RetrospectiveEvent{
+			RetrospectiveId: retro.RetrospectiveID,
+			Timestamp:       now,
+			Event: &pb.RetrospectiveEvent_ItemUpdated{
+				ItemUpdated: &pb.ItemUpdatedEvent{
+					Item: convertVstoreItemForViewer(retro, item, userID),
+				},
+			},
+		}
+	})
+}
+
+// BroadcastItemsRevealed broadcasts every card of a silent brainstorm at once
+// when the cards are revealed
+func BroadcastItemsRevealed(retroID string, items []*pb.RetrospectiveItem, revealedBy string) {
 	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
 		RetrospectiveId: retroID,
 		Timestamp:       timestamppb.Now(),
-		Event: &pb.RetrospectiveEvent_ItemUpdated{
-			ItemUpdated: &pb.ItemUpdatedEvent{
-				Item: item,
+		Event: &pb.RetrospectiveEvent_ItemsRevealed{
+			ItemsRevealed: &pb.ItemsRevealedEvent{
+				Items:      items,
+				RevealedBy: revealedBy,
 			},
 		},
 	})
@@ -288,14 +331,114 @@ func BroadcastItemDeleted(retroID, itemID, columnID string) {
 	})
 }
 
-// BroadcastVoteCast broadcasts a vote cast event
-func BroadcastVoteCast(retroID, itemID string, newVoteCount int32, userID string) {
+// BroadcastItemGroupCreated broadcasts an item group created event
+func BroadcastItemGroupCreated(retroID string, group *pb.ItemGroup) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ItemGroupCreated{
+			ItemGroupCreated: &pb.ItemGroupCreatedEvent{
+				Group: group,
+			},
+		},
+	})
+}
+
+// BroadcastItemGroupUpdated broadcasts an item group updated event, sent when
+// a group is renamed or items join or leave it
+func BroadcastItemGroupUpdated(retroID string, group *pb.ItemGroup) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ItemGroupUpdated{
+			ItemGroupUpdated: &pb.ItemGroupUpdatedEvent{
+				Group: group,
+			},
+		},
+	})
+}
+
+// BroadcastItemGroupDeleted broadcasts an item group deleted event
+func BroadcastItemGroupDeleted(retroID, groupID, columnID string) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ItemGroupDeleted{
+			ItemGroupDeleted: &pb.ItemGroupDeletedEvent{
+				GroupId:  groupID,
+				ColumnId: columnID,
+			},
+		},
+	})
+}
+
+// BroadcastCommentCreated broadcasts a comment created event
+func BroadcastCommentCreated(retroID string, comment *pb.ItemComment) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_CommentCreated{
+			CommentCreated: &pb.CommentCreatedEvent{
+				Comment: comment,
+			},
+		},
+	})
+}
+
+// BroadcastCommentUpdated broadcasts a comment updated event
+func BroadcastCommentUpdated(retroID string, comment *pb.ItemComment) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_CommentUpdated{
+			CommentUpdated: &pb.CommentUpdatedEvent{
+				Comment: comment,
+			},
+		},
+	})
+}
+
+// BroadcastCommentDeleted broadcasts a comment deleted event
+func BroadcastCommentDeleted(retroID, commentID, itemID string) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_CommentDeleted{
+			CommentDeleted: &pb.CommentDeletedEvent{
+				CommentId: commentID,
+				ItemId:    itemID,
+			},
+		},
+	})
+}
+
+// BroadcastReactionsUpdated broadcasts an item's reaction counts, flagging
+// the emojis each subscriber reacted with themselves
+func BroadcastReactionsUpdated(retro *vstore.Retrospective, itemID string, reactions []*vstore.Reaction) {
+	now := timestamppb.Now()
+	broadcaster.BroadcastEach(retro.RetrospectiveID, func(userID string) *pb.RetrospectiveEvent {
+		return &pb.RetrospectiveEvent{
+			RetrospectiveId: retro.RetrospectiveID,
+			Timestamp:       now,
+			Event: &pb.RetrospectiveEvent_ReactionsUpdated{
+				ReactionsUpdated: &pb.ReactionsUpdatedEvent{
+					ItemId:    itemID,
+					Reactions: countReactions(retro, reactions, userID),
+				},
+			},
+		}
+	})
+}
+
+// BroadcastVoteCast broadcasts a vote cast event for an item or, when groupID is set, a group
+func BroadcastVoteCast(retroID, itemID, groupID string, newVoteCount int32, userID string) {
 	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
 		RetrospectiveId: retroID,
 		Timestamp:       timestamppb.Now(),
 		Event: &pb.RetrospectiveEvent_VoteCast{
 			VoteCast: &pb.VoteCastEvent{
 				ItemId:       itemID,
+				GroupId:      groupID,
 				NewVoteCount: newVoteCount,
 				UserId:       userID,
 			},
@@ -303,6 +446,37 @@ func BroadcastVoteCast(retroID, itemID string, newVoteCount int32, userID string
 	})
 }
 
+// BroadcastVoteRemoved broadcasts a vote removed event for an item or, when groupID is set, a group
+func BroadcastVoteRemoved(retroID, itemID, groupID string, newVoteCount int32, userID string) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_VoteRemoved{
+			VoteRemoved: &pb.VoteRemovedEvent{
+				ItemId:       itemID,
+				GroupId:      groupID,
+				NewVoteCount: newVoteCount,
+				UserId:       userID,
+			},
+		},
+	})
+}
+
+// BroadcastVoteResultsRevealed broadcasts the results of a blind vote once revealed
+func BroadcastVoteResultsRevealed(retroID string, summaries []*pb.VoteSummary, totalVotes int32, revealedBy string) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_VoteResultsRevealed{
+			VoteResultsRevealed: &pb.VoteResultsRevealedEvent{
+				Summaries:  summaries,
+				TotalVotes: totalVotes,
+				RevealedBy: revealedBy,
+			},
+		},
+	})
+}
+
 // BroadcastStatusChanged broadcasts a status changed event
 func BroadcastStatusChanged(retroID string, prevStatus, newStatus pb.RetrospectiveStatus, changedBy string) {
 	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
@@ -318,6 +492,59 @@ func BroadcastStatusChanged(retroID string, prevStatus, newStatus pb.Retrospecti
 	})
 }
 
+// BroadcastPhaseTimer broadcasts a change to, tick of or expiry of a phase timer
+func BroadcastPhaseTimer(retroID string, timer *pb.PhaseTimer, action pb.PhaseTimerAction) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_PhaseTimer{
+			PhaseTimer: &pb.PhaseTimerEvent{
+				Timer:  timer,
+				Action: action,
+			},
+		},
+	})
+}
+
+// BroadcastActionItemCreated broadcasts an action item created event
+func BroadcastActionItemCreated(retroID string, actionItem *pb.ActionItem) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ActionItemCreated{
+			ActionItemCreated: &pb.ActionItemCreatedEvent{
+				ActionItem: actionItem,
+			},
+		},
+	})
+}
+
+// BroadcastActionItemUpdated broadcasts an action item updated event
+func BroadcastActionItemUpdated(retroID string, actionItem *pb.ActionItem) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ActionItemUpdated{
+			ActionItemUpdated: &pb.ActionItemUpdatedEvent{
+				ActionItem: actionItem,
+			},
+		},
+	})
+}
+
+// BroadcastActionItemDeleted broadcasts an action item deleted event
+func BroadcastActionItemDeleted(retroID, actionItemID string) {
+	broadcaster.Broadcast(retroID, &pb.RetrospectiveEvent{
+		RetrospectiveId: retroID,
+		Timestamp:       timestamppb.Now(),
+		Event: &pb.RetrospectiveEvent_ActionItemDeleted{
+			ActionItemDeleted: &pb.ActionItemDeletedEvent{
+				ActionItemId: actionItemID,
+			},
+		},
+	})
+}
+
 func convertVstoreParticipantToPb(p *vstore.Participant) *pb.Participant {
 	return &pb.Participant{
 		UserId:      p.UserID,
@@ -5,34 +5,56 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/auth"
 	"github.com/vendasta/retrospective/internal/vstore"
 )
 
 // RetrospectiveService implements the RetrospectiveService gRPC service
 type RetrospectiveService struct {
 	pb.UnimplementedRetrospectiveServiceServer
-	retroStore      *InMemoryRetrospectiveStore
-	itemStore       *InMemoryItemStore
-	actionItemStore *InMemoryActionItemStore
+	retroStore       RetrospectiveStore
+	itemStore        ItemStore
+	groupStore       ItemGroupStore
+	commentStore     CommentStore
+	voteStore        VoteStore
+	actionItemStore  ActionItemStore
+	participantStore ParticipantStore
+	teamStore        TeamStore
+	timers           *PhaseTimers
 }
 
 // NewRetrospectiveService creates a new RetrospectiveService
 func NewRetrospectiveService(
-	retroStore *InMemoryRetrospectiveStore,
-	itemStore *InMemoryItemStore,
-	actionItemStore *InMemoryActionItemStore,
+	retroStore RetrospectiveStore,
+	itemStore ItemStore,
+	groupStore ItemGroupStore,
+	commentStore CommentStore,
+	voteStore VoteStore,
+	actionItemStore ActionItemStore,
+	participantStore ParticipantStore,
+	teamStore TeamStore,
+	timers *PhaseTimers,
 ) *RetrospectiveService {
 	return &RetrospectiveService{
-		retroStore:      retroStore,
-		itemStore:       itemStore,
-		actionItemStore: actionItemStore,
+		retroStore:       retroStore,
+		itemStore:        itemStore,
+		groupStore:       groupStore,
+		commentStore:     commentStore,
+		voteStore:        voteStore,
+		actionItemStore:  actionItemStore,
+		participantStore: participantStore,
+		teamStore:        teamStore,
+		timers:           timers,
 	}
 }
 
@@ -45,6 +67,23 @@ func (s *RetrospectiveService) Create(ctx context.Context, req *pb.CreateRetrosp
 		return nil, ToGRPCError(fmt.Errorf("%w: sprint_name is required", ErrInvalidArgument))
 	}
 
+	team, err := s.teamStore.Get(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	member, err := requireTeamMember(ctx, s.teamStore, req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireTeamWriter(member); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if req.FacilitatorId != "" {
+		if _, err := s.teamStore.GetMember(req.TeamId, req.FacilitatorId); err != nil {
+			return nil, ToGRPCError(fmt.Errorf("%w: facilitator must be a member of the team", ErrInvalidArgument))
+		}
+	}
+
 	// Generate ID
 	retroID := fmt.Sprintf("RETRO-%d", time.Now().UnixNano())
 
@@ -61,6 +100,7 @@ func (s *RetrospectiveService) Create(ctx context.Context, req *pb.CreateRetrosp
 
 	// Set default voting config
 	votingConfig := &vstore.VotingConfig{
+		Mode:                      vstore.VotingModeDot,
 		MaxVotesPerUser:           5,
 		AllowMultipleVotesPerItem: false,
 		AnonymousVoting:           false,
@@ -69,14 +109,30 @@ func (s *RetrospectiveService) Create(ctx context.Context, req *pb.CreateRetrosp
 		if req.VotingConfig.MaxVotesPerUser > 0 {
 			votingConfig.MaxVotesPerUser = req.VotingConfig.MaxVotesPerUser
 		}
+		if req.VotingConfig.MaxVotesPerColumn < 0 {
+			return nil, ToGRPCError(fmt.Errorf("%w: max_votes_per_column cannot be negative", ErrInvalidArgument))
+		}
+		if req.VotingConfig.Mode < 0 || req.VotingConfig.Mode > pb.VotingMode_VOTING_MODE_FIST_OF_FIVE {
+			return nil, ToGRPCError(fmt.Errorf("%w: unknown voting mode %d", ErrInvalidArgument, req.VotingConfig.Mode))
+		}
+		if req.VotingConfig.Mode != pb.VotingMode_VOTING_MODE_UNSPECIFIED {
+			votingConfig.Mode = vstore.VotingMode(req.VotingConfig.Mode)
+		}
+		votingConfig.MaxVotesPerColumn = req.VotingConfig.MaxVotesPerColumn
 		votingConfig.AllowMultipleVotesPerItem = req.VotingConfig.AllowMultipleVotesPerItem
 		votingConfig.AnonymousVoting = req.VotingConfig.AnonymousVoting
+		votingConfig.BlindVoting = req.VotingConfig.BlindVoting
+	}
+
+	if err := validateReactionEmojis(req.ReactionEmojis); err != nil {
+		return nil, ToGRPCError(err)
 	}
 
 	// Create retrospective
 	retro := &vstore.Retrospective{
 		RetrospectiveID:  retroID,
 		TeamID:           req.TeamId,
+		TeamName:         team.Name,
 		SprintName:       req.SprintName,
 		Description:      req.Description,
 		TemplateType:     vstore.TemplateType(templateType),
@@ -85,6 +141,8 @@ func (s *RetrospectiveService) Create(ctx context.Context, req *pb.CreateRetrosp
 		VotingConfig:     votingConfig,
 		CreatedBy:        getUserIDFromContext(ctx),
 		FacilitatorID:    req.FacilitatorId,
+		SilentBrainstorm: req.SilentBrainstorm,
+		ReactionEmojis:   req.ReactionEmojis,
 	}
 
 	if retro.FacilitatorID == "" {
@@ -107,7 +165,7 @@ func (s *RetrospectiveService) Get(ctx context.Context, req *pb.GetRetrospective
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
@@ -120,8 +178,9 @@ func (s *RetrospectiveService) Get(ctx context.Context, req *pb.GetRetrospective
 	if req.IncludeItems {
 		items, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, "", false)
 		if err == nil {
+			userID := getUserIDFromContext(ctx)
 			for _, item := range items {
-				result.Items = append(result.Items, convertVstoreItemToPb(item))
+				result.Items = append(result.Items, convertVstoreItemForViewer(retro, item, userID))
 			}
 		}
 	}
@@ -146,7 +205,8 @@ func (s *RetrospectiveService) GetMulti(ctx context.Context, req *pb.GetMultiRet
 	var containers []*pb.GetMultiRetrospectivesResponse_RetrospectiveContainer
 
 	for _, id := range req.RetrospectiveIds {
-		retro, err := s.retroStore.Get(id)
+		// Missing retrospectives and those of other teams are returned as empty containers
+		retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, id)
 		if err != nil {
 			containers = append(containers, &pb.GetMultiRetrospectivesResponse_RetrospectiveContainer{})
 			continue
@@ -173,6 +233,13 @@ func (s *RetrospectiveService) List(ctx context.Context, req *pb.ListRetrospecti
 		}
 	}
 
+	if teamID == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id filter is required", ErrInvalidArgument))
+	}
+	if _, err := requireTeamMember(ctx, s.teamStore, teamID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
 	cursor := ""
 	pageSize := int64(20)
 	if req.PagingOptions != nil {
@@ -207,10 +274,13 @@ func (s *RetrospectiveService) Update(ctx context.Context, req *pb.UpdateRetrosp
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective is required", ErrInvalidArgument))
 	}
 
-	existing, err := s.retroStore.Get(req.Retrospective.RetrospectiveId)
+	existing, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.Retrospective.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := requireFacilitator(ctx, member, existing, "update a retrospective"); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Apply updates based on field mask (simplified - update all provided fields)
 	if req.Retrospective.SprintName != "" {
@@ -220,8 +290,17 @@ func (s *RetrospectiveService) Update(ctx context.Context, req *pb.UpdateRetrosp
 		existing.Description = req.Retrospective.Description
 	}
 	if req.Retrospective.FacilitatorId != "" {
+		if _, err := s.teamStore.GetMember(existing.TeamID, req.Retrospective.FacilitatorId); err != nil {
+			return nil, ToGRPCError(fmt.Errorf("%w: facilitator must be a member of the team", ErrInvalidArgument))
+		}
 		existing.FacilitatorID = req.Retrospective.FacilitatorId
 	}
+	if len(req.Retrospective.ReactionEmojis) > 0 {
+		if err := validateReactionEmojis(req.Retrospective.ReactionEmojis); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		existing.ReactionEmojis = req.Retrospective.ReactionEmojis
+	}
 
 	if err := s.retroStore.Update(existing); err != nil {
 		return nil, ToGRPCError(err)
@@ -236,91 +315,248 @@ func (s *RetrospectiveService) Delete(ctx context.Context, req *pb.DeleteRetrosp
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireFacilitator(ctx, member, retro, "delete a retrospective"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
 	if err := s.retroStore.Delete(req.RetrospectiveId); err != nil {
 		return nil, ToGRPCError(err)
 	}
+	s.clearTimer(req.RetrospectiveId)
 
 	return &emptypb.Empty{}, nil
 }
 
+// Start opens a draft retrospective for adding items
+func (s *RetrospectiveService) Start(ctx context.Context, req *pb.StartRetrospectiveRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseStart)
+}
+
 // StartVoting transitions a retrospective to voting phase
 func (s *RetrospectiveService) StartVoting(ctx context.Context, req *pb.StartVotingRequest) (*emptypb.Empty, error) {
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	return s.changePhase(ctx, req.RetrospectiveId, phaseStartVoting)
+}
+
+// StartDiscussion transitions a retrospective to discussion phase
+func (s *RetrospectiveService) StartDiscussion(ctx context.Context, req *pb.StartDiscussionRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseStartDiscussion)
+}
+
+// Complete marks a retrospective as complete
+func (s *RetrospectiveService) Complete(ctx context.Context, req *pb.CompleteRetrospectiveRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseComplete)
+}
+
+// GoBack returns a retrospective to its previous phase
+func (s *RetrospectiveService) GoBack(ctx context.Context, req *pb.GoBackRetrospectiveRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseGoBack)
+}
+
+// Reopen moves a completed retrospective back to discussion
+func (s *RetrospectiveService) Reopen(ctx context.Context, req *pb.ReopenRetrospectiveRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseReopen)
+}
+
+// Archive archives a completed retrospective
+func (s *RetrospectiveService) Archive(ctx context.Context, req *pb.ArchiveRetrospectiveRequest) (*emptypb.Empty, error) {
+	return s.changePhase(ctx, req.RetrospectiveId, phaseArchive)
+}
+
+// changePhase applies a phase action from the transition table and broadcasts the change
+func (s *RetrospectiveService) changePhase(ctx context.Context, retroID string, action phaseAction) (*emptypb.Empty, error) {
+	if retroID == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, retroID)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	if retro.Status != vstore.RetrospectiveStatusActive && retro.Status != vstore.RetrospectiveStatusDraft {
-		return nil, ToGRPCError(fmt.Errorf("%w: can only start voting from DRAFT or ACTIVE status", ErrInvalidStatus))
+	hidden := itemsHidden(retro)
+	change, err := applyPhaseTransition(ctx, member, retro, action)
+	if err != nil {
+		return nil, ToGRPCError(err)
 	}
-
-	retro.Status = vstore.RetrospectiveStatusVoting
 	if err := s.retroStore.Update(retro); err != nil {
 		return nil, ToGRPCError(err)
 	}
 
+	BroadcastStatusChanged(retro.RetrospectiveID, pb.RetrospectiveStatus(change.FromStatus), pb.RetrospectiveStatus(change.ToStatus), change.ChangedBy)
+	if hidden && !itemsHidden(retro) {
+		broadcastItemsRevealed(s.itemStore, retro, change.ChangedBy)
+	}
+
+	// A timer belongs to the phase it was started in
+	s.clearTimer(retro.RetrospectiveID)
+
 	return &emptypb.Empty{}, nil
 }
 
-// StartDiscussion transitions a retrospective to discussion phase
-func (s *RetrospectiveService) StartDiscussion(ctx context.Context, req *pb.StartDiscussionRequest) (*emptypb.Empty, error) {
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+// clearTimer cancels the timer of a retrospective, if it has one
+func (s *RetrospectiveService) clearTimer(retroID string) {
+	if err := s.timers.Cancel(retroID); err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("phase timer %s: cancel: %v", retroID, err)
+	}
+}
+
+// GetPhaseHistory lists the phase changes of a retrospective, oldest first
+func (s *RetrospectiveService) GetPhaseHistory(ctx context.Context, req *pb.GetPhaseHistoryRequest) (*pb.GetPhaseHistoryResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	if retro.Status != vstore.RetrospectiveStatusVoting {
-		return nil, ToGRPCError(fmt.Errorf("%w: can only start discussion from VOTING status", ErrInvalidStatus))
+	var transitions []*pb.PhaseTransition
+	for _, t := range retro.PhaseHistory {
+		transitions = append(transitions, &pb.PhaseTransition{
+			FromStatus:    pb.RetrospectiveStatus(t.FromStatus),
+			ToStatus:      pb.RetrospectiveStatus(t.ToStatus),
+			ChangedBy:     t.ChangedBy,
+			ChangedByName: t.ChangedByName,
+			ChangedAt:     timestamppb.New(t.ChangedAt),
+		})
 	}
 
-	retro.Status = vstore.RetrospectiveStatusDiscussing
-	if err := s.retroStore.Update(retro); err != nil {
+	return &pb.GetPhaseHistoryResponse{
+		Transitions: transitions,
+	}, nil
+}
+
+// StartTimer starts a countdown for the current phase, replacing any running timer
+func (s *RetrospectiveService) StartTimer(ctx context.Context, req *pb.StartTimerRequest) (*pb.StartTimerResponse, error) {
+	retro, err := s.getRetroForTimer(ctx, req.RetrospectiveId)
+	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	return &emptypb.Empty{}, nil
+	timer, err := s.timers.Start(retro, time.Duration(req.DurationSeconds)*time.Second, req.AutoAdvance, getUserIDFromContext(ctx), getUserNameFromContext(ctx))
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.StartTimerResponse{
+		Timer: convertVstorePhaseTimerToPb(timer),
+	}, nil
 }
 
-// Complete marks a retrospective as complete
-func (s *RetrospectiveService) Complete(ctx context.Context, req *pb.CompleteRetrospectiveRequest) (*emptypb.Empty, error) {
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+// PauseTimer pauses the running phase timer
+func (s *RetrospectiveService) PauseTimer(ctx context.Context, req *pb.PauseTimerRequest) (*pb.PauseTimerResponse, error) {
+	if _, err := s.getRetroForTimer(ctx, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	timer, err := s.timers.Pause(req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	retro.Status = vstore.RetrospectiveStatusCompleted
-	retro.CompletedAt = time.Now()
-	if err := s.retroStore.Update(retro); err != nil {
+	return &pb.PauseTimerResponse{
+		Timer: convertVstorePhaseTimerToPb(timer),
+	}, nil
+}
+
+// ResumeTimer resumes a paused phase timer
+func (s *RetrospectiveService) ResumeTimer(ctx context.Context, req *pb.ResumeTimerRequest) (*pb.ResumeTimerResponse, error) {
+	if _, err := s.getRetroForTimer(ctx, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	timer, err := s.timers.Resume(req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.ResumeTimerResponse{
+		Timer: convertVstorePhaseTimerToPb(timer),
+	}, nil
+}
+
+// ExtendTimer adds time to the phase timer
+func (s *RetrospectiveService) ExtendTimer(ctx context.Context, req *pb.ExtendTimerRequest) (*pb.ExtendTimerResponse, error) {
+	if _, err := s.getRetroForTimer(ctx, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	timer, err := s.timers.Extend(req.RetrospectiveId, time.Duration(req.Seconds)*time.Second)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.ExtendTimerResponse{
+		Timer: convertVstorePhaseTimerToPb(timer),
+	}, nil
+}
+
+// CancelTimer stops and removes the phase timer
+func (s *RetrospectiveService) CancelTimer(ctx context.Context, req *pb.CancelTimerRequest) (*emptypb.Empty, error) {
+	if _, err := s.getRetroForTimer(ctx, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if err := s.timers.Cancel(req.RetrospectiveId); err != nil {
 		return nil, ToGRPCError(err)
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
-// Export exports a retrospective to various formats
-func (s *RetrospectiveService) Export(ctx context.Context, req *pb.ExportRetrospectiveRequest) (*pb.ExportRetrospectiveResponse, error) {
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+// GetTimer gets the phase timer of a retrospective
+func (s *RetrospectiveService) GetTimer(ctx context.Context, req *pb.GetTimerRequest) (*pb.GetTimerResponse, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
+	}
+
+	if _, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	timer, err := s.timers.Get(req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	items, _ := s.itemStore.ListByRetrospective(req.RetrospectiveId, "", true)
-	actionItems, _ := s.actionItemStore.ListByRetrospective(req.RetrospectiveId)
+	return &pb.GetTimerResponse{
+		Timer: convertVstorePhaseTimerToPb(timer),
+	}, nil
+}
 
-	var content []byte
-	var filename string
-	var contentType string
+// getRetroForTimer loads a retrospective whose timer the caller wants to control
+func (s *RetrospectiveService) getRetroForTimer(ctx context.Context, retroID string) (*vstore.Retrospective, error) {
+	if retroID == "" {
+		return nil, fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument)
+	}
 
-	switch req.Format {
-	case pb.ExportFormat_EXPORT_FORMAT_JSON:
-		content, filename, contentType = s.exportJSON(retro, items, actionItems)
-	case pb.ExportFormat_EXPORT_FORMAT_CSV:
-		content, filename, contentType = s.exportCSV(retro, items, actionItems)
-	case pb.ExportFormat_EXPORT_FORMAT_MARKDOWN:
-		content, filename, contentType = s.exportMarkdown(retro, items, actionItems)
-	default:
-		content, filename, contentType = s.exportMarkdown(retro, items, actionItems)
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, retroID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireFacilitator(ctx, member, retro, "control the phase timer"); err != nil {
+		return nil, err
+	}
+	return retro, nil
+}
+
+// Export exports a retrospective to various formats
+func (s *RetrospectiveService) Export(ctx context.Context, req *pb.ExportRetrospectiveRequest) (*pb.ExportRetrospectiveResponse, error) {
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	doc, err := s.buildExport(retro, getUserIDFromContext(ctx))
+	if err != nil {
+		return nil, ToGRPCError(err)
 	}
+	content, filename, contentType := RenderExport(doc, req.Format)
 
 	return &pb.ExportRetrospectiveResponse{
 		Content:     content,
@@ -329,104 +565,231 @@ func (s *RetrospectiveService) Export(ctx context.Context, req *pb.ExportRetrosp
 	}, nil
 }
 
-func (s *RetrospectiveService) exportJSON(retro *vstore.Retrospective, items []*vstore.RetrospectiveItem, actionItems []*vstore.ActionItem) ([]byte, string, string) {
-	data := map[string]interface{}{
-		"retrospective": retro,
-		"items":         items,
-		"action_items":  actionItems,
-	}
-	content, _ := json.MarshalIndent(data, "", "  ")
-	return content, fmt.Sprintf("%s.json", retro.SprintName), "application/json"
+func exportJSON(doc *ExportDocument) ([]byte, string, string) {
+	content, _ := json.MarshalIndent(doc, "", "  ")
+	return append(content, '\n'), exportFilename(doc.Retrospective.SprintName, "json"), "application/json"
 }
 
-func (s *RetrospectiveService) exportCSV(retro *vstore.Retrospective, items []*vstore.RetrospectiveItem, actionItems []*vstore.ActionItem) ([]byte, string, string) {
+func exportCSV(doc *ExportDocument) ([]byte, string, string) {
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
 	// Write header
-	writer.Write([]string{"Type", "Column", "Content", "Votes", "Created By"})
+	writer.Write([]string{"Type", "Column", "Group", "Content", "Author", "Votes", "Voters", "Status", "Assignee", "Due Date"})
 
-	// Write items
-	for _, item := range items {
-		writer.Write([]string{
-			"Item",
-			item.ColumnID,
-			item.Content,
-			fmt.Sprintf("%d", item.VoteCount),
-			item.CreatedByName,
-		})
+	votes := func(votes int32, voters int) []string {
+		if doc.Retrospective.VotesHidden {
+			return []string{"", ""}
+		}
+		return []string{fmt.Sprintf("%d", votes), fmt.Sprintf("%d", voters)}
+	}
+	writeItem := func(col *ExportColumn, group string, item *ExportItem) {
+		row := []string{"Item", csvCell(col.Name), csvCell(group), csvCell(item.Content), csvCell(exportAuthor(item.Author, item.Anonymous))}
+		writer.Write(append(append(row, votes(item.Votes, item.VoterCount)...), "", "", ""))
+		// Each item's comments follow it, indented to show replies
+		for _, c := range item.Comments {
+			writer.Write([]string{
+				"Comment",
+				csvCell(col.Name),
+				csvCell(group),
+				csvCell(strings.Repeat("> ", c.Depth) + c.Content),
+				csvCell(exportAuthor(c.Author, c.Anonymous)),
+				"", "", "", "", "",
+			})
+		}
+	}
+
+	// Write items column by column, grouped items after the rest
+	for _, col := range doc.Columns {
+		ungrouped, grouped := exportGroupItems(col)
+		for _, item := range ungrouped {
+			writeItem(col, "", item)
+		}
+		for _, group := range col.Groups {
+			row := []string{"Group", csvCell(col.Name), csvCell(group.Title), "", ""}
+			writer.Write(append(append(row, votes(group.Votes, group.VoterCount)...), "", "", ""))
+			for _, item := range grouped[group.ID] {
+				writeItem(col, group.Title, item)
+			}
+		}
 	}
 
 	// Write action items
-	for _, ai := range actionItems {
+	for _, ai := range doc.ActionItems {
+		status, _ := actionItemStatusLabel(ai.Status)
+		due := ""
+		if ai.DueDate != nil {
+			due = ai.DueDate.Format("2006-01-02")
+		}
 		writer.Write([]string{
 			"Action Item",
 			"",
-			ai.Description,
 			"",
-			ai.AssigneeName,
+			csvCell(ai.Description),
+			"",
+			"",
+			"",
+			status,
+			csvCell(ai.AssigneeName),
+			due,
 		})
 	}
 
 	writer.Flush()
-	return buf.Bytes(), fmt.Sprintf("%s.csv", retro.SprintName), "text/csv"
+	return buf.Bytes(), exportFilename(doc.Retrospective.SprintName, "csv"), "text/csv"
 }
 
-func (s *RetrospectiveService) exportMarkdown(retro *vstore.Retrospective, items []*vstore.RetrospectiveItem, actionItems []*vstore.ActionItem) ([]byte, string, string) {
+func exportMarkdown(doc *ExportDocument) ([]byte, string, string) {
 	var buf bytes.Buffer
+	retro := doc.Retrospective
 
 	buf.WriteString(fmt.Sprintf("# %s Retrospective\n\n", retro.SprintName))
 	buf.WriteString(fmt.Sprintf("**Team:** %s\n", retro.TeamName))
 	buf.WriteString(fmt.Sprintf("**Date:** %s\n\n", retro.Created.Format("January 2, 2006")))
+	if retro.VotesHidden {
+		buf.WriteString("_Vote counts stay hidden until the facilitator reveals them._\n\n")
+	}
 
-	// Group items by column
-	columnItems := make(map[string][]*vstore.RetrospectiveItem)
-	for _, item := range items {
-		columnItems[item.ColumnID] = append(columnItems[item.ColumnID], item)
+	writeItem := func(item *ExportItem, depth int) {
+		indent := strings.Repeat("  ", depth)
+		buf.WriteString(fmt.Sprintf("%s- %s", indent, markdownLines(item.Content, indent+"  ")))
+		if votes := exportVoteSummary(doc, item.Votes, item.VoterCount); votes != "" {
+			buf.WriteString(fmt.Sprintf(" (%s)", votes))
+		}
+		buf.WriteString("\n")
+		for _, c := range item.Comments {
+			replyIndent := indent + "  " + strings.Repeat("  ", c.Depth)
+			buf.WriteString(fmt.Sprintf("%s- 💬 **%s:** %s\n", replyIndent, exportAuthor(c.Author, c.Anonymous), markdownLines(c.Content, replyIndent+"  ")))
+		}
 	}
 
-	// Write each column
-	for _, col := range retro.TemplateColumns {
-		buf.WriteString(fmt.Sprintf("## %s %s\n\n", col.Icon, col.Name))
-		for _, item := range columnItems[col.ColumnID] {
-			buf.WriteString(fmt.Sprintf("- %s", item.Content))
-			if item.VoteCount > 0 {
-				buf.WriteString(fmt.Sprintf(" (%d votes)", item.VoteCount))
+	// Write each column, grouped items nested under their group
+	for _, col := range doc.Columns {
+		buf.WriteString(fmt.Sprintf("## %s\n\n", strings.TrimSpace(col.Icon+" "+col.Name)))
+		ungrouped, grouped := exportGroupItems(col)
+		for _, item := range ungrouped {
+			writeItem(item, 0)
+		}
+		for _, group := range col.Groups {
+			buf.WriteString(fmt.Sprintf("- **%s**", group.Title))
+			if votes := exportVoteSummary(doc, group.Votes, group.VoterCount); votes != "" {
+				buf.WriteString(fmt.Sprintf(" (%s)", votes))
 			}
 			buf.WriteString("\n")
+			for _, item := range grouped[group.ID] {
+				writeItem(item, 1)
+			}
+		}
+		if col.HiddenItems > 0 {
+			buf.WriteString(fmt.Sprintf("- _%s hidden until the facilitator reveals the cards_\n", plural(col.HiddenItems, "more card")))
 		}
 		buf.WriteString("\n")
 	}
 
 	// Write action items
-	if len(actionItems) > 0 {
+	if len(doc.ActionItems) > 0 {
 		buf.WriteString("## Action Items\n\n")
-		for _, ai := range actionItems {
+		for _, ai := range doc.ActionItems {
 			status := "⬜"
 			switch ai.Status {
-			case vstore.ActionItemStatusInProgress:
+			case "in_progress":
 				status = "🔄"
-			case vstore.ActionItemStatusDone:
+			case "done":
 				status = "✅"
-			case vstore.ActionItemStatusWontDo:
+			case "wont_do":
 				status = "❌"
 			}
-			buf.WriteString(fmt.Sprintf("- %s %s", status, ai.Description))
+			buf.WriteString(fmt.Sprintf("- %s %s", status, markdownLines(ai.Description, "  ")))
+			var details []string
 			if ai.AssigneeName != "" {
-				buf.WriteString(fmt.Sprintf(" (@%s)", ai.AssigneeName))
+				details = append(details, "@"+ai.AssigneeName)
+			}
+			if ai.DueDate != nil {
+				details = append(details, "due "+ai.DueDate.Format("January 2, 2006"))
+			}
+			if len(details) > 0 {
+				buf.WriteString(fmt.Sprintf(" (%s)", strings.Join(details, ", ")))
 			}
 			buf.WriteString("\n")
 		}
 	}
 
-	return buf.Bytes(), fmt.Sprintf("%s.md", retro.SprintName), "text/markdown"
+	return buf.Bytes(), exportFilename(retro.SprintName, "md"), "text/markdown"
+}
+
+// csvCell keeps spreadsheets from evaluating a cell that starts like a formula
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// markdownLines indents every line of s after the first, so that multi-line
+// content stays inside the list entry it starts
+func markdownLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines[1:] {
+		if line != "" {
+			lines[i+1] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportGroupItems splits a column's items into those outside any of its
+// groups and those of each group, keeping their order
+func exportGroupItems(col *ExportColumn) ([]*ExportItem, map[string][]*ExportItem) {
+	groups := make(map[string]bool, len(col.Groups))
+	for _, group := range col.Groups {
+		groups[group.ID] = true
+	}
+	var ungrouped []*ExportItem
+	grouped := make(map[string][]*ExportItem)
+	for _, item := range col.Items {
+		if groups[item.GroupID] {
+			grouped[item.GroupID] = append(grouped[item.GroupID], item)
+		} else {
+			ungrouped = append(ungrouped, item)
+		}
+	}
+	return ungrouped, grouped
+}
+
+// exportVoteSummary describes the votes on an item, or returns nothing when
+// it has none or they are hidden
+func exportVoteSummary(doc *ExportDocument, votes int32, voters int) string {
+	if doc.Retrospective.VotesHidden || votes == 0 {
+		return ""
+	}
+	// Imported votes are counts without the voters behind them
+	if voters == 0 {
+		return plural(int(votes), "vote")
+	}
+	people := plural(voters, "person")
+	if voters != 1 {
+		people = fmt.Sprintf("%d people", voters)
+	}
+	return fmt.Sprintf("%s from %s", plural(int(votes), "vote"), people)
 }
 
 // Helper functions
 
+// getUserIDFromContext returns the authenticated caller's user ID, or "" when
+// the request did not pass through the auth interceptors
 func getUserIDFromContext(ctx context.Context) string {
-	// In production, extract from auth context
-	return "mock-user-id"
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.UserID
+	}
+	return ""
+}
+
+// getScopesFromContext returns the scopes granted to the authenticated caller
+func getScopesFromContext(ctx context.Context) []string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Scopes
+	}
+	return nil
 }
 
 func getDefaultTemplateColumns(templateType pb.RetrospectiveTemplateType) []*vstore.TemplateColumn {
@@ -501,7 +864,10 @@ func convertVstoreRetroToPb(retro *vstore.Retrospective) *pb.Retrospective {
 		},
 		Status: pb.RetrospectiveStatus(retro.Status),
 		VotingConfig: &pb.VotingConfig{
+			BlindVoting:               retro.VotingConfig.BlindVoting,
+			Mode:                      pb.VotingMode(votingMode(retro.VotingConfig)),
 			MaxVotesPerUser:           retro.VotingConfig.MaxVotesPerUser,
+			MaxVotesPerColumn:         retro.VotingConfig.MaxVotesPerColumn,
 			AllowMultipleVotesPerItem: retro.VotingConfig.AllowMultipleVotesPerItem,
 			AnonymousVoting:           retro.VotingConfig.AnonymousVoting,
 		},
@@ -514,10 +880,16 @@ func convertVstoreRetroToPb(retro *vstore.Retrospective) *pb.Retrospective {
 		ItemCount:        retro.ItemCount,
 		ActionItemCount:  retro.ActionItemCount,
 		ParticipantCount: retro.ParticipantCount,
+		VotesRevealedAt:  timestamppb.New(retro.VotesRevealedAt),
+		SilentBrainstorm: retro.SilentBrainstorm,
+		ItemsRevealedAt:  timestamppb.New(retro.ItemsRevealedAt),
+		ReactionEmojis:   reactionEmojis(retro),
 	}
 }
 
-func convertVstoreItemToPb(item *vstore.RetrospectiveItem) *pb.RetrospectiveItem {
+// convertVstoreItemToPb converts an item of retro, hiding its vote count while
+// blind voting results are unrevealed
+func convertVstoreItemToPb(retro *vstore.Retrospective, item *vstore.RetrospectiveItem) *pb.RetrospectiveItem {
 	return &pb.RetrospectiveItem{
 		ItemId:          item.ItemID,
 		RetrospectiveId: item.RetrospectiveID,
@@ -525,13 +897,52 @@ func convertVstoreItemToPb(item *vstore.RetrospectiveItem) *pb.RetrospectiveItem
 		Content:         item.Content,
 		CreatedBy:       item.CreatedBy,
 		CreatedByName:   item.CreatedByName,
-		VoteCount:       item.VoteCount,
+		VoteCount:       visibleVoteCount(retro, item.VoteCount),
 		Created:         timestamppb.New(item.Created),
 		Updated:         timestamppb.New(item.Updated),
 		IsAnonymous:     item.IsAnonymous,
 		Position:        item.Position,
+		SortKey:         item.SortKey,
 		HasActionItem:   item.HasActionItem,
+		GroupId:         item.GroupID,
+		Contributors:    convertVstoreContributorsToPb(item.Contributors),
+	}
+}
+
+// convertVstoreItemForViewer converts an item of retro as userID may see it:
+// while a silent brainstorm hides cards, other people's cards are placeholders
+// that only show where the card is
+func convertVstoreItemForViewer(retro *vstore.Retrospective, item *vstore.RetrospectiveItem, userID string) *pb.RetrospectiveItem {
+	if !itemsHidden(retro) || item.CreatedBy == userID {
+		return convertVstoreItemToPb(retro, item)
+	}
+	return &pb.RetrospectiveItem{
+		ItemId:          item.ItemID,
+		RetrospectiveId: item.RetrospectiveID,
+		ColumnId:        item.ColumnID,
+		Created:         timestamppb.New(item.Created),
+		Position:        item.Position,
+		SortKey:         item.SortKey,
+		GroupId:         item.GroupID,
+		IsHidden:        true,
+	}
+}
+
+// convertVstoreContributorsToPb converts the creators of merged items, keeping
+// the identity of anonymous ones hidden
+func convertVstoreContributorsToPb(contributors []*vstore.ItemContributor) []*pb.ItemContributor {
+	var result []*pb.ItemContributor
+	for _, c := range contributors {
+		if c.IsAnonymous {
+			result = append(result, &pb.ItemContributor{DisplayName: "Anonymous"})
+			continue
+		}
+		result = append(result, &pb.ItemContributor{
+			UserId:      c.UserID,
+			DisplayName: c.DisplayName,
+		})
 	}
+	return result
 }
 
 func convertVstoreActionItemToPb(ai *vstore.ActionItem) *pb.ActionItem {
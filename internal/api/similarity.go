@@ -0,0 +1,182 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// defaultMergeThreshold is the similarity from which two items are suggested
+// as duplicates when the caller does not pick one
+const defaultMergeThreshold = 0.5
+
+// stopWords are left out of item features; they say nothing about what a card is about
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "had": true, "has": true, "have": true,
+	"i": true, "in": true, "is": true, "it": true, "its": true, "of": true, "on": true,
+	"or": true, "our": true, "so": true, "that": true, "the": true, "this": true, "to": true,
+	"too": true, "was": true, "we": true, "were": true, "with": true,
+}
+
+// duplicateCluster is a set of items that look like duplicates of one another
+type duplicateCluster struct {
+	items      []*vstore.RetrospectiveItem // oldest first
+	similarity float64                     // average similarity of the pairs that linked the cluster
+}
+
+// findDuplicates clusters items whose content is at least threshold similar.
+// Items are compared by the cosine similarity of their TF-IDF weighted words
+// and character trigrams, so reworded cards and typos still match. Items join
+// a cluster through any one similar member.
+func findDuplicates(items []*vstore.RetrospectiveItem, threshold float64) []*duplicateCluster {
+	vectors := tfidfVectors(items)
+
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type link struct {
+		a          int
+		similarity float64
+	}
+	var links []link
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			sim := cosineSimilarity(vectors[i], vectors[j])
+			if sim < threshold {
+				continue
+			}
+			parent[find(j)] = find(i)
+			links = append(links, link{a: i, similarity: sim})
+		}
+	}
+
+	byRoot := make(map[int]*duplicateCluster)
+	linkCount := make(map[int]int)
+	for i, item := range items {
+		root := find(i)
+		if byRoot[root] == nil {
+			byRoot[root] = &duplicateCluster{}
+		}
+		byRoot[root].items = append(byRoot[root].items, item)
+	}
+	for _, l := range links {
+		root := find(l.a)
+		byRoot[root].similarity += l.similarity
+		linkCount[root]++
+	}
+
+	var clusters []*duplicateCluster
+	for root, c := range byRoot {
+		if len(c.items) < 2 {
+			continue
+		}
+		c.similarity /= float64(linkCount[root])
+		sort.SliceStable(c.items, func(i, j int) bool { return c.items[i].Created.Before(c.items[j].Created) })
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].similarity != clusters[j].similarity {
+			return clusters[i].similarity > clusters[j].similarity
+		}
+		return clusters[i].items[0].Created.Before(clusters[j].items[0].Created)
+	})
+	return clusters
+}
+
+// similarItems returns the items in others that are at least threshold similar to item
+func similarItems(item *vstore.RetrospectiveItem, others []*vstore.RetrospectiveItem, threshold float64) []*vstore.RetrospectiveItem {
+	all := append([]*vstore.RetrospectiveItem{item}, others...)
+	vectors := tfidfVectors(all)
+	var similar []*vstore.RetrospectiveItem
+	for i, other := range others {
+		if other.ItemID != item.ItemID && cosineSimilarity(vectors[0], vectors[i+1]) >= threshold {
+			similar = append(similar, other)
+		}
+	}
+	return similar
+}
+
+// tfidfVectors weights every item's features by how rare they are across items
+func tfidfVectors(items []*vstore.RetrospectiveItem) []map[string]float64 {
+	vectors := make([]map[string]float64, len(items))
+	docFreq := make(map[string]int)
+	for i, item := range items {
+		vectors[i] = contentFeatures(item.Content)
+		for feature := range vectors[i] {
+			docFreq[feature]++
+		}
+	}
+	n := float64(len(items))
+	for _, vector := range vectors {
+		for feature, tf := range vector {
+			// Smoothed so features shared by every item still count a little
+			idf := math.Log((1+n)/(1+float64(docFreq[feature]))) + 1
+			vector[feature] = tf * idf
+		}
+	}
+	return vectors
+}
+
+// contentFeatures counts the normalized words and character trigrams of content
+func contentFeatures(content string) map[string]float64 {
+	features := make(map[string]float64)
+	for _, word := range contentWords(content) {
+		features["w:"+word]++
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			features["t:"+string(padded[i:i+3])]++
+		}
+	}
+	return features
+}
+
+// contentWords lowercases content, splits it into words, drops stop words and
+// strips common suffixes so "tests", "testing" and "tested" match
+func contentWords(content string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopWords[word] {
+			continue
+		}
+		for _, suffix := range []string{"ing", "ed", "es", "s"} {
+			if len(word)-len(suffix) >= 3 && strings.HasSuffix(word, suffix) {
+				word = strings.TrimSuffix(word, suffix)
+				break
+			}
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+func cosineSimilarity(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for feature, wa := range a {
+		normA += wa * wa
+		if wb, ok := b[feature]; ok {
+			dot += wa * wb
+		}
+	}
+	for _, wb := range b {
+		normB += wb * wb
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
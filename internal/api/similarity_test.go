@@ -0,0 +1,139 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/auth"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// newSimilarityBoard returns an item service over an active retrospective
+// RETRO-1 whose went_well column holds cards with contents, oldest first as
+// ITEM-1, ITEM-2 and so on, and the context of its member bob
+func newSimilarityBoard(t *testing.T, contents ...string) (*api.RetrospectiveItemService, context.Context) {
+	t.Helper()
+	stores := api.NewInMemoryStores()
+	must := func(err error, op string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+	}
+	must(stores.Teams.Create(&vstore.Team{TeamID: "TEAM-1", Name: "Team"}), "Teams.Create")
+	must(stores.Teams.PutMember(&vstore.TeamMember{TeamID: "TEAM-1", UserID: "bob", Role: vstore.TeamRoleMember}), "Teams.PutMember")
+	must(stores.Retrospectives.Create(&vstore.Retrospective{
+		RetrospectiveID: "RETRO-1", TeamID: "TEAM-1", SprintName: "Sprint 1", Status: vstore.RetrospectiveStatusActive,
+		TemplateColumns: []*vstore.TemplateColumn{{ColumnID: "went_well", Name: "What Went Well", SortOrder: 1}},
+	}), "Retrospectives.Create")
+
+	day := time.Date(2024, time.September, 27, 15, 0, 0, 0, time.UTC)
+	for i, content := range contents {
+		item := &vstore.RetrospectiveItem{ItemID: fmt.Sprintf("ITEM-%d", i+1), RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: content, Position: int32(i)}
+		must(stores.Items.Create(item), "Items.Create")
+		item.Created = day.Add(time.Duration(i) * time.Minute)
+		must(stores.Items.Update(item), "Items.Update")
+	}
+
+	svc := api.NewRetrospectiveItemService(stores.Items, stores.ItemGroups, stores.Comments, stores.Reactions,
+		stores.Votes, stores.Retrospectives, stores.Participants, stores.Teams)
+	return svc, auth.NewContext(context.Background(), &auth.Principal{UserID: "bob"})
+}
+
+// suggestMerges returns the item IDs and similarity of each suggestion
+func suggestMerges(t *testing.T, svc *api.RetrospectiveItemService, ctx context.Context, minSimilarity float64) ([][]string, []float64) {
+	t.Helper()
+	resp, err := svc.SuggestMerges(ctx, &pb.SuggestMergesRequest{RetrospectiveId: "RETRO-1", MinSimilarity: minSimilarity})
+	if err != nil {
+		t.Fatalf("SuggestMerges: %v", err)
+	}
+	var clusters [][]string
+	var similarities []float64
+	for _, s := range resp.Suggestions {
+		clusters = append(clusters, s.ItemIds)
+		similarities = append(similarities, s.Similarity)
+	}
+	return clusters, similarities
+}
+
+// TestDuplicatePairs pins what the default threshold takes for duplicates
+func TestDuplicatePairs(t *testing.T) {
+	for name, tt := range map[string]struct {
+		a, b      string
+		duplicate bool
+	}{
+		"reworded":              {"Flaky tests slowed down our deploys", "Deploys were slowed by flaky testing", true},
+		"typo":                  {"Standup meetings run too long", "Standup meetigns run too long", true},
+		"typo and dropped word": {"The deploy pipeline is too slow", "Deploy pipline is slow", true},
+		"case and punctuation":  {"CI is slow!", "slow ci", true},
+		"stop words only":       {"It was the", "and the", false},
+		"stop words and a card": {"It was the", "Flaky tests", false},
+		"one shared word":       {"Release notes were late", "Release went smoothly", false},
+		"unrelated":             {"Great pairing on the billing migration", "The coffee machine broke again", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, ctx := newSimilarityBoard(t, tt.a)
+			resp, err := svc.Create(ctx, &pb.CreateItemRequest{RetrospectiveId: "RETRO-1", ColumnId: "went_well", Content: tt.b})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			var wantIDs []string
+			var want [][]string
+			if tt.duplicate {
+				wantIDs = []string{"ITEM-1"}
+				want = [][]string{{"ITEM-1", resp.Item.ItemId}}
+			}
+			if !reflect.DeepEqual(resp.PossibleDuplicateIds, wantIDs) {
+				t.Errorf("possible duplicates = %v, want %v", resp.PossibleDuplicateIds, wantIDs)
+			}
+
+			// Suggestions agree with what Create flags
+			if clusters, _ := suggestMerges(t, svc, ctx, 0); !reflect.DeepEqual(clusters, want) {
+				t.Errorf("suggestions = %v, want %v", clusters, want)
+			}
+		})
+	}
+}
+
+// TestDuplicateClusters checks cards cluster through any similar member and
+// clusters come most similar first
+func TestDuplicateClusters(t *testing.T) {
+	svc, ctx := newSimilarityBoard(t,
+		"Standup meetings run too long",
+		"Flaky tests slowed down our deploys",
+		"Great pairing on the billing migration",
+		"Deploys were slowed by flaky testing",
+		"It was the",
+		"Standup meetigns run too long",
+		"flaky test deploys",
+		"The coffee machine broke again",
+		"Release notes were late",
+		"Release went smoothly",
+	)
+
+	clusters, similarities := suggestMerges(t, svc, ctx, 0)
+	want := [][]string{{"ITEM-2", "ITEM-4", "ITEM-7"}, {"ITEM-1", "ITEM-6"}}
+	if !reflect.DeepEqual(clusters, want) {
+		t.Fatalf("suggestions = %v, want %v", clusters, want)
+	}
+	for i, sim := range similarities {
+		if sim < 0.5 || sim > 1 {
+			t.Errorf("suggestion %d similarity = %v, want it between the default threshold and 1", i+1, sim)
+		}
+	}
+
+	// A higher threshold drops the typo, a lower one also takes in cards
+	// sharing a word
+	if clusters, _ := suggestMerges(t, svc, ctx, 0.95); len(clusters) != 0 {
+		t.Errorf("suggestions at 0.95 = %v, want none", clusters)
+	}
+	clusters, _ = suggestMerges(t, svc, ctx, 0.2)
+	if len(clusters) != 3 || !reflect.DeepEqual(clusters[2], []string{"ITEM-9", "ITEM-10"}) {
+		t.Errorf("suggestions at 0.2 = %v, want the release cards added", clusters)
+	}
+}
@@ -1,12 +1,187 @@
 package api
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/vendasta/retrospective/internal/vstore"
 )
 
+// RetrospectiveStore persists retrospectives
+type RetrospectiveStore interface {
+	Create(retro *vstore.Retrospective) error
+	Get(id string) (*vstore.Retrospective, error)
+	Update(retro *vstore.Retrospective) error
+	Delete(id string) error
+	List(teamID string, statuses []vstore.RetrospectiveStatus, cursor string, pageSize int) ([]*vstore.Retrospective, string, bool, error)
+}
+
+// ItemStore persists retrospective items. VoteCount is owned by the vote
+// store: Update keeps the stored count so edits never overwrite concurrent votes.
+// ListByRetrospective returns items in position order (see sortItems).
+type ItemStore interface {
+	Create(item *vstore.RetrospectiveItem) error
+	Get(id string) (*vstore.RetrospectiveItem, error)
+	Update(item *vstore.RetrospectiveItem) error
+	Delete(id string) error
+	ListByRetrospective(retroID string, columnID string, sortByVotes bool) ([]*vstore.RetrospectiveItem, error)
+	IncrementVoteCount(itemID string) error
+	DecrementVoteCount(itemID string) error
+}
+
+// ItemGroupStore persists item groups. Like ItemStore, Update keeps the
+// stored VoteCount, which the vote store owns.
+type ItemGroupStore interface {
+	Create(group *vstore.ItemGroup) error
+	Get(id string) (*vstore.ItemGroup, error)
+	Update(group *vstore.ItemGroup) error
+	Delete(id string) error
+	ListByRetrospective(retroID string) ([]*vstore.ItemGroup, error)
+}
+
+// CommentStore persists comments on items
+type CommentStore interface {
+	Create(comment *vstore.Comment) error
+	Get(id string) (*vstore.Comment, error)
+	Update(comment *vstore.Comment) error
+	Delete(id string) error
+	ListByItem(itemID string) ([]*vstore.Comment, error)
+	ListByRetrospective(retroID string) ([]*vstore.Comment, error)
+}
+
+// ReactionStore persists emoji reactions on items
+type ReactionStore interface {
+	// Add fails with ErrAlreadyExists if the user already reacted to the item with the emoji
+	Add(reaction *vstore.Reaction) error
+	// Remove fails with ErrNotFound if the user has no such reaction on the item
+	Remove(retroID, itemID, userID, emoji string) error
+	ListByItem(retroID, itemID string) ([]*vstore.Reaction, error)
+	ListByRetrospective(retroID string) ([]*vstore.Reaction, error)
+}
+
+// VoteStore persists votes. A vote with GroupVote set counts towards the
+// VoteCount of the group its ItemID names rather than an item's.
+type VoteStore interface {
+	// Cast checks limits, records vote (replacing the user's earlier vote on the
+	// item in modes that allow only one) and adds its weight to the item's
+	// VoteCount as one unit, returning the item's new vote count. It fails with
+	// ErrAlreadyExists or ErrVoteLimitExceeded when the vote would break limits.
+	Cast(vote *vstore.Vote, limits VoteLimits) (int32, error)
+	// Retract removes one of userID's votes on itemID and subtracts its weight
+	// from the item's VoteCount as one unit, returning the item's new vote count
+	Retract(retroID, itemID, userID string) (int32, error)
+	// MoveVotes re-points every vote on fromItemID at toItemID and moves their
+	// weight between the items' VoteCounts as one unit, returning toItemID's
+	// new vote count. Votes keep their values; callers drop any that would break limits first.
+	MoveVotes(retroID, fromItemID, toItemID string) (int32, error)
+	// Create and Delete write vote records only; they leave vote counts alone
+	Create(vote *vstore.Vote) error
+	Delete(voteID string) error
+	GetByUserAndItem(retroID, itemID, userID string) (*vstore.Vote, error)
+	CountByUser(retroID, userID string) (int, error)
+	ListByUser(retroID, userID string) ([]*vstore.Vote, error)
+	ListByItem(retroID, itemID string) ([]*vstore.Vote, error)
+}
+
+// errNoVote is returned by VoteStore.Retract when the user has no vote on the item
+var errNoVote = fmt.Errorf("%w: you have not voted for this item", ErrNotFound)
+
+// errReacted and errNoReaction are returned by ReactionStore.Add and Remove
+var (
+	errReacted    = fmt.Errorf("%w: you already reacted with this emoji", ErrAlreadyExists)
+	errNoReaction = fmt.Errorf("%w: you have not reacted with this emoji", ErrNotFound)
+)
+
+// ActionItemStore persists action items
+type ActionItemStore interface {
+	Create(item *vstore.ActionItem) error
+	Get(id string) (*vstore.ActionItem, error)
+	Update(item *vstore.ActionItem) error
+	Delete(id string) error
+	ListByRetrospective(retroID string) ([]*vstore.ActionItem, error)
+	ListByTeam(teamID string, includeCompleted bool) ([]*vstore.ActionItem, error)
+	ListByAssignee(teamID, assigneeID string) ([]*vstore.ActionItem, error)
+}
+
+// ParticipantStore tracks presence in retrospective sessions
+type ParticipantStore interface {
+	Join(participant *vstore.Participant) error
+	Leave(retroID, userID string) error
+	Heartbeat(retroID, userID string) error
+	Get(retroID, userID string) (*vstore.Participant, error)
+	ListByRetrospective(retroID string) ([]*vstore.Participant, error)
+}
+
+// TeamStore persists teams and their members
+type TeamStore interface {
+	Create(team *vstore.Team) error
+	Get(id string) (*vstore.Team, error)
+	Update(team *vstore.Team) error
+	Delete(id string) error
+	ListByMember(userID string) ([]*vstore.Team, error)
+	PutMember(member *vstore.TeamMember) error
+	GetMember(teamID, userID string) (*vstore.TeamMember, error)
+	RemoveMember(teamID, userID string) error
+	ListMembers(teamID string) ([]*vstore.TeamMember, error)
+}
+
+// PhaseTimerStore persists the countdown timers of retrospective phases
+type PhaseTimerStore interface {
+	Put(timer *vstore.PhaseTimer) error
+	Get(retroID string) (*vstore.PhaseTimer, error)
+	Delete(retroID string) error
+	// List returns every stored timer so they can be rescheduled after a restart
+	List() ([]*vstore.PhaseTimer, error)
+}
+
+// Stores bundles one implementation of each store used by the services
+type Stores struct {
+	Retrospectives RetrospectiveStore
+	Items          ItemStore
+	Votes          VoteStore
+	ActionItems    ActionItemStore
+	Participants   ParticipantStore
+	Teams          TeamStore
+	PhaseTimers    PhaseTimerStore
+	ItemGroups     ItemGroupStore
+	Comments       CommentStore
+	Reactions      ReactionStore
+}
+
+// NewInMemoryStores creates a fresh set of in-memory stores
+func NewInMemoryStores() Stores {
+	items := NewInMemoryItemStore()
+	groups := NewInMemoryItemGroupStore()
+	return Stores{
+		Retrospectives: NewInMemoryRetrospectiveStore(),
+		Items:          items,
+		Votes:          NewInMemoryVoteStore(items, groups),
+		ActionItems:    NewInMemoryActionItemStore(),
+		Participants:   NewInMemoryParticipantStore(),
+		Teams:          NewInMemoryTeamStore(),
+		PhaseTimers:    NewInMemoryPhaseTimerStore(),
+		ItemGroups:     groups,
+		Comments:       NewInMemoryCommentStore(),
+		Reactions:      NewInMemoryReactionStore(),
+	}
+}
+
+var (
+	_ RetrospectiveStore = (*InMemoryRetrospectiveStore)(nil)
+	_ ItemStore          = (*InMemoryItemStore)(nil)
+	_ VoteStore          = (*InMemoryVoteStore)(nil)
+	_ ActionItemStore    = (*InMemoryActionItemStore)(nil)
+	_ ParticipantStore   = (*InMemoryParticipantStore)(nil)
+	_ TeamStore          = (*InMemoryTeamStore)(nil)
+	_ PhaseTimerStore    = (*InMemoryPhaseTimerStore)(nil)
+	_ ItemGroupStore     = (*InMemoryItemGroupStore)(nil)
+	_ CommentStore       = (*InMemoryCommentStore)(nil)
+	_ ReactionStore      = (*InMemoryReactionStore)(nil)
+)
+
 // InMemoryRetrospectiveStore provides in-memory storage for retrospectives
 // In production, this would be backed by vstore
 type InMemoryRetrospectiveStore struct {
@@ -77,16 +252,32 @@ func (s *InMemoryRetrospectiveStore) List(teamID string, statuses []vstore.Retro
 		results = append(results, retro)
 	}
 
-	// Simple pagination (in production, use cursor-based pagination)
+	// The cursor is the offset of the page, newest first
 	if pageSize <= 0 {
 		pageSize = 20
 	}
-	hasMore := len(results) > pageSize
-	if hasMore {
-		results = results[:pageSize]
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			return nil, "", false, fmt.Errorf("%w: invalid cursor", ErrInvalidArgument)
+		}
 	}
-
-	return results, "", hasMore, nil
+	sort.SliceStable(results, func(i, j int) bool {
+		if !results[i].Created.Equal(results[j].Created) {
+			return results[i].Created.After(results[j].Created)
+		}
+		return results[i].RetrospectiveID < results[j].RetrospectiveID
+	})
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if len(results) > pageSize {
+		return results[:pageSize], strconv.Itoa(offset + pageSize), true, nil
+	}
+	return results, "", false, nil
 }
 
 // InMemoryItemStore provides in-memory storage for retrospective items
@@ -106,15 +297,18 @@ func (s *InMemoryItemStore) Create(item *vstore.RetrospectiveItem) error {
 	defer s.mu.Unlock()
 	item.Created = time.Now()
 	item.Updated = time.Now()
-	s.items[item.ItemID] = item
+	stored := *item
+	s.items[item.ItemID] = &stored
 	return nil
 }
 
+// Items are copied in and out so vote counts only change under the store lock
 func (s *InMemoryItemStore) Get(id string) (*vstore.RetrospectiveItem, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if item, ok := s.items[id]; ok {
-		return item, nil
+		copied := *item
+		return &copied, nil
 	}
 	return nil, ErrNotFound
 }
@@ -122,8 +316,12 @@ func (s *InMemoryItemStore) Get(id string) (*vstore.RetrospectiveItem, error) {
 func (s *InMemoryItemStore) Update(item *vstore.RetrospectiveItem) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if existing, ok := s.items[item.ItemID]; ok {
+		item.VoteCount = existing.VoteCount
+	}
 	item.Updated = time.Now()
-	s.items[item.ItemID] = item
+	stored := *item
+	s.items[item.ItemID] = &stored
 	return nil
 }
 
@@ -146,20 +344,11 @@ func (s *InMemoryItemStore) ListByRetrospective(retroID string, columnID string,
 		if columnID != "" && item.ColumnID != columnID {
 			continue
 		}
-		results = append(results, item)
-	}
-
-	// Sort by votes if requested (simple bubble sort for MVP)
-	if sortByVotes {
-		for i := 0; i < len(results)-1; i++ {
-			for j := 0; j < len(results)-i-1; j++ {
-				if results[j].VoteCount < results[j+1].VoteCount {
-					results[j], results[j+1] = results[j+1], results[j]
-				}
-			}
-		}
+		copied := *item
+		results = append(results, &copied)
 	}
 
+	sortItems(results, sortByVotes)
 	return results, nil
 }
 
@@ -189,14 +378,137 @@ func (s *InMemoryItemStore) DecrementVoteCount(itemID string) error {
 
 // InMemoryVoteStore provides in-memory storage for votes
 type InMemoryVoteStore struct {
-	mu    sync.RWMutex
-	votes map[string]*vstore.Vote // key: vote_id
+	mu     sync.RWMutex
+	votes  map[string]*vstore.Vote // key: vote_id
+	items  *InMemoryItemStore      // vote counts are kept in step with votes
+	groups *InMemoryItemGroupStore
 }
 
-func NewInMemoryVoteStore() *InMemoryVoteStore {
+func NewInMemoryVoteStore(items *InMemoryItemStore, groups *InMemoryItemGroupStore) *InMemoryVoteStore {
 	return &InMemoryVoteStore{
-		votes: make(map[string]*vstore.Vote),
+		votes:  make(map[string]*vstore.Vote),
+		items:  items,
+		groups: groups,
+	}
+}
+
+// Cast holds the vote, item and group locks (always in that order) for the
+// whole check-and-write so concurrent votes cannot pass the limits together
+func (s *InMemoryVoteStore) Cast(vote *vstore.Vote, limits VoteLimits) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items.mu.Lock()
+	defer s.items.mu.Unlock()
+	s.groups.mu.Lock()
+	defer s.groups.mu.Unlock()
+
+	count, updated, ok := s.target(vote.RetrospectiveID, vote.ItemID, vote.GroupVote)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if _, ok := s.votes[vote.VoteID]; ok {
+		return 0, fmt.Errorf("%w: vote %s", ErrAlreadyExists, vote.VoteID)
+	}
+	var userVotes []*vstore.Vote
+	for _, v := range s.votes {
+		if v.RetrospectiveID == vote.RetrospectiveID && v.UserID == vote.UserID {
+			userVotes = append(userVotes, v)
+		}
+	}
+	replaced, err := limits.check(userVotes, vote)
+	if err != nil {
+		return 0, err
+	}
+
+	if replaced != nil {
+		delete(s.votes, replaced.VoteID)
+		*count -= voteWeight(replaced)
+	}
+	vote.Created = time.Now()
+	s.votes[vote.VoteID] = vote
+	*count += voteWeight(vote)
+	*updated = time.Now()
+	return *count, nil
+}
+
+func (s *InMemoryVoteStore) Retract(retroID, itemID, userID string) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items.mu.Lock()
+	defer s.items.mu.Unlock()
+	s.groups.mu.Lock()
+	defer s.groups.mu.Unlock()
+
+	var vote *vstore.Vote
+	for _, v := range s.votes {
+		if v.RetrospectiveID == retroID && v.ItemID == itemID && v.UserID == userID {
+			vote = v
+			break
+		}
+	}
+	if vote == nil {
+		return 0, errNoVote
+	}
+	delete(s.votes, vote.VoteID)
+
+	count, updated, ok := s.target(retroID, itemID, vote.GroupVote)
+	if !ok {
+		return 0, nil
+	}
+	*count -= voteWeight(vote)
+	if *count < 0 {
+		*count = 0
+	}
+	*updated = time.Now()
+	return *count, nil
+}
+
+func (s *InMemoryVoteStore) MoveVotes(retroID, fromItemID, toItemID string) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items.mu.Lock()
+	defer s.items.mu.Unlock()
+
+	from, ok := s.items.items[fromItemID]
+	if !ok || from.RetrospectiveID != retroID {
+		return 0, ErrNotFound
+	}
+	to, ok := s.items.items[toItemID]
+	if !ok || to.RetrospectiveID != retroID {
+		return 0, ErrNotFound
+	}
+	for _, v := range s.votes {
+		if v.RetrospectiveID != retroID || v.ItemID != fromItemID || v.GroupVote {
+			continue
+		}
+		v.ItemID = toItemID
+		v.ColumnID = to.ColumnID
+		from.VoteCount -= voteWeight(v)
+		to.VoteCount += voteWeight(v)
+	}
+	if from.VoteCount < 0 {
+		from.VoteCount = 0
 	}
+	from.Updated = time.Now()
+	to.Updated = time.Now()
+	return to.VoteCount, nil
+}
+
+// target returns the vote count and update time of the item or group a vote
+// is for. The item and group locks must be held.
+func (s *InMemoryVoteStore) target(retroID, id string, group bool) (count *int32, updated *time.Time, ok bool) {
+	if group {
+		g, ok := s.groups.groups[id]
+		if !ok || g.RetrospectiveID != retroID {
+			return nil, nil, false
+		}
+		return &g.VoteCount, &g.Updated, true
+	}
+	item, ok := s.items.items[id]
+	if !ok || item.RetrospectiveID != retroID {
+		return nil, nil, false
+	}
+	return &item.VoteCount, &item.Updated, true
 }
 
 func (s *InMemoryVoteStore) Create(vote *vstore.Vote) error {
@@ -334,6 +646,18 @@ func (s *InMemoryActionItemStore) ListByTeam(teamID string, includeCompleted boo
 	return results, nil
 }
 
+func (s *InMemoryActionItemStore) ListByAssignee(teamID, assigneeID string) ([]*vstore.ActionItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.ActionItem
+	for _, item := range s.actionItems {
+		if item.TeamID == teamID && item.AssigneeID == assigneeID {
+			results = append(results, item)
+		}
+	}
+	return results, nil
+}
+
 // InMemoryParticipantStore provides in-memory storage for participants
 type InMemoryParticipantStore struct {
 	mu           sync.RWMutex
@@ -400,3 +724,366 @@ func (s *InMemoryParticipantStore) ListByRetrospective(retroID string) ([]*vstor
 	}
 	return results, nil
 }
+
+// InMemoryTeamStore provides in-memory storage for teams and members
+type InMemoryTeamStore struct {
+	mu      sync.RWMutex
+	teams   map[string]*vstore.Team       // key: team_id
+	members map[string]*vstore.TeamMember // key: team_id:user_id
+}
+
+func NewInMemoryTeamStore() *InMemoryTeamStore {
+	return &InMemoryTeamStore{
+		teams:   make(map[string]*vstore.Team),
+		members: make(map[string]*vstore.TeamMember),
+	}
+}
+
+func (s *InMemoryTeamStore) Create(team *vstore.Team) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	team.Created = time.Now()
+	team.Updated = time.Now()
+	s.teams[team.TeamID] = team
+	return nil
+}
+
+func (s *InMemoryTeamStore) Get(id string) (*vstore.Team, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if team, ok := s.teams[id]; ok {
+		return team, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *InMemoryTeamStore) Update(team *vstore.Team) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	team.Updated = time.Now()
+	s.teams[team.TeamID] = team
+	return nil
+}
+
+func (s *InMemoryTeamStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.teams, id)
+	for key, m := range s.members {
+		if m.TeamID == id {
+			delete(s.members, key)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryTeamStore) ListByMember(userID string) ([]*vstore.Team, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.Team
+	for _, m := range s.members {
+		if m.UserID != userID {
+			continue
+		}
+		if team, ok := s.teams[m.TeamID]; ok {
+			results = append(results, team)
+		}
+	}
+	return results, nil
+}
+
+func (s *InMemoryTeamStore) PutMember(member *vstore.TeamMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := member.TeamID + ":" + member.UserID
+	if existing, ok := s.members[key]; ok {
+		member.Created = existing.Created
+	} else {
+		member.Created = time.Now()
+	}
+	member.Updated = time.Now()
+	s.members[key] = member
+	return nil
+}
+
+func (s *InMemoryTeamStore) GetMember(teamID, userID string) (*vstore.TeamMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if m, ok := s.members[teamID+":"+userID]; ok {
+		return m, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *InMemoryTeamStore) RemoveMember(teamID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members, teamID+":"+userID)
+	return nil
+}
+
+func (s *InMemoryTeamStore) ListMembers(teamID string) ([]*vstore.TeamMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.TeamMember
+	for _, m := range s.members {
+		if m.TeamID == teamID {
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}
+
+// InMemoryPhaseTimerStore provides in-memory storage for phase timers
+type InMemoryPhaseTimerStore struct {
+	mu     sync.RWMutex
+	timers map[string]*vstore.PhaseTimer // key: retrospective_id
+}
+
+func NewInMemoryPhaseTimerStore() *InMemoryPhaseTimerStore {
+	return &InMemoryPhaseTimerStore{
+		timers: make(map[string]*vstore.PhaseTimer),
+	}
+}
+
+func (s *InMemoryPhaseTimerStore) Put(timer *vstore.PhaseTimer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.timers[timer.RetrospectiveID]; ok {
+		timer.Created = existing.Created
+	} else {
+		timer.Created = time.Now()
+	}
+	timer.Updated = time.Now()
+	stored := *timer
+	s.timers[timer.RetrospectiveID] = &stored
+	return nil
+}
+
+func (s *InMemoryPhaseTimerStore) Get(retroID string) (*vstore.PhaseTimer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if timer, ok := s.timers[retroID]; ok {
+		result := *timer
+		return &result, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *InMemoryPhaseTimerStore) Delete(retroID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.timers, retroID)
+	return nil
+}
+
+func (s *InMemoryPhaseTimerStore) List() ([]*vstore.PhaseTimer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.PhaseTimer
+	for _, timer := range s.timers {
+		result := *timer
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// InMemoryItemGroupStore provides in-memory storage for item groups
+type InMemoryItemGroupStore struct {
+	mu     sync.RWMutex
+	groups map[string]*vstore.ItemGroup // key: group_id
+}
+
+func NewInMemoryItemGroupStore() *InMemoryItemGroupStore {
+	return &InMemoryItemGroupStore{
+		groups: make(map[string]*vstore.ItemGroup),
+	}
+}
+
+func (s *InMemoryItemGroupStore) Create(group *vstore.ItemGroup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group.Created = time.Now()
+	group.Updated = time.Now()
+	stored := *group
+	s.groups[group.GroupID] = &stored
+	return nil
+}
+
+func (s *InMemoryItemGroupStore) Get(id string) (*vstore.ItemGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if group, ok := s.groups[id]; ok {
+		copied := *group
+		return &copied, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *InMemoryItemGroupStore) Update(group *vstore.ItemGroup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.groups[group.GroupID]; ok {
+		group.VoteCount = existing.VoteCount
+	}
+	group.Updated = time.Now()
+	stored := *group
+	s.groups[group.GroupID] = &stored
+	return nil
+}
+
+func (s *InMemoryItemGroupStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups, id)
+	return nil
+}
+
+func (s *InMemoryItemGroupStore) ListByRetrospective(retroID string) ([]*vstore.ItemGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.ItemGroup
+	for _, group := range s.groups {
+		if group.RetrospectiveID == retroID {
+			copied := *group
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+// InMemoryCommentStore provides in-memory storage for comments
+type InMemoryCommentStore struct {
+	mu       sync.RWMutex
+	comments map[string]*vstore.Comment // key: comment_id
+}
+
+func NewInMemoryCommentStore() *InMemoryCommentStore {
+	return &InMemoryCommentStore{
+		comments: make(map[string]*vstore.Comment),
+	}
+}
+
+func (s *InMemoryCommentStore) Create(comment *vstore.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comment.Created = time.Now()
+	comment.Updated = time.Now()
+	stored := *comment
+	s.comments[comment.CommentID] = &stored
+	return nil
+}
+
+func (s *InMemoryCommentStore) Get(id string) (*vstore.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if comment, ok := s.comments[id]; ok {
+		copied := *comment
+		return &copied, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *InMemoryCommentStore) Update(comment *vstore.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comment.Updated = time.Now()
+	stored := *comment
+	s.comments[comment.CommentID] = &stored
+	return nil
+}
+
+func (s *InMemoryCommentStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.comments, id)
+	return nil
+}
+
+func (s *InMemoryCommentStore) ListByItem(itemID string) ([]*vstore.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.Comment
+	for _, comment := range s.comments {
+		if comment.ItemID == itemID {
+			copied := *comment
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+func (s *InMemoryCommentStore) ListByRetrospective(retroID string) ([]*vstore.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.Comment
+	for _, comment := range s.comments {
+		if comment.RetrospectiveID == retroID {
+			copied := *comment
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+// InMemoryReactionStore provides in-memory storage for reactions
+type InMemoryReactionStore struct {
+	mu        sync.RWMutex
+	reactions map[string]*vstore.Reaction // key: retro_id:item_id:user_id:emoji
+}
+
+func NewInMemoryReactionStore() *InMemoryReactionStore {
+	return &InMemoryReactionStore{
+		reactions: make(map[string]*vstore.Reaction),
+	}
+}
+
+func (s *InMemoryReactionStore) Add(reaction *vstore.Reaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := reaction.RetrospectiveID + ":" + reaction.ItemID + ":" + reaction.UserID + ":" + reaction.Emoji
+	if _, ok := s.reactions[key]; ok {
+		return errReacted
+	}
+	reaction.Created = time.Now()
+	stored := *reaction
+	s.reactions[key] = &stored
+	return nil
+}
+
+func (s *InMemoryReactionStore) Remove(retroID, itemID, userID, emoji string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := retroID + ":" + itemID + ":" + userID + ":" + emoji
+	if _, ok := s.reactions[key]; !ok {
+		return errNoReaction
+	}
+	delete(s.reactions, key)
+	return nil
+}
+
+func (s *InMemoryReactionStore) ListByItem(retroID, itemID string) ([]*vstore.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.Reaction
+	for _, reaction := range s.reactions {
+		if reaction.RetrospectiveID == retroID && reaction.ItemID == itemID {
+			copied := *reaction
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+func (s *InMemoryReactionStore) ListByRetrospective(retroID string) ([]*vstore.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*vstore.Reaction
+	for _, reaction := range s.reactions {
+		if reaction.RetrospectiveID == retroID {
+			copied := *reaction
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
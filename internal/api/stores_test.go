@@ -0,0 +1,35 @@
+package api_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/api/storetest"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("InMemory", func(t *testing.T) {
+		storetest.Run(t, api.NewInMemoryStores)
+	})
+	t.Run("VStore", func(t *testing.T) {
+		storetest.Run(t, func() api.Stores {
+			return api.NewVStoreStores(vstore.NewLocalClient())
+		})
+	})
+	t.Run("Bolt", func(t *testing.T) {
+		dir := t.TempDir()
+		files := 0
+		storetest.Run(t, func() api.Stores {
+			files++
+			db, err := api.OpenBoltDB(filepath.Join(dir, fmt.Sprintf("stores-%d.db", files)))
+			if err != nil {
+				t.Fatalf("OpenBoltDB: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			return api.NewBoltStores(db)
+		})
+	})
+}
@@ -0,0 +1,1096 @@
+// Package storetest provides a conformance suite for api store implementations.
+//
+// Every backend must behave like the in-memory stores. A backend's tests call
+// Run with a factory returning a fresh, empty set of stores:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func() api.Stores {
+//			return api.NewVStoreStores(vstore.NewLocalClient())
+//		})
+//	}
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// Run executes the conformance suite against stores created by newStores.
+// newStores is called once per subtest and must return empty stores.
+func Run(t *testing.T, newStores func() api.Stores) {
+	t.Run("RetrospectiveStore", func(t *testing.T) { testRetrospectiveStore(t, newStores().Retrospectives) })
+	t.Run("ItemStore", func(t *testing.T) { testItemStore(t, newStores().Items) })
+	t.Run("VoteStore", func(t *testing.T) { testVoteStore(t, newStores().Votes) })
+	t.Run("VoteCasting", func(t *testing.T) { testVoteCasting(t, newStores()) })
+	t.Run("VotingModes", func(t *testing.T) { testVotingModes(t, newStores()) })
+	t.Run("MoveVotes", func(t *testing.T) { testMoveVotes(t, newStores()) })
+	t.Run("VoteCastingConcurrency", func(t *testing.T) { testVoteCastingConcurrency(t, newStores()) })
+	t.Run("ActionItemStore", func(t *testing.T) { testActionItemStore(t, newStores().ActionItems) })
+	t.Run("ParticipantStore", func(t *testing.T) { testParticipantStore(t, newStores().Participants) })
+	t.Run("TeamStore", func(t *testing.T) { testTeamStore(t, newStores().Teams) })
+	t.Run("PhaseTimerStore", func(t *testing.T) { testPhaseTimerStore(t, newStores().PhaseTimers) })
+	t.Run("ItemGroupStore", func(t *testing.T) { testItemGroupStore(t, newStores().ItemGroups) })
+	t.Run("GroupVoting", func(t *testing.T) { testGroupVoting(t, newStores()) })
+	t.Run("CommentStore", func(t *testing.T) { testCommentStore(t, newStores().Comments) })
+	t.Run("ReactionStore", func(t *testing.T) { testReactionStore(t, newStores().Reactions) })
+	t.Run("ItemOrder", func(t *testing.T) { testItemOrder(t, newStores().Items) })
+}
+
+func testRetrospectiveStore(t *testing.T, store api.RetrospectiveStore) {
+	newRetro := func(id, teamID string, status vstore.RetrospectiveStatus) *vstore.Retrospective {
+		return &vstore.Retrospective{
+			RetrospectiveID: id,
+			TeamID:          teamID,
+			SprintName:      "Sprint " + id,
+			Status:          status,
+			VotingConfig:    &vstore.VotingConfig{MaxVotesPerUser: 5},
+			TemplateColumns: []*vstore.TemplateColumn{
+				{ColumnID: "went_well", Name: "What Went Well", SortOrder: 1, Color: "#22c55e"},
+			},
+		}
+	}
+
+	retro := newRetro("RETRO-1", "team-a", vstore.RetrospectiveStatusDraft)
+	mustNoErr(t, store.Create(retro), "Create")
+	if retro.Created.IsZero() || retro.Updated.IsZero() {
+		t.Errorf("Create did not set Created/Updated")
+	}
+
+	got, err := store.Get("RETRO-1")
+	mustNoErr(t, err, "Get")
+	if got.SprintName != "Sprint RETRO-1" || got.TeamID != "team-a" {
+		t.Errorf("Get returned %+v", got)
+	}
+	if got.VotingConfig == nil || got.VotingConfig.MaxVotesPerUser != 5 {
+		t.Errorf("Get lost VotingConfig: %+v", got.VotingConfig)
+	}
+	if len(got.TemplateColumns) != 1 || got.TemplateColumns[0].Name != "What Went Well" {
+		t.Errorf("Get lost TemplateColumns: %+v", got.TemplateColumns)
+	}
+
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got.SprintName = "Renamed"
+	got.Status = vstore.RetrospectiveStatusActive
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("RETRO-1")
+	mustNoErr(t, err, "Get after Update")
+	if got.SprintName != "Renamed" || got.Status != vstore.RetrospectiveStatusActive {
+		t.Errorf("Update not persisted: %+v", got)
+	}
+
+	mustNoErr(t, store.Create(newRetro("RETRO-2", "team-a", vstore.RetrospectiveStatusCompleted)), "Create")
+	mustNoErr(t, store.Create(newRetro("RETRO-3", "team-a", vstore.RetrospectiveStatusVoting)), "Create")
+	mustNoErr(t, store.Create(newRetro("RETRO-4", "team-b", vstore.RetrospectiveStatusActive)), "Create")
+
+	retros, _, hasMore, err := store.List("team-a", nil, "", 20)
+	mustNoErr(t, err, "List by team")
+	if len(retros) != 3 || hasMore {
+		t.Errorf("List(team-a) = %d retros, hasMore %v; want 3, false", len(retros), hasMore)
+	}
+	for _, r := range retros {
+		if r.TeamID != "team-a" {
+			t.Errorf("List(team-a) returned retro of team %s", r.TeamID)
+		}
+	}
+
+	retros, _, _, err = store.List("team-a", []vstore.RetrospectiveStatus{vstore.RetrospectiveStatusActive, vstore.RetrospectiveStatusVoting}, "", 20)
+	mustNoErr(t, err, "List by status")
+	if ids := retroIDs(retros); !sameSet(ids, []string{"RETRO-1", "RETRO-3"}) {
+		t.Errorf("List(team-a, active|voting) = %v", ids)
+	}
+
+	retros, cursor, hasMore, err := store.List("", nil, "", 2)
+	mustNoErr(t, err, "List paged")
+	if len(retros) != 2 || !hasMore {
+		t.Errorf("List(pageSize 2) = %d retros, hasMore %v; want 2, true", len(retros), hasMore)
+	}
+	rest, _, hasMore, err := store.List("", nil, cursor, 2)
+	mustNoErr(t, err, "List next page")
+	if ids := retroIDs(append(retros, rest...)); !sameSet(ids, []string{"RETRO-1", "RETRO-2", "RETRO-3", "RETRO-4"}) || hasMore {
+		t.Errorf("List over two pages = %v, hasMore %v; want every retro once", ids, hasMore)
+	}
+
+	mustNoErr(t, store.Delete("RETRO-1"), "Delete")
+	if _, err := store.Get("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, store.Delete("missing"), "Delete(missing)")
+}
+
+func testItemStore(t *testing.T, store api.ItemStore) {
+	for _, item := range []*vstore.RetrospectiveItem{
+		{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Shipped on time", CreatedBy: "u1"},
+		{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Pairing", CreatedBy: "u2"},
+		{ItemID: "ITEM-3", RetrospectiveID: "RETRO-1", ColumnID: "to_improve", Content: "Flaky CI", CreatedBy: "u1"},
+		{ItemID: "ITEM-4", RetrospectiveID: "RETRO-2", ColumnID: "went_well", Content: "Other retro", CreatedBy: "u1"},
+	} {
+		mustNoErr(t, store.Create(item), "Create")
+		if item.Created.IsZero() {
+			t.Errorf("Create did not set Created")
+		}
+	}
+
+	got, err := store.Get("ITEM-1")
+	mustNoErr(t, err, "Get")
+	if got.Content != "Shipped on time" || got.RetrospectiveID != "RETRO-1" {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got.Content = "Shipped early"
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("ITEM-1")
+	mustNoErr(t, err, "Get after Update")
+	if got.Content != "Shipped early" {
+		t.Errorf("Update not persisted: %+v", got)
+	}
+
+	items, err := store.ListByRetrospective("RETRO-1", "", false)
+	mustNoErr(t, err, "ListByRetrospective")
+	if ids := itemIDs(items); !sameSet(ids, []string{"ITEM-1", "ITEM-2", "ITEM-3"}) {
+		t.Errorf("ListByRetrospective(RETRO-1) = %v", ids)
+	}
+	items, err = store.ListByRetrospective("RETRO-1", "went_well", false)
+	mustNoErr(t, err, "ListByRetrospective by column")
+	if ids := itemIDs(items); !sameSet(ids, []string{"ITEM-1", "ITEM-2"}) {
+		t.Errorf("ListByRetrospective(RETRO-1, went_well) = %v", ids)
+	}
+
+	mustNoErr(t, store.IncrementVoteCount("ITEM-2"), "IncrementVoteCount")
+	mustNoErr(t, store.IncrementVoteCount("ITEM-2"), "IncrementVoteCount")
+	mustNoErr(t, store.IncrementVoteCount("ITEM-3"), "IncrementVoteCount")
+	items, err = store.ListByRetrospective("RETRO-1", "", true)
+	mustNoErr(t, err, "ListByRetrospective sorted")
+	if ids := itemIDs(items); len(ids) != 3 || ids[0] != "ITEM-2" || ids[1] != "ITEM-3" {
+		t.Errorf("ListByRetrospective sorted by votes = %v, want ITEM-2, ITEM-3 first", ids)
+	}
+
+	mustNoErr(t, store.DecrementVoteCount("ITEM-3"), "DecrementVoteCount")
+	mustNoErr(t, store.DecrementVoteCount("ITEM-3"), "DecrementVoteCount below zero")
+	got, err = store.Get("ITEM-3")
+	mustNoErr(t, err, "Get after DecrementVoteCount")
+	if got.VoteCount != 0 {
+		t.Errorf("VoteCount = %d after decrementing past zero, want 0", got.VoteCount)
+	}
+	if err := store.IncrementVoteCount("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("IncrementVoteCount(missing) error = %v, want ErrNotFound", err)
+	}
+
+	mustNoErr(t, store.Delete("ITEM-1"), "Delete")
+	if _, err := store.Get("ITEM-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testVoteStore(t *testing.T, store api.VoteStore) {
+	for _, vote := range []*vstore.Vote{
+		{VoteID: "VOTE-1", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u1"},
+		{VoteID: "VOTE-2", RetrospectiveID: "RETRO-1", ItemID: "ITEM-2", UserID: "u1"},
+		{VoteID: "VOTE-3", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u2"},
+		{VoteID: "VOTE-4", RetrospectiveID: "RETRO-2", ItemID: "ITEM-9", UserID: "u1"},
+	} {
+		mustNoErr(t, store.Create(vote), "Create")
+	}
+
+	vote, err := store.GetByUserAndItem("RETRO-1", "ITEM-1", "u2")
+	mustNoErr(t, err, "GetByUserAndItem")
+	if vote.VoteID != "VOTE-3" {
+		t.Errorf("GetByUserAndItem returned %s, want VOTE-3", vote.VoteID)
+	}
+	if _, err := store.GetByUserAndItem("RETRO-1", "ITEM-2", "u2"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("GetByUserAndItem(missing) error = %v, want ErrNotFound", err)
+	}
+
+	count, err := store.CountByUser("RETRO-1", "u1")
+	mustNoErr(t, err, "CountByUser")
+	if count != 2 {
+		t.Errorf("CountByUser(RETRO-1, u1) = %d, want 2", count)
+	}
+
+	votes, err := store.ListByUser("RETRO-1", "u1")
+	mustNoErr(t, err, "ListByUser")
+	if ids := voteIDs(votes); !sameSet(ids, []string{"VOTE-1", "VOTE-2"}) {
+		t.Errorf("ListByUser(RETRO-1, u1) = %v", ids)
+	}
+	votes, err = store.ListByItem("RETRO-1", "ITEM-1")
+	mustNoErr(t, err, "ListByItem")
+	if ids := voteIDs(votes); !sameSet(ids, []string{"VOTE-1", "VOTE-3"}) {
+		t.Errorf("ListByItem(RETRO-1, ITEM-1) = %v", ids)
+	}
+
+	mustNoErr(t, store.Delete("VOTE-1"), "Delete")
+	count, err = store.CountByUser("RETRO-1", "u1")
+	mustNoErr(t, err, "CountByUser after Delete")
+	if count != 1 {
+		t.Errorf("CountByUser after Delete = %d, want 1", count)
+	}
+}
+
+func testVoteCasting(t *testing.T, stores api.Stores) {
+	for _, item := range []*vstore.RetrospectiveItem{
+		{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Pairing"},
+		{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: "Demos"},
+		{ItemID: "ITEM-3", RetrospectiveID: "RETRO-1", ColumnID: "to_improve", Content: "Flaky CI"},
+	} {
+		mustNoErr(t, stores.Items.Create(item), "Items.Create")
+	}
+	limits := api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 2}
+	cast := func(id, itemID, userID string) (int32, error) {
+		return stores.Votes.Cast(&vstore.Vote{VoteID: id, RetrospectiveID: "RETRO-1", ItemID: itemID, UserID: userID}, limits)
+	}
+
+	count, err := cast("VOTE-1", "ITEM-1", "u1")
+	mustNoErr(t, err, "Cast")
+	if count != 1 {
+		t.Errorf("Cast returned count %d, want 1", count)
+	}
+	if _, err := cast("VOTE-2", "ITEM-1", "u1"); !errors.Is(err, api.ErrAlreadyExists) {
+		t.Errorf("second Cast on the same item error = %v, want ErrAlreadyExists", err)
+	}
+	mustNoErr(t, errOnly(cast("VOTE-3", "ITEM-2", "u1")), "Cast")
+	if _, err := cast("VOTE-4", "ITEM-3", "u1"); !errors.Is(err, api.ErrVoteLimitExceeded) {
+		t.Errorf("Cast over the limit error = %v, want ErrVoteLimitExceeded", err)
+	}
+	count, err = cast("VOTE-5", "ITEM-1", "u2")
+	mustNoErr(t, err, "Cast by another user")
+	if count != 2 {
+		t.Errorf("Cast by another user returned count %d, want 2", count)
+	}
+	if _, err := cast("VOTE-6", "missing", "u2"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Cast on a missing item error = %v, want ErrNotFound", err)
+	}
+
+	limits.AllowMultipleVotesPerItem = true
+	limits.MaxVotesPerUser = 3
+	mustNoErr(t, errOnly(cast("VOTE-7", "ITEM-1", "u2")), "Cast again with multiple votes per item")
+
+	// Content edits must not overwrite counts changed by votes
+	item, err := stores.Items.Get("ITEM-1")
+	mustNoErr(t, err, "Items.Get")
+	item.VoteCount = 0
+	item.Content = "Pair programming"
+	mustNoErr(t, stores.Items.Update(item), "Items.Update")
+	assertVoteCount(t, stores, "ITEM-1", 3)
+
+	count, err = stores.Votes.Retract("RETRO-1", "ITEM-1", "u2")
+	mustNoErr(t, err, "Retract")
+	if count != 2 {
+		t.Errorf("Retract returned count %d, want 2", count)
+	}
+	if _, err := stores.Votes.Retract("RETRO-1", "ITEM-3", "u1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Retract without a vote error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, errOnly(stores.Votes.Retract("RETRO-1", "ITEM-2", "u1")), "Retract")
+	// The retracted vote frees up room under the limit
+	limits = api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 2}
+	mustNoErr(t, errOnly(cast("VOTE-8", "ITEM-3", "u1")), "Cast after Retract")
+
+	assertVoteCount(t, stores, "ITEM-1", 2)
+	assertVoteCount(t, stores, "ITEM-2", 0)
+	assertVoteCount(t, stores, "ITEM-3", 1)
+}
+
+// testVotingModes covers the per-column budget and the modes in which a user's
+// vote on an item carries a value that a later vote replaces
+func testVotingModes(t *testing.T, stores api.Stores) {
+	columns := map[string]string{"ITEM-1": "went_well", "ITEM-2": "went_well", "ITEM-3": "to_improve"}
+	for itemID, columnID := range columns {
+		mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{ItemID: itemID, RetrospectiveID: "RETRO-1", ColumnID: columnID}), "Items.Create")
+	}
+	cast := func(limits api.VoteLimits, vote vstore.Vote) (int32, error) {
+		vote.RetrospectiveID = "RETRO-1"
+		vote.ColumnID = columns[vote.ItemID]
+		return stores.Votes.Cast(&vote, limits)
+	}
+
+	// Dot voting with one vote per column
+	dot := api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 3, MaxVotesPerColumn: 1}
+	mustNoErr(t, errOnly(cast(dot, vstore.Vote{VoteID: "DOT-1", ItemID: "ITEM-1", UserID: "dot"})), "Cast")
+	if _, err := cast(dot, vstore.Vote{VoteID: "DOT-2", ItemID: "ITEM-2", UserID: "dot"}); !errors.Is(err, api.ErrVoteLimitExceeded) {
+		t.Errorf("Cast over the column budget error = %v, want ErrVoteLimitExceeded", err)
+	}
+	mustNoErr(t, errOnly(cast(dot, vstore.Vote{VoteID: "DOT-3", ItemID: "ITEM-3", UserID: "dot"})), "Cast in another column")
+	assertVoteCount(t, stores, "ITEM-1", 1)
+
+	// A vote counts in the column its item has moved to since
+	mustNoErr(t, errOnly(cast(dot, vstore.Vote{VoteID: "MOVE-1", ItemID: "ITEM-1", UserID: "mover"})), "Cast")
+	moved := dot
+	moved.Columns = map[string]string{"ITEM-1": "to_improve", "ITEM-2": "went_well", "ITEM-3": "to_improve"}
+	if _, err := cast(moved, vstore.Vote{VoteID: "MOVE-2", ItemID: "ITEM-3", UserID: "mover"}); !errors.Is(err, api.ErrVoteLimitExceeded) {
+		t.Errorf("Cast over the budget of the column a voted item moved to error = %v, want ErrVoteLimitExceeded", err)
+	}
+	mustNoErr(t, errOnly(cast(moved, vstore.Vote{VoteID: "MOVE-3", ItemID: "ITEM-2", UserID: "mover"})), "Cast in the column a voted item left")
+	mustNoErr(t, errOnly(stores.Votes.Retract("RETRO-1", "ITEM-1", "mover")), "Retract")
+	mustNoErr(t, errOnly(stores.Votes.Retract("RETRO-1", "ITEM-2", "mover")), "Retract")
+
+	// Weighted voting spends points; a new vote on an item replaces the old one
+	weighted := api.VoteLimits{Mode: vstore.VotingModeWeighted, MaxVotesPerUser: 5}
+	count, err := cast(weighted, vstore.Vote{VoteID: "W-1", ItemID: "ITEM-1", UserID: "w", Points: 3})
+	mustNoErr(t, err, "Cast weighted")
+	if count != 4 {
+		t.Errorf("weighted Cast returned count %d, want 4", count)
+	}
+	if _, err := cast(weighted, vstore.Vote{VoteID: "W-2", ItemID: "ITEM-2", UserID: "w", Points: 3}); !errors.Is(err, api.ErrVoteLimitExceeded) {
+		t.Errorf("weighted Cast over budget error = %v, want ErrVoteLimitExceeded", err)
+	}
+	count, err = cast(weighted, vstore.Vote{VoteID: "W-3", ItemID: "ITEM-1", UserID: "w", Points: 1})
+	mustNoErr(t, err, "Cast weighted replacement")
+	if count != 2 {
+		t.Errorf("weighted replacement returned count %d, want 2", count)
+	}
+	mustNoErr(t, errOnly(cast(weighted, vstore.Vote{VoteID: "W-4", ItemID: "ITEM-2", UserID: "w", Points: 4})), "Cast with freed points")
+	votes, err := stores.Votes.ListByUser("RETRO-1", "w")
+	mustNoErr(t, err, "ListByUser")
+	if len(votes) != 2 {
+		t.Errorf("weighted voter has %d votes, want 2", len(votes))
+	}
+	count, err = stores.Votes.Retract("RETRO-1", "ITEM-2", "w")
+	mustNoErr(t, err, "Retract weighted")
+	if count != 0 {
+		t.Errorf("weighted Retract returned count %d, want 0", count)
+	}
+
+	// Ranked choice allows each rank once
+	ranked := api.VoteLimits{Mode: vstore.VotingModeRankedChoice, MaxVotesPerUser: 3}
+	mustNoErr(t, errOnly(cast(ranked, vstore.Vote{VoteID: "R-1", ItemID: "ITEM-1", UserID: "r", Rank: 1})), "Cast ranked")
+	if _, err := cast(ranked, vstore.Vote{VoteID: "R-2", ItemID: "ITEM-2", UserID: "r", Rank: 1}); !errors.Is(err, api.ErrAlreadyExists) {
+		t.Errorf("ranked Cast reusing a rank error = %v, want ErrAlreadyExists", err)
+	}
+	mustNoErr(t, errOnly(cast(ranked, vstore.Vote{VoteID: "R-3", ItemID: "ITEM-1", UserID: "r", Rank: 2})), "Cast ranked replacement")
+	mustNoErr(t, errOnly(cast(ranked, vstore.Vote{VoteID: "R-4", ItemID: "ITEM-2", UserID: "r", Rank: 1})), "Cast freed rank")
+
+	// Fist of five keeps one score per user and item
+	fist := api.VoteLimits{Mode: vstore.VotingModeFistOfFive}
+	mustNoErr(t, errOnly(cast(fist, vstore.Vote{VoteID: "F-1", ItemID: "ITEM-3", UserID: "f", Score: 2})), "Cast score")
+	mustNoErr(t, errOnly(cast(fist, vstore.Vote{VoteID: "F-2", ItemID: "ITEM-3", UserID: "f", Score: 5})), "Cast score replacement")
+	vote, err := stores.Votes.GetByUserAndItem("RETRO-1", "ITEM-3", "f")
+	mustNoErr(t, err, "GetByUserAndItem")
+	if vote.Score != 5 {
+		t.Errorf("score after replacement = %d, want 5", vote.Score)
+	}
+
+	assertVoteCount(t, stores, "ITEM-1", 3)
+	assertVoteCount(t, stores, "ITEM-2", 1)
+	assertVoteCount(t, stores, "ITEM-3", 2)
+}
+
+// testMoveVotes covers moving the votes of a merged item onto the item kept
+func testMoveVotes(t *testing.T, stores api.Stores) {
+	mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well"}), "Items.Create")
+	mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "to_improve"}), "Items.Create")
+	limits := api.VoteLimits{Mode: vstore.VotingModeWeighted, MaxVotesPerUser: 10}
+	for _, vote := range []*vstore.Vote{
+		{VoteID: "VOTE-1", ItemID: "ITEM-1", UserID: "u1", Points: 3},
+		{VoteID: "VOTE-2", ItemID: "ITEM-1", UserID: "u2", Points: 2},
+		{VoteID: "VOTE-3", ItemID: "ITEM-2", UserID: "u3", Points: 1},
+	} {
+		vote.RetrospectiveID = "RETRO-1"
+		mustNoErr(t, errOnly(stores.Votes.Cast(vote, limits)), "Cast")
+	}
+
+	count, err := stores.Votes.MoveVotes("RETRO-1", "ITEM-1", "ITEM-2")
+	mustNoErr(t, err, "MoveVotes")
+	if count != 6 {
+		t.Errorf("MoveVotes returned count %d, want 6", count)
+	}
+	assertVoteCount(t, stores, "ITEM-1", 0)
+	assertVoteCount(t, stores, "ITEM-2", 6)
+
+	votes, err := stores.Votes.ListByItem("RETRO-1", "ITEM-2")
+	mustNoErr(t, err, "ListByItem")
+	if ids := voteIDs(votes); !sameSet(ids, []string{"VOTE-1", "VOTE-2", "VOTE-3"}) {
+		t.Errorf("ListByItem after MoveVotes = %v", ids)
+	}
+	for _, vote := range votes {
+		if vote.VoteID != "VOTE-3" && vote.ColumnID != "to_improve" {
+			t.Errorf("moved vote %s has column %q, want to_improve", vote.VoteID, vote.ColumnID)
+		}
+	}
+
+	// Moved votes are retracted from the item they now belong to
+	count, err = stores.Votes.Retract("RETRO-1", "ITEM-2", "u1")
+	mustNoErr(t, err, "Retract moved vote")
+	if count != 3 {
+		t.Errorf("Retract returned count %d, want 3", count)
+	}
+	if _, err := stores.Votes.MoveVotes("RETRO-1", "ITEM-1", "missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("MoveVotes to a missing item error = %v, want ErrNotFound", err)
+	}
+}
+
+// testVoteCastingConcurrency casts and retracts votes from many goroutines at
+// once in each budgeted mode and checks that the limits held and item counts
+// match the vote records
+func testVoteCastingConcurrency(t *testing.T, stores api.Stores) {
+	one := func(int) int32 { return 0 }
+	t.Run("Dot", func(t *testing.T) {
+		raceVotes(t, stores, "RETRO-DOT", api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 2}, one)
+	})
+	t.Run("Weighted", func(t *testing.T) {
+		raceVotes(t, stores, "RETRO-WEIGHTED", api.VoteLimits{Mode: vstore.VotingModeWeighted, MaxVotesPerUser: 4}, func(a int) int32 { return int32(1 + a%3) })
+	})
+	t.Run("PerColumn", func(t *testing.T) {
+		raceVotes(t, stores, "RETRO-COLUMN", api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 6, MaxVotesPerColumn: 1}, one)
+	})
+	t.Run("WeightedPerColumn", func(t *testing.T) {
+		raceVotes(t, stores, "RETRO-WEIGHTED-COLUMN", api.VoteLimits{Mode: vstore.VotingModeWeighted, MaxVotesPerUser: 8, MaxVotesPerColumn: 3}, func(a int) int32 { return int32(1 + a%2) })
+	})
+}
+
+// raceVotes has a few users cast votes worth points(attempt) on more items
+// than their budget covers, spread over two columns, while also retracting
+// some, then checks every budget in limits held and that item counts match
+// the vote records
+func raceVotes(t *testing.T, stores api.Stores, retroID string, limits api.VoteLimits, points func(attempt int) int32) {
+	const (
+		items    = 6
+		users    = 3
+		attempts = 36 // per user, spread over the items
+	)
+	columnOf := func(i int) string {
+		if i%2 == 0 {
+			return "went_well"
+		}
+		return "to_improve"
+	}
+	for i := 0; i < items; i++ {
+		mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{
+			ItemID:          fmt.Sprintf("%s-ITEM-%d", retroID, i),
+			RetrospectiveID: retroID,
+			ColumnID:        columnOf(i),
+		}), "Items.Create")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, users*attempts*2)
+	for u := 0; u < users; u++ {
+		for a := 0; a < attempts; a++ {
+			wg.Add(1)
+			go func(u, a int) {
+				defer wg.Done()
+				userID := fmt.Sprintf("u%d", u)
+				itemID := fmt.Sprintf("%s-ITEM-%d", retroID, a%items)
+				_, err := stores.Votes.Cast(&vstore.Vote{
+					VoteID:          fmt.Sprintf("%s-VOTE-%d-%d", retroID, u, a),
+					RetrospectiveID: retroID,
+					ItemID:          itemID,
+					ColumnID:        columnOf(a % items),
+					UserID:          userID,
+					Points:          points(a),
+				}, limits)
+				// Replacing a vote still being recorded may be refused as a
+				// conflict for the client to retry
+				if err != nil && !errors.Is(err, api.ErrAlreadyExists) && !errors.Is(err, api.ErrVoteLimitExceeded) && !errors.Is(err, api.ErrConflict) {
+					errs <- fmt.Errorf("Cast: %w", err)
+				}
+				// Every third attempt also retracts, so casts race with removals
+				if a%3 == 0 {
+					_, err := stores.Votes.Retract(retroID, itemID, userID)
+					if err != nil && !errors.Is(err, api.ErrNotFound) {
+						errs <- fmt.Errorf("Retract: %w", err)
+					}
+				}
+			}(u, a)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for u := 0; u < users; u++ {
+		userID := fmt.Sprintf("u%d", u)
+		votes, err := stores.Votes.ListByUser(retroID, userID)
+		mustNoErr(t, err, "ListByUser")
+		var used int32
+		perColumn := make(map[string]int32)
+		perItem := make(map[string]int)
+		for _, v := range votes {
+			used += voteWeight(v)
+			perColumn[v.ColumnID] += voteWeight(v)
+			perItem[v.ItemID]++
+			if perItem[v.ItemID] > 1 {
+				t.Errorf("%s voted more than once for %s", userID, v.ItemID)
+			}
+		}
+		if used > limits.MaxVotesPerUser {
+			t.Errorf("%s used %d votes, limit is %d", userID, used, limits.MaxVotesPerUser)
+		}
+		for columnID, n := range perColumn {
+			if limits.MaxVotesPerColumn > 0 && n > limits.MaxVotesPerColumn {
+				t.Errorf("%s used %d votes in %s, limit is %d", userID, n, columnID, limits.MaxVotesPerColumn)
+			}
+		}
+	}
+	for i := 0; i < items; i++ {
+		itemID := fmt.Sprintf("%s-ITEM-%d", retroID, i)
+		votes, err := stores.Votes.ListByItem(retroID, itemID)
+		mustNoErr(t, err, "ListByItem")
+		var want int32
+		for _, v := range votes {
+			want += voteWeight(v)
+		}
+		assertVoteCount(t, stores, itemID, want)
+	}
+}
+
+// voteWeight is what a vote adds to its item's count: its points in weighted
+// voting and one otherwise
+func voteWeight(vote *vstore.Vote) int32 {
+	if vote.Points > 0 {
+		return vote.Points
+	}
+	return 1
+}
+
+func assertVoteCount(t *testing.T, stores api.Stores, itemID string, want int32) {
+	t.Helper()
+	item, err := stores.Items.Get(itemID)
+	mustNoErr(t, err, "Items.Get")
+	if item.VoteCount != want {
+		t.Errorf("%s VoteCount = %d, want %d", itemID, item.VoteCount, want)
+	}
+}
+
+func errOnly(_ int32, err error) error {
+	return err
+}
+
+func testActionItemStore(t *testing.T, store api.ActionItemStore) {
+	for _, item := range []*vstore.ActionItem{
+		{ActionItemID: "ACTION-1", RetrospectiveID: "RETRO-1", TeamID: "team-a", Description: "Fix CI", AssigneeID: "u1", Status: vstore.ActionItemStatusNotStarted},
+		{ActionItemID: "ACTION-2", RetrospectiveID: "RETRO-1", TeamID: "team-a", Description: "Write docs", AssigneeID: "u2", Status: vstore.ActionItemStatusDone},
+		{ActionItemID: "ACTION-3", RetrospectiveID: "RETRO-2", TeamID: "team-a", Description: "Drop legacy API", AssigneeID: "u1", Status: vstore.ActionItemStatusWontDo},
+		{ActionItemID: "ACTION-4", RetrospectiveID: "RETRO-9", TeamID: "team-b", Description: "Other team", AssigneeID: "u1", Status: vstore.ActionItemStatusInProgress},
+	} {
+		mustNoErr(t, store.Create(item), "Create")
+	}
+
+	got, err := store.Get("ACTION-1")
+	mustNoErr(t, err, "Get")
+	if got.Description != "Fix CI" {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got.Status = vstore.ActionItemStatusInProgress
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("ACTION-1")
+	mustNoErr(t, err, "Get after Update")
+	if got.Status != vstore.ActionItemStatusInProgress {
+		t.Errorf("Update not persisted: %+v", got)
+	}
+
+	items, err := store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective")
+	if ids := actionItemIDs(items); !sameSet(ids, []string{"ACTION-1", "ACTION-2"}) {
+		t.Errorf("ListByRetrospective(RETRO-1) = %v", ids)
+	}
+
+	items, err = store.ListByTeam("team-a", true)
+	mustNoErr(t, err, "ListByTeam")
+	if ids := actionItemIDs(items); !sameSet(ids, []string{"ACTION-1", "ACTION-2", "ACTION-3"}) {
+		t.Errorf("ListByTeam(team-a, includeCompleted) = %v", ids)
+	}
+	items, err = store.ListByTeam("team-a", false)
+	mustNoErr(t, err, "ListByTeam open")
+	if ids := actionItemIDs(items); !sameSet(ids, []string{"ACTION-1"}) {
+		t.Errorf("ListByTeam(team-a) = %v, want only open items", ids)
+	}
+
+	items, err = store.ListByAssignee("team-a", "u1")
+	mustNoErr(t, err, "ListByAssignee")
+	if ids := actionItemIDs(items); !sameSet(ids, []string{"ACTION-1", "ACTION-3"}) {
+		t.Errorf("ListByAssignee(team-a, u1) = %v", ids)
+	}
+
+	mustNoErr(t, store.Delete("ACTION-1"), "Delete")
+	if _, err := store.Get("ACTION-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testParticipantStore(t *testing.T, store api.ParticipantStore) {
+	mustNoErr(t, store.Join(&vstore.Participant{RetrospectiveID: "RETRO-1", UserID: "u1", DisplayName: "Ada"}), "Join")
+	mustNoErr(t, store.Join(&vstore.Participant{RetrospectiveID: "RETRO-1", UserID: "u2", DisplayName: "Grace"}), "Join")
+	mustNoErr(t, store.Join(&vstore.Participant{RetrospectiveID: "RETRO-2", UserID: "u1", DisplayName: "Ada"}), "Join")
+
+	p, err := store.Get("RETRO-1", "u1")
+	mustNoErr(t, err, "Get")
+	if !p.IsOnline || p.DisplayName != "Ada" || p.JoinedAt.IsZero() {
+		t.Errorf("Get after Join returned %+v", p)
+	}
+	if _, err := store.Get("RETRO-1", "missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	participants, err := store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective")
+	if len(participants) != 2 {
+		t.Errorf("ListByRetrospective(RETRO-1) = %d participants, want 2", len(participants))
+	}
+
+	mustNoErr(t, store.Leave("RETRO-1", "u2"), "Leave")
+	participants, err = store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective after Leave")
+	if len(participants) != 1 || participants[0].UserID != "u1" {
+		t.Errorf("ListByRetrospective after Leave returned %d participants", len(participants))
+	}
+
+	mustNoErr(t, store.Heartbeat("RETRO-1", "u2"), "Heartbeat")
+	p, err = store.Get("RETRO-1", "u2")
+	mustNoErr(t, err, "Get after Heartbeat")
+	if !p.IsOnline {
+		t.Errorf("Heartbeat did not mark participant online")
+	}
+
+	mustNoErr(t, store.Leave("RETRO-1", "missing"), "Leave(missing)")
+	mustNoErr(t, store.Heartbeat("RETRO-1", "missing"), "Heartbeat(missing)")
+}
+
+func testTeamStore(t *testing.T, store api.TeamStore) {
+	team := &vstore.Team{TeamID: "team-a", Name: "Platform", CreatedBy: "u1"}
+	mustNoErr(t, store.Create(team), "Create")
+	if team.Created.IsZero() {
+		t.Errorf("Create did not set Created")
+	}
+	mustNoErr(t, store.Create(&vstore.Team{TeamID: "team-b", Name: "Payments"}), "Create")
+
+	got, err := store.Get("team-a")
+	mustNoErr(t, err, "Get")
+	if got.Name != "Platform" {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got.Name = "Platform Core"
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("team-a")
+	mustNoErr(t, err, "Get after Update")
+	if got.Name != "Platform Core" {
+		t.Errorf("Update not persisted: %+v", got)
+	}
+
+	mustNoErr(t, store.PutMember(&vstore.TeamMember{TeamID: "team-a", UserID: "u1", DisplayName: "Ada", Role: vstore.TeamRoleAdmin}), "PutMember")
+	mustNoErr(t, store.PutMember(&vstore.TeamMember{TeamID: "team-a", UserID: "u2", DisplayName: "Grace", Role: vstore.TeamRoleMember}), "PutMember")
+	mustNoErr(t, store.PutMember(&vstore.TeamMember{TeamID: "team-b", UserID: "u2", DisplayName: "Grace", Role: vstore.TeamRoleObserver}), "PutMember")
+
+	member, err := store.GetMember("team-a", "u1")
+	mustNoErr(t, err, "GetMember")
+	if member.Role != vstore.TeamRoleAdmin || member.Created.IsZero() {
+		t.Errorf("GetMember returned %+v", member)
+	}
+	if _, err := store.GetMember("team-b", "u1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("GetMember(non-member) error = %v, want ErrNotFound", err)
+	}
+
+	mustNoErr(t, store.PutMember(&vstore.TeamMember{TeamID: "team-a", UserID: "u2", DisplayName: "Grace", Role: vstore.TeamRoleAdmin}), "PutMember update")
+	member, err = store.GetMember("team-a", "u2")
+	mustNoErr(t, err, "GetMember after role change")
+	if member.Role != vstore.TeamRoleAdmin {
+		t.Errorf("PutMember did not update role: %+v", member)
+	}
+
+	members, err := store.ListMembers("team-a")
+	mustNoErr(t, err, "ListMembers")
+	if len(members) != 2 {
+		t.Errorf("ListMembers(team-a) = %d members, want 2", len(members))
+	}
+
+	teams, err := store.ListByMember("u2")
+	mustNoErr(t, err, "ListByMember")
+	if ids := teamIDs(teams); !sameSet(ids, []string{"team-a", "team-b"}) {
+		t.Errorf("ListByMember(u2) = %v", ids)
+	}
+
+	mustNoErr(t, store.RemoveMember("team-a", "u2"), "RemoveMember")
+	teams, err = store.ListByMember("u2")
+	mustNoErr(t, err, "ListByMember after RemoveMember")
+	if ids := teamIDs(teams); !sameSet(ids, []string{"team-b"}) {
+		t.Errorf("ListByMember(u2) after RemoveMember = %v", ids)
+	}
+
+	mustNoErr(t, store.Delete("team-a"), "Delete")
+	if _, err := store.Get("team-a"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetMember("team-a", "u1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("GetMember after team Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testPhaseTimerStore(t *testing.T, store api.PhaseTimerStore) {
+	endsAt := time.Now().Add(5 * time.Minute).Truncate(time.Millisecond)
+	timer := &vstore.PhaseTimer{
+		RetrospectiveID: "RETRO-1",
+		Phase:           vstore.RetrospectiveStatusVoting,
+		Duration:        5 * time.Minute,
+		EndsAt:          endsAt,
+		AutoAdvance:     true,
+		StartedBy:       "u1",
+	}
+	mustNoErr(t, store.Put(timer), "Put")
+	if timer.Created.IsZero() {
+		t.Errorf("Put did not set Created")
+	}
+	mustNoErr(t, store.Put(&vstore.PhaseTimer{RetrospectiveID: "RETRO-2", Paused: true, Remaining: time.Minute}), "Put")
+
+	got, err := store.Get("RETRO-1")
+	mustNoErr(t, err, "Get")
+	if got.Duration != 5*time.Minute || !got.EndsAt.Equal(endsAt) || !got.AutoAdvance || got.Phase != vstore.RetrospectiveStatusVoting {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got.Paused = true
+	got.Remaining = 90 * time.Second
+	got.EndsAt = time.Time{}
+	mustNoErr(t, store.Put(got), "Put update")
+	got, err = store.Get("RETRO-1")
+	mustNoErr(t, err, "Get after update")
+	if !got.Paused || got.Remaining != 90*time.Second || !got.EndsAt.IsZero() {
+		t.Errorf("update not persisted: %+v", got)
+	}
+
+	timers, err := store.List()
+	mustNoErr(t, err, "List")
+	if len(timers) != 2 {
+		t.Errorf("List = %d timers, want 2", len(timers))
+	}
+
+	mustNoErr(t, store.Delete("RETRO-1"), "Delete")
+	if _, err := store.Get("RETRO-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, store.Delete("RETRO-1"), "Delete missing")
+}
+
+func testItemGroupStore(t *testing.T, store api.ItemGroupStore) {
+	for _, group := range []*vstore.ItemGroup{
+		{GroupID: "GROUP-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Title: "Collaboration"},
+		{GroupID: "GROUP-2", RetrospectiveID: "RETRO-1", ColumnID: "to_improve", Title: "Tooling"},
+		{GroupID: "GROUP-3", RetrospectiveID: "RETRO-2", ColumnID: "went_well", Title: "Other retro"},
+	} {
+		mustNoErr(t, store.Create(group), "Create")
+	}
+
+	got, err := store.Get("GROUP-1")
+	mustNoErr(t, err, "Get")
+	if got.Title != "Collaboration" || got.ColumnID != "went_well" || got.Created.IsZero() {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	groups, err := store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective")
+	if ids := groupIDs(groups); !sameSet(ids, []string{"GROUP-1", "GROUP-2"}) {
+		t.Errorf("ListByRetrospective = %v", ids)
+	}
+
+	// Update keeps the stored vote count, which belongs to the vote store
+	got.Title = "Teamwork"
+	got.VoteCount = 7
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("GROUP-1")
+	mustNoErr(t, err, "Get after Update")
+	if got.Title != "Teamwork" || got.VoteCount != 0 {
+		t.Errorf("after Update got %+v", got)
+	}
+
+	mustNoErr(t, store.Delete("GROUP-1"), "Delete")
+	if _, err := store.Get("GROUP-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, store.Delete("GROUP-1"), "Delete missing")
+	groups, err = store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective after Delete")
+	if ids := groupIDs(groups); !sameSet(ids, []string{"GROUP-2"}) {
+		t.Errorf("ListByRetrospective after Delete = %v", ids)
+	}
+}
+
+func testCommentStore(t *testing.T, store api.CommentStore) {
+	for _, c := range []*vstore.Comment{
+		{CommentID: "COMMENT-1", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", Content: "Agreed", CreatedBy: "u1"},
+		{CommentID: "COMMENT-2", RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", ParentID: "COMMENT-1", Content: "Same here"},
+		{CommentID: "COMMENT-3", RetrospectiveID: "RETRO-1", ItemID: "ITEM-2", Content: "Why?"},
+		{CommentID: "COMMENT-4", RetrospectiveID: "RETRO-2", ItemID: "ITEM-3", Content: "Other retro"},
+	} {
+		mustNoErr(t, store.Create(c), "Create")
+	}
+
+	got, err := store.Get("COMMENT-2")
+	mustNoErr(t, err, "Get")
+	if got.Content != "Same here" || got.ParentID != "COMMENT-1" || got.ItemID != "ITEM-1" || got.Created.IsZero() {
+		t.Errorf("Get returned %+v", got)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	comments, err := store.ListByItem("ITEM-1")
+	mustNoErr(t, err, "ListByItem")
+	if ids := commentIDs(comments); !sameSet(ids, []string{"COMMENT-1", "COMMENT-2"}) {
+		t.Errorf("ListByItem = %v", ids)
+	}
+	comments, err = store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective")
+	if ids := commentIDs(comments); !sameSet(ids, []string{"COMMENT-1", "COMMENT-2", "COMMENT-3"}) {
+		t.Errorf("ListByRetrospective = %v", ids)
+	}
+
+	// Moving a comment to another item re-indexes it
+	got.ItemID = "ITEM-2"
+	got.Content = "Moved"
+	mustNoErr(t, store.Update(got), "Update")
+	got, err = store.Get("COMMENT-2")
+	mustNoErr(t, err, "Get after Update")
+	if got.Content != "Moved" || got.ItemID != "ITEM-2" {
+		t.Errorf("after Update got %+v", got)
+	}
+	comments, err = store.ListByItem("ITEM-1")
+	mustNoErr(t, err, "ListByItem after Update")
+	if ids := commentIDs(comments); !sameSet(ids, []string{"COMMENT-1"}) {
+		t.Errorf("ListByItem(ITEM-1) after Update = %v", ids)
+	}
+	comments, err = store.ListByItem("ITEM-2")
+	mustNoErr(t, err, "ListByItem after Update")
+	if ids := commentIDs(comments); !sameSet(ids, []string{"COMMENT-2", "COMMENT-3"}) {
+		t.Errorf("ListByItem(ITEM-2) after Update = %v", ids)
+	}
+
+	mustNoErr(t, store.Delete("COMMENT-1"), "Delete")
+	if _, err := store.Get("COMMENT-1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, store.Delete("COMMENT-1"), "Delete missing")
+	comments, err = store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective after Delete")
+	if ids := commentIDs(comments); !sameSet(ids, []string{"COMMENT-2", "COMMENT-3"}) {
+		t.Errorf("ListByRetrospective after Delete = %v", ids)
+	}
+}
+
+// testItemOrder checks that items are listed in the order of their sort keys,
+// with vote counts taking precedence when sorting by votes
+func testItemOrder(t *testing.T, store api.ItemStore) {
+	for _, item := range []*vstore.RetrospectiveItem{
+		{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", SortKey: "k"},
+		{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well", SortKey: "V"},
+		{ItemID: "ITEM-3", RetrospectiveID: "RETRO-1", ColumnID: "went_well", SortKey: "VV"},
+		{ItemID: "ITEM-4", RetrospectiveID: "RETRO-1", ColumnID: "went_well", SortKey: "a"},
+	} {
+		mustNoErr(t, store.Create(item), "Create")
+	}
+
+	items, err := store.ListByRetrospective("RETRO-1", "went_well", false)
+	mustNoErr(t, err, "ListByRetrospective")
+	if ids := fmt.Sprint(itemIDs(items)); ids != "[ITEM-2 ITEM-3 ITEM-4 ITEM-1]" {
+		t.Errorf("ListByRetrospective = %v, want ITEM-2, ITEM-3, ITEM-4, ITEM-1", ids)
+	}
+
+	mustNoErr(t, store.IncrementVoteCount("ITEM-1"), "IncrementVoteCount")
+	items, err = store.ListByRetrospective("RETRO-1", "went_well", true)
+	mustNoErr(t, err, "ListByRetrospective sorted")
+	if ids := fmt.Sprint(itemIDs(items)); ids != "[ITEM-1 ITEM-2 ITEM-3 ITEM-4]" {
+		t.Errorf("ListByRetrospective sorted by votes = %v, want ITEM-1 then position order", ids)
+	}
+}
+
+func testReactionStore(t *testing.T, store api.ReactionStore) {
+	for _, r := range []*vstore.Reaction{
+		{RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u1", Emoji: "👍"},
+		{RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u1", Emoji: "🎉"},
+		{RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u2", Emoji: "👍"},
+		{RetrospectiveID: "RETRO-1", ItemID: "ITEM-2", UserID: "u1", Emoji: "👍"},
+		{RetrospectiveID: "RETRO-2", ItemID: "ITEM-3", UserID: "u1", Emoji: "👍"},
+	} {
+		mustNoErr(t, store.Add(r), "Add")
+		if r.Created.IsZero() {
+			t.Errorf("Add did not set Created on %+v", r)
+		}
+	}
+	if err := store.Add(&vstore.Reaction{RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u1", Emoji: "👍"}); !errors.Is(err, api.ErrAlreadyExists) {
+		t.Errorf("Add twice error = %v, want ErrAlreadyExists", err)
+	}
+
+	reactions, err := store.ListByItem("RETRO-1", "ITEM-1")
+	mustNoErr(t, err, "ListByItem")
+	if keys := reactionKeys(reactions); !sameSet(keys, []string{"u1 👍", "u1 🎉", "u2 👍"}) {
+		t.Errorf("ListByItem(ITEM-1) = %v", keys)
+	}
+	reactions, err = store.ListByRetrospective("RETRO-1")
+	mustNoErr(t, err, "ListByRetrospective")
+	if len(reactions) != 4 {
+		t.Errorf("ListByRetrospective(RETRO-1) returned %d reactions, want 4", len(reactions))
+	}
+
+	mustNoErr(t, store.Remove("RETRO-1", "ITEM-1", "u1", "👍"), "Remove")
+	if err := store.Remove("RETRO-1", "ITEM-1", "u1", "👍"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Remove twice error = %v, want ErrNotFound", err)
+	}
+	reactions, err = store.ListByItem("RETRO-1", "ITEM-1")
+	mustNoErr(t, err, "ListByItem after Remove")
+	if keys := reactionKeys(reactions); !sameSet(keys, []string{"u1 🎉", "u2 👍"}) {
+		t.Errorf("ListByItem(ITEM-1) after Remove = %v", keys)
+	}
+	mustNoErr(t, store.Add(&vstore.Reaction{RetrospectiveID: "RETRO-1", ItemID: "ITEM-1", UserID: "u1", Emoji: "👍"}), "Add after Remove")
+}
+
+// testGroupVoting checks that group votes count towards the group, share the
+// voter's limits with item votes and leave item counts alone
+func testGroupVoting(t *testing.T, stores api.Stores) {
+	mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{ItemID: "ITEM-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", GroupID: "GROUP-1"}), "Items.Create")
+	mustNoErr(t, stores.Items.Create(&vstore.RetrospectiveItem{ItemID: "ITEM-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well"}), "Items.Create")
+	mustNoErr(t, stores.ItemGroups.Create(&vstore.ItemGroup{GroupID: "GROUP-1", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Title: "Pairing"}), "ItemGroups.Create")
+	mustNoErr(t, stores.ItemGroups.Create(&vstore.ItemGroup{GroupID: "GROUP-2", RetrospectiveID: "RETRO-1", ColumnID: "went_well", Title: "Demos"}), "ItemGroups.Create")
+	limits := api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 2}
+	castGroup := func(id, groupID, userID string) (int32, error) {
+		return stores.Votes.Cast(&vstore.Vote{VoteID: id, RetrospectiveID: "RETRO-1", ItemID: groupID, GroupVote: true, UserID: userID}, limits)
+	}
+
+	count, err := castGroup("VOTE-1", "GROUP-1", "u1")
+	mustNoErr(t, err, "Cast on group")
+	if count != 1 {
+		t.Errorf("Cast on group returned count %d, want 1", count)
+	}
+	mustNoErr(t, errOnly(castGroup("VOTE-2", "GROUP-1", "u2")), "Cast on group by another user")
+	if _, err := castGroup("VOTE-3", "GROUP-1", "u1"); !errors.Is(err, api.ErrAlreadyExists) {
+		t.Errorf("second Cast on the same group error = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := castGroup("VOTE-4", "ITEM-2", "u1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("group Cast naming an item error = %v, want ErrNotFound", err)
+	}
+	mustNoErr(t, errOnly(stores.Votes.Cast(&vstore.Vote{VoteID: "VOTE-5", RetrospectiveID: "RETRO-1", ItemID: "ITEM-2", UserID: "u1"}, limits)), "Cast on item")
+	if _, err := castGroup("VOTE-6", "GROUP-2", "u1"); !errors.Is(err, api.ErrVoteLimitExceeded) {
+		t.Errorf("Cast over the limit error = %v, want ErrVoteLimitExceeded", err)
+	}
+	assertGroupVoteCount(t, stores, "GROUP-1", 2)
+	assertVoteCount(t, stores, "ITEM-1", 0)
+	assertVoteCount(t, stores, "ITEM-2", 1)
+
+	count, err = stores.Votes.Retract("RETRO-1", "GROUP-1", "u2")
+	mustNoErr(t, err, "Retract from group")
+	if count != 1 {
+		t.Errorf("Retract from group returned count %d, want 1", count)
+	}
+	assertGroupVoteCount(t, stores, "GROUP-1", 1)
+	assertVoteCount(t, stores, "ITEM-1", 0)
+}
+
+func assertGroupVoteCount(t *testing.T, stores api.Stores, groupID string, want int32) {
+	t.Helper()
+	group, err := stores.ItemGroups.Get(groupID)
+	mustNoErr(t, err, "ItemGroups.Get")
+	if group.VoteCount != want {
+		t.Errorf("%s VoteCount = %d, want %d", groupID, group.VoteCount, want)
+	}
+}
+
+func mustNoErr(t *testing.T, err error, op string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", op, err)
+	}
+}
+
+func sameSet(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[string]int)
+	for _, id := range got {
+		seen[id]++
+	}
+	for _, id := range want {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
+
+func retroIDs(retros []*vstore.Retrospective) []string {
+	var ids []string
+	for _, r := range retros {
+		ids = append(ids, r.RetrospectiveID)
+	}
+	return ids
+}
+
+func itemIDs(items []*vstore.RetrospectiveItem) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ItemID)
+	}
+	return ids
+}
+
+func commentIDs(comments []*vstore.Comment) []string {
+	var ids []string
+	for _, c := range comments {
+		ids = append(ids, c.CommentID)
+	}
+	return ids
+}
+
+func reactionKeys(reactions []*vstore.Reaction) []string {
+	var keys []string
+	for _, r := range reactions {
+		keys = append(keys, r.UserID+" "+r.Emoji)
+	}
+	return keys
+}
+
+func groupIDs(groups []*vstore.ItemGroup) []string {
+	var ids []string
+	for _, group := range groups {
+		ids = append(ids, group.GroupID)
+	}
+	return ids
+}
+
+func voteIDs(votes []*vstore.Vote) []string {
+	var ids []string
+	for _, v := range votes {
+		ids = append(ids, v.VoteID)
+	}
+	return ids
+}
+
+func actionItemIDs(items []*vstore.ActionItem) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ActionItemID)
+	}
+	return ids
+}
+
+func teamIDs(teams []*vstore.Team) []string {
+	var ids []string
+	for _, team := range teams {
+		ids = append(ids, team.TeamID)
+	}
+	return ids
+}
@@ -0,0 +1,616 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// teamReportThemes is how many of the most voted themes a team report lists
+const teamReportThemes = 10
+
+// teamReport sums up the retrospectives a team completed in a period
+type teamReport struct {
+	TeamName    string
+	From, To    time.Time // To is exclusive
+	Retros      []*teamReportRetro
+	Columns     []*teamReportColumn
+	Themes      []*teamReportTheme
+	ActionItems teamReportActionItems
+	Overdue     []*teamReportOverdue
+}
+
+type teamReportRetro struct {
+	SprintName  string
+	Completed   time.Time
+	Items       int
+	Votes       int
+	ActionItems int
+}
+
+// teamReportColumn counts the items of the columns that share a name
+type teamReportColumn struct {
+	Name   string
+	Color  string
+	Items  int
+	Retros int // retrospectives with items in the column
+}
+
+// teamReportTheme is something the team voted on: similar items and groups,
+// from one or more retrospectives
+type teamReportTheme struct {
+	Title   string // the most voted of its items and groups
+	Color   string
+	Votes   int32
+	Items   int
+	Sprints []string
+	first   int // index of its first candidate, to keep ties in order
+}
+
+// teamReportActionItems counts action items by status
+type teamReportActionItems struct {
+	Total, NotStarted, InProgress, Done, WontDo int
+}
+
+type teamReportOverdue struct {
+	Description string
+	Assignee    string
+	Status      string
+	StatusColor string
+	Due         time.Time
+	SprintName  string
+}
+
+// ExportTeamReport sums up the retrospectives a team completed between
+// start_time and end_time: items per column, the most voted themes, how many
+// action items got done and which are overdue. The period defaults to the
+// three months up to end_time, which defaults to now.
+func (s *RetrospectiveService) ExportTeamReport(ctx context.Context, req *pb.ExportTeamReportRequest) (*pb.ExportTeamReportResponse, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+	now := time.Now()
+	to := now
+	if req.EndTime != nil {
+		to = req.EndTime.AsTime()
+	}
+	from := to.AddDate(0, -3, 0)
+	if req.StartTime != nil {
+		from = req.StartTime.AsTime()
+	}
+	if !from.Before(to) {
+		return nil, ToGRPCError(fmt.Errorf("%w: start_time must be before end_time", ErrInvalidArgument))
+	}
+	switch req.Format {
+	case pb.ExportFormat_EXPORT_FORMAT_UNSPECIFIED, pb.ExportFormat_EXPORT_FORMAT_MARKDOWN,
+		pb.ExportFormat_EXPORT_FORMAT_CSV, pb.ExportFormat_EXPORT_FORMAT_HTML:
+	default:
+		return nil, ToGRPCError(fmt.Errorf("%w: team reports render as Markdown, CSV or HTML", ErrInvalidArgument))
+	}
+
+	team, err := s.teamStore.Get(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := requireTeamMember(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	retros, err := s.completedRetrospectives(req.TeamId, from, to)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	report, err := s.buildTeamReport(team, retros, from, to, getUserIDFromContext(ctx), now)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	var content []byte
+	var filename, contentType string
+	switch req.Format {
+	case pb.ExportFormat_EXPORT_FORMAT_CSV:
+		content, filename, contentType = teamReportCSV(report)
+	case pb.ExportFormat_EXPORT_FORMAT_HTML:
+		content, filename, contentType = teamReportHTML(report)
+	default:
+		content, filename, contentType = teamReportMarkdown(report)
+	}
+	return &pb.ExportTeamReportResponse{
+		Content:     content,
+		Filename:    filename,
+		ContentType: contentType,
+	}, nil
+}
+
+// completedRetrospectives returns the team's completed and archived
+// retrospectives that were completed in [from, to), oldest first
+func (s *RetrospectiveService) completedRetrospectives(teamID string, from, to time.Time) ([]*vstore.Retrospective, error) {
+	statuses := []vstore.RetrospectiveStatus{vstore.RetrospectiveStatusCompleted, vstore.RetrospectiveStatusArchived}
+	var completed []*vstore.Retrospective
+	cursor := ""
+	for {
+		retros, next, hasMore, err := s.retroStore.List(teamID, statuses, cursor, 100)
+		if err != nil {
+			return nil, err
+		}
+		for _, retro := range retros {
+			if at := completedAt(retro); !at.Before(from) && at.Before(to) {
+				completed = append(completed, retro)
+			}
+		}
+		if !hasMore {
+			break
+		}
+		cursor = next
+	}
+	sort.SliceStable(completed, func(i, j int) bool {
+		a, b := completedAt(completed[i]), completedAt(completed[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return completed[i].RetrospectiveID < completed[j].RetrospectiveID
+	})
+	return completed, nil
+}
+
+// completedAt is when retro was completed. Retrospectives completed before
+// completion times were recorded fall back to their last update.
+func completedAt(retro *vstore.Retrospective) time.Time {
+	if retro.CompletedAt.IsZero() {
+		return retro.Updated
+	}
+	return retro.CompletedAt
+}
+
+// themeCandidate is a voted item or group that may make up a theme
+type themeCandidate struct {
+	content string
+	color   string
+	votes   int32
+	sprint  string
+	created time.Time
+}
+
+// buildTeamReport exports each retrospective as userID would see it and sums
+// the exports up
+func (s *RetrospectiveService) buildTeamReport(team *vstore.Team, retros []*vstore.Retrospective, from, to time.Time, userID string, now time.Time) (*teamReport, error) {
+	report := &teamReport{TeamName: team.Name, From: from, To: to}
+	columns := make(map[string]*teamReportColumn)
+	var candidates []*themeCandidate
+
+	for _, retro := range retros {
+		doc, err := s.buildExport(retro, userID)
+		if err != nil {
+			return nil, err
+		}
+		sprint := doc.Retrospective.SprintName
+		summary := &teamReportRetro{SprintName: sprint, Completed: completedAt(retro), ActionItems: len(doc.ActionItems)}
+
+		// Columns are matched by name, as teams rename templates and swap them
+		counted := make(map[string]bool)
+		for _, col := range doc.Columns {
+			key := strings.ToLower(strings.TrimSpace(col.Name))
+			column, ok := columns[key]
+			if !ok {
+				column = &teamReportColumn{Name: col.Name, Color: htmlColor(col.Color)}
+				columns[key] = column
+				report.Columns = append(report.Columns, column)
+			}
+			column.Items += len(col.Items)
+			if len(col.Items) > 0 && !counted[key] {
+				column.Retros++
+				counted[key] = true
+			}
+			summary.Items += len(col.Items)
+
+			for _, item := range col.Items {
+				summary.Votes += int(item.Votes)
+				if item.Votes > 0 {
+					candidates = append(candidates, &themeCandidate{item.Content, column.Color, item.Votes, sprint, item.Created})
+				}
+			}
+			for _, group := range col.Groups {
+				summary.Votes += int(group.Votes)
+				if group.Votes > 0 {
+					candidates = append(candidates, &themeCandidate{group.Title, column.Color, group.Votes, sprint, summary.Completed})
+				}
+			}
+		}
+		report.Retros = append(report.Retros, summary)
+
+		for _, ai := range doc.ActionItems {
+			report.ActionItems.Total++
+			switch ai.Status {
+			case "done":
+				report.ActionItems.Done++
+				continue
+			case "wont_do":
+				report.ActionItems.WontDo++
+				continue
+			case "in_progress":
+				report.ActionItems.InProgress++
+			default:
+				report.ActionItems.NotStarted++
+			}
+			if ai.DueDate != nil && ai.DueDate.Before(now) {
+				status, color := actionItemStatusLabel(ai.Status)
+				report.Overdue = append(report.Overdue, &teamReportOverdue{
+					Description: ai.Description,
+					Assignee:    ai.AssigneeName,
+					Status:      status,
+					StatusColor: color,
+					Due:         *ai.DueDate,
+					SprintName:  sprint,
+				})
+			}
+		}
+	}
+
+	report.Themes = teamThemes(candidates)
+	sort.SliceStable(report.Overdue, func(i, j int) bool {
+		return report.Overdue[i].Due.Before(report.Overdue[j].Due)
+	})
+	return report, nil
+}
+
+// teamThemes clusters similar candidates into themes, as merge suggestions
+// do within a retrospective, and returns the most voted
+func teamThemes(candidates []*themeCandidate) []*teamReportTheme {
+	items := make([]*vstore.RetrospectiveItem, len(candidates))
+	for i, c := range candidates {
+		items[i] = &vstore.RetrospectiveItem{ItemID: strconv.Itoa(i), Content: c.content, Created: c.created}
+	}
+
+	var themes []*teamReportTheme
+	add := func(members []int) {
+		sort.Ints(members)
+		theme := &teamReportTheme{Items: len(members), first: members[0]}
+		var top *themeCandidate
+		seen := make(map[string]bool)
+		for _, i := range members {
+			c := candidates[i]
+			theme.Votes += c.votes
+			if top == nil || c.votes > top.votes {
+				top = c
+			}
+			if !seen[c.sprint] {
+				seen[c.sprint] = true
+				theme.Sprints = append(theme.Sprints, c.sprint)
+			}
+		}
+		theme.Title, theme.Color = top.content, top.color
+		themes = append(themes, theme)
+	}
+
+	clustered := make([]bool, len(candidates))
+	for _, cluster := range findDuplicates(items, defaultMergeThreshold) {
+		members := make([]int, 0, len(cluster.items))
+		for _, item := range cluster.items {
+			i, _ := strconv.Atoi(item.ItemID)
+			members = append(members, i)
+			clustered[i] = true
+		}
+		add(members)
+	}
+	for i := range candidates {
+		if !clustered[i] {
+			add([]int{i})
+		}
+	}
+
+	sort.Slice(themes, func(i, j int) bool {
+		if themes[i].Votes != themes[j].Votes {
+			return themes[i].Votes > themes[j].Votes
+		}
+		return themes[i].first < themes[j].first
+	})
+	if len(themes) > teamReportThemes {
+		themes = themes[:teamReportThemes]
+	}
+	return themes
+}
+
+// Period is the report's date range, with its last day rather than the
+// exclusive end
+func (r *teamReport) Period() string {
+	last := r.To.Add(-time.Nanosecond)
+	return fmt.Sprintf("%s – %s", r.From.Format("January 2, 2006"), last.Format("January 2, 2006"))
+}
+
+func (r *teamReport) filename(ext string) string {
+	last := r.To.Add(-time.Nanosecond)
+	return exportFilename(fmt.Sprintf("%s report %s to %s", r.TeamName, r.From.Format("2006-01-02"), last.Format("2006-01-02")), ext)
+}
+
+// Summary describes how the team's action items are going
+func (a teamReportActionItems) Summary() string {
+	if a.Total == 0 {
+		return "No action items were created"
+	}
+	counted := a.Total - a.WontDo
+	if counted == 0 {
+		return fmt.Sprintf("None of the %s will be done", plural(a.Total, "action item"))
+	}
+	summary := fmt.Sprintf("%d%% complete (%d of %d done)", a.Done*100/counted, a.Done, counted)
+	if a.WontDo > 0 {
+		summary = fmt.Sprintf("%s, not counting %d won't do", summary, a.WontDo)
+	}
+	return summary
+}
+
+// Summary describes the theme's votes, items and sprints
+func (t *teamReportTheme) Summary() string {
+	return fmt.Sprintf("%s · %s · %s", plural(int(t.Votes), "vote"), plural(t.Items, "card"), strings.Join(t.Sprints, ", "))
+}
+
+func (r *teamReport) heading() string {
+	return fmt.Sprintf("%s Retrospective Report", r.TeamName)
+}
+
+func teamReportMarkdown(r *teamReport) ([]byte, string, string) {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("# %s\n\n", r.heading()))
+	buf.WriteString(fmt.Sprintf("**Period:** %s\n", r.Period()))
+	buf.WriteString(fmt.Sprintf("**Retrospectives:** %d\n", len(r.Retros)))
+	buf.WriteString(fmt.Sprintf("**Action items:** %s\n\n", r.ActionItems.Summary()))
+	if len(r.Retros) == 0 {
+		buf.WriteString("_No retrospectives were completed in this period._\n")
+		return buf.Bytes(), r.filename("md"), "text/markdown"
+	}
+
+	buf.WriteString("## Retrospectives\n\n")
+	buf.WriteString("| Sprint | Completed | Items | Votes | Action Items |\n")
+	buf.WriteString("| --- | --- | ---: | ---: | ---: |\n")
+	for _, retro := range r.Retros {
+		buf.WriteString(fmt.Sprintf("| %s | %s | %d | %d | %d |\n",
+			markdownCell(retro.SprintName), retro.Completed.Format("Jan 2, 2006"), retro.Items, retro.Votes, retro.ActionItems))
+	}
+
+	buf.WriteString("\n## Items per Column\n\n")
+	buf.WriteString("| Column | Items | Retrospectives |\n")
+	buf.WriteString("| --- | ---: | ---: |\n")
+	for _, col := range r.Columns {
+		buf.WriteString(fmt.Sprintf("| %s | %d | %d |\n", markdownCell(col.Name), col.Items, col.Retros))
+	}
+
+	buf.WriteString("\n## Top Themes\n\n")
+	if len(r.Themes) == 0 {
+		buf.WriteString("_Nothing was voted on._\n")
+	}
+	for i, theme := range r.Themes {
+		buf.WriteString(fmt.Sprintf("%d. **%s** (%s)\n", i+1, markdownCell(theme.Title), theme.Summary()))
+	}
+
+	buf.WriteString("\n## Action Items\n\n")
+	buf.WriteString("| Status | Action Items |\n")
+	buf.WriteString("| --- | ---: |\n")
+	for _, status := range r.ActionItems.byStatus() {
+		buf.WriteString(fmt.Sprintf("| %s | %d |\n", status.label, status.count))
+	}
+
+	buf.WriteString("\n### Overdue\n\n")
+	if len(r.Overdue) == 0 {
+		buf.WriteString("_No action items are overdue._\n")
+		return buf.Bytes(), r.filename("md"), "text/markdown"
+	}
+	buf.WriteString("| Action | Assignee | Status | Due | Sprint |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, ai := range r.Overdue {
+		buf.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+			markdownCell(ai.Description), markdownCell(exportAssignee(ai.Assignee)), ai.Status, ai.Due.Format("Jan 2, 2006"), markdownCell(ai.SprintName)))
+	}
+	return buf.Bytes(), r.filename("md"), "text/markdown"
+}
+
+// teamReportCSV writes one row per retrospective, column, theme, action item
+// status and overdue action item, told apart by the Type column
+func teamReportCSV(r *teamReport) ([]byte, string, string) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"Type", "Name", "Sprint", "Date", "Items", "Votes", "Action Items", "Retrospectives", "Status", "Assignee"})
+	for _, retro := range r.Retros {
+		writer.Write([]string{"Retrospective", "", csvCell(retro.SprintName), retro.Completed.Format("2006-01-02"),
+			strconv.Itoa(retro.Items), strconv.Itoa(retro.Votes), strconv.Itoa(retro.ActionItems), "", "", ""})
+	}
+	for _, col := range r.Columns {
+		writer.Write([]string{"Column", csvCell(col.Name), "", "", strconv.Itoa(col.Items), "", "", strconv.Itoa(col.Retros), "", ""})
+	}
+	for _, theme := range r.Themes {
+		writer.Write([]string{"Theme", csvCell(theme.Title), csvCell(strings.Join(theme.Sprints, ", ")), "",
+			strconv.Itoa(theme.Items), strconv.Itoa(int(theme.Votes)), "", strconv.Itoa(len(theme.Sprints)), "", ""})
+	}
+	for _, status := range r.ActionItems.byStatus() {
+		writer.Write([]string{"Action Items", "", "", "", "", "", strconv.Itoa(status.count), "", status.label, ""})
+	}
+	for _, ai := range r.Overdue {
+		writer.Write([]string{"Overdue", csvCell(ai.Description), csvCell(ai.SprintName), ai.Due.Format("2006-01-02"),
+			"", "", "", "", ai.Status, csvCell(ai.Assignee)})
+	}
+
+	writer.Flush()
+	return buf.Bytes(), r.filename("csv"), "text/csv"
+}
+
+type teamReportStatus struct {
+	label string
+	color string
+	count int
+}
+
+// byStatus counts action items for each status, in the order they progress
+func (a teamReportActionItems) byStatus() []teamReportStatus {
+	counts := []int{a.NotStarted, a.InProgress, a.Done, a.WontDo}
+	statuses := make([]teamReportStatus, len(counts))
+	for i, name := range []string{"not_started", "in_progress", "done", "wont_do"} {
+		label, color := actionItemStatusLabel(name)
+		statuses[i] = teamReportStatus{label, color, counts[i]}
+	}
+	return statuses
+}
+
+// markdownCell keeps s on one line of a Markdown table
+func markdownCell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
+
+// exportAssignee is how an action item's assignee is shown in a report
+func exportAssignee(name string) string {
+	if name == "" {
+		return "Unassigned"
+	}
+	return name
+}
+
+// htmlTeamReport is what the team report template renders
+type htmlTeamReport struct {
+	*teamReport
+	Title    string
+	Chart    *htmlChart
+	Columns  []*htmlTeamColumn
+	Statuses []*htmlTeamStatus
+	Overdue  []*htmlActionItem
+}
+
+type htmlTeamColumn struct {
+	*teamReportColumn
+	Percent int // share of the items
+}
+
+type htmlTeamStatus struct {
+	Label string
+	Color string
+	Count int
+}
+
+func teamReportHTML(r *teamReport) ([]byte, string, string) {
+	page := &htmlTeamReport{teamReport: r, Title: r.heading()}
+
+	total := 0
+	for _, col := range r.Columns {
+		total += col.Items
+	}
+	for _, col := range r.Columns {
+		column := &htmlTeamColumn{teamReportColumn: col}
+		if total > 0 {
+			column.Percent = col.Items * 100 / total
+		}
+		page.Columns = append(page.Columns, column)
+	}
+
+	entries := make([]htmlChartEntry, len(r.Themes))
+	for i, theme := range r.Themes {
+		entries[i] = htmlChartEntry{Label: theme.Title, Votes: theme.Votes, Color: theme.Color}
+	}
+	page.Chart = newHTMLChart(entries)
+
+	for _, status := range r.ActionItems.byStatus() {
+		page.Statuses = append(page.Statuses, &htmlTeamStatus{status.label, status.color, status.count})
+	}
+	for _, ai := range r.Overdue {
+		page.Overdue = append(page.Overdue, &htmlActionItem{
+			Description: ai.Description,
+			Status:      ai.Status,
+			StatusColor: ai.StatusColor,
+			Assignee:    fmt.Sprintf("%s · %s", exportAssignee(ai.Assignee), ai.SprintName),
+			Due:         ai.Due.Format("Jan 2, 2006"),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTeamReportTemplate.Execute(&buf, page); err != nil {
+		log.Printf("html team report %s: %v", r.TeamName, err)
+	}
+	return buf.Bytes(), r.filename("html"), "text/html"
+}
+
+var htmlTeamReportTemplate = template.Must(template.Must(htmlBase.Clone()).New("team").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "style"}}
+<style>
+.share { height: 8px; border-radius: 4px; }
+.number { text-align: right; white-space: nowrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="muted">{{.Period}}</div>
+<div class="muted">{{len .Retros}} retrospectives · Action items: {{.ActionItems.Summary}}</div>
+{{- if not .Retros}}
+<p class="muted">No retrospectives were completed in this period.</p>
+{{- else}}
+
+<h2>Retrospectives</h2>
+<table>
+<thead><tr><th>Sprint</th><th>Completed</th><th class="number">Items</th><th class="number">Votes</th><th class="number">Action Items</th></tr></thead>
+<tbody>
+{{- range .Retros}}
+<tr><td>{{.SprintName}}</td><td>{{.Completed.Format "Jan 2, 2006"}}</td><td class="number">{{.Items}}</td><td class="number">{{.Votes}}</td><td class="number">{{.ActionItems}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Items per Column</h2>
+<table>
+<thead><tr><th>Column</th><th class="number">Items</th><th class="number">Retrospectives</th><th>Share</th></tr></thead>
+<tbody>
+{{- range .Columns}}
+<tr><td>{{.Name}}</td><td class="number">{{.Items}}</td><td class="number">{{.Retros}}</td><td><div class="share" style="width: {{.Percent}}%; background: {{.Color}}"></div></td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Top Themes</h2>
+{{- if .Chart}}
+{{template "chart" .Chart}}
+<ol>
+{{- range .Themes}}
+<li><strong>{{.Title}}</strong> <span class="muted">{{.Summary}}</span></li>
+{{- end}}
+</ol>
+{{- else}}
+<p class="muted">Nothing was voted on.</p>
+{{- end}}
+
+<h2>Action Items</h2>
+<table>
+<thead><tr><th>Status</th><th class="number">Action Items</th></tr></thead>
+<tbody>
+{{- range .Statuses}}
+<tr><td><span class="status" style="background: {{.Color}}">{{.Label}}</span></td><td class="number">{{.Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Overdue</h2>
+{{- if .Overdue}}
+<table>
+<thead><tr><th>Status</th><th>Action</th><th>Assignee · Sprint</th><th>Due</th></tr></thead>
+<tbody>
+{{- range .Overdue}}
+<tr><td><span class="status" style="background: {{.StatusColor}}">{{.Status}}</span></td><td>{{.Description}}</td><td>{{.Assignee}}</td><td>{{.Due}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p class="muted">No action items are overdue.</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
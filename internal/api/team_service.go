@@ -0,0 +1,375 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// TeamService implements the TeamService gRPC service
+type TeamService struct {
+	pb.UnimplementedTeamServiceServer
+	teamStore  TeamStore
+	retroStore RetrospectiveStore
+}
+
+// NewTeamService creates a new TeamService
+func NewTeamService(
+	teamStore TeamStore,
+	retroStore RetrospectiveStore,
+) *TeamService {
+	return &TeamService{
+		teamStore:  teamStore,
+		retroStore: retroStore,
+	}
+}
+
+// Create creates a new team with the caller as its first admin
+func (s *TeamService) Create(ctx context.Context, req *pb.CreateTeamRequest) (*pb.CreateTeamResponse, error) {
+	if req.Name == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: name is required", ErrInvalidArgument))
+	}
+
+	userID := getUserIDFromContext(ctx)
+	team := &vstore.Team{
+		TeamID:      fmt.Sprintf("TEAM-%d", time.Now().UnixNano()),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   userID,
+		MemberCount: 1,
+	}
+	if err := s.teamStore.Create(team); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	admin := &vstore.TeamMember{
+		TeamID:      team.TeamID,
+		UserID:      userID,
+		DisplayName: getUserNameFromContext(ctx),
+		Role:        vstore.TeamRoleAdmin,
+	}
+	if err := s.teamStore.PutMember(admin); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.CreateTeamResponse{
+		TeamId: team.TeamID,
+		Team:   convertVstoreTeamToPb(team),
+	}, nil
+}
+
+// Get retrieves a team and its members
+func (s *TeamService) Get(ctx context.Context, req *pb.GetTeamRequest) (*pb.GetTeamResponse, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+
+	team, err := s.teamStore.Get(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := requireTeamMember(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	members, err := s.teamStore.ListMembers(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.GetTeamResponse{
+		Team:    convertVstoreTeamToPb(team),
+		Members: convertVstoreTeamMembersToPb(members),
+	}, nil
+}
+
+// Update updates a team's name and description
+func (s *TeamService) Update(ctx context.Context, req *pb.UpdateTeamRequest) (*emptypb.Empty, error) {
+	if req.Team == nil || req.Team.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team is required", ErrInvalidArgument))
+	}
+
+	existing, err := s.teamStore.Get(req.Team.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := requireTeamAdmin(ctx, s.teamStore, existing.TeamID); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	renamed := req.Team.Name != "" && req.Team.Name != existing.Name
+	if req.Team.Name != "" {
+		existing.Name = req.Team.Name
+	}
+	if req.Team.Description != "" {
+		existing.Description = req.Team.Description
+	}
+
+	if err := s.teamStore.Update(existing); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	if renamed {
+		if err := s.renameRetrospectives(existing); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// renameRetrospectives copies the team name onto every retrospective of the team
+func (s *TeamService) renameRetrospectives(team *vstore.Team) error {
+	cursor := ""
+	for {
+		retros, next, hasMore, err := s.retroStore.List(team.TeamID, nil, cursor, 100)
+		if err != nil {
+			return err
+		}
+		for _, retro := range retros {
+			retro.TeamName = team.Name
+			if err := s.retroStore.Update(retro); err != nil {
+				return err
+			}
+		}
+		if !hasMore {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Delete deletes a team and its memberships. Teams that still have
+// retrospectives cannot be deleted, since those would become unreachable.
+func (s *TeamService) Delete(ctx context.Context, req *pb.DeleteTeamRequest) (*emptypb.Empty, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+
+	if _, err := s.teamStore.Get(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := requireTeamAdmin(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	retros, _, _, err := s.retroStore.List(req.TeamId, nil, "", 1)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if len(retros) > 0 {
+		return nil, ToGRPCError(fmt.Errorf("%w: team %s still has retrospectives; delete them first", ErrFailedPrecondition, req.TeamId))
+	}
+
+	if err := s.teamStore.Delete(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// List lists the teams the caller belongs to
+func (s *TeamService) List(ctx context.Context, req *pb.ListTeamsRequest) (*pb.ListTeamsResponse, error) {
+	teams, err := s.teamStore.ListByMember(getUserIDFromContext(ctx))
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	var pbTeams []*pb.Team
+	for _, team := range teams {
+		pbTeams = append(pbTeams, convertVstoreTeamToPb(team))
+	}
+
+	return &pb.ListTeamsResponse{
+		Teams: pbTeams,
+	}, nil
+}
+
+// AddMember adds a user to a team
+func (s *TeamService) AddMember(ctx context.Context, req *pb.AddTeamMemberRequest) (*emptypb.Empty, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+	if req.UserId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: user_id is required", ErrInvalidArgument))
+	}
+
+	if _, err := s.teamStore.Get(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := requireTeamAdmin(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if _, err := s.teamStore.GetMember(req.TeamId, req.UserId); err == nil {
+		return nil, ToGRPCError(fmt.Errorf("%w: user is already a member of this team", ErrAlreadyExists))
+	}
+
+	role := vstore.TeamRole(req.Role)
+	if role == vstore.TeamRoleUnspecified {
+		role = vstore.TeamRoleMember
+	}
+
+	member := &vstore.TeamMember{
+		TeamID:      req.TeamId,
+		UserID:      req.UserId,
+		DisplayName: req.DisplayName,
+		Role:        role,
+	}
+	if err := s.teamStore.PutMember(member); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := s.updateMemberCount(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// UpdateMemberRole changes a member's role
+func (s *TeamService) UpdateMemberRole(ctx context.Context, req *pb.UpdateTeamMemberRoleRequest) (*emptypb.Empty, error) {
+	if req.TeamId == "" || req.UserId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id and user_id are required", ErrInvalidArgument))
+	}
+	if req.Role == pb.TeamRole_TEAM_ROLE_UNSPECIFIED {
+		return nil, ToGRPCError(fmt.Errorf("%w: role is required", ErrInvalidArgument))
+	}
+
+	if _, err := requireTeamAdmin(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	member, err := s.teamStore.GetMember(req.TeamId, req.UserId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	role := vstore.TeamRole(req.Role)
+	if member.Role == vstore.TeamRoleAdmin && role != vstore.TeamRoleAdmin {
+		if err := s.requireAnotherAdmin(req.TeamId, req.UserId); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	member.Role = role
+	if err := s.teamStore.PutMember(member); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RemoveMember removes a user from a team. Admins can remove anyone; members can remove themselves.
+func (s *TeamService) RemoveMember(ctx context.Context, req *pb.RemoveTeamMemberRequest) (*emptypb.Empty, error) {
+	if req.TeamId == "" || req.UserId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id and user_id are required", ErrInvalidArgument))
+	}
+
+	if _, err := s.teamStore.Get(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if req.UserId != getUserIDFromContext(ctx) {
+		if _, err := requireTeamAdmin(ctx, s.teamStore, req.TeamId); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	member, err := s.teamStore.GetMember(req.TeamId, req.UserId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if member.Role == vstore.TeamRoleAdmin {
+		if err := s.requireAnotherAdmin(req.TeamId, req.UserId); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+
+	if err := s.teamStore.RemoveMember(req.TeamId, req.UserId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := s.updateMemberCount(req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListMembers lists the members of a team
+func (s *TeamService) ListMembers(ctx context.Context, req *pb.ListTeamMembersRequest) (*pb.ListTeamMembersResponse, error) {
+	if req.TeamId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: team_id is required", ErrInvalidArgument))
+	}
+
+	if _, err := requireTeamMember(ctx, s.teamStore, req.TeamId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	members, err := s.teamStore.ListMembers(req.TeamId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+
+	return &pb.ListTeamMembersResponse{
+		Members: convertVstoreTeamMembersToPb(members),
+	}, nil
+}
+
+// updateMemberCount sets a team's MemberCount from its member list rather
+// than adjusting the count it read, so concurrent adds and removals cannot
+// leave it off
+func (s *TeamService) updateMemberCount(teamID string) error {
+	members, err := s.teamStore.ListMembers(teamID)
+	if err != nil {
+		return err
+	}
+	team, err := s.teamStore.Get(teamID)
+	if err != nil {
+		return err
+	}
+	team.MemberCount = int32(len(members))
+	return s.teamStore.Update(team)
+}
+
+// requireAnotherAdmin keeps every team with at least one admin
+func (s *TeamService) requireAnotherAdmin(teamID, userID string) error {
+	members, err := s.teamStore.ListMembers(teamID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.UserID != userID && m.Role == vstore.TeamRoleAdmin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: a team must keep at least one admin", ErrInvalidArgument)
+}
+
+func convertVstoreTeamToPb(team *vstore.Team) *pb.Team {
+	return &pb.Team{
+		TeamId:      team.TeamID,
+		Name:        team.Name,
+		Description: team.Description,
+		CreatedBy:   team.CreatedBy,
+		MemberCount: team.MemberCount,
+		Created:     timestamppb.New(team.Created),
+		Updated:     timestamppb.New(team.Updated),
+	}
+}
+
+func convertVstoreTeamMembersToPb(members []*vstore.TeamMember) []*pb.TeamMember {
+	var result []*pb.TeamMember
+	for _, m := range members {
+		result = append(result, &pb.TeamMember{
+			TeamId:      m.TeamID,
+			UserId:      m.UserID,
+			DisplayName: m.DisplayName,
+			Role:        pb.TeamRole(m.Role),
+			Joined:      timestamppb.New(m.Created),
+		})
+	}
+	return result
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// VoteLimits are the voting rules VoteStore.Cast enforces. Columns maps item
+// and group IDs to the column they are in now, since items move after votes
+// are cast on them; a vote on a target missing from it counts in the column
+// it was cast in.
+type VoteLimits struct {
+	Mode                      vstore.VotingMode
+	MaxVotesPerUser           int32
+	MaxVotesPerColumn         int32
+	AllowMultipleVotesPerItem bool
+	Columns                   map[string]string
+}
+
+// voteLimits returns the limits configured on a retrospective
+func voteLimits(cfg *vstore.VotingConfig) VoteLimits {
+	return VoteLimits{
+		Mode:                      votingMode(cfg),
+		MaxVotesPerUser:           cfg.MaxVotesPerUser,
+		MaxVotesPerColumn:         cfg.MaxVotesPerColumn,
+		AllowMultipleVotesPerItem: cfg.AllowMultipleVotesPerItem,
+	}
+}
+
+// votingMode returns the configured mode, defaulting to dot voting
+func votingMode(cfg *vstore.VotingConfig) vstore.VotingMode {
+	if cfg == nil || cfg.Mode == vstore.VotingModeUnspecified {
+		return vstore.VotingModeDot
+	}
+	return cfg.Mode
+}
+
+// voteWeight is what a vote adds to its item's VoteCount: its points in
+// weighted voting and one otherwise
+func voteWeight(vote *vstore.Vote) int32 {
+	if vote.Points > 0 {
+		return vote.Points
+	}
+	return 1
+}
+
+// check validates vote against the votes the same user already cast in the
+// retrospective. In every mode except dot voting a user has one vote per item,
+// so the earlier vote on the item is returned as replaced.
+func (l VoteLimits) check(userVotes []*vstore.Vote, vote *vstore.Vote) (replaced *vstore.Vote, err error) {
+	var others []*vstore.Vote
+	for _, v := range userVotes {
+		if v.ItemID == vote.ItemID && replaced == nil {
+			replaced = v
+			continue
+		}
+		others = append(others, v)
+	}
+
+	switch l.Mode {
+	case vstore.VotingModeWeighted:
+		used, usedInColumn := l.votePoints(others, l.column(vote))
+		if used+vote.Points > l.MaxVotesPerUser {
+			return nil, fmt.Errorf("%w: you have %d of %d points left", ErrVoteLimitExceeded, l.MaxVotesPerUser-used, l.MaxVotesPerUser)
+		}
+		if l.MaxVotesPerColumn > 0 && usedInColumn+vote.Points > l.MaxVotesPerColumn {
+			return nil, fmt.Errorf("%w: you have %d of %d points left in this column", ErrVoteLimitExceeded, l.MaxVotesPerColumn-usedInColumn, l.MaxVotesPerColumn)
+		}
+		return replaced, nil
+
+	case vstore.VotingModeRankedChoice:
+		for _, v := range others {
+			if v.Rank == vote.Rank {
+				return nil, fmt.Errorf("%w: you already ranked another item #%d", ErrAlreadyExists, vote.Rank)
+			}
+		}
+		return replaced, nil
+
+	case vstore.VotingModeFistOfFive:
+		return replaced, nil
+
+	default:
+		if replaced != nil && !l.AllowMultipleVotesPerItem {
+			return nil, fmt.Errorf("%w: you have already voted for this item", ErrAlreadyExists)
+		}
+		used, usedInColumn := l.votePoints(userVotes, l.column(vote))
+		if used >= l.MaxVotesPerUser {
+			return nil, fmt.Errorf("%w: you have used all %d votes", ErrVoteLimitExceeded, l.MaxVotesPerUser)
+		}
+		if l.MaxVotesPerColumn > 0 && usedInColumn >= l.MaxVotesPerColumn {
+			return nil, fmt.Errorf("%w: you have used all %d votes in this column", ErrVoteLimitExceeded, l.MaxVotesPerColumn)
+		}
+		return nil, nil
+	}
+}
+
+// boardColumns maps every item and group in a retrospective to its column
+func boardColumns(itemStore ItemStore, groupStore ItemGroupStore, retroID string) (map[string]string, error) {
+	items, err := itemStore.ListByRetrospective(retroID, "", false)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := groupStore.ListByRetrospective(retroID)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]string, len(items)+len(groups))
+	for _, item := range items {
+		columns[item.ItemID] = item.ColumnID
+	}
+	for _, group := range groups {
+		columns[group.GroupID] = group.ColumnID
+	}
+	return columns, nil
+}
+
+// votePoints sums the weight of votes overall and within columnID
+func (l VoteLimits) votePoints(votes []*vstore.Vote, columnID string) (total, inColumn int32) {
+	for _, v := range votes {
+		total += voteWeight(v)
+		if l.column(v) == columnID {
+			inColumn += voteWeight(v)
+		}
+	}
+	return total, inColumn
+}
+
+// column returns the column the item or group vote is for is in now
+func (l VoteLimits) column(vote *vstore.Vote) string {
+	if columnID, ok := l.Columns[vote.ItemID]; ok {
+		return columnID
+	}
+	return vote.ColumnID
+}
+
+// validateVote checks the value a caller gave a vote in the configured mode and
+// fills in defaults
+func validateVote(cfg *vstore.VotingConfig, vote *vstore.Vote) error {
+	switch votingMode(cfg) {
+	case vstore.VotingModeWeighted:
+		if vote.Points == 0 {
+			vote.Points = 1
+		}
+		if vote.Points < 0 {
+			return fmt.Errorf("%w: points must be positive", ErrInvalidArgument)
+		}
+		vote.Rank, vote.Score = 0, 0
+	case vstore.VotingModeRankedChoice:
+		if vote.Rank < 1 || vote.Rank > cfg.MaxVotesPerUser {
+			return fmt.Errorf("%w: rank must be between 1 and %d", ErrInvalidArgument, cfg.MaxVotesPerUser)
+		}
+		vote.Points, vote.Score = 0, 0
+	case vstore.VotingModeFistOfFive:
+		if vote.Score < 0 || vote.Score > 5 {
+			return fmt.Errorf("%w: score must be between 0 and 5", ErrInvalidArgument)
+		}
+		vote.Points, vote.Rank = 0, 0
+	default:
+		vote.Points, vote.Rank, vote.Score = 0, 0, 0
+	}
+	return nil
+}
+
+// votesRemaining returns how many votes (or points, or ranks) a user has left
+func votesRemaining(cfg *vstore.VotingConfig, userVotes []*vstore.Vote) int32 {
+	var used int32
+	switch votingMode(cfg) {
+	case vstore.VotingModeFistOfFive:
+		return 0
+	case vstore.VotingModeWeighted:
+		used, _ = VoteLimits{}.votePoints(userVotes, "")
+	default:
+		used = int32(len(userVotes))
+	}
+	if used >= cfg.MaxVotesPerUser {
+		return 0
+	}
+	return cfg.MaxVotesPerUser - used
+}
+
+// voteTarget is something votes are tallied for: a standalone item or a group.
+// count is its stored VoteCount, which is all imported votes have.
+type voteTarget struct {
+	id    string
+	group bool
+	votes []*vstore.Vote
+	count int32
+}
+
+// itemTally is one item's or group's result under the retrospective's voting mode
+type itemTally struct {
+	itemID    string // the group's ID when group is set
+	group     bool
+	voteCount int32   // votes (dot), points (weighted), ballots ranking it or scores given
+	score     float64 // what items are ranked by
+	rank      int32
+	userVoted bool
+	userValue int32 // the caller's votes, points, rank or score
+}
+
+// tallyVotes computes every target's result and ranks the targets. Dot and
+// weighted voting rank by votes or points, ranked-choice ballots are counted
+// with a Borda count (a top choice earns MaxVotesPerUser points, the next one
+// less), and fist of five ranks by the average agreement score. A target
+// without vote records, such as an imported item, counts its stored VoteCount
+// as that many dot votes.
+func tallyVotes(cfg *vstore.VotingConfig, targets []*voteTarget, userID string) []*itemTally {
+	mode := votingMode(cfg)
+	tallies := make([]*itemTally, 0, len(targets))
+	for _, target := range targets {
+		t := &itemTally{itemID: target.id, group: target.group}
+		var total float64
+		for _, v := range target.votes {
+			mine := v.UserID == userID
+			if mine {
+				t.userVoted = true
+			}
+			switch mode {
+			case vstore.VotingModeWeighted:
+				t.voteCount += voteWeight(v)
+				total += float64(voteWeight(v))
+				if mine {
+					t.userValue += voteWeight(v)
+				}
+			case vstore.VotingModeRankedChoice:
+				t.voteCount++
+				if v.Rank >= 1 && v.Rank <= cfg.MaxVotesPerUser {
+					total += float64(cfg.MaxVotesPerUser - v.Rank + 1)
+				}
+				if mine {
+					t.userValue = v.Rank
+				}
+			case vstore.VotingModeFistOfFive:
+				t.voteCount++
+				total += float64(v.Score)
+				if mine {
+					t.userValue = v.Score
+				}
+			default:
+				t.voteCount++
+				total++
+				if mine {
+					t.userValue++
+				}
+			}
+		}
+		t.score = total
+		if mode == vstore.VotingModeFistOfFive && t.voteCount > 0 {
+			t.score = total / float64(t.voteCount)
+		}
+		if len(target.votes) == 0 && target.count > 0 {
+			t.voteCount = target.count
+			t.score = float64(target.count)
+		}
+		tallies = append(tallies, t)
+	}
+
+	// Rank by score descending; tied items share a rank
+	ranked := append([]*itemTally(nil), tallies...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	for i, t := range ranked {
+		if i > 0 && t.score == ranked[i-1].score {
+			t.rank = ranked[i-1].rank
+		} else {
+			t.rank = int32(i + 1)
+		}
+	}
+	return tallies
+}
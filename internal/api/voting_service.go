@@ -3,7 +3,6 @@ package api
 import (
 	"context"
 	"fmt"
-	"sort"
 	"time"
 
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -15,186 +14,275 @@ import (
 // VotingService implements the VotingService gRPC service
 type VotingService struct {
 	pb.UnimplementedVotingServiceServer
-	voteStore  *InMemoryVoteStore
-	itemStore  *InMemoryItemStore
-	retroStore *InMemoryRetrospectiveStore
+	voteStore        VoteStore
+	itemStore        ItemStore
+	groupStore       ItemGroupStore
+	retroStore       RetrospectiveStore
+	participantStore ParticipantStore
+	teamStore        TeamStore
 }
 
 // NewVotingService creates a new VotingService
 func NewVotingService(
-	voteStore *InMemoryVoteStore,
-	itemStore *InMemoryItemStore,
-	retroStore *InMemoryRetrospectiveStore,
+	voteStore VoteStore,
+	itemStore ItemStore,
+	groupStore ItemGroupStore,
+	retroStore RetrospectiveStore,
+	participantStore ParticipantStore,
+	teamStore TeamStore,
 ) *VotingService {
 	return &VotingService{
-		voteStore:  voteStore,
-		itemStore:  itemStore,
-		retroStore: retroStore,
+		voteStore:        voteStore,
+		itemStore:        itemStore,
+		groupStore:       groupStore,
+		retroStore:       retroStore,
+		participantStore: participantStore,
+		teamStore:        teamStore,
 	}
 }
 
-// CastVote casts a vote for an item
+// CastVote casts a vote for an item or an item group
 func (s *VotingService) CastVote(ctx context.Context, req *pb.CastVoteRequest) (*emptypb.Empty, error) {
 	if req.RetrospectiveId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
-	if req.ItemId == "" {
-		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	if (req.ItemId == "") == (req.GroupId == "") {
+		return nil, ToGRPCError(fmt.Errorf("%w: exactly one of item_id and group_id is required", ErrInvalidArgument))
 	}
 
 	// Get retrospective to check voting config
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
+	if err := requireContributor(ctx, s.participantStore, member, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
+	}
 
 	// Verify retrospective is in voting phase
 	if retro.Status != vstore.RetrospectiveStatusVoting && retro.Status != vstore.RetrospectiveStatusActive {
 		return nil, ToGRPCError(fmt.Errorf("%w: voting is not currently allowed", ErrInvalidStatus))
 	}
 
-	// Verify item exists and belongs to this retrospective
-	item, err := s.itemStore.Get(req.ItemId)
-	if err != nil {
-		return nil, ToGRPCError(err)
-	}
-	if item.RetrospectiveID != req.RetrospectiveId {
-		return nil, ToGRPCError(fmt.Errorf("%w: item does not belong to this retrospective", ErrInvalidArgument))
-	}
-
 	userID := getUserIDFromContext(ctx)
 
-	// Check if user has already voted for this item
-	existingVote, _ := s.voteStore.GetByUserAndItem(req.RetrospectiveId, req.ItemId, userID)
-	if existingVote != nil && !retro.VotingConfig.AllowMultipleVotesPerItem {
-		return nil, ToGRPCError(fmt.Errorf("%w: you have already voted for this item", ErrAlreadyExists))
-	}
-
-	// Check vote limit
-	currentVotes, _ := s.voteStore.CountByUser(req.RetrospectiveId, userID)
-	if int32(currentVotes) >= retro.VotingConfig.MaxVotesPerUser {
-		return nil, ToGRPCError(fmt.Errorf("%w: you have used all %d votes", ErrVoteLimitExceeded, retro.VotingConfig.MaxVotesPerUser))
-	}
-
-	// Create vote
-	voteID := fmt.Sprintf("VOTE-%d", time.Now().UnixNano())
 	vote := &vstore.Vote{
-		VoteID:          voteID,
+		VoteID:          fmt.Sprintf("VOTE-%d", time.Now().UnixNano()),
 		RetrospectiveID: req.RetrospectiveId,
-		ItemID:          req.ItemId,
 		UserID:          userID,
+		Points:          req.Points,
+		Rank:            req.Rank,
+		Score:           req.Score,
 	}
-
-	if err := s.voteStore.Create(vote); err != nil {
+	if err := s.setVoteTarget(vote, req.ItemId, req.GroupId); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := validateVote(retro.VotingConfig, vote); err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	// Increment vote count on item
-	if err := s.itemStore.IncrementVoteCount(req.ItemId); err != nil {
-		// Rollback vote creation
-		s.voteStore.Delete(voteID)
+	// Check the vote limits and record the vote in one store operation
+	limits := voteLimits(retro.VotingConfig)
+	if limits.MaxVotesPerColumn > 0 {
+		if limits.Columns, err = boardColumns(s.itemStore, s.groupStore, req.RetrospectiveId); err != nil {
+			return nil, ToGRPCError(err)
+		}
+	}
+	voteCount, err := s.voteStore.Cast(vote, limits)
+	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
+	// Broadcast the new vote count
+	BroadcastVoteCast(req.RetrospectiveId, req.ItemId, req.GroupId, visibleVoteCount(retro, voteCount), visibleVoterID(retro, userID))
+
 	return &emptypb.Empty{}, nil
 }
 
-// RemoveVote removes a vote from an item
+// setVoteTarget points vote at the item or group being voted for. Grouped
+// items are voted on through their group.
+func (s *VotingService) setVoteTarget(vote *vstore.Vote, itemID, groupID string) error {
+	if groupID != "" {
+		group, err := s.groupStore.Get(groupID)
+		if err != nil {
+			return err
+		}
+		if group.RetrospectiveID != vote.RetrospectiveID {
+			return fmt.Errorf("%w: group does not belong to this retrospective", ErrInvalidArgument)
+		}
+		vote.ItemID = group.GroupID
+		vote.GroupVote = true
+		vote.ColumnID = group.ColumnID
+		return nil
+	}
+
+	// Verify item exists and belongs to this retrospective
+	item, err := s.itemStore.Get(itemID)
+	if err != nil {
+		return err
+	}
+	if item.RetrospectiveID != vote.RetrospectiveID {
+		return fmt.Errorf("%w: item does not belong to this retrospective", ErrInvalidArgument)
+	}
+	if item.GroupID != "" {
+		return fmt.Errorf("%w: item is in a group; vote for the group instead", ErrInvalidArgument)
+	}
+	vote.ItemID = item.ItemID
+	vote.ColumnID = item.ColumnID
+	return nil
+}
+
+// RemoveVote removes a vote from an item or an item group
 func (s *VotingService) RemoveVote(ctx context.Context, req *pb.RemoveVoteRequest) (*emptypb.Empty, error) {
 	if req.RetrospectiveId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
-	if req.ItemId == "" {
-		return nil, ToGRPCError(fmt.Errorf("%w: item_id is required", ErrInvalidArgument))
+	if (req.ItemId == "") == (req.GroupId == "") {
+		return nil, ToGRPCError(fmt.Errorf("%w: exactly one of item_id and group_id is required", ErrInvalidArgument))
+	}
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireContributor(ctx, s.participantStore, member, req.RetrospectiveId); err != nil {
+		return nil, ToGRPCError(err)
 	}
 
 	userID := getUserIDFromContext(ctx)
 
-	// Find the vote
-	vote, err := s.voteStore.GetByUserAndItem(req.RetrospectiveId, req.ItemId, userID)
-	if err != nil {
-		return nil, ToGRPCError(fmt.Errorf("%w: you have not voted for this item", ErrNotFound))
+	// Group votes are stored under the group's ID
+	targetID := req.ItemId
+	if req.GroupId != "" {
+		targetID = req.GroupId
 	}
 
-	// Delete vote
-	if err := s.voteStore.Delete(vote.VoteID); err != nil {
+	// Delete the vote and decrement the target's count in one store operation
+	voteCount, err := s.voteStore.Retract(req.RetrospectiveId, targetID, userID)
+	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	// Decrement vote count on item
-	s.itemStore.DecrementVoteCount(req.ItemId)
+	// Broadcast the new vote count
+	BroadcastVoteRemoved(req.RetrospectiveId, req.ItemId, req.GroupId, visibleVoteCount(retro, voteCount), visibleVoterID(retro, userID))
 
 	return &emptypb.Empty{}, nil
 }
 
-// GetVoteSummary gets vote summary for all items in a retrospective
+// GetVoteSummary gets the vote summary of every group and standalone item in a retrospective
 func (s *VotingService) GetVoteSummary(ctx context.Context, req *pb.GetVoteSummaryRequest) (*pb.GetVoteSummaryResponse, error) {
 	if req.RetrospectiveId == "" {
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	userID := getUserIDFromContext(ctx)
+	retro, _, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
 
-	// Get all items
-	items, err := s.itemStore.ListByRetrospective(req.RetrospectiveId, "", false)
+	tallies, err := s.tally(retro, getUserIDFromContext(ctx))
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	// Get user's votes
-	userVotes, _ := s.voteStore.ListByUser(req.RetrospectiveId, userID)
-	userVotedItems := make(map[string]bool)
-	for _, vote := range userVotes {
-		userVotedItems[vote.ItemID] = true
+	// Under blind voting callers only see their own votes until the results are revealed
+	hidden := votesHidden(retro)
+	var summaries []*pb.VoteSummary
+	totalVotes := int32(0)
+	for _, t := range tallies {
+		summary := convertTallyToPb(t)
+		if hidden {
+			summary.VoteCount, summary.Rank, summary.Score = 0, 0, 0
+		}
+		summaries = append(summaries, summary)
+		totalVotes += summary.VoteCount
 	}
 
-	// Create summaries and sort by vote count for ranking
-	type itemVote struct {
-		itemID    string
-		voteCount int32
+	return &pb.GetVoteSummaryResponse{
+		Summaries:     summaries,
+		TotalVotes:    totalVotes,
+		Mode:          pb.VotingMode(votingMode(retro.VotingConfig)),
+		ResultsHidden: hidden,
+	}, nil
+}
+
+// RevealResults reveals the tallies of a blind vote to everyone in the retrospective
+func (s *VotingService) RevealResults(ctx context.Context, req *pb.RevealVoteResultsRequest) (*emptypb.Empty, error) {
+	if req.RetrospectiveId == "" {
+		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
-	var itemVotes []itemVote
-	for _, item := range items {
-		itemVotes = append(itemVotes, itemVote{
-			itemID:    item.ItemID,
-			voteCount: item.VoteCount,
-		})
-	}
-
-	// Sort by vote count descending
-	sort.Slice(itemVotes, func(i, j int) bool {
-		return itemVotes[i].voteCount > itemVotes[j].voteCount
-	})
-
-	// Create rank map
-	rankMap := make(map[string]int32)
-	currentRank := int32(1)
-	lastVoteCount := int32(-1)
-	for i, iv := range itemVotes {
-		if iv.voteCount != lastVoteCount {
-			currentRank = int32(i + 1)
-			lastVoteCount = iv.voteCount
+
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if err := requireFacilitator(ctx, member, retro, "reveal vote results"); err != nil {
+		return nil, ToGRPCError(err)
+	}
+	if retro.VotingConfig == nil || !retro.VotingConfig.BlindVoting {
+		return nil, ToGRPCError(fmt.Errorf("%w: blind voting is not enabled for this retrospective", ErrInvalidArgument))
+	}
+
+	if retro.VotesRevealedAt.IsZero() {
+		retro.VotesRevealedAt = time.Now()
+		if err := s.retroStore.Update(retro); err != nil {
+			return nil, ToGRPCError(err)
 		}
-		rankMap[iv.itemID] = currentRank
 	}
 
-	// Build response
+	tallies, err := s.tally(retro, "")
+	if err != nil {
+		return nil, ToGRPCError(err)
+	}
 	var summaries []*pb.VoteSummary
 	totalVotes := int32(0)
-	for _, item := range items {
-		summaries = append(summaries, &pb.VoteSummary{
-			ItemId:           item.ItemID,
-			VoteCount:        item.VoteCount,
-			Rank:             rankMap[item.ItemID],
-			CurrentUserVoted: userVotedItems[item.ItemID],
-		})
-		totalVotes += item.VoteCount
+	for _, t := range tallies {
+		summaries = append(summaries, convertTallyToPb(t))
+		totalVotes += t.voteCount
 	}
 
-	return &pb.GetVoteSummaryResponse{
-		Summaries:  summaries,
-		TotalVotes: totalVotes,
-	}, nil
+	BroadcastVoteResultsRevealed(retro.RetrospectiveID, summaries, totalVotes, getUserIDFromContext(ctx))
+
+	return &emptypb.Empty{}, nil
+}
+
+// tally computes the result of every group and standalone item in retro as
+// seen by userID. Votes cast on an item before it joined a group count
+// towards the group.
+func (s *VotingService) tally(retro *vstore.Retrospective, userID string) ([]*itemTally, error) {
+	groups, err := s.groupStore.ListByRetrospective(retro.RetrospectiveID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.itemStore.ListByRetrospective(retro.RetrospectiveID, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*voteTarget, 0, len(groups)+len(items))
+	groupTargets := make(map[string]*voteTarget)
+	for _, group := range groups {
+		votes, err := s.voteStore.ListByItem(retro.RetrospectiveID, group.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		target := &voteTarget{id: group.GroupID, group: true, votes: votes, count: group.VoteCount}
+		groupTargets[group.GroupID] = target
+		targets = append(targets, target)
+	}
+	for _, item := range items {
+		votes, err := s.voteStore.ListByItem(retro.RetrospectiveID, item.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if target, ok := groupTargets[item.GroupID]; ok {
+			target.votes = append(target.votes, votes...)
+			target.count += item.VoteCount
+			continue
+		}
+		targets = append(targets, &voteTarget{id: item.ItemID, votes: votes, count: item.VoteCount})
+	}
+	return tallyVotes(retro.VotingConfig, targets, userID), nil
 }
 
 // GetUserVotes gets a user's votes in a retrospective
@@ -203,40 +291,97 @@ func (s *VotingService) GetUserVotes(ctx context.Context, req *pb.GetUserVotesRe
 		return nil, ToGRPCError(fmt.Errorf("%w: retrospective_id is required", ErrInvalidArgument))
 	}
 
-	userID := req.UserId
-	if userID == "" {
-		userID = getUserIDFromContext(ctx)
-	}
-
 	// Get retrospective for vote limit
-	retro, err := s.retroStore.Get(req.RetrospectiveId)
+	retro, member, err := getRetroForMember(ctx, s.retroStore, s.teamStore, req.RetrospectiveId)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
+	userID := getUserIDFromContext(ctx)
+	if req.UserId != "" && req.UserId != userID {
+		if err := canViewVotesOf(ctx, member, retro); err != nil {
+			return nil, ToGRPCError(err)
+		}
+		userID = req.UserId
+	}
+
 	// Get user's votes
 	votes, err := s.voteStore.ListByUser(req.RetrospectiveId, userID)
 	if err != nil {
 		return nil, ToGRPCError(err)
 	}
 
-	var votedItemIDs []string
+	var votedItemIDs, votedGroupIDs []string
 	for _, vote := range votes {
-		votedItemIDs = append(votedItemIDs, vote.ItemID)
-	}
-
-	votesCast := int32(len(votes))
-	votesRemaining := retro.VotingConfig.MaxVotesPerUser - votesCast
-	if votesRemaining < 0 {
-		votesRemaining = 0
+		if vote.GroupVote {
+			votedGroupIDs = append(votedGroupIDs, vote.ItemID)
+		} else {
+			votedItemIDs = append(votedItemIDs, vote.ItemID)
+		}
 	}
 
 	return &pb.GetUserVotesResponse{
 		Summary: &pb.UserVoteSummary{
 			UserId:         userID,
-			VotesCast:      votesCast,
-			VotesRemaining: votesRemaining,
+			VotesCast:      int32(len(votes)),
+			VotesRemaining: votesRemaining(retro.VotingConfig, votes),
 			VotedItemIds:   votedItemIDs,
+			VotedGroupIds:  votedGroupIDs,
 		},
 	}, nil
 }
+
+// votesHidden reports whether blind voting still hides retro's tallies
+func votesHidden(retro *vstore.Retrospective) bool {
+	return retro.VotingConfig != nil && retro.VotingConfig.BlindVoting && retro.VotesRevealedAt.IsZero()
+}
+
+// visibleVoteCount returns the vote count callers may see on an item of retro
+func visibleVoteCount(retro *vstore.Retrospective, count int32) int32 {
+	if votesHidden(retro) {
+		return 0
+	}
+	return count
+}
+
+// anonymousVoting reports whether voter identities are hidden in retro
+func anonymousVoting(retro *vstore.Retrospective) bool {
+	return retro.VotingConfig != nil && retro.VotingConfig.AnonymousVoting
+}
+
+// visibleVoterID returns the voter's ID, or nothing when voting is anonymous
+func visibleVoterID(retro *vstore.Retrospective, userID string) string {
+	if anonymousVoting(retro) {
+		return ""
+	}
+	return userID
+}
+
+// canViewVotesOf checks whether the caller may see another user's votes. Only
+// the facilitator or a team admin can, and never when voting is anonymous or
+// blind voting results are still hidden.
+func canViewVotesOf(ctx context.Context, member *vstore.TeamMember, retro *vstore.Retrospective) error {
+	if anonymousVoting(retro) {
+		return fmt.Errorf("%w: votes are anonymous in this retrospective", ErrPermissionDenied)
+	}
+	if votesHidden(retro) {
+		return fmt.Errorf("%w: vote results have not been revealed", ErrPermissionDenied)
+	}
+	return requireFacilitator(ctx, member, retro, "see another user's votes")
+}
+
+func convertTallyToPb(t *itemTally) *pb.VoteSummary {
+	summary := &pb.VoteSummary{
+		VoteCount:        t.voteCount,
+		Rank:             t.rank,
+		CurrentUserVoted: t.userVoted,
+		Score:            t.score,
+		CurrentUserValue: t.userValue,
+	}
+	if t.group {
+		summary.GroupId = t.itemID
+	} else {
+		summary.ItemId = t.itemID
+	}
+	return summary
+}
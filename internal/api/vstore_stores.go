@@ -0,0 +1,1311 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// vstore kind names, matching the "name" of each schema in vstore.AllSchemas
+const (
+	kindRetrospective = "Retrospective"
+	kindItem          = "RetrospectiveItem"
+	kindVote          = "Vote"
+	kindVoteBallot    = "VoteBallot"
+	kindActionItem    = "ActionItem"
+	kindParticipant   = "Participant"
+	kindTeam          = "Team"
+	kindTeamMember    = "TeamMember"
+	kindPhaseTimer    = "PhaseTimer"
+	kindItemGroup     = "ItemGroup"
+	kindComment       = "Comment"
+	kindReaction      = "Reaction"
+)
+
+// NewVStoreStores creates a set of stores backed by client
+func NewVStoreStores(client vstore.Client) Stores {
+	return Stores{
+		Retrospectives: NewVStoreRetrospectiveStore(client),
+		Items:          NewVStoreItemStore(client),
+		Votes:          NewVStoreVoteStore(client),
+		ActionItems:    NewVStoreActionItemStore(client),
+		Participants:   NewVStoreParticipantStore(client),
+		Teams:          NewVStoreTeamStore(client),
+		PhaseTimers:    NewVStorePhaseTimerStore(client),
+		ItemGroups:     NewVStoreItemGroupStore(client),
+		Comments:       NewVStoreCommentStore(client),
+		Reactions:      NewVStoreReactionStore(client),
+	}
+}
+
+var (
+	_ RetrospectiveStore = (*VStoreRetrospectiveStore)(nil)
+	_ ItemStore          = (*VStoreItemStore)(nil)
+	_ VoteStore          = (*VStoreVoteStore)(nil)
+	_ ActionItemStore    = (*VStoreActionItemStore)(nil)
+	_ ParticipantStore   = (*VStoreParticipantStore)(nil)
+	_ TeamStore          = (*VStoreTeamStore)(nil)
+	_ PhaseTimerStore    = (*VStorePhaseTimerStore)(nil)
+	_ ItemGroupStore     = (*VStoreItemGroupStore)(nil)
+	_ CommentStore       = (*VStoreCommentStore)(nil)
+	_ ReactionStore      = (*VStoreReactionStore)(nil)
+)
+
+// VStoreRetrospectiveStore provides vstore-backed storage for retrospectives
+type VStoreRetrospectiveStore struct {
+	client vstore.Client
+}
+
+func NewVStoreRetrospectiveStore(client vstore.Client) *VStoreRetrospectiveStore {
+	return &VStoreRetrospectiveStore{client: client}
+}
+
+func (s *VStoreRetrospectiveStore) Create(retro *vstore.Retrospective) error {
+	retro.Created = time.Now()
+	retro.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindRetrospective, retro))
+}
+
+func (s *VStoreRetrospectiveStore) Get(id string) (*vstore.Retrospective, error) {
+	// The key is team_id + retrospective_id, so look the retrospective up by its key part
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindRetrospective,
+		Filters:  []vstore.Filter{{Field: "retrospective_id", Value: id}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	retro := &vstore.Retrospective{}
+	if err := result.Entities[0].Decode(retro); err != nil {
+		return nil, err
+	}
+	return retro, nil
+}
+
+func (s *VStoreRetrospectiveStore) Update(retro *vstore.Retrospective) error {
+	retro.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindRetrospective, retro))
+}
+
+func (s *VStoreRetrospectiveStore) Delete(id string) error {
+	retro, err := s.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindRetrospective, []string{retro.TeamID, retro.RetrospectiveID}))
+}
+
+func (s *VStoreRetrospectiveStore) List(teamID string, statuses []vstore.RetrospectiveStatus, cursor string, pageSize int) ([]*vstore.Retrospective, string, bool, error) {
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var keyPrefix []string
+	if teamID != "" {
+		keyPrefix = []string{teamID}
+	}
+
+	// A single status (or none) maps directly onto one by_status/by_created query
+	if len(statuses) <= 1 {
+		q := &vstore.Query{
+			Kind:       kindRetrospective,
+			KeyPrefix:  keyPrefix,
+			OrderBy:    "by_created",
+			Descending: true,
+			Cursor:     cursor,
+			PageSize:   pageSize,
+		}
+		if len(statuses) == 1 {
+			q.Filters = []vstore.Filter{{Field: "status", Value: statuses[0]}}
+		}
+		result, err := s.client.Query(context.Background(), q)
+		if err != nil {
+			return nil, "", false, fromVStoreError(err)
+		}
+		retros, err := decodeEntities[vstore.Retrospective](result.Entities)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return retros, result.NextCursor, result.HasMore, nil
+	}
+
+	// Several statuses: query the by_status index once per status and merge
+	var retros []*vstore.Retrospective
+	for _, status := range statuses {
+		result, err := s.client.Query(context.Background(), &vstore.Query{
+			Kind:      kindRetrospective,
+			KeyPrefix: keyPrefix,
+			Filters:   []vstore.Filter{{Field: "status", Value: status}},
+		})
+		if err != nil {
+			return nil, "", false, fromVStoreError(err)
+		}
+		decoded, err := decodeEntities[vstore.Retrospective](result.Entities)
+		if err != nil {
+			return nil, "", false, err
+		}
+		retros = append(retros, decoded...)
+	}
+	sort.SliceStable(retros, func(i, j int) bool {
+		return retros[i].Created.After(retros[j].Created)
+	})
+
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			return nil, "", false, fmt.Errorf("%w: invalid cursor", ErrInvalidArgument)
+		}
+	}
+	if offset > len(retros) {
+		offset = len(retros)
+	}
+	retros = retros[offset:]
+	if len(retros) > pageSize {
+		return retros[:pageSize], strconv.Itoa(offset + pageSize), true, nil
+	}
+	return retros, "", false, nil
+}
+
+// VStoreItemStore provides vstore-backed storage for retrospective items
+type VStoreItemStore struct {
+	client vstore.Client
+}
+
+func NewVStoreItemStore(client vstore.Client) *VStoreItemStore {
+	return &VStoreItemStore{client: client}
+}
+
+func (s *VStoreItemStore) Create(item *vstore.RetrospectiveItem) error {
+	item.Created = time.Now()
+	item.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindItem, item))
+}
+
+func (s *VStoreItemStore) Get(id string) (*vstore.RetrospectiveItem, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindItem,
+		Filters:  []vstore.Filter{{Field: "item_id", Value: id}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	item := &vstore.RetrospectiveItem{}
+	if err := result.Entities[0].Decode(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *VStoreItemStore) Update(item *vstore.RetrospectiveItem) error {
+	item.Updated = time.Now()
+	stored := &vstore.RetrospectiveItem{}
+	err := s.client.Transaction(context.Background(), kindItem, []string{item.RetrospectiveID, item.ItemID}, stored, func(exists bool) error {
+		voteCount := stored.VoteCount
+		*stored = *item
+		if exists {
+			stored.VoteCount = voteCount
+		}
+		return nil
+	})
+	if err != nil {
+		return fromVStoreError(err)
+	}
+	item.VoteCount = stored.VoteCount
+	return nil
+}
+
+func (s *VStoreItemStore) Delete(id string) error {
+	item, err := s.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindItem, []string{item.RetrospectiveID, item.ItemID}))
+}
+
+func (s *VStoreItemStore) ListByRetrospective(retroID string, columnID string, sortByVotes bool) ([]*vstore.RetrospectiveItem, error) {
+	q := &vstore.Query{
+		Kind:      kindItem,
+		KeyPrefix: []string{retroID},
+	}
+	if columnID != "" {
+		q.Filters = []vstore.Filter{{Field: "column_id", Value: columnID}}
+	}
+	result, err := s.client.Query(context.Background(), q)
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	items, err := decodeEntities[vstore.RetrospectiveItem](result.Entities)
+	if err != nil {
+		return nil, err
+	}
+	sortItems(items, sortByVotes)
+	return items, nil
+}
+
+func (s *VStoreItemStore) IncrementVoteCount(itemID string) error {
+	item, err := s.Get(itemID)
+	if err != nil {
+		return err
+	}
+	_, err = adjustItemVoteCount(s.client, item.RetrospectiveID, itemID, 1)
+	return err
+}
+
+func (s *VStoreItemStore) DecrementVoteCount(itemID string) error {
+	item, err := s.Get(itemID)
+	if err != nil {
+		return err
+	}
+	_, err = adjustItemVoteCount(s.client, item.RetrospectiveID, itemID, -1)
+	return err
+}
+
+// adjustItemVoteCount changes an item's VoteCount in a transaction so
+// concurrent votes never lose updates, returning the new count
+func adjustItemVoteCount(client vstore.Client, retroID, itemID string, delta int32) (int32, error) {
+	item := &vstore.RetrospectiveItem{}
+	err := client.Transaction(context.Background(), kindItem, []string{retroID, itemID}, item, func(exists bool) error {
+		if !exists {
+			return ErrNotFound
+		}
+		item.VoteCount += delta
+		if item.VoteCount < 0 {
+			item.VoteCount = 0
+		}
+		item.Updated = time.Now()
+		return nil
+	})
+	if err != nil {
+		return 0, fromVStoreError(err)
+	}
+	return item.VoteCount, nil
+}
+
+// adjustGroupVoteCount is adjustItemVoteCount for item groups
+func adjustGroupVoteCount(client vstore.Client, retroID, groupID string, delta int32) (int32, error) {
+	group := &vstore.ItemGroup{}
+	err := client.Transaction(context.Background(), kindItemGroup, []string{retroID, groupID}, group, func(exists bool) error {
+		if !exists {
+			return ErrNotFound
+		}
+		group.VoteCount += delta
+		if group.VoteCount < 0 {
+			group.VoteCount = 0
+		}
+		group.Updated = time.Now()
+		return nil
+	})
+	if err != nil {
+		return 0, fromVStoreError(err)
+	}
+	return group.VoteCount, nil
+}
+
+// adjustVoteTargetCount changes the VoteCount of the item or group vote is for
+func adjustVoteTargetCount(client vstore.Client, vote *vstore.Vote, delta int32) (int32, error) {
+	if vote.GroupVote {
+		return adjustGroupVoteCount(client, vote.RetrospectiveID, vote.ItemID, delta)
+	}
+	return adjustItemVoteCount(client, vote.RetrospectiveID, vote.ItemID, delta)
+}
+
+// VStoreVoteStore provides vstore-backed storage for votes
+type VStoreVoteStore struct {
+	client vstore.Client
+}
+
+func NewVStoreVoteStore(client vstore.Client) *VStoreVoteStore {
+	return &VStoreVoteStore{client: client}
+}
+
+// pendingVoteTimeout is how long a cast or retraction may stay pending on a
+// ballot before the next ballot update assumes its request failed and reverts it
+const pendingVoteTimeout = time.Minute
+
+// Cast commits the vote against the user's ballot first: the ballot
+// transaction is what enforces the limits under concurrent requests. The vote
+// record and the item count follow, and are reverted along with the ballot if
+// either fails. Until then the vote stays pending, so it cannot be retracted
+// or replaced before its record and count exist.
+func (s *VStoreVoteStore) Cast(vote *vstore.Vote, limits VoteLimits) (int32, error) {
+	ctx := context.Background()
+	var target interface{} = &vstore.RetrospectiveItem{}
+	kind := kindItem
+	if vote.GroupVote {
+		target, kind = &vstore.ItemGroup{}, kindItemGroup
+	}
+	if err := s.client.Get(ctx, kind, []string{vote.RetrospectiveID, vote.ItemID}, target); err != nil {
+		return 0, fromVStoreError(err)
+	}
+
+	pending := &vstore.PendingVote{Vote: vote, Started: time.Now()}
+	err := s.updateBallot(vote.RetrospectiveID, vote.UserID, func(ballot *vstore.VoteBallot) error {
+		replaced, err := limits.check(ballot.Votes, vote)
+		if err != nil {
+			return err
+		}
+		if replaced != nil && isPending(ballot, replaced) {
+			return fmt.Errorf("%w: your previous vote on this item is still being recorded", ErrConflict)
+		}
+		pending.Replaced = replaced
+		ballot.Votes = append(withoutVote(ballot.Votes, replaced), vote)
+		ballot.Pending = append(ballot.Pending, pending)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.writeCast(pending)
+	if err != nil {
+		if rerr := s.revertPending(vote.RetrospectiveID, vote.UserID, pending, false); rerr != nil {
+			return 0, fmt.Errorf("%w (reverting the vote: %v)", err, rerr)
+		}
+		return 0, err
+	}
+	if err := s.finishPending(vote.RetrospectiveID, vote.UserID, pending); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// writeCast writes the vote record in place of the one it replaces and moves
+// the difference in weight onto the count of the item or group
+func (s *VStoreVoteStore) writeCast(pending *vstore.PendingVote) (int32, error) {
+	vote, replaced := pending.Vote, pending.Replaced
+	if err := s.Create(vote); err != nil {
+		return 0, err
+	}
+	delta := voteWeight(vote)
+	if replaced != nil {
+		if err := s.deleteVote(replaced); err != nil {
+			return 0, err
+		}
+		delta -= voteWeight(replaced)
+	}
+	return adjustVoteTargetCount(s.client, vote, delta)
+}
+
+// Retract marks the vote pending on the ballot before deleting its record and
+// count, and drops it from the ballot once both are gone
+func (s *VStoreVoteStore) Retract(retroID, itemID, userID string) (int32, error) {
+	var pending *vstore.PendingVote
+	err := s.updateBallot(retroID, userID, func(ballot *vstore.VoteBallot) error {
+		pending = nil
+		for _, v := range ballot.Votes {
+			if v.ItemID == itemID && !isPending(ballot, v) {
+				pending = &vstore.PendingVote{Vote: v, Retract: true, Started: time.Now()}
+				break
+			}
+		}
+		if pending == nil {
+			return errNoVote
+		}
+		ballot.Pending = append(ballot.Pending, pending)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.writeRetract(pending)
+	if err != nil {
+		if rerr := s.revertPending(retroID, userID, pending, false); rerr != nil {
+			return 0, fmt.Errorf("%w (restoring the vote: %v)", err, rerr)
+		}
+		return 0, err
+	}
+	if err := s.finishPending(retroID, userID, pending); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// writeRetract deletes the vote record and takes its weight off the count.
+// A vote whose item or group is already gone leaves no count behind.
+func (s *VStoreVoteStore) writeRetract(pending *vstore.PendingVote) (int32, error) {
+	if err := s.deleteVote(pending.Vote); err != nil {
+		return 0, err
+	}
+	count, err := adjustVoteTargetCount(s.client, pending.Vote, -voteWeight(pending.Vote))
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	return count, err
+}
+
+// MoveVotes moves one vote at a time: the vote is re-pointed on its ballot,
+// rewritten under its new key and its weight moved between the item counts
+func (s *VStoreVoteStore) MoveVotes(retroID, fromItemID, toItemID string) (int32, error) {
+	ctx := context.Background()
+	from := &vstore.RetrospectiveItem{}
+	if err := s.client.Get(ctx, kindItem, []string{retroID, fromItemID}, from); err != nil {
+		return 0, fromVStoreError(err)
+	}
+	to := &vstore.RetrospectiveItem{}
+	if err := s.client.Get(ctx, kindItem, []string{retroID, toItemID}, to); err != nil {
+		return 0, fromVStoreError(err)
+	}
+	votes, err := s.ListByItem(retroID, fromItemID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := to.VoteCount
+	for _, vote := range votes {
+		if vote.GroupVote {
+			continue
+		}
+		moved := *vote
+		moved.ItemID = toItemID
+		moved.ColumnID = to.ColumnID
+		err := s.updateBallot(retroID, vote.UserID, func(ballot *vstore.VoteBallot) error {
+			ballot.Votes = append(withoutVote(ballot.Votes, vote), &moved)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if err := s.client.Put(ctx, kindVote, &moved); err != nil {
+			return 0, fromVStoreError(err)
+		}
+		if err := s.client.Delete(ctx, kindVote, []string{retroID, fromItemID, vote.UserID, vote.VoteID}); err != nil {
+			return 0, fromVStoreError(err)
+		}
+		if _, err := adjustItemVoteCount(s.client, retroID, fromItemID, -voteWeight(vote)); err != nil {
+			return 0, err
+		}
+		if count, err = adjustItemVoteCount(s.client, retroID, toItemID, voteWeight(vote)); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// updateBallot applies fn to the user's ballot in a transaction, after
+// reverting the casts and retractions left pending by requests that failed.
+// Ballots missing for votes cast before they existed are rebuilt from the
+// vote records.
+func (s *VStoreVoteStore) updateBallot(retroID, userID string, fn func(*vstore.VoteBallot) error) error {
+	if err := s.reconcileBallot(retroID, userID); err != nil {
+		return err
+	}
+	return s.writeBallot(retroID, userID, fn)
+}
+
+// writeBallot is updateBallot without the reconciliation
+func (s *VStoreVoteStore) writeBallot(retroID, userID string, fn func(*vstore.VoteBallot) error) error {
+	ballot := &vstore.VoteBallot{}
+	err := s.client.Transaction(context.Background(), kindVoteBallot, []string{retroID, userID}, ballot, func(exists bool) error {
+		if !exists {
+			votes, err := s.ListByUser(retroID, userID)
+			if err != nil {
+				return err
+			}
+			ballot.RetrospectiveID = retroID
+			ballot.UserID = userID
+			ballot.Votes = votes
+		}
+		if err := fn(ballot); err != nil {
+			return err
+		}
+		ballot.Updated = time.Now()
+		return nil
+	})
+	return fromVStoreError(err)
+}
+
+// reconcileBallot reverts the pending entries of the user's ballot that are
+// older than pendingVoteTimeout. Whether their change reached the item count
+// is unknown, so the count is recounted from the vote records.
+func (s *VStoreVoteStore) reconcileBallot(retroID, userID string) error {
+	ballot := &vstore.VoteBallot{}
+	err := s.client.Get(context.Background(), kindVoteBallot, []string{retroID, userID}, ballot)
+	if err != nil {
+		if errors.Is(fromVStoreError(err), ErrNotFound) {
+			return nil
+		}
+		return fromVStoreError(err)
+	}
+	for _, pending := range ballot.Pending {
+		if time.Since(pending.Started) < pendingVoteTimeout {
+			continue
+		}
+		if err := s.revertPending(retroID, userID, pending, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revertPending undoes the writes of a cast or retraction that did not finish
+// and restores the ballot to what it was before. Counts are only recounted
+// when recount is set: a request reverting its own change knows the count
+// was never adjusted.
+func (s *VStoreVoteStore) revertPending(retroID, userID string, pending *vstore.PendingVote, recount bool) error {
+	ctx := context.Background()
+	vote, replaced := pending.Vote, pending.Replaced
+	if pending.Retract {
+		if err := s.client.Put(ctx, kindVote, vote); err != nil {
+			return fromVStoreError(err)
+		}
+	} else {
+		if err := s.deleteVote(vote); err != nil {
+			return err
+		}
+		if replaced != nil {
+			if err := s.client.Put(ctx, kindVote, replaced); err != nil {
+				return fromVStoreError(err)
+			}
+		}
+	}
+	if recount {
+		if err := s.recountVoteTarget(vote); err != nil {
+			return err
+		}
+	}
+	return s.writeBallot(retroID, userID, func(ballot *vstore.VoteBallot) error {
+		if !hasPending(ballot, pending) {
+			return nil
+		}
+		ballot.Pending = withoutPending(ballot.Pending, vote)
+		if !pending.Retract {
+			ballot.Votes = withoutVote(ballot.Votes, vote)
+			if replaced != nil {
+				ballot.Votes = append(withoutVote(ballot.Votes, replaced), replaced)
+			}
+		}
+		return nil
+	})
+}
+
+// finishPending drops a cast or retraction whose writes all succeeded from
+// the ballot's pending entries, and a retracted vote from its votes
+func (s *VStoreVoteStore) finishPending(retroID, userID string, pending *vstore.PendingVote) error {
+	return s.writeBallot(retroID, userID, func(ballot *vstore.VoteBallot) error {
+		if !hasPending(ballot, pending) {
+			return nil
+		}
+		ballot.Pending = withoutPending(ballot.Pending, pending.Vote)
+		if pending.Retract {
+			ballot.Votes = withoutVote(ballot.Votes, pending.Vote)
+		}
+		return nil
+	})
+}
+
+// recountVoteTarget sets the VoteCount of the item or group vote is for to the
+// weight of its vote records. Votes whose item or group is gone have no count.
+func (s *VStoreVoteStore) recountVoteTarget(vote *vstore.Vote) error {
+	votes, err := s.ListByItem(vote.RetrospectiveID, vote.ItemID)
+	if err != nil {
+		return err
+	}
+	var total int32
+	for _, v := range votes {
+		if v.GroupVote == vote.GroupVote {
+			total += voteWeight(v)
+		}
+	}
+
+	ctx := context.Background()
+	key := []string{vote.RetrospectiveID, vote.ItemID}
+	if vote.GroupVote {
+		group := &vstore.ItemGroup{}
+		err = s.client.Transaction(ctx, kindItemGroup, key, group, func(exists bool) error {
+			if !exists {
+				return ErrNotFound
+			}
+			group.VoteCount = total
+			group.Updated = time.Now()
+			return nil
+		})
+	} else {
+		item := &vstore.RetrospectiveItem{}
+		err = s.client.Transaction(ctx, kindItem, key, item, func(exists bool) error {
+			if !exists {
+				return ErrNotFound
+			}
+			item.VoteCount = total
+			item.Updated = time.Now()
+			return nil
+		})
+	}
+	if err = fromVStoreError(err); errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// deleteVote deletes the record of vote
+func (s *VStoreVoteStore) deleteVote(vote *vstore.Vote) error {
+	key := []string{vote.RetrospectiveID, vote.ItemID, vote.UserID, vote.VoteID}
+	return fromVStoreError(s.client.Delete(context.Background(), kindVote, key))
+}
+
+// isPending reports whether vote is still being cast or retracted
+func isPending(ballot *vstore.VoteBallot, vote *vstore.Vote) bool {
+	for _, p := range ballot.Pending {
+		if p.Vote.VoteID == vote.VoteID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPending reports whether the ballot still holds the pending entry
+func hasPending(ballot *vstore.VoteBallot, pending *vstore.PendingVote) bool {
+	for _, p := range ballot.Pending {
+		if p.Vote.VoteID == pending.Vote.VoteID && p.Retract == pending.Retract && p.Started.Equal(pending.Started) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutPending returns pending minus the entry for vote
+func withoutPending(pending []*vstore.PendingVote, vote *vstore.Vote) []*vstore.PendingVote {
+	result := make([]*vstore.PendingVote, 0, len(pending))
+	for _, p := range pending {
+		if p.Vote.VoteID != vote.VoteID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// withoutVote returns votes minus the one with vote's ID
+func withoutVote(votes []*vstore.Vote, vote *vstore.Vote) []*vstore.Vote {
+	if vote == nil {
+		return votes
+	}
+	result := make([]*vstore.Vote, 0, len(votes))
+	for _, v := range votes {
+		if v.VoteID != vote.VoteID {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (s *VStoreVoteStore) Create(vote *vstore.Vote) error {
+	vote.Created = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindVote, vote))
+}
+
+func (s *VStoreVoteStore) Delete(voteID string) error {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindVote,
+		Filters:  []vstore.Filter{{Field: "vote_id", Value: voteID}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindVote, result.Entities[0].Key))
+}
+
+func (s *VStoreVoteStore) GetByUserAndItem(retroID, itemID, userID string) (*vstore.Vote, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindVote,
+		KeyPrefix: []string{retroID, itemID, userID},
+		PageSize:  1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	vote := &vstore.Vote{}
+	if err := result.Entities[0].Decode(vote); err != nil {
+		return nil, err
+	}
+	return vote, nil
+}
+
+func (s *VStoreVoteStore) CountByUser(retroID, userID string) (int, error) {
+	votes, err := s.ListByUser(retroID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(votes), nil
+}
+
+func (s *VStoreVoteStore) ListByUser(retroID, userID string) ([]*vstore.Vote, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindVote,
+		KeyPrefix: []string{retroID},
+		Filters:   []vstore.Filter{{Field: "user_id", Value: userID}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Vote](result.Entities)
+}
+
+func (s *VStoreVoteStore) ListByItem(retroID, itemID string) ([]*vstore.Vote, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindVote,
+		KeyPrefix: []string{retroID},
+		Filters:   []vstore.Filter{{Field: "item_id", Value: itemID}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Vote](result.Entities)
+}
+
+// VStoreActionItemStore provides vstore-backed storage for action items
+type VStoreActionItemStore struct {
+	client vstore.Client
+}
+
+func NewVStoreActionItemStore(client vstore.Client) *VStoreActionItemStore {
+	return &VStoreActionItemStore{client: client}
+}
+
+func (s *VStoreActionItemStore) Create(item *vstore.ActionItem) error {
+	item.Created = time.Now()
+	item.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindActionItem, item))
+}
+
+func (s *VStoreActionItemStore) Get(id string) (*vstore.ActionItem, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindActionItem,
+		Filters:  []vstore.Filter{{Field: "action_item_id", Value: id}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	item := &vstore.ActionItem{}
+	if err := result.Entities[0].Decode(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *VStoreActionItemStore) Update(item *vstore.ActionItem) error {
+	item.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindActionItem, item))
+}
+
+func (s *VStoreActionItemStore) Delete(id string) error {
+	item, err := s.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindActionItem, []string{item.TeamID, item.ActionItemID}))
+}
+
+func (s *VStoreActionItemStore) ListByRetrospective(retroID string) ([]*vstore.ActionItem, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:    kindActionItem,
+		Filters: []vstore.Filter{{Field: "retrospective_id", Value: retroID}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.ActionItem](result.Entities)
+}
+
+func (s *VStoreActionItemStore) ListByTeam(teamID string, includeCompleted bool) ([]*vstore.ActionItem, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindActionItem,
+		KeyPrefix: []string{teamID},
+		OrderBy:   "by_due_date",
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	items, err := decodeEntities[vstore.ActionItem](result.Entities)
+	if err != nil {
+		return nil, err
+	}
+	if includeCompleted {
+		return items, nil
+	}
+	var results []*vstore.ActionItem
+	for _, item := range items {
+		if item.Status == vstore.ActionItemStatusDone || item.Status == vstore.ActionItemStatusWontDo {
+			continue
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+
+func (s *VStoreActionItemStore) ListByAssignee(teamID, assigneeID string) ([]*vstore.ActionItem, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindActionItem,
+		KeyPrefix: []string{teamID},
+		Filters:   []vstore.Filter{{Field: "assignee_id", Value: assigneeID}},
+		OrderBy:   "by_due_date",
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.ActionItem](result.Entities)
+}
+
+// VStoreParticipantStore provides vstore-backed storage for participants.
+// Entries expire through the Participant schema TTL; every write refreshes it.
+type VStoreParticipantStore struct {
+	client vstore.Client
+}
+
+func NewVStoreParticipantStore(client vstore.Client) *VStoreParticipantStore {
+	return &VStoreParticipantStore{client: client}
+}
+
+func (s *VStoreParticipantStore) Join(participant *vstore.Participant) error {
+	participant.JoinedAt = time.Now()
+	participant.LastActive = time.Now()
+	participant.IsOnline = true
+	return fromVStoreError(s.client.Put(context.Background(), kindParticipant, participant))
+}
+
+func (s *VStoreParticipantStore) Leave(retroID, userID string) error {
+	p, err := s.Get(retroID, userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.IsOnline = false
+	p.LastActive = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindParticipant, p))
+}
+
+func (s *VStoreParticipantStore) Heartbeat(retroID, userID string) error {
+	p, err := s.Get(retroID, userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.LastActive = time.Now()
+	p.IsOnline = true
+	return fromVStoreError(s.client.Put(context.Background(), kindParticipant, p))
+}
+
+func (s *VStoreParticipantStore) Get(retroID, userID string) (*vstore.Participant, error) {
+	p := &vstore.Participant{}
+	if err := s.client.Get(context.Background(), kindParticipant, []string{retroID, userID}, p); err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return p, nil
+}
+
+func (s *VStoreParticipantStore) ListByRetrospective(retroID string) ([]*vstore.Participant, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindParticipant,
+		KeyPrefix: []string{retroID},
+		Filters:   []vstore.Filter{{Field: "is_online", Value: true}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Participant](result.Entities)
+}
+
+// VStoreTeamStore provides vstore-backed storage for teams and members
+type VStoreTeamStore struct {
+	client vstore.Client
+}
+
+func NewVStoreTeamStore(client vstore.Client) *VStoreTeamStore {
+	return &VStoreTeamStore{client: client}
+}
+
+func (s *VStoreTeamStore) Create(team *vstore.Team) error {
+	team.Created = time.Now()
+	team.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindTeam, team))
+}
+
+func (s *VStoreTeamStore) Get(id string) (*vstore.Team, error) {
+	team := &vstore.Team{}
+	if err := s.client.Get(context.Background(), kindTeam, []string{id}, team); err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return team, nil
+}
+
+func (s *VStoreTeamStore) Update(team *vstore.Team) error {
+	team.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindTeam, team))
+}
+
+func (s *VStoreTeamStore) Delete(id string) error {
+	members, err := s.ListMembers(id)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if err := s.RemoveMember(id, m.UserID); err != nil {
+			return err
+		}
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindTeam, []string{id}))
+}
+
+func (s *VStoreTeamStore) ListByMember(userID string) ([]*vstore.Team, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:    kindTeamMember,
+		Filters: []vstore.Filter{{Field: "user_id", Value: userID}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	members, err := decodeEntities[vstore.TeamMember](result.Entities)
+	if err != nil {
+		return nil, err
+	}
+	var teams []*vstore.Team
+	for _, m := range members {
+		team, err := s.Get(m.TeamID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+func (s *VStoreTeamStore) PutMember(member *vstore.TeamMember) error {
+	member.Created = time.Now()
+	if existing, err := s.GetMember(member.TeamID, member.UserID); err == nil {
+		member.Created = existing.Created
+	}
+	member.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindTeamMember, member))
+}
+
+func (s *VStoreTeamStore) GetMember(teamID, userID string) (*vstore.TeamMember, error) {
+	member := &vstore.TeamMember{}
+	if err := s.client.Get(context.Background(), kindTeamMember, []string{teamID, userID}, member); err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return member, nil
+}
+
+func (s *VStoreTeamStore) RemoveMember(teamID, userID string) error {
+	return fromVStoreError(s.client.Delete(context.Background(), kindTeamMember, []string{teamID, userID}))
+}
+
+func (s *VStoreTeamStore) ListMembers(teamID string) ([]*vstore.TeamMember, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindTeamMember,
+		KeyPrefix: []string{teamID},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.TeamMember](result.Entities)
+}
+
+// VStorePhaseTimerStore provides vstore-backed storage for phase timers
+type VStorePhaseTimerStore struct {
+	client vstore.Client
+}
+
+func NewVStorePhaseTimerStore(client vstore.Client) *VStorePhaseTimerStore {
+	return &VStorePhaseTimerStore{client: client}
+}
+
+func (s *VStorePhaseTimerStore) Put(timer *vstore.PhaseTimer) error {
+	timer.Created = time.Now()
+	if existing, err := s.Get(timer.RetrospectiveID); err == nil {
+		timer.Created = existing.Created
+	}
+	timer.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindPhaseTimer, timer))
+}
+
+func (s *VStorePhaseTimerStore) Get(retroID string) (*vstore.PhaseTimer, error) {
+	timer := &vstore.PhaseTimer{}
+	if err := s.client.Get(context.Background(), kindPhaseTimer, []string{retroID}, timer); err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return timer, nil
+}
+
+func (s *VStorePhaseTimerStore) Delete(retroID string) error {
+	return fromVStoreError(s.client.Delete(context.Background(), kindPhaseTimer, []string{retroID}))
+}
+
+func (s *VStorePhaseTimerStore) List() ([]*vstore.PhaseTimer, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{Kind: kindPhaseTimer})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.PhaseTimer](result.Entities)
+}
+
+// VStoreItemGroupStore provides vstore-backed storage for item groups
+type VStoreItemGroupStore struct {
+	client vstore.Client
+}
+
+func NewVStoreItemGroupStore(client vstore.Client) *VStoreItemGroupStore {
+	return &VStoreItemGroupStore{client: client}
+}
+
+func (s *VStoreItemGroupStore) Create(group *vstore.ItemGroup) error {
+	group.Created = time.Now()
+	group.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindItemGroup, group))
+}
+
+func (s *VStoreItemGroupStore) Get(id string) (*vstore.ItemGroup, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindItemGroup,
+		Filters:  []vstore.Filter{{Field: "group_id", Value: id}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	group := &vstore.ItemGroup{}
+	if err := result.Entities[0].Decode(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *VStoreItemGroupStore) Update(group *vstore.ItemGroup) error {
+	group.Updated = time.Now()
+	stored := &vstore.ItemGroup{}
+	err := s.client.Transaction(context.Background(), kindItemGroup, []string{group.RetrospectiveID, group.GroupID}, stored, func(exists bool) error {
+		voteCount := stored.VoteCount
+		*stored = *group
+		if exists {
+			stored.VoteCount = voteCount
+		}
+		return nil
+	})
+	if err != nil {
+		return fromVStoreError(err)
+	}
+	group.VoteCount = stored.VoteCount
+	return nil
+}
+
+func (s *VStoreItemGroupStore) Delete(id string) error {
+	group, err := s.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindItemGroup, []string{group.RetrospectiveID, group.GroupID}))
+}
+
+func (s *VStoreItemGroupStore) ListByRetrospective(retroID string) ([]*vstore.ItemGroup, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindItemGroup,
+		KeyPrefix: []string{retroID},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.ItemGroup](result.Entities)
+}
+
+// VStoreCommentStore provides vstore-backed storage for comments
+type VStoreCommentStore struct {
+	client vstore.Client
+}
+
+func NewVStoreCommentStore(client vstore.Client) *VStoreCommentStore {
+	return &VStoreCommentStore{client: client}
+}
+
+func (s *VStoreCommentStore) Create(comment *vstore.Comment) error {
+	comment.Created = time.Now()
+	comment.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindComment, comment))
+}
+
+func (s *VStoreCommentStore) Get(id string) (*vstore.Comment, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:     kindComment,
+		Filters:  []vstore.Filter{{Field: "comment_id", Value: id}},
+		PageSize: 1,
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	if len(result.Entities) == 0 {
+		return nil, ErrNotFound
+	}
+	comment := &vstore.Comment{}
+	if err := result.Entities[0].Decode(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *VStoreCommentStore) Update(comment *vstore.Comment) error {
+	comment.Updated = time.Now()
+	return fromVStoreError(s.client.Put(context.Background(), kindComment, comment))
+}
+
+func (s *VStoreCommentStore) Delete(id string) error {
+	comment, err := s.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fromVStoreError(s.client.Delete(context.Background(), kindComment, []string{comment.RetrospectiveID, comment.CommentID}))
+}
+
+func (s *VStoreCommentStore) ListByItem(itemID string) ([]*vstore.Comment, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:    kindComment,
+		Filters: []vstore.Filter{{Field: "item_id", Value: itemID}},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Comment](result.Entities)
+}
+
+func (s *VStoreCommentStore) ListByRetrospective(retroID string) ([]*vstore.Comment, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindComment,
+		KeyPrefix: []string{retroID},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Comment](result.Entities)
+}
+
+// VStoreReactionStore provides vstore-backed storage for reactions
+type VStoreReactionStore struct {
+	client vstore.Client
+}
+
+func NewVStoreReactionStore(client vstore.Client) *VStoreReactionStore {
+	return &VStoreReactionStore{client: client}
+}
+
+// Add writes the reaction in a transaction so two concurrent adds of the same
+// reaction cannot both succeed
+func (s *VStoreReactionStore) Add(reaction *vstore.Reaction) error {
+	key := []string{reaction.RetrospectiveID, reaction.ItemID, reaction.UserID, reaction.Emoji}
+	stored := &vstore.Reaction{}
+	err := s.client.Transaction(context.Background(), kindReaction, key, stored, func(exists bool) error {
+		if exists {
+			return errReacted
+		}
+		*stored = *reaction
+		stored.Created = time.Now()
+		return nil
+	})
+	if err != nil {
+		return fromVStoreError(err)
+	}
+	reaction.Created = stored.Created
+	return nil
+}
+
+func (s *VStoreReactionStore) Remove(retroID, itemID, userID, emoji string) error {
+	ctx := context.Background()
+	key := []string{retroID, itemID, userID, emoji}
+	if err := s.client.Get(ctx, kindReaction, key, &vstore.Reaction{}); err != nil {
+		if errors.Is(err, vstore.ErrNotFound) {
+			return errNoReaction
+		}
+		return fromVStoreError(err)
+	}
+	return fromVStoreError(s.client.Delete(ctx, kindReaction, key))
+}
+
+func (s *VStoreReactionStore) ListByItem(retroID, itemID string) ([]*vstore.Reaction, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindReaction,
+		KeyPrefix: []string{retroID, itemID},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Reaction](result.Entities)
+}
+
+func (s *VStoreReactionStore) ListByRetrospective(retroID string) ([]*vstore.Reaction, error) {
+	result, err := s.client.Query(context.Background(), &vstore.Query{
+		Kind:      kindReaction,
+		KeyPrefix: []string{retroID},
+	})
+	if err != nil {
+		return nil, fromVStoreError(err)
+	}
+	return decodeEntities[vstore.Reaction](result.Entities)
+}
+
+func decodeEntities[T any](entities []*vstore.Entity) ([]*T, error) {
+	var results []*T
+	for _, entity := range entities {
+		model := new(T)
+		if err := entity.Decode(model); err != nil {
+			return nil, err
+		}
+		results = append(results, model)
+	}
+	return results, nil
+}
+
+func fromVStoreError(err error) error {
+	if errors.Is(err, vstore.ErrNotFound) {
+		return ErrNotFound
+	}
+	if errors.Is(err, vstore.ErrConflict) {
+		return ErrConflict
+	}
+	return err
+}
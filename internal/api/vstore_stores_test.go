@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/vstore"
+)
+
+// TestVStoreVotesRevertStalePending leaves casts and retractions pending the
+// way a request failing halfway does and checks the next ballot update
+// reverts them instead of leaving the voter stuck
+func TestVStoreVotesRevertStalePending(t *testing.T) {
+	ctx := context.Background()
+	client := vstore.NewLocalClient()
+	stores := api.NewVStoreStores(client)
+	for _, id := range []string{"ITEM-1", "ITEM-2"} {
+		item := &vstore.RetrospectiveItem{ItemID: id, RetrospectiveID: "RETRO-1", ColumnID: "went_well", Content: id}
+		if err := stores.Items.Create(item); err != nil {
+			t.Fatalf("Items.Create: %v", err)
+		}
+	}
+	limits := api.VoteLimits{Mode: vstore.VotingModeDot, MaxVotesPerUser: 2}
+	cast := func(voteID, itemID string) error {
+		_, err := stores.Votes.Cast(&vstore.Vote{VoteID: voteID, RetrospectiveID: "RETRO-1", ItemID: itemID, UserID: "u1"}, limits)
+		return err
+	}
+	// leavePending marks vote pending on u1's ballot as of two minutes ago
+	leavePending := func(vote *vstore.Vote, retract bool) {
+		t.Helper()
+		ballot := &vstore.VoteBallot{}
+		if err := client.Get(ctx, "VoteBallot", []string{"RETRO-1", "u1"}, ballot); err != nil {
+			t.Fatalf("Get ballot: %v", err)
+		}
+		ballot.Pending = append(ballot.Pending, &vstore.PendingVote{Vote: vote, Retract: retract, Started: time.Now().Add(-2 * time.Minute)})
+		if err := client.Put(ctx, "VoteBallot", ballot); err != nil {
+			t.Fatalf("Put ballot: %v", err)
+		}
+	}
+	assertCount := func(itemID string, want int32) {
+		t.Helper()
+		item, err := stores.Items.Get(itemID)
+		if err != nil {
+			t.Fatalf("Items.Get: %v", err)
+		}
+		if item.VoteCount != want {
+			t.Errorf("%s VoteCount = %d, want %d", itemID, item.VoteCount, want)
+		}
+	}
+
+	// A cast whose request failed before clearing its pending entry is
+	// reverted, so the voter can vote on the item again
+	if err := cast("VOTE-1", "ITEM-1"); err != nil {
+		t.Fatalf("Cast: %v", err)
+	}
+	vote, err := stores.Votes.GetByUserAndItem("RETRO-1", "ITEM-1", "u1")
+	if err != nil {
+		t.Fatalf("GetByUserAndItem: %v", err)
+	}
+	leavePending(vote, false)
+	if _, err := stores.Votes.Retract("RETRO-1", "ITEM-1", "u1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Retract of a reverted cast error = %v, want ErrNotFound", err)
+	}
+	assertCount("ITEM-1", 0)
+	if err := cast("VOTE-2", "ITEM-1"); err != nil {
+		t.Errorf("Cast after the stale cast was reverted: %v", err)
+	}
+	assertCount("ITEM-1", 1)
+
+	// A retraction whose request failed after deleting the record is
+	// reverted, so the vote is back and can be retracted again
+	vote, err = stores.Votes.GetByUserAndItem("RETRO-1", "ITEM-1", "u1")
+	if err != nil {
+		t.Fatalf("GetByUserAndItem: %v", err)
+	}
+	leavePending(vote, true)
+	if err := client.Delete(ctx, "Vote", []string{"RETRO-1", "ITEM-1", "u1", vote.VoteID}); err != nil {
+		t.Fatalf("Delete vote: %v", err)
+	}
+	if err := cast("VOTE-3", "ITEM-2"); err != nil {
+		t.Fatalf("Cast: %v", err)
+	}
+	if _, err := stores.Votes.GetByUserAndItem("RETRO-1", "ITEM-1", "u1"); err != nil {
+		t.Errorf("GetByUserAndItem after the stale retraction was reverted: %v", err)
+	}
+	assertCount("ITEM-1", 1)
+	count, err := stores.Votes.Retract("RETRO-1", "ITEM-1", "u1")
+	if err != nil {
+		t.Fatalf("Retract after the stale retraction was reverted: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Retract returned count %d, want 0", count)
+	}
+}
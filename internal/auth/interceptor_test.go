@@ -0,0 +1,116 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vendasta/retrospective/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizations are the authorization headers the interceptors must reject;
+// an empty value sends no header at all
+func authorizations(t *testing.T) map[string]string {
+	return map[string]string{
+		"no header":     "",
+		"basic":         "Basic YWxpY2U6c2VjcmV0",
+		"no token":      "Bearer ",
+		"bad signature": "Bearer " + signHMAC(t, "other-secret", validClaims()),
+		"expired":       "Bearer " + signHMAC(t, testSecret, with(jwt.MapClaims{"exp": int64(1)})),
+	}
+}
+
+func incoming(authorization string) context.Context {
+	if authorization == "" {
+		return context.Background()
+	}
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	intercept := auth.UnaryServerInterceptor(newHMACVerifier(t))
+	info := &grpc.UnaryServerInfo{FullMethod: "/retrospective.v1.RetrospectiveService/Get"}
+	var caller *auth.Principal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		caller, _ = auth.FromContext(ctx)
+		return "ok", nil
+	}
+
+	for name, authorization := range authorizations(t) {
+		t.Run(name, func(t *testing.T) {
+			caller = nil
+			_, err := intercept(incoming(authorization), nil, info, handler)
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("error = %v, want Unauthenticated", err)
+			}
+			if caller != nil {
+				t.Error("handler ran without a valid token")
+			}
+		})
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		resp, err := intercept(incoming("bearer "+signHMAC(t, testSecret, validClaims())), nil, info, handler)
+		if err != nil || resp != "ok" {
+			t.Fatalf("intercept = %v, %v", resp, err)
+		}
+		if caller == nil || caller.UserID != "alice" {
+			t.Errorf("handler saw principal %+v, want alice", caller)
+		}
+	})
+
+	t.Run("health check", func(t *testing.T) {
+		caller = nil
+		health := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+		if _, err := intercept(context.Background(), nil, health, handler); err != nil {
+			t.Errorf("health check without a token: %v", err)
+		}
+	})
+}
+
+// serverStream is a grpc.ServerStream with only a context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	intercept := auth.StreamServerInterceptor(newHMACVerifier(t))
+	info := &grpc.StreamServerInfo{FullMethod: "/retrospective.v1.RealtimeService/Subscribe", IsServerStream: true}
+	var caller *auth.Principal
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		caller, _ = auth.FromContext(ss.Context())
+		return nil
+	}
+
+	for name, authorization := range authorizations(t) {
+		t.Run(name, func(t *testing.T) {
+			caller = nil
+			err := intercept(nil, &serverStream{ctx: incoming(authorization)}, info, handler)
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("error = %v, want Unauthenticated", err)
+			}
+			if caller != nil {
+				t.Error("handler ran without a valid token")
+			}
+		})
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		err := intercept(nil, &serverStream{ctx: incoming("Bearer " + signHMAC(t, testSecret, validClaims()))}, info, handler)
+		if err != nil {
+			t.Fatalf("intercept: %v", err)
+		}
+		if caller == nil || caller.UserID != "alice" {
+			t.Errorf("handler saw principal %+v, want alice", caller)
+		}
+	})
+}
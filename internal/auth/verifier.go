@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config selects how bearer tokens are verified. Exactly one of JWKSFile and
+// HMACSecret must be set.
+type Config struct {
+	JWKSFile   string // path to a JSON Web Key Set with the RS*/ES* signing keys
+	HMACSecret string // shared secret for HS256/HS384/HS512 tokens
+	Issuer     string // optional; required "iss" claim
+	Audience   string // optional; required "aud" claim
+}
+
+// Verifier validates bearer tokens and turns their claims into a Principal
+type Verifier struct {
+	keyFunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+// NewVerifier creates a Verifier from cfg
+func NewVerifier(cfg Config) (*Verifier, error) {
+	var (
+		keyFunc jwt.Keyfunc
+		methods []string
+	)
+	switch {
+	case cfg.JWKSFile != "" && cfg.HMACSecret != "":
+		return nil, errors.New("auth: configure either a JWKS file or an HMAC secret, not both")
+	case cfg.JWKSFile != "":
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFunc = keys.keyFunc
+		methods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+	case cfg.HMACSecret != "":
+		secret := []byte(cfg.HMACSecret)
+		keyFunc = func(*jwt.Token) (interface{}, error) { return secret, nil }
+		methods = []string{"HS256", "HS384", "HS512"}
+	default:
+		return nil, errors.New("auth: a JWKS file or an HMAC secret is required")
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &Verifier{keyFunc: keyFunc, parser: jwt.NewParser(opts...)}, nil
+}
+
+// Verify checks the signature and standard claims of token and returns its principal
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims["sub"].(string)
+	if userID == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &Principal{
+		UserID:      userID,
+		DisplayName: displayName(claims, userID),
+		Scopes:      scopes(claims),
+	}, nil
+}
+
+func displayName(claims jwt.MapClaims, fallback string) string {
+	for _, claim := range []string{"name", "preferred_username", "email"} {
+		if name, _ := claims[claim].(string); name != "" {
+			return name
+		}
+	}
+	return fallback
+}
+
+// scopes reads the OAuth2 "scope" claim (space separated) or the "scp" claim
+// (space separated or a list)
+func scopes(claims jwt.MapClaims) []string {
+	for _, claim := range []string{"scope", "scp"} {
+		if list := stringList(claims[claim]); list != nil {
+			return list
+		}
+	}
+	return nil
+}
+
+// stringList reads a claim holding either a space separated string or a list
+func stringList(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var result []string
+		for _, s := range v {
+			result = append(result, fmt.Sprint(s))
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vendasta/retrospective/internal/auth"
+)
+
+const (
+	testSecret   = "test-secret"
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "retrospective"
+)
+
+// validClaims are the claims of a token the test verifiers accept
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "alice",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// with returns validClaims with changes applied; a nil value removes the claim
+func with(changes jwt.MapClaims) jwt.MapClaims {
+	claims := validClaims()
+	for k, v := range changes {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func signHMAC(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return token
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func newHMACVerifier(t *testing.T) *auth.Verifier {
+	t.Helper()
+	v, err := auth.NewVerifier(auth.Config{HMACSecret: testSecret, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	v := newHMACVerifier(t)
+	rsaKey := mustRSAKey(t)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error // nil when any error will do
+		want    *auth.Principal
+	}{
+		{
+			name:  "valid",
+			token: signHMAC(t, testSecret, validClaims()),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice"},
+		},
+		{
+			name:  "display name from name",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"name": "Alice Liddell", "email": "alice@example.com"})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "Alice Liddell"},
+		},
+		{
+			name:  "display name from preferred_username",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"preferred_username": "aliddell", "email": "alice@example.com"})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "aliddell"},
+		},
+		{
+			name:  "display name from email",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"email": "alice@example.com"})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice@example.com"},
+		},
+		{
+			name:  "space separated scope",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"scope": "retrospective:read  retrospective:write"})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice", Scopes: []string{"retrospective:read", "retrospective:write"}},
+		},
+		{
+			name:  "space separated scp",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"scp": "retrospective:read"})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice", Scopes: []string{"retrospective:read"}},
+		},
+		{
+			name:  "scp list",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"scp": []string{"retrospective:read", "retrospective:write"}})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice", Scopes: []string{"retrospective:read", "retrospective:write"}},
+		},
+		{
+			name:  "scope wins over scp",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"scope": "retrospective:read", "scp": []string{"retrospective:write"}})),
+			want:  &auth.Principal{UserID: "alice", DisplayName: "alice", Scopes: []string{"retrospective:read"}},
+		},
+		{
+			name:    "expired",
+			token:   signHMAC(t, testSecret, with(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:    "no expiry",
+			token:   signHMAC(t, testSecret, with(jwt.MapClaims{"exp": nil})),
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "wrong issuer",
+			token:   signHMAC(t, testSecret, with(jwt.MapClaims{"iss": "https://other.example.com"})),
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:    "wrong audience",
+			token:   signHMAC(t, testSecret, with(jwt.MapClaims{"aud": "billing"})),
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:    "wrong secret",
+			token:   signHMAC(t, "other-secret", validClaims()),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "RS256 for an HMAC verifier",
+			token:   sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims()),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:  "unsigned",
+			token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+		},
+		{
+			name:  "missing subject",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"sub": nil})),
+		},
+		{
+			name:  "empty subject",
+			token: signHMAC(t, testSecret, with(jwt.MapClaims{"sub": ""})),
+		},
+		{
+			name:    "malformed",
+			token:   "not.a.token",
+			wantErr: jwt.ErrTokenMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if tt.want != nil {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Verify = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Verify = %+v, want an error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVerifierConfig(t *testing.T) {
+	if _, err := auth.NewVerifier(auth.Config{}); err == nil {
+		t.Error("NewVerifier without keys succeeded")
+	}
+	jwks := writeJWKS(t, []map[string]string{rsaJWK(t, "", &mustRSAKey(t).PublicKey)})
+	if _, err := auth.NewVerifier(auth.Config{JWKSFile: jwks, HMACSecret: testSecret}); err == nil {
+		t.Error("NewVerifier with both a JWKS file and an HMAC secret succeeded")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey := mustRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := mustRSAKey(t)
+
+	t.Run("single key without kid", func(t *testing.T) {
+		v, err := auth.NewVerifier(auth.Config{JWKSFile: writeJWKS(t, []map[string]string{rsaJWK(t, "", &rsaKey.PublicKey)})})
+		if err != nil {
+			t.Fatalf("NewVerifier: %v", err)
+		}
+		if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims())); err != nil {
+			t.Errorf("token without kid: %v", err)
+		}
+		if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, otherKey, "", validClaims())); err == nil {
+			t.Error("token signed with another key was accepted")
+		}
+	})
+
+	t.Run("keys by kid", func(t *testing.T) {
+		v, err := auth.NewVerifier(auth.Config{JWKSFile: writeJWKS(t, []map[string]string{
+			rsaJWK(t, "rsa-1", &rsaKey.PublicKey),
+			ecJWK(t, "ec-1", &ecKey.PublicKey),
+			// Encryption keys are not used to verify signatures
+			{"kid": "enc-1", "kty": "RSA", "use": "enc", "n": b64(otherKey.N.Bytes()), "e": b64(big.NewInt(int64(otherKey.E)).Bytes())},
+		})})
+		if err != nil {
+			t.Fatalf("NewVerifier: %v", err)
+		}
+		tests := []struct {
+			name  string
+			token string
+			ok    bool
+		}{
+			{"RS256 with its kid", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()), true},
+			{"ES256 with its kid", sign(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims()), true},
+			{"kid of another key", sign(t, jwt.SigningMethodRS256, rsaKey, "ec-1", validClaims()), false},
+			{"unknown kid", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", validClaims()), false},
+			{"encryption key kid", sign(t, jwt.SigningMethodRS256, otherKey, "enc-1", validClaims()), false},
+			{"no kid with several keys", sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims()), false},
+			{"HS256 for a JWKS verifier", signHMAC(t, testSecret, validClaims()), false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := v.Verify(tt.token)
+				if tt.ok && err != nil {
+					t.Errorf("Verify: %v", err)
+				}
+				if !tt.ok && err == nil {
+					t.Error("Verify succeeded, want an error")
+				}
+			})
+		}
+	})
+
+	t.Run("invalid sets", func(t *testing.T) {
+		dir := t.TempDir()
+		invalid := map[string]string{
+			"missing file":     filepath.Join(dir, "missing.json"),
+			"not JSON":         writeFile(t, "{"),
+			"no signing keys":  writeJWKS(t, []map[string]string{{"kid": "enc-1", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"}}),
+			"unknown key type": writeJWKS(t, []map[string]string{{"kid": "oct-1", "kty": "oct"}}),
+			"unknown curve":    writeJWKS(t, []map[string]string{{"kid": "ec-1", "kty": "EC", "crv": "P-192", "x": "AQAB", "y": "AQAB"}}),
+			"bad modulus":      writeJWKS(t, []map[string]string{{"kid": "rsa-1", "kty": "RSA", "n": "!!", "e": "AQAB"}}),
+		}
+		for name, path := range invalid {
+			if _, err := auth.LoadJWKS(path); err == nil {
+				t.Errorf("LoadJWKS with %s succeeded", name)
+			}
+		}
+	})
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(t *testing.T, kid string, key *rsa.PublicKey) map[string]string {
+	t.Helper()
+	return map[string]string{"kid": kid, "kty": "RSA", "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(t *testing.T, kid string, key *ecdsa.PublicKey) map[string]string {
+	t.Helper()
+	return map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes())}
+}
+
+// writeJWKS writes a key set to a temporary file and returns its path
+func writeJWKS(t *testing.T, keys []map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, string(data))
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "jwks-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
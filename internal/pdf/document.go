@@ -0,0 +1,298 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageSize is the size of a page in points
+type PageSize struct {
+	Width, Height float64
+}
+
+var (
+	A4     = PageSize{Width: 595.28, Height: 841.89}
+	Letter = PageSize{Width: 612, Height: 792}
+)
+
+// Style selects whether a shape is filled, outlined or both
+type Style int
+
+const (
+	Fill Style = 1 << iota
+	Stroke
+	FillStroke = Fill | Stroke
+)
+
+// Point is a position on the page
+type Point struct {
+	X, Y float64
+}
+
+// Document lays out pages of text and vector shapes. Positions are in points
+// from the top-left corner of the page, with y growing down the page.
+type Document struct {
+	size        PageSize
+	title       string
+	pages       []*bytes.Buffer
+	fillColor   Color
+	strokeColor Color
+	textColor   Color
+	lineWidth   float64
+}
+
+// New creates a document whose pages are all of the given size
+func New(size PageSize) *Document {
+	return &Document{
+		size:      size,
+		lineWidth: 1,
+	}
+}
+
+// SetTitle sets the title shown by PDF readers
+func (d *Document) SetTitle(title string) {
+	d.title = title
+}
+
+// Size returns the page size
+func (d *Document) Size() PageSize {
+	return d.size
+}
+
+// PageCount returns the number of pages added so far
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// AddPage starts a new page; everything drawn after it lands on that page
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) SetFillColor(c Color)   { d.fillColor = c }
+func (d *Document) SetStrokeColor(c Color) { d.strokeColor = c }
+func (d *Document) SetTextColor(c Color)   { d.textColor = c }
+func (d *Document) SetLineWidth(w float64) { d.lineWidth = w }
+
+// Text draws s with its baseline starting at x, y
+func (d *Document) Text(x, y float64, font Font, size float64, s string) {
+	b := encode(s)
+	if len(b) == 0 {
+		return
+	}
+	d.printf("q %s rg BT /F%d %s Tf %s %s Td (%s) Tj ET Q\n",
+		d.textColor.operands(), int(font)+1, num(size), num(x), num(d.size.Height-y), escape(b))
+}
+
+// Rect draws a rectangle with its top-left corner at x, y
+func (d *Document) Rect(x, y, w, h float64, style Style) {
+	d.paint(fmt.Sprintf("%s %s %s %s re", num(x), num(d.size.Height-y-h), num(w), num(h)), style)
+}
+
+// RoundedRect draws a rectangle whose corners are rounded with radius r
+func (d *Document) RoundedRect(x, y, w, h, r float64, style Style) {
+	if r > w/2 {
+		r = w / 2
+	}
+	if r > h/2 {
+		r = h / 2
+	}
+	k := r * (1 - kappa)
+	p := &Path{}
+	p.MoveTo(x+r, y)
+	p.LineTo(x+w-r, y)
+	p.CurveTo(x+w-k, y, x+w, y+k, x+w, y+r)
+	p.LineTo(x+w, y+h-r)
+	p.CurveTo(x+w, y+h-k, x+w-k, y+h, x+w-r, y+h)
+	p.LineTo(x+r, y+h)
+	p.CurveTo(x+k, y+h, x, y+h-k, x, y+h-r)
+	p.LineTo(x, y+r)
+	p.CurveTo(x, y+k, x+k, y, x+r, y)
+	p.Close()
+	d.DrawPath(p, style)
+}
+
+// Circle draws a circle of radius r centred on cx, cy
+func (d *Document) Circle(cx, cy, r float64, style Style) {
+	k := r * kappa
+	p := &Path{}
+	p.MoveTo(cx+r, cy)
+	p.CurveTo(cx+r, cy+k, cx+k, cy+r, cx, cy+r)
+	p.CurveTo(cx-k, cy+r, cx-r, cy+k, cx-r, cy)
+	p.CurveTo(cx-r, cy-k, cx-k, cy-r, cx, cy-r)
+	p.CurveTo(cx+k, cy-r, cx+r, cy-k, cx+r, cy)
+	p.Close()
+	d.DrawPath(p, style)
+}
+
+// Line draws a straight line in the stroke color
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	p := &Path{}
+	p.MoveTo(x1, y1)
+	p.LineTo(x2, y2)
+	d.DrawPath(p, Stroke)
+}
+
+// Polygon draws the closed shape through points
+func (d *Document) Polygon(points []Point, style Style) {
+	if len(points) == 0 {
+		return
+	}
+	p := &Path{}
+	p.MoveTo(points[0].X, points[0].Y)
+	for _, pt := range points[1:] {
+		p.LineTo(pt.X, pt.Y)
+	}
+	p.Close()
+	d.DrawPath(p, style)
+}
+
+// DrawPath draws a path built up with the Path methods
+func (d *Document) DrawPath(p *Path, style Style) {
+	var ops []string
+	for _, seg := range p.segments {
+		var operands []string
+		for _, pt := range seg.points {
+			operands = append(operands, num(pt.X), num(d.size.Height-pt.Y))
+		}
+		ops = append(ops, strings.Join(append(operands, seg.op), " "))
+	}
+	d.paint(strings.Join(ops, " "), style)
+}
+
+// paint fills and strokes the path built by ops in the current colors
+func (d *Document) paint(ops string, style Style) {
+	op := "n"
+	switch style {
+	case Fill:
+		op = "f"
+	case Stroke:
+		op = "S"
+	case FillStroke:
+		op = "B"
+	}
+	d.printf("q %s rg %s RG %s w 1 J 1 j %s %s Q\n",
+		d.fillColor.operands(), d.strokeColor.operands(), num(d.lineWidth), ops, op)
+}
+
+func (d *Document) printf(format string, args ...interface{}) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], format, args...)
+}
+
+// Bytes renders the document. The output depends only on what was drawn, so
+// the same document always renders to the same bytes.
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+	}
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts; each
+	// page is then a page object followed by its content stream
+	const firstPage = 5
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+	for _, font := range []Font{Helvetica, HelveticaBold} {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[font]), nil)
+	}
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(d.size.Width), num(d.size.Height), firstPage+2*i+1), nil)
+		stream := deflate(page.Bytes())
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(stream)), stream)
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (retrospective) >>", escape(encode(d.title))), nil)
+	info := len(offsets)
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+	return buf.Bytes()
+}
+
+// Path is an outline of straight and curved segments
+type Path struct {
+	segments []pathSegment
+}
+
+type pathSegment struct {
+	op     string
+	points []Point
+}
+
+func (p *Path) MoveTo(x, y float64) {
+	p.segments = append(p.segments, pathSegment{op: "m", points: []Point{{x, y}}})
+}
+
+func (p *Path) LineTo(x, y float64) {
+	p.segments = append(p.segments, pathSegment{op: "l", points: []Point{{x, y}}})
+}
+
+// CurveTo adds a cubic Bézier curve with control points x1, y1 and x2, y2
+func (p *Path) CurveTo(x1, y1, x2, y2, x3, y3 float64) {
+	p.segments = append(p.segments, pathSegment{op: "c", points: []Point{{x1, y1}, {x2, y2}, {x3, y3}}})
+}
+
+// Close joins the end of the current outline back to its start
+func (p *Path) Close() {
+	p.segments = append(p.segments, pathSegment{op: "h"})
+}
+
+// kappa places the control points of a Bézier curve approximating a quarter circle
+const kappa = 0.5522847498
+
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// escape quotes the characters that are special inside a PDF string
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '\\', '(', ')':
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func deflate(b []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
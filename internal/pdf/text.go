@@ -0,0 +1,88 @@
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Color is an RGB color
+type Color struct {
+	R, G, B uint8
+}
+
+var (
+	Black = Color{0, 0, 0}
+	White = Color{255, 255, 255}
+)
+
+// ParseColor parses a CSS-style hex color such as "#22c55e" or "#fff"
+func ParseColor(hex string) (Color, error) {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 6 || err != nil {
+		return Color{}, fmt.Errorf("pdf: %q is not a hex color", hex)
+	}
+	return Color{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// Tint mixes c with white; t runs from 0, which keeps c, to 1, which is white
+func (c Color) Tint(t float64) Color {
+	mix := func(v uint8) uint8 {
+		return uint8(float64(v) + (255-float64(v))*t + 0.5)
+	}
+	return Color{mix(c.R), mix(c.G), mix(c.B)}
+}
+
+func (c Color) operands() string {
+	return fmt.Sprintf("%s %s %s", num(float64(c.R)/255), num(float64(c.G)/255), num(float64(c.B)/255))
+}
+
+// WrapText breaks s into lines no wider than width when set in font at size.
+// Lines break between words and at newlines; a word wider than a whole line
+// is broken between characters.
+func WrapText(font Font, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if TextWidth(font, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for TextWidth(font, size, word) > width {
+				head := fitRunes(font, size, word, width)
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fitRunes returns the longest prefix of s, at least one rune long, that fits in width
+func fitRunes(font Font, size float64, s string, width float64) string {
+	end := 0
+	for end < len(s) {
+		_, n := utf8.DecodeRuneInString(s[end:])
+		if end > 0 && TextWidth(font, size, s[:end+n]) > width {
+			break
+		}
+		end += n
+	}
+	return s[:end]
+}
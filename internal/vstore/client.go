@@ -0,0 +1,371 @@
+package vstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Namespace is the vstore namespace all retrospective kinds are registered under
+const Namespace = "retrospective"
+
+var (
+	// ErrNotFound is returned when no entity exists for a key
+	ErrNotFound = errors.New("vstore: entity not found")
+
+	// ErrUnknownKind is returned for kinds that have no schema in AllSchemas
+	ErrUnknownKind = errors.New("vstore: unknown kind")
+
+	// ErrUnindexedField is returned when a query filters or orders on a field
+	// that is neither a key part nor covered by a declared index
+	ErrUnindexedField = errors.New("vstore: field is not indexed")
+
+	// ErrConflict is returned when a transaction keeps losing to concurrent writers
+	ErrConflict = errors.New("vstore: transaction conflict")
+)
+
+// transactionAttempts bounds how often Transaction re-runs fn after a conflict
+const transactionAttempts = 50
+
+// Filter restricts a query to entities whose field equals Value.
+// Field must be a key part or covered by one of the kind's indexes.
+type Filter struct {
+	Field string
+	Value interface{}
+}
+
+// Query describes a lookup against a single kind
+type Query struct {
+	Kind       string
+	KeyPrefix  []string // leading key parts, in schema key_parts order
+	Filters    []Filter
+	OrderBy    string // name of a declared index; empty means key order
+	Descending bool
+	Cursor     string
+	PageSize   int // zero returns every match
+}
+
+// Entity is a single stored row
+type Entity struct {
+	Key        []string
+	Properties map[string]interface{}
+}
+
+// Decode populates dst (a pointer to a model) from the entity properties
+func (e *Entity) Decode(dst interface{}) error {
+	return DecodeProperties(e.Properties, dst)
+}
+
+// QueryResult is a page of entities returned by Client.Query
+type QueryResult struct {
+	Entities   []*Entity
+	NextCursor string
+	HasMore    bool
+}
+
+// Client is the subset of vstore operations used by the retrospective stores
+type Client interface {
+	// RegisterSchemas registers the kinds, key layouts, indexes and TTLs
+	RegisterSchemas(ctx context.Context, schemas []map[string]interface{}) error
+	// Get loads the entity stored at key into dst
+	Get(ctx context.Context, kind string, key []string, dst interface{}) error
+	// Put stores src under the key derived from its key_parts fields
+	Put(ctx context.Context, kind string, src interface{}) error
+	// Delete removes the entity stored at key
+	Delete(ctx context.Context, kind string, key []string) error
+	// Query returns the entities matching q
+	Query(ctx context.Context, q *Query) (*QueryResult, error)
+	// Transaction loads the entity stored at key into dst (left zeroed when
+	// missing), calls fn and stores dst only if no other write touched the
+	// entity in between. fn is re-run on conflict, so it must only change dst;
+	// an error from fn aborts the transaction without writing.
+	Transaction(ctx context.Context, kind string, key []string, dst interface{}, fn func(exists bool) error) error
+}
+
+// resetModel zeroes the model dst points to before a transaction attempt
+func resetModel(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("vstore: transaction target must be a non-nil pointer")
+	}
+	rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+	return nil
+}
+
+// SchemaFor returns the schema registered for kind
+func SchemaFor(kind string) (map[string]interface{}, error) {
+	for _, schema := range AllSchemas() {
+		if schema["name"] == kind {
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+}
+
+// KeyParts returns the key_parts of a schema
+func KeyParts(schema map[string]interface{}) []string {
+	parts, _ := schema["key_parts"].([]string)
+	return parts
+}
+
+// IndexFields returns the fields covered by the named index of a schema
+func IndexFields(schema map[string]interface{}, name string) ([]string, bool) {
+	indexes, _ := schema["indexes"].([]map[string]interface{})
+	for _, index := range indexes {
+		if index["name"] == name {
+			fields, _ := index["fields"].([]string)
+			return fields, true
+		}
+	}
+	return nil, false
+}
+
+// IsIndexed reports whether field is a key part or covered by an index
+func IsIndexed(schema map[string]interface{}, field string) bool {
+	for _, part := range KeyParts(schema) {
+		if part == field {
+			return true
+		}
+	}
+	indexes, _ := schema["indexes"].([]map[string]interface{})
+	for _, index := range indexes {
+		fields, _ := index["fields"].([]string)
+		for _, f := range fields {
+			if f == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TTL returns the time-to-live of a schema, or zero if entities never expire
+func TTL(schema map[string]interface{}) time.Duration {
+	raw, _ := schema["ttl"].(string)
+	if raw == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0
+	}
+	return ttl
+}
+
+// KeyOf builds the key of a model from the schema key_parts
+func KeyOf(schema map[string]interface{}, model interface{}) ([]string, error) {
+	props, err := EncodeProperties(model)
+	if err != nil {
+		return nil, err
+	}
+	var key []string
+	for _, part := range KeyParts(schema) {
+		value, _ := props[part].(string)
+		if value == "" {
+			return nil, fmt.Errorf("vstore: key part %s is empty", part)
+		}
+		key = append(key, value)
+	}
+	return key, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// EncodeProperties converts a model into a property map keyed by its vstore tags
+func EncodeProperties(model interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(model)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("vstore: cannot encode nil model")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("vstore: cannot encode %s", rv.Kind())
+	}
+	props, _ := encodeValue(rv).(map[string]interface{})
+	return props, nil
+}
+
+func encodeValue(rv reflect.Value) interface{} {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return encodeValue(rv.Elem())
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return rv.Interface().(time.Time)
+		}
+		props := make(map[string]interface{})
+		for i := 0; i < rv.NumField(); i++ {
+			name := rv.Type().Field(i).Tag.Get("vstore")
+			if name == "" {
+				continue
+			}
+			props[name] = encodeValue(rv.Field(i))
+		}
+		return props
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = encodeValue(rv.Index(i))
+		}
+		return values
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		values := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[fmt.Sprint(iter.Key().Interface())] = encodeValue(iter.Value())
+		}
+		return values
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	default:
+		return nil
+	}
+}
+
+// DecodeProperties populates dst (a pointer to a model) from a property map
+func DecodeProperties(props map[string]interface{}, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("vstore: decode target must be a non-nil pointer")
+	}
+	return decodeValue(props, rv.Elem())
+}
+
+func decodeValue(src interface{}, rv reflect.Value) error {
+	if src == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(rv.Type().Elem())
+		if err := decodeValue(src, elem.Elem()); err != nil {
+			return err
+		}
+		rv.Set(elem)
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			switch v := src.(type) {
+			case time.Time:
+				rv.Set(reflect.ValueOf(v))
+			case string:
+				t, err := time.Parse(time.RFC3339Nano, v)
+				if err != nil {
+					return err
+				}
+				rv.Set(reflect.ValueOf(t))
+			default:
+				return fmt.Errorf("vstore: cannot decode %T into time", src)
+			}
+			return nil
+		}
+		props, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("vstore: cannot decode %T into %s", src, rv.Type())
+		}
+		for i := 0; i < rv.NumField(); i++ {
+			name := rv.Type().Field(i).Tag.Get("vstore")
+			if name == "" {
+				continue
+			}
+			if err := decodeValue(props[name], rv.Field(i)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	case reflect.Slice:
+		values, ok := src.([]interface{})
+		if !ok {
+			return fmt.Errorf("vstore: cannot decode %T into %s", src, rv.Type())
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := decodeValue(v, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Map:
+		values, ok := src.(map[string]interface{})
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("vstore: cannot decode %T into %s", src, rv.Type())
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), len(values))
+		for k, v := range values {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(v, elem); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+		}
+		rv.Set(m)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		rv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(n)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fmt.Errorf("vstore: cannot decode %T into bool", src)
+		}
+		rv.SetBool(b)
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("vstore: cannot decode %T into string", src)
+		}
+		rv.SetString(s)
+	}
+	return nil
+}
+
+func toFloat(src interface{}) (float64, error) {
+	switch v := src.(type) {
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("vstore: cannot decode %T into number", src)
+	}
+}
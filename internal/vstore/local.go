@@ -0,0 +1,370 @@
+package vstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalClient is an in-process fake of vstore for tests and local development.
+// It enforces the same key layouts, indexes and TTLs as the real service.
+type LocalClient struct {
+	mu       sync.RWMutex
+	schemas  map[string]map[string]interface{}  // key: kind
+	entities map[string]map[string]*localEntity // key: kind, then joined key
+	now      func() time.Time
+}
+
+type localEntity struct {
+	key        []string
+	properties map[string]interface{}
+	expires    time.Time
+	version    int64 // bumped on every write; used by Transaction
+}
+
+// NewLocalClient creates an empty LocalClient with AllSchemas registered
+func NewLocalClient() *LocalClient {
+	c := &LocalClient{
+		schemas:  make(map[string]map[string]interface{}),
+		entities: make(map[string]map[string]*localEntity),
+		now:      time.Now,
+	}
+	c.RegisterSchemas(context.Background(), AllSchemas())
+	return c
+}
+
+// SetClock overrides the clock used for TTL expiry
+func (c *LocalClient) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *LocalClient) RegisterSchemas(ctx context.Context, schemas []map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, schema := range schemas {
+		kind, _ := schema["name"].(string)
+		c.schemas[kind] = schema
+		if c.entities[kind] == nil {
+			c.entities[kind] = make(map[string]*localEntity)
+		}
+	}
+	return nil
+}
+
+func (c *LocalClient) schema(kind string) (map[string]interface{}, error) {
+	schema, ok := c.schemas[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	return schema, nil
+}
+
+func (c *LocalClient) Get(ctx context.Context, kind string, key []string, dst interface{}) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, err := c.schema(kind); err != nil {
+		return err
+	}
+	entity, ok := c.entities[kind][joinKey(key)]
+	if !ok || c.expired(entity) {
+		return ErrNotFound
+	}
+	return DecodeProperties(entity.properties, dst)
+}
+
+func (c *LocalClient) Put(ctx context.Context, kind string, src interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	schema, err := c.schema(kind)
+	if err != nil {
+		return err
+	}
+	key, err := KeyOf(schema, src)
+	if err != nil {
+		return err
+	}
+	props, err := EncodeProperties(src)
+	if err != nil {
+		return err
+	}
+	c.store(schema, kind, key, props)
+	return nil
+}
+
+// store writes props under key; the caller holds c.mu
+func (c *LocalClient) store(schema map[string]interface{}, kind string, key []string, props map[string]interface{}) {
+	entity := &localEntity{key: key, properties: props, version: 1}
+	if existing, ok := c.entities[kind][joinKey(key)]; ok {
+		entity.version = existing.version + 1
+	}
+	if ttl := TTL(schema); ttl > 0 {
+		entity.expires = c.now().Add(ttl)
+	}
+	c.entities[kind][joinKey(key)] = entity
+}
+
+func (c *LocalClient) Transaction(ctx context.Context, kind string, key []string, dst interface{}, fn func(exists bool) error) error {
+	for attempt := 0; attempt < transactionAttempts; attempt++ {
+		if err := resetModel(dst); err != nil {
+			return err
+		}
+		version, exists, err := c.read(kind, key, dst)
+		if err != nil {
+			return err
+		}
+		if err := fn(exists); err != nil {
+			return err
+		}
+		written, err := c.compareAndPut(kind, key, version, dst)
+		if err != nil {
+			return err
+		}
+		if written {
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+// read decodes the entity at key into dst and returns its version, which is
+// zero when the entity does not exist
+func (c *LocalClient) read(kind string, key []string, dst interface{}) (int64, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, err := c.schema(kind); err != nil {
+		return 0, false, err
+	}
+	entity, ok := c.entities[kind][joinKey(key)]
+	if !ok || c.expired(entity) {
+		return 0, false, nil
+	}
+	return entity.version, true, DecodeProperties(entity.properties, dst)
+}
+
+// compareAndPut stores src only if the entity at key is still at version
+func (c *LocalClient) compareAndPut(kind string, key []string, version int64, src interface{}) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	schema, err := c.schema(kind)
+	if err != nil {
+		return false, err
+	}
+	srcKey, err := KeyOf(schema, src)
+	if err != nil {
+		return false, err
+	}
+	if joinKey(srcKey) != joinKey(key) {
+		return false, fmt.Errorf("vstore: transaction cannot change the key of %s", kind)
+	}
+	var current int64
+	if entity, ok := c.entities[kind][joinKey(key)]; ok && !c.expired(entity) {
+		current = entity.version
+	}
+	if current != version {
+		return false, nil
+	}
+	props, err := EncodeProperties(src)
+	if err != nil {
+		return false, err
+	}
+	c.store(schema, kind, key, props)
+	return true, nil
+}
+
+func (c *LocalClient) Delete(ctx context.Context, kind string, key []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.schema(kind); err != nil {
+		return err
+	}
+	delete(c.entities[kind], joinKey(key))
+	return nil
+}
+
+func (c *LocalClient) Query(ctx context.Context, q *Query) (*QueryResult, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	schema, err := c.schema(q.Kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range q.Filters {
+		if !IsIndexed(schema, f.Field) {
+			return nil, fmt.Errorf("%w: %s.%s", ErrUnindexedField, q.Kind, f.Field)
+		}
+	}
+	var orderFields []string
+	if q.OrderBy != "" {
+		fields, ok := IndexFields(schema, q.OrderBy)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has no index %s", ErrUnindexedField, q.Kind, q.OrderBy)
+		}
+		orderFields = fields
+	}
+
+	var matches []*localEntity
+	for _, entity := range c.entities[q.Kind] {
+		if c.expired(entity) || !hasPrefix(entity.key, q.KeyPrefix) {
+			continue
+		}
+		if !matchesFilters(entity.properties, q.Filters) {
+			continue
+		}
+		matches = append(matches, entity)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, field := range orderFields {
+			cmp := compareValues(matches[i].properties[field], matches[j].properties[field])
+			if cmp != 0 {
+				if q.Descending {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+		}
+		return joinKey(matches[i].key) < joinKey(matches[j].key)
+	})
+
+	offset := 0
+	if q.Cursor != "" {
+		offset, err = strconv.Atoi(q.Cursor)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("vstore: invalid cursor %q", q.Cursor)
+		}
+	}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+
+	result := &QueryResult{}
+	if q.PageSize > 0 && len(matches) > q.PageSize {
+		matches = matches[:q.PageSize]
+		result.HasMore = true
+		result.NextCursor = strconv.Itoa(offset + q.PageSize)
+	}
+	for _, entity := range matches {
+		// Copy properties so callers never share state with the store
+		result.Entities = append(result.Entities, &Entity{
+			Key:        append([]string(nil), entity.key...),
+			Properties: copyProperties(entity.properties),
+		})
+	}
+	return result, nil
+}
+
+func (c *LocalClient) expired(entity *localEntity) bool {
+	return !entity.expires.IsZero() && !c.now().Before(entity.expires)
+}
+
+func joinKey(key []string) string {
+	return strings.Join(key, "\x00")
+}
+
+func hasPrefix(key, prefix []string) bool {
+	if len(prefix) > len(key) {
+		return false
+	}
+	for i, part := range prefix {
+		if key[i] != part {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesFilters(props map[string]interface{}, filters []Filter) bool {
+	for _, f := range filters {
+		if compareValues(props[f.Field], normalizeValue(f.Value)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeValue converts filter values into the representation produced by EncodeProperties
+func normalizeValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.(time.Time); ok {
+		return v
+	}
+	return encodeValue(reflect.ValueOf(v))
+}
+
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case int64:
+		bv, _ := b.(int64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case float64:
+		bv, _ := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case string:
+		bv, _ := b.(string)
+		return strings.Compare(av, bv)
+	case bool:
+		bv, _ := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		}
+		return 1
+	case time.Time:
+		bv, _ := b.(time.Time)
+		return av.Compare(bv)
+	case nil:
+		if b == nil {
+			return 0
+		}
+		return -1
+	}
+	return 0
+}
+
+func copyProperties(props map[string]interface{}) map[string]interface{} {
+	copied, _ := copyValue(props).(map[string]interface{})
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for k, elem := range value {
+			copied[k] = copyValue(elem)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, elem := range value {
+			copied[i] = copyValue(elem)
+		}
+		return copied
+	default:
+		return v
+	}
+}
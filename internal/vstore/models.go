@@ -14,6 +14,7 @@ const (
 	RetrospectiveStatusVoting      RetrospectiveStatus = 3
 	RetrospectiveStatusDiscussing  RetrospectiveStatus = 4
 	RetrospectiveStatusCompleted   RetrospectiveStatus = 5
+	RetrospectiveStatusArchived    RetrospectiveStatus = 6
 )
 
 // ActionItemStatus represents the state of an action item
@@ -60,6 +61,27 @@ const (
 	ParticipantRoleObserver    ParticipantRole = 3
 )
 
+// VotingMode selects how votes are cast and tallied
+type VotingMode int32
+
+const (
+	VotingModeUnspecified  VotingMode = 0 // treated as VotingModeDot
+	VotingModeDot          VotingMode = 1 // one point per vote, limited by MaxVotesPerUser
+	VotingModeWeighted     VotingMode = 2 // users spread MaxVotesPerUser points over items
+	VotingModeRankedChoice VotingMode = 3 // users rank up to MaxVotesPerUser items
+	VotingModeFistOfFive   VotingMode = 4 // users score their agreement with each item from 0 to 5
+)
+
+// TeamRole defines the role of a team member
+type TeamRole int32
+
+const (
+	TeamRoleUnspecified TeamRole = 0
+	TeamRoleMember      TeamRole = 1
+	TeamRoleAdmin       TeamRole = 2
+	TeamRoleObserver    TeamRole = 3
+)
+
 // Retrospective represents a sprint retrospective session
 type Retrospective struct {
 	RetrospectiveID string              `vstore:"retrospective_id"`
@@ -76,6 +98,11 @@ type Retrospective struct {
 	ItemCount       int32               `vstore:"item_count"`
 	ActionItemCount int32               `vstore:"action_item_count"`
 	ParticipantCount int32              `vstore:"participant_count"`
+	PhaseHistory    []*PhaseTransition  `vstore:"phase_history"`
+	VotesRevealedAt time.Time           `vstore:"votes_revealed_at"`
+	SilentBrainstorm bool               `vstore:"silent_brainstorm"` // cards stay hidden from everyone but their author until revealed
+	ItemsRevealedAt time.Time           `vstore:"items_revealed_at"`
+	ReactionEmojis  []string            `vstore:"reaction_emojis"` // empty means the default set
 	StartedAt       time.Time           `vstore:"started_at"`
 	CompletedAt     time.Time           `vstore:"completed_at"`
 	Created         time.Time           `vstore:"created"`
@@ -83,6 +110,15 @@ type Retrospective struct {
 	Deleted         time.Time           `vstore:"deleted"`
 }
 
+// PhaseTransition records a change of a retrospective's phase
+type PhaseTransition struct {
+	FromStatus    RetrospectiveStatus `vstore:"from_status"`
+	ToStatus      RetrospectiveStatus `vstore:"to_status"`
+	ChangedBy     string              `vstore:"changed_by"`
+	ChangedByName string              `vstore:"changed_by_name"`
+	ChangedAt     time.Time           `vstore:"changed_at"`
+}
+
 // TemplateColumn represents a column in the retrospective board
 type TemplateColumn struct {
 	ColumnID    string `vstore:"column_id"`
@@ -95,37 +131,78 @@ type TemplateColumn struct {
 
 // VotingConfig defines the voting rules for a retrospective
 type VotingConfig struct {
-	MaxVotesPerUser           int32 `vstore:"max_votes_per_user"`
-	AllowMultipleVotesPerItem bool  `vstore:"allow_multiple_votes_per_item"`
-	AnonymousVoting           bool  `vstore:"anonymous_voting"`
+	Mode                      VotingMode `vstore:"mode"`
+	MaxVotesPerUser           int32      `vstore:"max_votes_per_user"`   // votes, points or ranks per user
+	MaxVotesPerColumn         int32      `vstore:"max_votes_per_column"` // votes or points per column; 0 means no column budget
+	AllowMultipleVotesPerItem bool       `vstore:"allow_multiple_votes_per_item"`
+	AnonymousVoting           bool       `vstore:"anonymous_voting"` // voter identity is never shown
+	BlindVoting               bool       `vstore:"blind_voting"`     // tallies stay hidden until the facilitator reveals them
 }
 
 // RetrospectiveItem represents a card on the retrospective board
 type RetrospectiveItem struct {
-	ItemID          string    `vstore:"item_id"`
-	RetrospectiveID string    `vstore:"retrospective_id"`
-	ColumnID        string    `vstore:"column_id"`
-	Content         string    `vstore:"content"`
-	CreatedBy       string    `vstore:"created_by"`
-	CreatedByName   string    `vstore:"created_by_name"`
-	VoteCount       int32     `vstore:"vote_count"`
-	IsAnonymous     bool      `vstore:"is_anonymous"`
-	Position        int32     `vstore:"position"`
-	HasActionItem   bool      `vstore:"has_action_item"`
-	Created         time.Time `vstore:"created"`
-	Updated         time.Time `vstore:"updated"`
-	Deleted         time.Time `vstore:"deleted"`
+	ItemID          string             `vstore:"item_id"`
+	RetrospectiveID string             `vstore:"retrospective_id"`
+	ColumnID        string             `vstore:"column_id"`
+	Content         string             `vstore:"content"`
+	CreatedBy       string             `vstore:"created_by"`
+	CreatedByName   string             `vstore:"created_by_name"`
+	VoteCount       int32              `vstore:"vote_count"`
+	IsAnonymous     bool               `vstore:"is_anonymous"`
+	Position        int32              `vstore:"position"`
+	SortKey         string             `vstore:"sort_key"` // fractional index ordering the item in its column
+	HasActionItem   bool               `vstore:"has_action_item"`
+	GroupID         string             `vstore:"group_id"`     // empty for standalone items
+	Contributors    []*ItemContributor `vstore:"contributors"` // creators of the items merged into this one
+	Created         time.Time          `vstore:"created"`
+	Updated         time.Time          `vstore:"updated"`
+	Deleted         time.Time          `vstore:"deleted"`
+}
+
+// ItemContributor is the creator of an item that was merged into another
+type ItemContributor struct {
+	UserID      string `vstore:"user_id"`
+	DisplayName string `vstore:"display_name"`
+	IsAnonymous bool   `vstore:"is_anonymous"`
 }
 
 // Vote represents a single vote cast by a user
 type Vote struct {
 	VoteID          string    `vstore:"vote_id"`
 	RetrospectiveID string    `vstore:"retrospective_id"`
-	ItemID          string    `vstore:"item_id"`
+	ItemID          string    `vstore:"item_id"` // the group's ID when GroupVote is set
 	UserID          string    `vstore:"user_id"`
+	GroupVote       bool      `vstore:"group_vote"`
+	ColumnID        string    `vstore:"column_id"` // column of the item when the vote was cast
+	Points          int32     `vstore:"points"`    // weighted voting
+	Rank            int32     `vstore:"rank"`      // ranked-choice voting, 1 is the top choice
+	Score           int32     `vstore:"score"`     // fist of five, 0 to 5
 	Created         time.Time `vstore:"created"`
 }
 
+// VoteBallot holds one user's votes in a retrospective. Vote casting commits
+// against it in a transaction so vote limits hold under concurrent requests.
+// Pending lists the casts and retractions whose vote record and item count are
+// still being written.
+type VoteBallot struct {
+	RetrospectiveID string         `vstore:"retrospective_id"`
+	UserID          string         `vstore:"user_id"`
+	Votes           []*Vote        `vstore:"votes"`
+	Pending         []*PendingVote `vstore:"pending"`
+	Updated         time.Time      `vstore:"updated"`
+}
+
+// PendingVote is a cast or retraction of a vote that is on the ballot but not
+// yet written to the vote records and item count. Replaced is the vote a cast
+// replaces. An entry older than a minute belongs to a request that failed and
+// is reverted on the next ballot update.
+type PendingVote struct {
+	Vote     *Vote     `vstore:"vote"`
+	Replaced *Vote     `vstore:"replaced"`
+	Retract  bool      `vstore:"retract"`
+	Started  time.Time `vstore:"started"`
+}
+
 // ActionItem represents a task created from a retrospective
 type ActionItem struct {
 	ActionItemID     string             `vstore:"action_item_id"`
@@ -158,3 +235,82 @@ type Participant struct {
 	JoinedAt        time.Time       `vstore:"joined_at"`
 	LastActive      time.Time       `vstore:"last_active"`
 }
+
+// Team represents a group of people that hold retrospectives together
+type Team struct {
+	TeamID      string    `vstore:"team_id"`
+	Name        string    `vstore:"name"`
+	Description string    `vstore:"description"`
+	CreatedBy   string    `vstore:"created_by"`
+	MemberCount int32     `vstore:"member_count"`
+	Created     time.Time `vstore:"created"`
+	Updated     time.Time `vstore:"updated"`
+	Deleted     time.Time `vstore:"deleted"`
+}
+
+// TeamMember represents a user's membership in a team
+type TeamMember struct {
+	TeamID      string    `vstore:"team_id"`
+	UserID      string    `vstore:"user_id"`
+	DisplayName string    `vstore:"display_name"`
+	Role        TeamRole  `vstore:"role"`
+	Created     time.Time `vstore:"created"`
+	Updated     time.Time `vstore:"updated"`
+}
+
+// PhaseTimer is the countdown for the current phase of a retrospective. A
+// running timer ends at EndsAt; a paused one keeps the time it had left in
+// Remaining. Timers are deleted when they expire or are cancelled.
+type PhaseTimer struct {
+	RetrospectiveID string              `vstore:"retrospective_id"`
+	Phase           RetrospectiveStatus `vstore:"phase"`
+	Duration        time.Duration       `vstore:"duration"` // including extensions
+	EndsAt          time.Time           `vstore:"ends_at"`
+	Remaining       time.Duration       `vstore:"remaining"`
+	Paused          bool                `vstore:"paused"`
+	AutoAdvance     bool                `vstore:"auto_advance"` // move to the next phase on expiry
+	StartedBy       string              `vstore:"started_by"`
+	StartedByName   string              `vstore:"started_by_name"`
+	Created         time.Time           `vstore:"created"`
+	Updated         time.Time           `vstore:"updated"`
+}
+
+// ItemGroup clusters related items on the board. Items join a group by
+// setting their GroupID, and the group is voted on as a single unit.
+type ItemGroup struct {
+	GroupID         string    `vstore:"group_id"`
+	RetrospectiveID string    `vstore:"retrospective_id"`
+	ColumnID        string    `vstore:"column_id"`
+	Title           string    `vstore:"title"`
+	CreatedBy       string    `vstore:"created_by"`
+	CreatedByName   string    `vstore:"created_by_name"`
+	VoteCount       int32     `vstore:"vote_count"`
+	Position        int32     `vstore:"position"`
+	Created         time.Time `vstore:"created"`
+	Updated         time.Time `vstore:"updated"`
+}
+
+// Comment is a remark on a retrospective item. Replies name the comment they
+// answer in ParentID, which makes comments on an item a thread.
+type Comment struct {
+	CommentID       string    `vstore:"comment_id"`
+	RetrospectiveID string    `vstore:"retrospective_id"`
+	ItemID          string    `vstore:"item_id"`
+	ParentID        string    `vstore:"parent_id"` // empty for top-level comments
+	Content         string    `vstore:"content"`
+	CreatedBy       string    `vstore:"created_by"`
+	CreatedByName   string    `vstore:"created_by_name"`
+	IsAnonymous     bool      `vstore:"is_anonymous"`
+	Created         time.Time `vstore:"created"`
+	Updated         time.Time `vstore:"updated"`
+}
+
+// Reaction is one user's emoji reaction to a retrospective item. Reactions
+// don't count as votes; a user reacts to an item with each emoji at most once.
+type Reaction struct {
+	RetrospectiveID string    `vstore:"retrospective_id"`
+	ItemID          string    `vstore:"item_id"`
+	UserID          string    `vstore:"user_id"`
+	Emoji           string    `vstore:"emoji"`
+	Created         time.Time `vstore:"created"`
+}
@@ -0,0 +1,209 @@
+package vstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	vstorepb "github.com/vendasta/generated-protos-go/vstore/v1"
+)
+
+// RemoteClient talks to a vstore server over gRPC through the VStore service
+// of vendastaapis' vstore/v1 protos, as generated into generated-protos-go:
+// CreateKind, Get, Put with a version condition, Delete and Lookup. The README
+// lists the fields it relies on; remote_test.go runs it against a fake of
+// that service.
+type RemoteClient struct {
+	conn   *grpc.ClientConn
+	client vstorepb.VStoreClient
+}
+
+// Dial connects to the vstore server at endpoint
+func Dial(ctx context.Context, endpoint string) (*RemoteClient, error) {
+	conn, err := grpc.DialContext(ctx, endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("vstore: dial %s: %w", endpoint, err)
+	}
+	return &RemoteClient{
+		conn:   conn,
+		client: vstorepb.NewVStoreClient(conn),
+	}, nil
+}
+
+// Close closes the underlying connection
+func (c *RemoteClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *RemoteClient) RegisterSchemas(ctx context.Context, schemas []map[string]interface{}) error {
+	for _, schema := range schemas {
+		kind, _ := schema["name"].(string)
+		req := &vstorepb.CreateKindRequest{
+			Namespace:  Namespace,
+			Kind:       kind,
+			PrimaryKey: KeyParts(schema),
+		}
+		indexes, _ := schema["indexes"].([]map[string]interface{})
+		for _, index := range indexes {
+			name, _ := index["name"].(string)
+			fields, _ := index["fields"].([]string)
+			req.Indexes = append(req.Indexes, &vstorepb.Index{Name: name, Fields: fields})
+		}
+		if ttl := TTL(schema); ttl > 0 {
+			req.Ttl = durationpb.New(ttl)
+		}
+		if _, err := c.client.CreateKind(ctx, req); err != nil && status.Code(err) != codes.AlreadyExists {
+			return fmt.Errorf("vstore: register %s: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+func (c *RemoteClient) Get(ctx context.Context, kind string, key []string, dst interface{}) error {
+	resp, err := c.client.Get(ctx, &vstorepb.GetRequest{
+		KeySet: &vstorepb.KeySet{Namespace: Namespace, Kind: kind, Keys: key},
+	})
+	if err != nil {
+		return fromGRPCError(err)
+	}
+	return DecodeProperties(resp.Entity.Values.AsMap(), dst)
+}
+
+func (c *RemoteClient) Put(ctx context.Context, kind string, src interface{}) error {
+	props, err := EncodeProperties(src)
+	if err != nil {
+		return err
+	}
+	values, err := structpb.NewStruct(toWireValues(props).(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Put(ctx, &vstorepb.PutRequest{
+		Entity: &vstorepb.Entity{Namespace: Namespace, Kind: kind, Values: values},
+	})
+	return fromGRPCError(err)
+}
+
+func (c *RemoteClient) Delete(ctx context.Context, kind string, key []string) error {
+	_, err := c.client.Delete(ctx, &vstorepb.DeleteRequest{
+		KeySet: &vstorepb.KeySet{Namespace: Namespace, Kind: kind, Keys: key},
+	})
+	return fromGRPCError(err)
+}
+
+func (c *RemoteClient) Transaction(ctx context.Context, kind string, key []string, dst interface{}, fn func(exists bool) error) error {
+	for attempt := 0; attempt < transactionAttempts; attempt++ {
+		if err := resetModel(dst); err != nil {
+			return err
+		}
+		// Version zero makes the write conditional on the entity still not existing
+		var version int64
+		resp, err := c.client.Get(ctx, &vstorepb.GetRequest{
+			KeySet: &vstorepb.KeySet{Namespace: Namespace, Kind: kind, Keys: key},
+		})
+		exists := err == nil
+		if exists {
+			version = resp.Entity.Version
+			if err := DecodeProperties(resp.Entity.Values.AsMap(), dst); err != nil {
+				return err
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return fromGRPCError(err)
+		}
+
+		if err := fn(exists); err != nil {
+			return err
+		}
+
+		props, err := EncodeProperties(dst)
+		if err != nil {
+			return err
+		}
+		values, err := structpb.NewStruct(toWireValues(props).(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		_, err = c.client.Put(ctx, &vstorepb.PutRequest{
+			Entity:    &vstorepb.Entity{Namespace: Namespace, Kind: kind, Values: values},
+			Condition: &vstorepb.PutCondition{Version: version},
+		})
+		if status.Code(err) == codes.Aborted {
+			continue
+		}
+		return fromGRPCError(err)
+	}
+	return ErrConflict
+}
+
+func (c *RemoteClient) Query(ctx context.Context, q *Query) (*QueryResult, error) {
+	req := &vstorepb.LookupRequest{
+		Namespace:  Namespace,
+		Kind:       q.Kind,
+		KeyPrefix:  q.KeyPrefix,
+		OrderBy:    q.OrderBy,
+		Descending: q.Descending,
+		Cursor:     q.Cursor,
+		PageSize:   int64(q.PageSize),
+	}
+	for _, f := range q.Filters {
+		value, err := structpb.NewValue(toWireValues(normalizeValue(f.Value)))
+		if err != nil {
+			return nil, err
+		}
+		req.Filters = append(req.Filters, &vstorepb.LookupFilter{Field: f.Field, Value: value})
+	}
+	resp, err := c.client.Lookup(ctx, req)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	result := &QueryResult{
+		NextCursor: resp.NextCursor,
+		HasMore:    resp.HasMore,
+	}
+	for _, entity := range resp.Entities {
+		result.Entities = append(result.Entities, &Entity{
+			Key:        entity.Keys,
+			Properties: entity.Values.AsMap(),
+		})
+	}
+	return result, nil
+}
+
+// toWireValues converts encoded properties into values structpb accepts
+func toWireValues(v interface{}) interface{} {
+	switch value := v.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for k, elem := range value {
+			converted[k] = toWireValues(elem)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, elem := range value {
+			converted[i] = toWireValues(elem)
+		}
+		return converted
+	default:
+		return v
+	}
+}
+
+func fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
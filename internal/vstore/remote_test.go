@@ -0,0 +1,337 @@
+package vstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	vstorepb "github.com/vendasta/generated-protos-go/vstore/v1"
+)
+
+// fakeVStoreServer stands in for the vstore service behind the generated
+// client. Like the server it versions entities, rejects conditional writes
+// against a stale version with Aborted and pages lookups.
+type fakeVStoreServer struct {
+	mu        sync.Mutex
+	kinds     map[string]*vstorepb.CreateKindRequest
+	entities  map[string]map[string]*vstorepb.Entity // key: kind, then joined key
+	beforePut func()                                 // run once before the next Put, as a concurrent writer
+}
+
+func newFakeRemoteClient() (*RemoteClient, *fakeVStoreServer) {
+	server := &fakeVStoreServer{
+		kinds:    make(map[string]*vstorepb.CreateKindRequest),
+		entities: make(map[string]map[string]*vstorepb.Entity),
+	}
+	return &RemoteClient{client: server}, server
+}
+
+func (f *fakeVStoreServer) CreateKind(ctx context.Context, in *vstorepb.CreateKindRequest, opts ...grpc.CallOption) (*vstorepb.CreateKindResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.kinds[in.Kind]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "kind %s exists", in.Kind)
+	}
+	f.kinds[in.Kind] = in
+	f.entities[in.Kind] = make(map[string]*vstorepb.Entity)
+	return &vstorepb.CreateKindResponse{}, nil
+}
+
+func (f *fakeVStoreServer) Get(ctx context.Context, in *vstorepb.GetRequest, opts ...grpc.CallOption) (*vstorepb.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entity, ok := f.entities[in.KeySet.Kind][strings.Join(in.KeySet.Keys, "/")]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no entity")
+	}
+	return &vstorepb.GetResponse{Entity: entity}, nil
+}
+
+func (f *fakeVStoreServer) Put(ctx context.Context, in *vstorepb.PutRequest, opts ...grpc.CallOption) (*vstorepb.PutResponse, error) {
+	f.mu.Lock()
+	hook := f.beforePut
+	f.beforePut = nil
+	f.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind, ok := f.kinds[in.Entity.Kind]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown kind %s", in.Entity.Kind)
+	}
+	var keys []string
+	for _, part := range kind.PrimaryKey {
+		value := in.Entity.Values.Fields[part].GetStringValue()
+		if value == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "key part %s is empty", part)
+		}
+		keys = append(keys, value)
+	}
+	key := strings.Join(keys, "/")
+	var version int64
+	if current, ok := f.entities[kind.Kind][key]; ok {
+		version = current.Version
+	}
+	if in.Condition != nil && in.Condition.Version != version {
+		return nil, status.Error(codes.Aborted, "version mismatch")
+	}
+	f.entities[kind.Kind][key] = &vstorepb.Entity{
+		Namespace: in.Entity.Namespace,
+		Kind:      kind.Kind,
+		Keys:      keys,
+		Values:    in.Entity.Values,
+		Version:   version + 1,
+	}
+	return &vstorepb.PutResponse{}, nil
+}
+
+func (f *fakeVStoreServer) Delete(ctx context.Context, in *vstorepb.DeleteRequest, opts ...grpc.CallOption) (*vstorepb.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entities[in.KeySet.Kind], strings.Join(in.KeySet.Keys, "/"))
+	return &vstorepb.DeleteResponse{}, nil
+}
+
+func (f *fakeVStoreServer) Lookup(ctx context.Context, in *vstorepb.LookupRequest, opts ...grpc.CallOption) (*vstorepb.LookupResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind, ok := f.kinds[in.Kind]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown kind %s", in.Kind)
+	}
+
+	var matches []*vstorepb.Entity
+	for _, entity := range f.entities[in.Kind] {
+		if !hasPrefix(entity.Keys, in.KeyPrefix) {
+			continue
+		}
+		matched := true
+		for _, filter := range in.Filters {
+			if !proto.Equal(entity.Values.Fields[filter.Field], filter.Value) {
+				matched = false
+			}
+		}
+		if matched {
+			matches = append(matches, entity)
+		}
+	}
+
+	var field string
+	for _, index := range kind.Indexes {
+		if index.Name == in.OrderBy {
+			field = index.Fields[0]
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		c := 0
+		if field != "" {
+			c = compareValues(matches[i].Values.Fields[field].AsInterface(), matches[j].Values.Fields[field].AsInterface())
+		}
+		if c == 0 {
+			c = strings.Compare(strings.Join(matches[i].Keys, "/"), strings.Join(matches[j].Keys, "/"))
+		}
+		if in.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	offset := 0
+	if in.Cursor != "" {
+		offset, _ = strconv.Atoi(in.Cursor)
+	}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+	resp := &vstorepb.LookupResponse{}
+	if in.PageSize > 0 && int64(len(matches)) > in.PageSize {
+		matches = matches[:in.PageSize]
+		resp.NextCursor = strconv.Itoa(offset + int(in.PageSize))
+		resp.HasMore = true
+	}
+	resp.Entities = matches
+	return resp, nil
+}
+
+func TestRemoteClientRegisterSchemas(t *testing.T) {
+	client, server := newFakeRemoteClient()
+	ctx := context.Background()
+	// Registering again on every start finds the kinds already there
+	for i := 0; i < 2; i++ {
+		if err := client.RegisterSchemas(ctx, AllSchemas()); err != nil {
+			t.Fatalf("RegisterSchemas: %v", err)
+		}
+	}
+
+	participant := server.kinds["Participant"]
+	if participant == nil || participant.Namespace != Namespace {
+		t.Fatalf("Participant kind = %+v", participant)
+	}
+	if participant.Ttl == nil || participant.Ttl.AsDuration() != TTL(ParticipantSchema()) {
+		t.Errorf("Participant TTL = %v, want %v", participant.Ttl, TTL(ParticipantSchema()))
+	}
+	item := server.kinds["RetrospectiveItem"]
+	if strings.Join(item.PrimaryKey, ",") != "retrospective_id,item_id" {
+		t.Errorf("RetrospectiveItem key = %v", item.PrimaryKey)
+	}
+	indexed := false
+	for _, index := range item.Indexes {
+		indexed = indexed || (index.Name == "by_vote_count" && index.Fields[0] == "vote_count")
+	}
+	if !indexed {
+		t.Errorf("RetrospectiveItem indexes = %v, want by_vote_count", item.Indexes)
+	}
+}
+
+func TestRemoteClientPutGetDelete(t *testing.T) {
+	client, _ := newFakeRemoteClient()
+	ctx := context.Background()
+	if err := client.RegisterSchemas(ctx, AllSchemas()); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)
+	retro := &Retrospective{
+		RetrospectiveID: "retro-1",
+		TeamID:          "team-1",
+		SprintName:      "Sprint 1",
+		Status:          RetrospectiveStatusActive,
+		TemplateColumns: []*TemplateColumn{{ColumnID: "went_well", Name: "What Went Well", SortOrder: 1}},
+		Created:         created,
+	}
+	if err := client.Put(ctx, "Retrospective", retro); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	key := []string{"team-1", "retro-1"}
+	got := &Retrospective{}
+	if err := client.Get(ctx, "Retrospective", key, got); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.SprintName != retro.SprintName || got.Status != retro.Status || !got.Created.Equal(created) {
+		t.Errorf("Get = %+v, want %+v", got, retro)
+	}
+	if len(got.TemplateColumns) != 1 || got.TemplateColumns[0].Name != "What Went Well" || got.TemplateColumns[0].SortOrder != 1 {
+		t.Errorf("TemplateColumns = %+v", got.TemplateColumns)
+	}
+
+	if err := client.Delete(ctx, "Retrospective", key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := client.Get(ctx, "Retrospective", key, got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRemoteClientTransactionRetriesOnConflict(t *testing.T) {
+	client, server := newFakeRemoteClient()
+	ctx := context.Background()
+	if err := client.RegisterSchemas(ctx, AllSchemas()); err != nil {
+		t.Fatal(err)
+	}
+
+	key := []string{"team-1", "retro-1"}
+	retro := &Retrospective{}
+	err := client.Transaction(ctx, "Retrospective", key, retro, func(exists bool) error {
+		if exists {
+			t.Error("new entity reported as existing")
+		}
+		retro.TeamID, retro.RetrospectiveID, retro.SprintName = "team-1", "retro-1", "Sprint"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("creating Transaction: %v", err)
+	}
+
+	// Another writer renames the retro between the read and the write
+	server.beforePut = func() {
+		client.Put(ctx, "Retrospective", &Retrospective{TeamID: "team-1", RetrospectiveID: "retro-1", SprintName: "Renamed"})
+	}
+	attempts := 0
+	err = client.Transaction(ctx, "Retrospective", key, retro, func(exists bool) error {
+		attempts++
+		retro.SprintName += " 1"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("fn ran %d times, want 2", attempts)
+	}
+	got := &Retrospective{}
+	if err := client.Get(ctx, "Retrospective", key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SprintName != "Renamed 1" {
+		t.Errorf("SprintName = %q, want the retried write on top of the other writer's", got.SprintName)
+	}
+}
+
+func TestRemoteClientQuery(t *testing.T) {
+	client, _ := newFakeRemoteClient()
+	ctx := context.Background()
+	if err := client.RegisterSchemas(ctx, AllSchemas()); err != nil {
+		t.Fatal(err)
+	}
+	items := []*RetrospectiveItem{
+		{RetrospectiveID: "retro-1", ItemID: "item-1", ColumnID: "went_well", VoteCount: 2},
+		{RetrospectiveID: "retro-1", ItemID: "item-2", ColumnID: "went_well", VoteCount: 5},
+		{RetrospectiveID: "retro-1", ItemID: "item-3", ColumnID: "to_improve", VoteCount: 1},
+		{RetrospectiveID: "retro-2", ItemID: "item-4", ColumnID: "went_well", VoteCount: 9},
+	}
+	for _, item := range items {
+		if err := client.Put(ctx, "RetrospectiveItem", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := &Query{Kind: "RetrospectiveItem", KeyPrefix: []string{"retro-1"}, OrderBy: "by_vote_count", Descending: true, PageSize: 2}
+	var votes []int32
+	for {
+		page, err := client.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		for _, entity := range page.Entities {
+			item := &RetrospectiveItem{}
+			if err := entity.Decode(item); err != nil {
+				t.Fatal(err)
+			}
+			votes = append(votes, item.VoteCount)
+		}
+		if !page.HasMore {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if len(votes) != 3 || votes[0] != 5 || votes[1] != 2 || votes[2] != 1 {
+		t.Errorf("vote counts by page = %v, want [5 2 1]", votes)
+	}
+
+	page, err := client.Query(ctx, &Query{
+		Kind:      "RetrospectiveItem",
+		KeyPrefix: []string{"retro-1"},
+		Filters:   []Filter{{Field: "column_id", Value: "went_well"}},
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page.Entities) != 2 {
+		t.Errorf("filtered Query returned %d entities, want 2", len(page.Entities))
+	}
+}
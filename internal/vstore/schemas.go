@@ -54,11 +54,12 @@ func RetrospectiveItemSchema() map[string]interface{} {
 }
 
 // VoteSchema returns the vstore schema for Vote
-// Key: retrospective_id + item_id + user_id (ensures one vote per user per item)
+// Key: retrospective_id + item_id + user_id + vote_id (a user may vote on an item
+// more than once when AllowMultipleVotesPerItem is set)
 func VoteSchema() map[string]interface{} {
 	return map[string]interface{}{
 		"name":        "Vote",
-		"key_parts":   []string{"retrospective_id", "item_id", "user_id"},
+		"key_parts":   []string{"retrospective_id", "item_id", "user_id", "vote_id"},
 		"backup":      "daily",
 		"description": "Votes cast on retrospective items",
 		"indexes": []map[string]interface{}{
@@ -74,6 +75,17 @@ func VoteSchema() map[string]interface{} {
 	}
 }
 
+// VoteBallotSchema returns the vstore schema for VoteBallot
+// Key: retrospective_id + user_id (one ballot per voter)
+func VoteBallotSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "VoteBallot",
+		"key_parts":   []string{"retrospective_id", "user_id"},
+		"backup":      "daily",
+		"description": "Per-user copy of a voter's votes used to enforce vote limits",
+	}
+}
+
 // ActionItemSchema returns the vstore schema for ActionItem
 // Key: team_id + action_item_id (allows listing by team across retros)
 func ActionItemSchema() map[string]interface{} {
@@ -125,13 +137,114 @@ func ParticipantSchema() map[string]interface{} {
 	}
 }
 
+// TeamSchema returns the vstore schema for Team
+// Key: team_id
+func TeamSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "Team",
+		"key_parts":   []string{"team_id"},
+		"backup":      "daily",
+		"description": "Teams that own retrospectives and action items",
+		"indexes": []map[string]interface{}{
+			{
+				"name":   "by_name",
+				"fields": []string{"name"},
+			},
+		},
+	}
+}
+
+// TeamMemberSchema returns the vstore schema for TeamMember
+// Key: team_id + user_id (allows listing members by team)
+func TeamMemberSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "TeamMember",
+		"key_parts":   []string{"team_id", "user_id"},
+		"backup":      "daily",
+		"description": "Team memberships and roles",
+		"indexes": []map[string]interface{}{
+			{
+				"name":   "by_user",
+				"fields": []string{"user_id"},
+			},
+			{
+				"name":   "by_role",
+				"fields": []string{"role"},
+			},
+		},
+	}
+}
+
+// PhaseTimerSchema returns the vstore schema for PhaseTimer
+// Key: retrospective_id (one timer per retrospective)
+func PhaseTimerSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "PhaseTimer",
+		"key_parts":   []string{"retrospective_id"},
+		"backup":      "daily",
+		"description": "Countdown timers of running retrospective phases",
+	}
+}
+
+// ItemGroupSchema returns the vstore schema for ItemGroup
+// Key: retrospective_id + group_id (allows listing groups by retro)
+func ItemGroupSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "ItemGroup",
+		"key_parts":   []string{"retrospective_id", "group_id"},
+		"backup":      "daily",
+		"description": "Groups of related items on a retrospective board",
+		"indexes": []map[string]interface{}{
+			{
+				"name":   "by_column",
+				"fields": []string{"column_id"},
+			},
+		},
+	}
+}
+
+// CommentSchema returns the vstore schema for Comment
+// Key: retrospective_id + comment_id (allows listing comments by retro)
+func CommentSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "Comment",
+		"key_parts":   []string{"retrospective_id", "comment_id"},
+		"backup":      "daily",
+		"description": "Threaded comments on retrospective items",
+		"indexes": []map[string]interface{}{
+			{
+				"name":   "by_item",
+				"fields": []string{"item_id"},
+			},
+		},
+	}
+}
+
+// ReactionSchema returns the vstore schema for Reaction
+// Key: retrospective_id + item_id + user_id + emoji (one reaction per user and emoji)
+func ReactionSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "Reaction",
+		"key_parts":   []string{"retrospective_id", "item_id", "user_id", "emoji"},
+		"backup":      "daily",
+		"description": "Emoji reactions on retrospective items",
+	}
+}
+
 // AllSchemas returns all vstore schemas for the retrospective service
 func AllSchemas() []map[string]interface{} {
 	return []map[string]interface{}{
 		RetrospectiveSchema(),
 		RetrospectiveItemSchema(),
 		VoteSchema(),
+		VoteBallotSchema(),
 		ActionItemSchema(),
 		ParticipantSchema(),
+		TeamSchema(),
+		TeamMemberSchema(),
+		PhaseTimerSchema(),
+		ItemGroupSchema(),
+		CommentSchema(),
+		ReactionSchema(),
 	}
 }
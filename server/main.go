@@ -15,6 +15,8 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/vendasta/retrospective/internal/api"
+	"github.com/vendasta/retrospective/internal/auth"
+	"github.com/vendasta/retrospective/internal/vstore"
 	pb "github.com/vendasta/generated-protos-go/retrospective/v1"
 )
 
@@ -27,31 +29,79 @@ func main() {
 	if port == "" {
 		port = defaultPort
 	}
+	// DB_PATH selects the embedded file-backed store for single-node deployments
+	dbPath := os.Getenv("DB_PATH")
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	// Create gRPC server with interceptors
+	// Bearer tokens are verified against AUTH_JWKS_FILE or AUTH_HMAC_SECRET
+	verifier, err := auth.NewVerifier(auth.Config{
+		JWKSFile:   os.Getenv("AUTH_JWKS_FILE"),
+		HMACSecret: os.Getenv("AUTH_HMAC_SECRET"),
+		Issuer:     os.Getenv("AUTH_ISSUER"),
+		Audience:   os.Getenv("AUTH_AUDIENCE"),
+	})
+	if err != nil {
+		log.Fatalf("failed to configure authentication: %v", err)
+	}
+
+	// AUTH_SCOPE_MODE is enforce (default), audit or disabled; set per environment in microservice.yaml
+	scopeMode, err := api.ParseScopeMode(os.Getenv("AUTH_SCOPE_MODE"))
+	if err != nil {
+		log.Fatalf("failed to configure authorization: %v", err)
+	}
+
+	// Create gRPC server with interceptors: authenticate first, then check scopes
 	grpcServer := grpc.NewServer(
-		// Add your interceptors here (auth, logging, etc.)
+		grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor(verifier), api.ScopeUnaryInterceptor(scopeMode)),
+		grpc.ChainStreamInterceptor(auth.StreamServerInterceptor(verifier), api.ScopeStreamInterceptor(scopeMode)),
 	)
 
-	// Initialize stores (in production, these would be backed by vstore)
-	retroStore := api.NewInMemoryRetrospectiveStore()
-	itemStore := api.NewInMemoryItemStore()
-	voteStore := api.NewInMemoryVoteStore()
-	actionItemStore := api.NewInMemoryActionItemStore()
-	participantStore := api.NewInMemoryParticipantStore()
+	// Initialize stores: vstore when VSTORE_ENDPOINT is set, the embedded
+	// database when DB_PATH is set, in-memory otherwise (dev)
+	var stores api.Stores
+	if vstoreEndpoint := os.Getenv("VSTORE_ENDPOINT"); vstoreEndpoint != "" {
+		client, err := vstore.Dial(context.Background(), vstoreEndpoint)
+		if err != nil {
+			log.Fatalf("failed to connect to vstore: %v", err)
+		}
+		defer client.Close()
+		if err := client.RegisterSchemas(context.Background(), vstore.AllSchemas()); err != nil {
+			log.Fatalf("failed to register vstore schemas: %v", err)
+		}
+		stores = api.NewVStoreStores(client)
+		log.Printf("Using vstore at %s", vstoreEndpoint)
+	} else if dbPath != "" {
+		db, err := api.OpenBoltDB(dbPath)
+		if err != nil {
+			log.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+		stores = api.NewBoltStores(db)
+		log.Printf("Using embedded database at %s", dbPath)
+	} else {
+		stores = api.NewInMemoryStores()
+		log.Println("VSTORE_ENDPOINT and DB_PATH not set, using in-memory stores")
+	}
+
+	// Phase timers survive restarts when the stores are persistent
+	timers := api.NewPhaseTimers(stores.PhaseTimers, stores.Retrospectives, stores.Items)
+	if err := timers.Restore(); err != nil {
+		log.Fatalf("failed to restore phase timers: %v", err)
+	}
+	defer timers.Stop()
 
 	// Initialize and register services
-	retrospectiveService := api.NewRetrospectiveService(retroStore, itemStore, actionItemStore)
-	itemService := api.NewRetrospectiveItemService(itemStore, retroStore)
-	votingService := api.NewVotingService(voteStore, itemStore, retroStore)
-	actionItemService := api.NewActionItemService(actionItemStore, retroStore)
-	realtimeService := api.NewRealtimeService(participantStore, retroStore)
+	retrospectiveService := api.NewRetrospectiveService(stores.Retrospectives, stores.Items, stores.ItemGroups, stores.Comments, stores.Votes, stores.ActionItems, stores.Participants, stores.Teams, timers)
+	itemService := api.NewRetrospectiveItemService(stores.Items, stores.ItemGroups, stores.Comments, stores.Reactions, stores.Votes, stores.Retrospectives, stores.Participants, stores.Teams)
+	votingService := api.NewVotingService(stores.Votes, stores.Items, stores.ItemGroups, stores.Retrospectives, stores.Participants, stores.Teams)
+	actionItemService := api.NewActionItemService(stores.ActionItems, stores.Retrospectives, stores.Teams)
+	realtimeService := api.NewRealtimeService(stores.Participants, stores.Retrospectives, stores.Teams)
 	templateService := api.NewTemplateService()
+	teamService := api.NewTeamService(stores.Teams, stores.Retrospectives)
 
 	// Register services with gRPC server
 	pb.RegisterRetrospectiveServiceServer(grpcServer, retrospectiveService)
@@ -60,6 +110,7 @@ func main() {
 	pb.RegisterActionItemServiceServer(grpcServer, actionItemService)
 	pb.RegisterRealtimeServiceServer(grpcServer, realtimeService)
 	pb.RegisterTemplateServiceServer(grpcServer, templateService)
+	pb.RegisterTeamServiceServer(grpcServer, teamService)
 
 	// Register health check service
 	healthServer := health.NewServer()